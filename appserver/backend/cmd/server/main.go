@@ -1,17 +1,66 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 
+	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/config"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/container"
 	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/infra/routes"
 	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/http"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/trace"
 )
 
+/** go run cmd/server/main.go
+ * SIGINT / SIGTERM を受信すると GET /readyz を503に切り替え、処理中のリクエストの完了を待ってから停止する。
+ *
+ * 接続先やタイムアウトは config.Load で読み込む (環境変数の一覧は config.Load を参照)。
+ * 実際のAWSに接続する場合は APP_ENV=production とし、DATABASE_DSN などを環境変数か設定ファイルで指定する:
+ *   APP_ENV=production CONFIG_FILE=config.yml go run cmd/server/main.go
+ *
+ * 環境変数:
+ *   TRACE_EXPORTER  スパンの出力先 (stdout: 標準出力にJSONで出力, 未設定: 出力しない)
+ */
 func main() {
-	err := http.NewServer("localhost:8080", routes.NewRouter())
-	if err != nil {
+	if err := run(); err != nil {
 		fmt.Printf("failed to start server: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+func run() error {
+	if os.Getenv("TRACE_EXPORTER") == "stdout" {
+		trace.SetDefaultTracer(trace.NewTracer(trace.NewJSONExporter(os.Stdout), trace.WithServiceName("backend-api")))
+	}
+
+	cfg, err := config.Load(os.LookupEnv)
+	if err != nil {
+		return err
+	}
+
+	// データベースの接続プールなどは起動時に一度だけ生成し、サーバーの停止後に逆順に解放する
+	// データベースが停止していても起動し、/readyz で503を返す
+	c, err := container.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize container: %w", err)
+	}
+	defer func() {
+		if err := c.Close(); err != nil {
+			fmt.Printf("failed to close container: %v\n", err)
+		}
+	}()
+
+	server := http.NewServer(routes.NewRouter(c),
+		http.WithAddr(cfg.Server.Addr),
+		http.WithReadTimeout(cfg.Server.ReadTimeout),
+		http.WithReadHeaderTimeout(cfg.Server.ReadHeaderTimeout),
+		http.WithWriteTimeout(cfg.Server.WriteTimeout),
+		http.WithIdleTimeout(cfg.Server.IdleTimeout),
+		http.WithShutdownTimeout(cfg.Server.ShutdownTimeout),
+		// ロードバランサーが /readyz の失敗を検知して振り分け先から外すまで待つ
+		http.WithDrainDelay(cfg.Server.DrainDelay),
+		http.WithReadinessCheck("database", c.DB().PingContext),
+	)
+	return server.Start(context.Background())
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker"
+)
+
+const (
+	// dlqListVisibilityTimeout は一覧取得中にメッセージを隠しておく時間(秒)
+	// 同じメッセージを重複して受信しないようにするためで、経過後は再び参照できる
+	dlqListVisibilityTimeout = 10
+	// dlqRedriveVisibilityTimeout は再投入処理中にメッセージを隠しておく時間(秒)
+	dlqRedriveVisibilityTimeout = 30
+)
+
+const dlqUsage = `usage: go run cmd/worker/main.go dlq <command>
+
+commands:
+  list                         DLQのメッセージを一覧表示する
+  inspect <message-id>         メッセージの詳細(エラーチェーン、元の本文)を表示する
+  redrive <message-id>...      指定したメッセージをメインキューに再投入する
+  redrive -all                 すべてのメッセージをメインキューに再投入する`
+
+// dlqMessage はDLQから受信したメッセージです。
+// ワーカーが送信したデッドレターレコードと、SQSのリドライブポリシーで移動された元のメッセージの両方を扱う。
+type dlqMessage struct {
+	message types.Message
+	// deadLetter はデッドレターレコードの場合のみ設定される
+	deadLetter *worker.DeadLetter
+}
+
+func newDLQMessage(msg types.Message) dlqMessage {
+	d, err := worker.DecodeDeadLetter(aws.ToString(msg.Body))
+	if err != nil {
+		return dlqMessage{message: msg}
+	}
+	return dlqMessage{message: msg, deadLetter: d}
+}
+
+func (m dlqMessage) id() string {
+	return aws.ToString(m.message.MessageId)
+}
+
+// originalBody はメインキューに再投入する本文を返す
+func (m dlqMessage) originalBody() string {
+	if m.deadLetter != nil {
+		return m.deadLetter.Body
+	}
+	return aws.ToString(m.message.Body)
+}
+
+// redriveOptions は再投入時の送信オプションを返す
+// FIFOキューには元のメッセージグループID (なければDLQのメッセージID) で送信し、DLQのメッセージIDで重複を排除する
+func (m dlqMessage) redriveOptions(queueURL, body string) []sqs.SendMessageOptionFunc {
+	options := []sqs.SendMessageOptionFunc{sqs.WithMessageBody(body)}
+	if !sqs.IsFIFOQueue(queueURL) {
+		return options
+	}
+	groupID := m.id()
+	if m.deadLetter != nil && m.deadLetter.MessageGroupID != "" {
+		groupID = m.deadLetter.MessageGroupID
+	}
+	return append(options, sqs.WithMessageGroupID(groupID), sqs.WithMessageDeduplicationID(m.id()))
+}
+
+// runDLQ はDLQ操作のサブコマンドを実行する
+func runDLQ(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Println(dlqUsage)
+		return errors.New("dlq command is required")
+	}
+
+	client, err := newSQSClient(ctx)
+	if err != nil {
+		return err
+	}
+	dlqName := getEnv("SQS_DLQ_NAME", "worker-queue-dlq")
+	dlqURL, err := client.GetQueueURL(ctx, dlqName)
+	if err != nil {
+		return fmt.Errorf("failed to get queue url (queueName=%s): %w", dlqName, err)
+	}
+
+	switch args[0] {
+	case "list":
+		return listDLQ(ctx, client, dlqURL)
+	case "inspect":
+		if len(args) != 2 {
+			fmt.Println(dlqUsage)
+			return errors.New("inspect requires a message id")
+		}
+		return inspectDLQ(ctx, client, dlqURL, args[1])
+	case "redrive":
+		fs := flag.NewFlagSet("redrive", flag.ContinueOnError)
+		all := fs.Bool("all", false, "すべてのメッセージを再投入する")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if !*all && fs.NArg() == 0 {
+			fmt.Println(dlqUsage)
+			return errors.New("redrive requires message ids or -all")
+		}
+		queueName := getEnv("SQS_QUEUE_NAME", "worker-queue")
+		queueURL, err := client.GetQueueURL(ctx, queueName)
+		if err != nil {
+			return fmt.Errorf("failed to get queue url (queueName=%s): %w", queueName, err)
+		}
+		return redriveDLQ(ctx, client, dlqURL, queueURL, *all, fs.Args())
+	default:
+		fmt.Println(dlqUsage)
+		return fmt.Errorf("unknown dlq command: %s", args[0])
+	}
+}
+
+// scanDLQ はDLQのメッセージを一巡するまで受信し、1件ずつ fn に渡す
+// 受信したメッセージは visibilityTimeout の間隠れるため、新しいメッセージが返らなくなった時点で一巡したとみなす
+// fn が false を返した場合は走査を打ち切る
+func scanDLQ(ctx context.Context, client sqs.SQS, dlqURL string, visibilityTimeout int32, fn func(m dlqMessage) (bool, error)) error {
+	seen := make(map[string]struct{})
+	for {
+		messages, err := client.ReceiveMessages(ctx, dlqURL,
+			sqs.WithMaxMessages(10),
+			sqs.WithWaitTimeSeconds(1),
+			sqs.WithVisibilityTimeout(visibilityTimeout),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to receive messages (queueURL=%s): %w", dlqURL, err)
+		}
+
+		found := false
+		for _, msg := range messages {
+			m := newDLQMessage(msg)
+			if _, ok := seen[m.id()]; ok {
+				continue
+			}
+			seen[m.id()] = struct{}{}
+			found = true
+
+			next, err := fn(m)
+			if err != nil {
+				return err
+			}
+			if !next {
+				return nil
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+}
+
+func listDLQ(ctx context.Context, client sqs.SQS, dlqURL string) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MESSAGE_ID\tTASK_TYPE\tATTEMPTS\tFAILED_AT\tERROR")
+	count := 0
+	err := scanDLQ(ctx, client, dlqURL, dlqListVisibilityTimeout, func(m dlqMessage) (bool, error) {
+		count++
+		if m.deadLetter == nil {
+			// リドライブポリシーで移動されたメッセージはエラー情報を持たない
+			fmt.Fprintf(tw, "%s\t-\t-\t-\t(moved by redrive policy)\n", m.id())
+			return true, nil
+		}
+		d := m.deadLetter
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", m.id(), d.TaskType, d.Attempts, d.FailedAt.Format(time.RFC3339), d.Error)
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("%d message(s)\n", count)
+	return nil
+}
+
+func inspectDLQ(ctx context.Context, client sqs.SQS, dlqURL, messageID string) error {
+	var found *dlqMessage
+	err := scanDLQ(ctx, client, dlqURL, dlqListVisibilityTimeout, func(m dlqMessage) (bool, error) {
+		if m.id() != messageID {
+			return true, nil
+		}
+		found = &m
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+	if found == nil {
+		return fmt.Errorf("message not found (messageID=%s)", messageID)
+	}
+
+	fmt.Printf("MessageId: %s\n", found.id())
+	if found.deadLetter == nil {
+		fmt.Println("Source:    redrive policy (no error details)")
+		fmt.Printf("Body:\n%s\n", prettyJSON(found.originalBody()))
+		return nil
+	}
+	d := found.deadLetter
+	fmt.Printf("DeadLetterID: %s\n", d.ID)
+	fmt.Printf("Worker:       %s\n", d.Worker)
+	fmt.Printf("TaskType:     %s\n", d.TaskType)
+	fmt.Printf("Attempts:     %d\n", d.Attempts)
+	fmt.Printf("Permanent:    %v\n", d.Permanent)
+	fmt.Printf("FailedAt:     %s\n", d.FailedAt.Format(time.RFC3339))
+	fmt.Println("ErrorChain:")
+	for i, msg := range d.ErrorChain {
+		fmt.Printf("  %d: %s\n", i, msg)
+	}
+	if d.Stack != "" {
+		fmt.Printf("Stack:\n%s\n", d.Stack)
+	}
+	fmt.Printf("Body:\n%s\n", prettyJSON(d.Body))
+	return nil
+}
+
+func redriveDLQ(ctx context.Context, client sqs.SQS, dlqURL, queueURL string, all bool, messageIDs []string) error {
+	redriven, skipped := 0, 0
+	err := scanDLQ(ctx, client, dlqURL, dlqRedriveVisibilityTimeout, func(m dlqMessage) (bool, error) {
+		if !all && !slices.Contains(messageIDs, m.id()) {
+			return true, nil
+		}
+
+		body := m.originalBody()
+		if body == "" {
+			// 元の本文を持たないタスクは再投入できない
+			fmt.Printf("skipped %s: original body is not available\n", m.id())
+			skipped++
+			return true, nil
+		}
+		if _, err := client.SendMessage(ctx, queueURL, m.redriveOptions(queueURL, body)...); err != nil {
+			return false, fmt.Errorf("failed to redrive message (messageID=%s): %w", m.id(), err)
+		}
+		// 送信後に削除するため、削除に失敗した場合は重複して再投入される可能性がある
+		if err := client.DeleteMessage(ctx, dlqURL, sqs.WithReceiptHandle(aws.ToString(m.message.ReceiptHandle))); err != nil {
+			return false, fmt.Errorf("failed to delete redriven message (messageID=%s): %w", m.id(), err)
+		}
+		fmt.Printf("redriven %s\n", m.id())
+		redriven++
+		return all || redriven+skipped < len(messageIDs), nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d message(s) redriven, %d skipped\n", redriven, skipped)
+	if !all && redriven+skipped < len(messageIDs) {
+		return fmt.Errorf("%d message(s) not found", len(messageIDs)-redriven-skipped)
+	}
+	return nil
+}
+
+// prettyJSON はJSONであればインデントして返し、それ以外はそのまま返す
+func prettyJSON(body string) string {
+	var v any
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return body
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return body
+	}
+	return string(b)
+}
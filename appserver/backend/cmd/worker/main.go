@@ -2,36 +2,371 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	awssqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/metrics"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/trace"
 	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/idempotency"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/outbox"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/scheduler"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks"
 	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks/healthtask"
 )
 
+const (
+	// schedulerRunRetentionDays は定期実行の実行記録を保持する日数
+	schedulerRunRetentionDays = 7
+	// outboxRetentionDays は送信済みのアウトボックスのイベントを保持する日数
+	outboxRetentionDays = 7
+	// idempotencyTTL は処理済みのメッセージを重複として扱う期間
+	idempotencyTTL = 24 * time.Hour
+)
+
 /** go run cmd/worker/main.go
- * これはworkerを起動の確認するテストコマンドです。
- * 削除 or 変更予定 */
+ * SQS(LocalStack)の worker-queue をロングポーリングし、受信したメッセージをworkerで処理する。
+ * メッセージ本文は tasks.Envelope 形式で、type に登録済みのタスク種別を指定する。
+ * 事前に docker compose up -d localstack でキューを作成しておくこと。
+ *
+ * 環境変数:
+ *   AWS_ENDPOINT_URL        LocalStackエンドポイント (デフォルト: http://localhost:4566)
+ *   AWS_REGION              AWSリージョン (デフォルト: ap-northeast-1)
+ *   SQS_QUEUE_NAME          SQSキュー名 (デフォルト: worker-queue, ".fifo" で終わる場合はFIFOキューとして処理する)
+ *   SQS_MAX_MESSAGES        一度に受信する最大メッセージ数 (デフォルト: 10)
+ *   SQS_DLQ_NAME            デッドレターキュー名 (デフォルト: worker-queue-dlq)
+ *   SQS_VISIBILITY_TIMEOUT_SECONDS  受信したメッセージの可視性タイムアウト(秒) (デフォルト: 30)
+ *   SQS_HEARTBEAT_INTERVAL_SECONDS  実行中のタスクの可視性タイムアウトを延長する間隔(秒) (デフォルト: 10)
+ *   WORKER_RUNNING_WORKERS  同時処理ワーカー数の初期値 (デフォルト: 5)
+ *   WORKER_MIN_WORKERS      自動調整時の同時処理ワーカー数の下限 (デフォルト: 1)
+ *   WORKER_MAX_WORKERS      自動調整時の同時処理ワーカー数の上限 (デフォルト: 20)
+ *   WORKER_TASK_TIMEOUT_SECONDS  タスク1回の試行の制限時間(秒) (デフォルト: 25)
+ *   SCHEDULER_DB_DSN        定期実行の実行権を調停するMySQLのDSN (未設定の場合はプロセス内でのみ判定する)
+ *   OUTBOX_DB_DSN           アウトボックスを読み出すMySQLのDSN (未設定の場合はイベントを送信しない)
+ *   OUTBOX_QUEUE_NAME       アウトボックスのイベントの送信先キュー名 (デフォルト: user-sync-queue.fifo)
+ *   OUTBOX_RELAY_INTERVAL_SECONDS  アウトボックスのイベントを送信する間隔(秒) (デフォルト: 5)
+ *   IDEMPOTENCY_DB_DSN      処理済みのメッセージを記録するMySQLのDSN (未設定の場合はプロセス内でのみ重複を排除する)
+ *   METRICS_ADDR            メトリクスを公開するアドレス (デフォルト: :9090, GET /metrics でPrometheus形式で取得する)
+ *   TRACE_EXPORTER          スパンの出力先 (stdout: 標準出力にJSONで出力, 未設定: 出力しない)
+ *
+ * 定期実行するタスクは newScheduler で登録する。複数プロセスで起動する場合は SCHEDULER_DB_DSN を設定し、
+ * scheduler_runs テーブルで同じ実行時刻の二重実行を防ぐ。
+ *
+ * OUTBOX_DB_DSN を設定すると、ユーザーの作成・更新時に outbox テーブルへ書き込んだイベントを定期的にSQSに送信する。
+ *
+ * 再配信や重複送信されたメッセージは、処理済みであれば実行せずに削除する。複数プロセスで起動する場合は
+ * IDEMPOTENCY_DB_DSN を設定し、idempotency_keys テーブルで同じメッセージの同時実行を防ぐ。
+ *
+ * タスクの実行中は可視性タイムアウトを延長し続けるため、可視性タイムアウトより長いタスクも二重に実行されない。
+ *
+ * 再試行を使い切った、または恒久的なエラーで失敗したタスクはDLQに送信される。
+ * DLQの操作は dlq サブコマンドで行う:
+ *   go run cmd/worker/main.go dlq list
+ *   go run cmd/worker/main.go dlq inspect <message-id>
+ *   go run cmd/worker/main.go dlq redrive <message-id>... | -all */
 func main() {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if len(os.Args) > 1 && os.Args[1] == "dlq" {
+		if err := runDLQ(ctx, os.Args[2:]); err != nil {
+			fmt.Printf("failed to run dlq command: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	w := worker.NewWorker()
-	w.AddJob(healthtask.HealthTask)
-	if err := w.Run(ctx); err != nil {
+	if err := run(ctx); err != nil {
 		fmt.Printf("failed to run worker: %v\n", err)
 		os.Exit(1)
 	}
+}
+
+func run(ctx context.Context) error {
+	if getEnv("TRACE_EXPORTER", "") == "stdout" {
+		// メッセージ属性の traceparent を引き継ぎ、送信元と同じトレースとして出力する
+		trace.SetDefaultTracer(trace.NewTracer(trace.NewJSONExporter(os.Stdout), trace.WithServiceName("backend-worker")))
+	}
+	reg := metrics.NewRegistry()
+	client, err := newSQSClient(ctx)
+	if err != nil {
+		return err
+	}
+	client = sqs.NewInstrumentedClient(client, reg)
+	stopMetrics := serveMetrics(getEnv("METRICS_ADDR", ":9090"), reg)
+	defer stopMetrics()
 
-	fmt.Println("worker started")
-	fmt.Printf("Queue length: %d\n", w.QueueLength())
+	registry := tasks.NewRegistry()
+	if err := healthtask.Register(registry); err != nil {
+		return fmt.Errorf("failed to register tasks: %w", err)
+	}
+
+	queueName := getEnv("SQS_QUEUE_NAME", "worker-queue")
+	queueURL, err := client.GetQueueURL(ctx, queueName)
+	if err != nil {
+		return fmt.Errorf("failed to get queue url (queueName=%s): %w", queueName, err)
+	}
+	dlqName := getEnv("SQS_DLQ_NAME", "worker-queue-dlq")
+	dlqURL, err := client.GetQueueURL(ctx, dlqName)
+	if err != nil {
+		return fmt.Errorf("failed to get queue url (queueName=%s): %w", dlqName, err)
+	}
+
+	// タスクはシャットダウン中も完了まで実行させるため、シグナルとは独立したコンテキストで起動する
+	w := worker.NewWorker(
+		worker.WithName("sqs-worker"),
+		worker.WithRunningWorkers(getEnvInt("WORKER_RUNNING_WORKERS", 5)),
+		worker.WithMinWorkerJobs(getEnvInt("WORKER_MIN_WORKERS", 1)),
+		worker.WithMaxWorkerJobs(getEnvInt("WORKER_MAX_WORKERS", 20)),
+		worker.WithAutoscaling(worker.AutoscalePolicy{}),
+		worker.WithRetryPolicy(worker.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second}),
+		worker.WithDeadLetterSink(worker.NewSQSDeadLetterSink(client, dlqURL)),
+		worker.WithTaskTimeout(time.Duration(getEnvInt("WORKER_TASK_TIMEOUT_SECONDS", 25))*time.Second),
+		worker.WithMetrics(reg),
+	)
+	if err := w.Run(context.Background()); err != nil {
+		return fmt.Errorf("failed to start worker: %w", err)
+	}
+	if err := w.AddJob(healthtask.HealthTask); err != nil {
+		return fmt.Errorf("failed to add health check job: %w", err)
+	}
 
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	s, closeScheduler, err := newScheduler(ctx, w, client)
+	if err != nil {
+		return err
+	}
+	defer closeScheduler()
+	guard, closeGuard, err := newIdempotencyGuard(s)
+	if err != nil {
+		return err
+	}
+	defer closeGuard()
+	schedulerCtx, stopScheduler := context.WithCancel(ctx)
+	defer stopScheduler()
+	schedulerDone := make(chan error, 1)
+	go func() {
+		schedulerDone <- s.Run(schedulerCtx)
+	}()
+
+	pollerOpts := []worker.PollerOption{
+		worker.WithPollerMaxMessages(int32(getEnvInt("SQS_MAX_MESSAGES", 10))),
+		worker.WithVisibilityHeartbeat(
+			time.Duration(getEnvInt("SQS_VISIBILITY_TIMEOUT_SECONDS", 30))*time.Second,
+			time.Duration(getEnvInt("SQS_HEARTBEAT_INTERVAL_SECONDS", 10))*time.Second,
+		),
+		worker.WithPollerReceiveOptions(
+			sqs.WithApproximateReceiveCount(),
+			// FIFOキューの重複排除IDで重複を判定する (idempotency.MessageKey)
+			sqs.WithSystemAttributeNames(types.MessageSystemAttributeNameMessageDeduplicationId),
+		),
+	}
+	if sqs.IsFIFOQueue(queueName) {
+		// 同じメッセージグループ (ユーザーなど) のイベントを送信順に処理する
+		pollerOpts = append(pollerOpts, worker.WithPollerFIFO())
+	}
+	poller := worker.NewSQSPoller(client, w, queueURL, guard.MessageHandler(registry.MessageHandler()), pollerOpts...)
+	// シグナル受信でポーリングを停止する
+	if err := poller.Run(ctx); err != nil {
+		slog.Error("poller error", "error", err)
+	}
+	// ポーリングがエラーで終了した場合もスケジューラーを止め、Workerの停止前に投入が終わるのを待つ
+	stopScheduler()
+	if err := <-schedulerDone; err != nil {
+		slog.Error("scheduler error", "error", err)
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
 	if err := w.Shutdown(shutdownCtx); err != nil {
-		slog.Error("Shutdown error", "error", err)
+		return fmt.Errorf("failed to shutdown worker: %w", err)
+	}
+	return nil
+}
+
+// newScheduler は定期実行するタスクを登録したスケジューラーを生成する
+// SCHEDULER_DB_DSN が設定されている場合は scheduler_runs テーブルで実行権を調停し、古い実行記録を毎日削除する
+// OUTBOX_DB_DSN が設定されている場合はアウトボックスのイベントの送信を登録する (registerOutboxRelay)
+// 戻り値の関数でデータベース接続を閉じる
+func newScheduler(ctx context.Context, w worker.Worker, client sqs.SQS) (*scheduler.Scheduler, func(), error) {
+	var opts []scheduler.SchedulerOption
+	var closers []func()
+	closeDB := func() {
+		for _, c := range closers {
+			c()
+		}
+	}
+	var locker *scheduler.SQLLocker
+	if dsn := getEnv("SCHEDULER_DB_DSN", ""); dsn != "" {
+		db, err := sql.Open("mysql", dsn)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open scheduler database: %w", err)
+		}
+		closers = append(closers, func() { db.Close() })
+		locker = scheduler.NewSQLLocker(db)
+		opts = append(opts, scheduler.WithLocker(locker))
+	}
+
+	s := scheduler.New(w, opts...)
+	if err := s.Cron(healthtask.TaskType, "* * * * *", healthtask.HealthTask); err != nil {
+		closeDB()
+		return nil, nil, fmt.Errorf("failed to schedule health check: %w", err)
+	}
+	if locker != nil {
+		prune := tasks.NewTask(func(ctx context.Context) error {
+			n, err := locker.Prune(ctx, time.Now().AddDate(0, 0, -schedulerRunRetentionDays))
+			if err != nil {
+				return err
+			}
+			slog.Info("pruned scheduler runs", "deleted", n)
+			return nil
+		})
+		if err := s.Cron("scheduler.prune", "@daily", prune, scheduler.WithPriority(worker.PriorityLow)); err != nil {
+			closeDB()
+			return nil, nil, fmt.Errorf("failed to schedule scheduler prune: %w", err)
+		}
+	}
+	if dsn := getEnv("OUTBOX_DB_DSN", ""); dsn != "" {
+		db, err := sql.Open("mysql", dsn)
+		if err != nil {
+			closeDB()
+			return nil, nil, fmt.Errorf("failed to open outbox database: %w", err)
+		}
+		closers = append(closers, func() { db.Close() })
+		if err := registerOutboxRelay(ctx, s, db, client); err != nil {
+			closeDB()
+			return nil, nil, err
+		}
+	}
+	return s, closeDB, nil
+}
+
+// registerOutboxRelay はアウトボックスのイベントを送信するRelayと、送信済みのイベントの削除を登録する
+// 注意事項: イベントの配信の遅延を抑えるため、他の定期実行より優先度を上げる
+func registerOutboxRelay(ctx context.Context, s *scheduler.Scheduler, db *sql.DB, client sqs.SQS) error {
+	queueName := getEnv("OUTBOX_QUEUE_NAME", "user-sync-queue.fifo")
+	queueURL, err := client.GetQueueURL(ctx, queueName)
+	if err != nil {
+		return fmt.Errorf("failed to get queue url (queueName=%s): %w", queueName, err)
+	}
+
+	store := outbox.NewSQLStore(db)
+	interval := time.Duration(getEnvInt("OUTBOX_RELAY_INTERVAL_SECONDS", 5)) * time.Second
+	relay := outbox.NewRelay(store, client, queueURL)
+	if err := s.Schedule(outbox.TaskType, scheduler.Every(interval), relay, scheduler.WithPriority(worker.PriorityHigh)); err != nil {
+		return fmt.Errorf("failed to schedule outbox relay: %w", err)
+	}
+
+	prune := tasks.NewTask(func(ctx context.Context) error {
+		n, err := store.Prune(ctx, time.Now().AddDate(0, 0, -outboxRetentionDays))
+		if err != nil {
+			return err
+		}
+		slog.Info("pruned outbox messages", "deleted", n)
+		return nil
+	})
+	if err := s.Cron("outbox.prune", "@daily", prune, scheduler.WithPriority(worker.PriorityLow)); err != nil {
+		return fmt.Errorf("failed to schedule outbox prune: %w", err)
+	}
+	return nil
+}
+
+// newIdempotencyGuard は処理済みのメッセージの再実行を防ぐGuardを生成する
+// IDEMPOTENCY_DB_DSN が設定されている場合は idempotency_keys テーブルで複数プロセス間の重複を排除し、期限切れの記録を毎時削除する
+// 戻り値の関数でデータベース接続を閉じる
+func newIdempotencyGuard(s *scheduler.Scheduler) (*idempotency.Guard, func(), error) {
+	// リースはタスクの再試行を含めた実行時間より長くする
+	opts := []idempotency.Option{idempotency.WithTTL(idempotencyTTL), idempotency.WithLease(5 * time.Minute)}
+	dsn := getEnv("IDEMPOTENCY_DB_DSN", "")
+	if dsn == "" {
+		return idempotency.New(idempotency.NewMemoryStore(), opts...), func() {}, nil
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open idempotency database: %w", err)
+	}
+	store := idempotency.NewSQLStore(db)
+	prune := tasks.NewTask(func(ctx context.Context) error {
+		n, err := store.Prune(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+		slog.Info("pruned idempotency keys", "deleted", n)
+		return nil
+	})
+	if err := s.Cron("idempotency.prune", "@hourly", prune, scheduler.WithPriority(worker.PriorityLow)); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("failed to schedule idempotency prune: %w", err)
+	}
+	return idempotency.New(store, opts...), func() { db.Close() }, nil
+}
+
+// serveMetrics は reg のメトリクスを addr の GET /metrics で公開する
+// 戻り値の関数でサーバーを停止する
+func serveMetrics(addr string, reg *metrics.Registry) func() {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler(reg))
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("metrics server error", "addr", addr, "error", err)
+		}
+	}()
+	slog.Info("metrics server is listening", "addr", addr)
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}
+}
+
+// newSQSClient はLocalStack向けのSQSクライアントを生成する
+// 注意事項: LocalStackはダミーの認証情報を受け付けるため、固定値を使用する
+func newSQSClient(ctx context.Context) (sqs.SQS, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx,
+		awsconfig.WithRegion(getEnv("AWS_REGION", "ap-northeast-1")),
+		awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("test", "test", "")),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws config: %w", err)
+	}
+	endpoint := getEnv("AWS_ENDPOINT_URL", "http://localhost:4566")
+	return sqs.NewSQSClient(awssqs.NewFromConfig(awsCfg, func(o *awssqs.Options) {
+		o.BaseEndpoint = aws.String(endpoint)
+	})), nil
+}
+
+func getEnv(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		slog.Warn("invalid integer env, using default", "key", key, "value", v, "default", fallback)
+		return fallback
 	}
-	fmt.Printf("After shutdown - Running: %v, Status: %s\n", w.IsRunning(), w.Status())
+	return n
 }
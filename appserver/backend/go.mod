@@ -3,16 +3,17 @@ module github.com/takeuchi-shogo/golang-learn/app/backend
 go 1.25.1
 
 require (
-	github.com/aws/aws-sdk-go v1.55.8
 	github.com/aws/aws-sdk-go-v2 v1.40.0
 	github.com/aws/aws-sdk-go-v2/config v1.31.20
 	github.com/aws/aws-sdk-go-v2/credentials v1.18.24
 	github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider v1.57.13
 	github.com/aws/aws-sdk-go-v2/service/sqs v1.42.16
+	github.com/aws/smithy-go v1.23.2
 	github.com/go-sql-driver/mysql v1.9.3
 	github.com/golang-jwt/jwt/v5 v5.3.0
 	github.com/google/uuid v1.6.0
-	golang.org/x/sync v0.18.0
+	go.uber.org/mock v0.6.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -26,7 +27,4 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/sso v1.30.3 // indirect
 	github.com/aws/aws-sdk-go-v2/service/ssooidc v1.35.7 // indirect
 	github.com/aws/aws-sdk-go-v2/service/sts v1.40.2 // indirect
-	github.com/aws/smithy-go v1.23.2 // indirect
-	github.com/jmespath/go-jmespath v0.4.0 // indirect
-	go.uber.org/mock v0.6.0 // indirect
 )
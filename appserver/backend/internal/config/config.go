@@ -0,0 +1,154 @@
+// Package config はアプリケーションの設定を環境変数と設定ファイルから読み込みます。
+//
+// 設定は次の順で上書きされます (後のものが優先)。
+//  1. 環境 (APP_ENV) ごとのデフォルト値 (development はローカルのDocker環境、production は実際のAWS)
+//  2. CONFIG_FILE で指定したYAMLファイルの、環境名のセクション (dbconfig.yml と同じ構成)
+//  3. 環境変数
+//
+// 読み込んだ設定は Validate で検証し、起動時に不足や誤りを検出します。
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Environment は実行環境です。
+type Environment string
+
+const (
+	// EnvDevelopment はローカル開発環境 (MySQL、magnito、LocalStackをDockerで起動する)
+	EnvDevelopment Environment = "development"
+	// EnvProduction は本番環境 (実際のAWSに接続する)
+	EnvProduction Environment = "production"
+)
+
+// Config はアプリケーションの設定です。
+type Config struct {
+	// Env は実行環境 (環境変数 APP_ENV で指定し、設定ファイルでは変更できない)
+	Env      Environment    `yaml:"-"`
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	AWS      AWSConfig      `yaml:"aws"`
+	Cognito  CognitoConfig  `yaml:"cognito"`
+}
+
+// ServerConfig はHTTPサーバーの設定です。
+type ServerConfig struct {
+	// Addr は待ち受けるアドレス
+	Addr              string        `yaml:"addr"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	// ShutdownTimeout は停止時に処理中のリクエストの完了を待つ時間
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// DrainDelay は停止時に /readyz を503にしてから新しいリクエストの受け付けを止めるまでの時間
+	DrainDelay time.Duration `yaml:"drain_delay"`
+}
+
+// DatabaseConfig はデータベースの設定です。
+type DatabaseConfig struct {
+	// DSN はMySQLの接続文字列 (go-sql-driver/mysql の形式)
+	DSN string `yaml:"dsn"`
+}
+
+// AWSConfig はAWSの共通設定です。
+type AWSConfig struct {
+	// Region はAWSリージョン
+	Region string `yaml:"region"`
+}
+
+// CognitoConfig はCognitoの設定です。
+type CognitoConfig struct {
+	// Endpoint はCognito互換のエミュレータ (magnito) のURL。空の場合は実際のCognitoに接続する
+	Endpoint   string `yaml:"endpoint"`
+	UserPoolID string `yaml:"user_pool_id"`
+	ClientID   string `yaml:"client_id"`
+	// AccessKeyID と SecretAccessKey は固定の認証情報。空の場合はAWS SDKのデフォルトの認証情報を使用する
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+}
+
+// Default は env のデフォルト値を返します。
+// development はローカルのDocker環境 (compose.yml) に合わせた値で、そのまま起動できます。
+// production は接続先の秘匿情報を含めないため、DSNやユーザープールを環境変数か設定ファイルで指定する必要があります。
+func Default(env Environment) *Config {
+	cfg := &Config{
+		Env: env,
+		Server: ServerConfig{
+			Addr:              ":8080",
+			ReadTimeout:       10 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      10 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+			DrainDelay:        5 * time.Second,
+		},
+		AWS: AWSConfig{Region: "ap-northeast-1"},
+	}
+	if env == EnvDevelopment {
+		cfg.Server.Addr = "localhost:8080"
+		cfg.Server.DrainDelay = 0
+		cfg.Database.DSN = "root:password@tcp(localhost:3306)/golang_learn"
+		cfg.Cognito = CognitoConfig{
+			Endpoint:        "http://localhost:5050",
+			UserPoolID:      "ap-northeast-1_local",
+			ClientID:        "magnito-client-name",
+			AccessKeyID:     "magnito-access-key",
+			SecretAccessKey: "magnito-secret-key",
+		}
+	}
+	return cfg
+}
+
+// Validate は設定の不足や誤りを検証し、すべての問題をまとめたエラーを返します。
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Env != EnvDevelopment && c.Env != EnvProduction {
+		errs = append(errs, fmt.Errorf("env: unknown environment %q (want %s or %s)", c.Env, EnvDevelopment, EnvProduction))
+	}
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("server.addr: required"))
+	}
+	for _, t := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.read_header_timeout", c.Server.ReadHeaderTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+	} {
+		if t.d <= 0 {
+			errs = append(errs, fmt.Errorf("%s: must be positive, got %s", t.name, t.d))
+		}
+	}
+	if c.Server.DrainDelay < 0 {
+		errs = append(errs, fmt.Errorf("server.drain_delay: must not be negative, got %s", c.Server.DrainDelay))
+	}
+	if c.Database.DSN == "" {
+		errs = append(errs, errors.New("database.dsn: required"))
+	}
+	if c.AWS.Region == "" {
+		errs = append(errs, errors.New("aws.region: required"))
+	}
+	if c.Cognito.UserPoolID == "" {
+		errs = append(errs, errors.New("cognito.user_pool_id: required"))
+	}
+	if c.Cognito.ClientID == "" {
+		errs = append(errs, errors.New("cognito.client_id: required"))
+	}
+	if c.Cognito.Endpoint != "" {
+		if u, err := url.Parse(c.Cognito.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("cognito.endpoint: invalid url %q", c.Cognito.Endpoint))
+		}
+	}
+	if (c.Cognito.AccessKeyID == "") != (c.Cognito.SecretAccessKey == "") {
+		errs = append(errs, errors.New("cognito.access_key_id and cognito.secret_access_key: must be set together"))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,147 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/config"
+)
+
+// lookupEnv は env の値を返す config.LookupEnvFunc を生成する
+func lookupEnv(env map[string]string) config.LookupEnvFunc {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+// TestLoad は環境ごとのデフォルト値、設定ファイル、環境変数の優先順位と検証を確認します。
+func TestLoad(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(configFile, []byte(`
+development:
+  server:
+    addr: localhost:9000
+production:
+  server:
+    shutdown_timeout: 45s
+  database:
+    dsn: app:secret@tcp(mysql:3306)/golang_learn
+  cognito:
+    user_pool_id: ap-northeast-1_prod
+    client_id: file-client
+`), 0o600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		check   func(t *testing.T, cfg *config.Config)
+		wantErr []string
+	}{
+		{
+			name: "正常系: 未設定の場合はローカル開発環境のデフォルト値",
+			env:  map[string]string{},
+			check: func(t *testing.T, cfg *config.Config) {
+				if cfg.Env != config.EnvDevelopment || cfg.Server.Addr != "localhost:8080" {
+					t.Errorf("env = %s addr = %s, want development localhost:8080", cfg.Env, cfg.Server.Addr)
+				}
+				if cfg.Cognito.Endpoint != "http://localhost:5050" || cfg.Cognito.UserPoolID != "ap-northeast-1_local" {
+					t.Errorf("cognito = %+v, want magnito", cfg.Cognito)
+				}
+			},
+		},
+		{
+			name: "正常系: 設定ファイルの環境名のセクションで上書きする",
+			env:  map[string]string{"CONFIG_FILE": configFile},
+			check: func(t *testing.T, cfg *config.Config) {
+				if cfg.Server.Addr != "localhost:9000" {
+					t.Errorf("addr = %s, want localhost:9000", cfg.Server.Addr)
+				}
+				// 書かれていない項目はデフォルト値のまま
+				if cfg.Database.DSN != config.Default(config.EnvDevelopment).Database.DSN {
+					t.Errorf("dsn = %s, want default", cfg.Database.DSN)
+				}
+			},
+		},
+		{
+			name: "正常系: 本番環境は設定ファイルより環境変数を優先する",
+			env: map[string]string{
+				"APP_ENV":                     "production",
+				"CONFIG_FILE":                 configFile,
+				"COGNITO_USER_POOL_CLIENT_ID": "env-client",
+				"SERVER_DRAIN_DELAY":          "10s",
+			},
+			check: func(t *testing.T, cfg *config.Config) {
+				if cfg.Cognito.Endpoint != "" || cfg.Cognito.AccessKeyID != "" {
+					t.Errorf("cognito = %+v, want real AWS", cfg.Cognito)
+				}
+				if cfg.Cognito.UserPoolID != "ap-northeast-1_prod" || cfg.Cognito.ClientID != "env-client" {
+					t.Errorf("cognito = %+v, want pool from file and client from env", cfg.Cognito)
+				}
+				if cfg.Server.ShutdownTimeout != 45*time.Second || cfg.Server.DrainDelay != 10*time.Second {
+					t.Errorf("server = %+v", cfg.Server)
+				}
+			},
+		},
+		{
+			name: "正常系: none でエミュレータを使用しない",
+			env:  map[string]string{"COGNITO_ENDPOINT_URL": "none"},
+			check: func(t *testing.T, cfg *config.Config) {
+				if cfg.Cognito.Endpoint != "" {
+					t.Errorf("endpoint = %s, want empty", cfg.Cognito.Endpoint)
+				}
+			},
+		},
+		{
+			name:    "異常系: 本番環境で接続先が未設定",
+			env:     map[string]string{"APP_ENV": "production"},
+			wantErr: []string{"database.dsn", "cognito.user_pool_id", "cognito.client_id"},
+		},
+		{
+			name:    "異常系: 不明な環境",
+			env:     map[string]string{"APP_ENV": "staging"},
+			wantErr: []string{"unknown environment"},
+		},
+		{
+			name:    "異常系: 不正な時間の形式",
+			env:     map[string]string{"SERVER_READ_TIMEOUT": "10"},
+			wantErr: []string{"SERVER_READ_TIMEOUT"},
+		},
+		{
+			name:    "異常系: 不正なエンドポイント",
+			env:     map[string]string{"COGNITO_ENDPOINT_URL": "localhost:5050"},
+			wantErr: []string{"cognito.endpoint"},
+		},
+		{
+			name:    "異常系: 設定ファイルが存在しない",
+			env:     map[string]string{"CONFIG_FILE": filepath.Join(t.TempDir(), "missing.yml")},
+			wantErr: []string{"failed to read config file"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := config.Load(lookupEnv(tt.env))
+			if len(tt.wantErr) > 0 {
+				if err == nil {
+					t.Fatal("Load() error = nil, want error")
+				}
+				for _, want := range tt.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("Load() error = %v, want to contain %q", err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
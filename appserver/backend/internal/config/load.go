@@ -0,0 +1,135 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LookupEnvFunc は環境変数を取得する関数型です (os.LookupEnv と同じシグネチャ)。
+type LookupEnvFunc func(key string) (string, bool)
+
+// Load は設定を読み込み、検証します。
+// 引数:
+//   - lookupEnv: 環境変数を取得する関数 (通常は os.LookupEnv、テストでは固定の値を返す関数)
+//
+// 実装:
+//  1. APP_ENV (デフォルト: development) のデフォルト値を生成
+//  2. CONFIG_FILE が設定されている場合は、YAMLファイルの環境名のセクションで上書き
+//  3. 環境変数で上書き
+//  4. Validate で検証
+//
+// 環境変数:
+//
+//	APP_ENV                      実行環境 (development | production)
+//	CONFIG_FILE                  設定ファイルのパス (未設定の場合は読み込まない)
+//	SERVER_ADDR                  待ち受けるアドレス
+//	SERVER_READ_TIMEOUT          リクエストの読み込みの制限時間 (例: 10s)
+//	SERVER_READ_HEADER_TIMEOUT   リクエストヘッダーの読み込みの制限時間
+//	SERVER_WRITE_TIMEOUT         レスポンスの書き込みの制限時間
+//	SERVER_IDLE_TIMEOUT          キープアライブの接続を維持する時間
+//	SERVER_SHUTDOWN_TIMEOUT      停止時に処理中のリクエストの完了を待つ時間
+//	SERVER_DRAIN_DELAY           停止時に新しいリクエストの受け付けを止めるまでの時間
+//	DATABASE_DSN                 MySQLの接続文字列
+//	AWS_REGION                   AWSリージョン
+//	COGNITO_ENDPOINT_URL         Cognito互換のエミュレータのURL ("none" で実際のCognitoに接続する)
+//	COGNITO_USER_POOL_ID         ユーザープールID
+//	COGNITO_USER_POOL_CLIENT_ID  アプリクライアントID
+//	COGNITO_ACCESS_KEY           Cognitoの固定のアクセスキー
+//	COGNITO_SECRET_KEY           Cognitoの固定のシークレットキー
+func Load(lookupEnv LookupEnvFunc) (*Config, error) {
+	env := EnvDevelopment
+	if v, ok := lookupEnv("APP_ENV"); ok && v != "" {
+		env = Environment(v)
+	}
+	cfg := Default(env)
+
+	if path, ok := lookupEnv("CONFIG_FILE"); ok && path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.loadEnv(lookupEnv); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config (env=%s): %w", cfg.Env, err)
+	}
+	return cfg, nil
+}
+
+// loadFile は path のYAMLファイルから、実行環境の名前のセクションを読み込む
+// 注意事項: セクションに書かれていない項目はデフォルト値のまま残る
+func (c *Config) loadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file (path=%s): %w", path, err)
+	}
+	var profiles map[Environment]yaml.Node
+	if err := yaml.Unmarshal(b, &profiles); err != nil {
+		return fmt.Errorf("failed to parse config file (path=%s): %w", path, err)
+	}
+	node, ok := profiles[c.Env]
+	if !ok {
+		return nil
+	}
+	if err := node.Decode(c); err != nil {
+		return fmt.Errorf("failed to parse config file (path=%s, env=%s): %w", path, c.Env, err)
+	}
+	return nil
+}
+
+// loadEnv は設定されている環境変数で上書きする
+func (c *Config) loadEnv(lookupEnv LookupEnvFunc) error {
+	values := []struct {
+		key string
+		dst *string
+	}{
+		{"SERVER_ADDR", &c.Server.Addr},
+		{"DATABASE_DSN", &c.Database.DSN},
+		{"AWS_REGION", &c.AWS.Region},
+		{"COGNITO_ENDPOINT_URL", &c.Cognito.Endpoint},
+		{"COGNITO_USER_POOL_ID", &c.Cognito.UserPoolID},
+		{"COGNITO_USER_POOL_CLIENT_ID", &c.Cognito.ClientID},
+		{"COGNITO_ACCESS_KEY", &c.Cognito.AccessKeyID},
+		{"COGNITO_SECRET_KEY", &c.Cognito.SecretAccessKey},
+	}
+	for _, s := range values {
+		if v, ok := lookupEnv(s.key); ok && v != "" {
+			*s.dst = v
+		}
+	}
+	// development のデフォルトのエミュレータを環境変数だけで外せるようにする
+	if c.Cognito.Endpoint == "none" {
+		c.Cognito.Endpoint = ""
+	}
+
+	var errs []error
+	durations := []struct {
+		key string
+		dst *time.Duration
+	}{
+		{"SERVER_READ_TIMEOUT", &c.Server.ReadTimeout},
+		{"SERVER_READ_HEADER_TIMEOUT", &c.Server.ReadHeaderTimeout},
+		{"SERVER_WRITE_TIMEOUT", &c.Server.WriteTimeout},
+		{"SERVER_IDLE_TIMEOUT", &c.Server.IdleTimeout},
+		{"SERVER_SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout},
+		{"SERVER_DRAIN_DELAY", &c.Server.DrainDelay},
+	}
+	for _, d := range durations {
+		v, ok := lookupEnv(d.key)
+		if !ok || v == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.key, err))
+			continue
+		}
+		*d.dst = parsed
+	}
+	return errors.Join(errs...)
+}
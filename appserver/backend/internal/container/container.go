@@ -0,0 +1,195 @@
+// Package container はアプリケーション全体で共有する依存関係を、起動時に一度だけ組み立てます。
+//
+// リクエストごとにデータベースへの接続やJWKSの取得を行わないよう、HTTPハンドラーは Container が保持する
+// コンポーネントを使用します。テストでは With から始まるオプションで任意のコンポーネントを偽物に差し替えられます。
+package container
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/config"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/factory/userregistory"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/infra/database"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/auth/jwt"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/cognito"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/metrics"
+)
+
+// Container はアプリケーション全体で共有する依存関係を保持します。
+type Container struct {
+	cfg        *config.Config
+	db         *sql.DB
+	cognito    cognito.Cognito
+	jwtManager jwt.JwtManager
+	users      userregistory.UserRegistory
+	metrics    *metrics.Registry
+
+	mu sync.Mutex
+	// closers は Close で解放する資源 (登録の逆順に解放する)
+	closers []func() error
+	closed  bool
+}
+
+// Option はContainerのオプション関数型です。
+// 指定したコンポーネントは生成されず、Close でも解放されません (所有者は呼び出し元)。
+type Option func(*Container)
+
+// WithDB はデータベースの接続プールを設定します。
+func WithDB(db *sql.DB) Option {
+	return func(c *Container) {
+		c.db = db
+	}
+}
+
+// WithCognito はCognitoクライアントを設定します。
+func WithCognito(client cognito.Cognito) Option {
+	return func(c *Container) {
+		c.cognito = client
+	}
+}
+
+// WithJwtManager はトークンを検証するJwtManagerを設定します。
+func WithJwtManager(jwtManager jwt.JwtManager) Option {
+	return func(c *Container) {
+		c.jwtManager = jwtManager
+	}
+}
+
+// WithUserRegistory はユーザーの読み書きを行うリポジトリを設定します。
+func WithUserRegistory(users userregistory.UserRegistory) Option {
+	return func(c *Container) {
+		c.users = users
+	}
+}
+
+// WithMetrics はメトリクスのRegistryを設定します (デフォルト: metrics.DefaultRegistry)。
+func WithMetrics(reg *metrics.Registry) Option {
+	return func(c *Container) {
+		c.metrics = reg
+	}
+}
+
+// New はContainerを生成する
+// 引数:
+//   - cfg: アプリケーションの設定
+//   - opts: オプション (差し替えるコンポーネント)
+//
+// 実装:
+//  1. オプションで指定されていないコンポーネントを cfg に従って生成
+//  2. データベースの接続プールとJWKSの更新は Close で停止するよう登録
+//
+// 注意事項:
+//   - データベースには接続を確認しない (停止中でも起動し、/readyz で検出する)
+//   - JwtManager の生成時にJWKSを取得するため、Cognito (またはmagnito) が停止している場合はエラーを返す
+//   - エラーを返す場合も、それまでに生成した資源は解放する
+func New(cfg *config.Config, opts ...Option) (*Container, error) {
+	c := &Container{cfg: cfg}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if err := c.build(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// build は未設定のコンポーネントを生成する
+func (c *Container) build() error {
+	if c.metrics == nil {
+		c.metrics = metrics.DefaultRegistry
+	}
+	if c.db == nil {
+		db, err := database.Open(c.cfg.Database.DSN)
+		if err != nil {
+			return fmt.Errorf("failed to open database: %w", err)
+		}
+		c.db = db
+		c.OnClose(db.Close)
+	}
+	if c.users == nil {
+		c.users = userregistory.NewUserRegistory(c.db)
+	}
+	if c.cognito == nil {
+		client, err := cognito.New(c.cfg.AWS.Region, c.cfg.Cognito.UserPoolID, c.cfg.Cognito.ClientID,
+			cognito.WithEndpoint(c.cfg.Cognito.Endpoint),
+			cognito.WithStaticCredentials(c.cfg.Cognito.AccessKeyID, c.cfg.Cognito.SecretAccessKey),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to initialize cognito client: %w", err)
+		}
+		c.cognito = client
+	}
+	if c.jwtManager == nil {
+		// 鍵のローテーションに追従するため、Close までJWKSをバックグラウンドで更新する
+		refreshCtx, stopRefresh := context.WithCancel(context.Background())
+		// SPAとマシンクライアントはアクセストークンを送るため、IDトークンと両方を受け付ける
+		jwtManager, err := jwt.NewJwtManager(c.cfg.AWS.Region, c.cfg.Cognito.UserPoolID, c.cfg.Cognito.ClientID,
+			jwt.WithEndpoint(c.cfg.Cognito.Endpoint),
+			jwt.WithTokenTypes(jwt.TokenTypeID, jwt.TokenTypeAccess),
+			jwt.WithBackgroundRefresh(refreshCtx),
+		)
+		if err != nil {
+			stopRefresh()
+			return fmt.Errorf("failed to initialize jwt manager: %w", err)
+		}
+		c.jwtManager = jwtManager
+		c.OnClose(func() error {
+			stopRefresh()
+			return nil
+		})
+	}
+	return nil
+}
+
+// Config はアプリケーションの設定を返します。
+func (c *Container) Config() *config.Config { return c.cfg }
+
+// DB はデータベースの接続プールを返します。
+func (c *Container) DB() *sql.DB { return c.db }
+
+// Cognito はCognitoクライアントを返します。
+func (c *Container) Cognito() cognito.Cognito { return c.cognito }
+
+// JwtManager はトークンを検証するJwtManagerを返します。
+func (c *Container) JwtManager() jwt.JwtManager { return c.jwtManager }
+
+// Users はユーザーの読み書きを行うリポジトリを返します。
+func (c *Container) Users() userregistory.UserRegistory { return c.users }
+
+// Metrics はメトリクスのRegistryを返します。
+func (c *Container) Metrics() *metrics.Registry { return c.metrics }
+
+// OnClose は Close で解放する資源を登録します。
+// 登録の逆順に解放するため、後から生成した (先に生成した資源に依存する) 資源ほど先に解放されます。
+func (c *Container) OnClose(fn func() error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closers = append(c.closers, fn)
+}
+
+// Close は登録した資源を逆順に解放し、すべてのエラーをまとめて返します。
+// 注意事項: HTTPサーバーの停止 (処理中のリクエストの完了) を待ってから呼び出すこと。2回目以降の呼び出しは何もしない
+func (c *Container) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	closers := c.closers
+	c.closers = nil
+	c.mu.Unlock()
+
+	var errs []error
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
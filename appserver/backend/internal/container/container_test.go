@@ -0,0 +1,136 @@
+package container_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/config"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/container"
+	jwtpkg "github.com/takeuchi-shogo/golang-learn/app/backend/pkg/auth/jwt"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/cognito"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/metrics"
+)
+
+// fakeJwtManager はJWKSを取得しないJwtManager
+type fakeJwtManager struct{}
+
+func (fakeJwtManager) VerifyToken(ctx context.Context, tokenString string) (*jwt.Token, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (fakeJwtManager) GetUserInfo(token *jwt.Token) (*jwtpkg.UserInfo, error) {
+	return nil, errors.New("not implemented")
+}
+
+// fakeCognito はCognitoに接続しないCognitoクライアント
+type fakeCognito struct {
+	cognito.Cognito
+}
+
+// isClosed は db が Close 済みかを返す
+func isClosed(db *sql.DB) bool {
+	err := db.PingContext(context.Background())
+	return err != nil && err.Error() == "sql: database is closed"
+}
+
+// TestNew はオプションで指定したコンポーネントを使用し、それ以外を設定から生成することを確認します。
+func TestNew(t *testing.T) {
+	cfg := config.Default(config.EnvDevelopment)
+	jwtManager := fakeJwtManager{}
+	client := &fakeCognito{}
+	reg := metrics.NewRegistry()
+
+	c, err := container.New(cfg,
+		container.WithJwtManager(jwtManager),
+		container.WithCognito(client),
+		container.WithMetrics(reg),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer c.Close()
+
+	if c.JwtManager() != jwtManager || c.Cognito() != client || c.Metrics() != reg || c.Config() != cfg {
+		t.Error("New() did not use the injected components")
+	}
+	if c.DB() == nil || c.Users() == nil {
+		t.Fatal("New() did not build the database pool and user registory")
+	}
+}
+
+// TestContainer_Close は生成した資源のみを登録の逆順に解放することを確認します。
+func TestContainer_Close(t *testing.T) {
+	cfg := config.Default(config.EnvDevelopment)
+
+	t.Run("正常系: 生成した資源を登録の逆順に解放する", func(t *testing.T) {
+		c, err := container.New(cfg, container.WithJwtManager(fakeJwtManager{}), container.WithCognito(&fakeCognito{}))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		var order []string
+		c.OnClose(func() error {
+			order = append(order, "first")
+			return nil
+		})
+		c.OnClose(func() error {
+			// データベースより後に登録した資源は、データベースを閉じる前に解放される
+			if isClosed(c.DB()) {
+				t.Error("database closed before dependent resource")
+			}
+			order = append(order, "second")
+			return nil
+		})
+
+		if err := c.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		if !reflect.DeepEqual(order, []string{"second", "first"}) {
+			t.Errorf("close order = %v, want [second first]", order)
+		}
+		if !isClosed(c.DB()) {
+			t.Error("database is not closed")
+		}
+		// 2回目は何もしない
+		if err := c.Close(); err != nil || len(order) != 2 {
+			t.Errorf("second Close() error = %v, order = %v", err, order)
+		}
+	})
+
+	t.Run("正常系: 注入した資源は解放しない", func(t *testing.T) {
+		db, err := sql.Open("mysql", cfg.Database.DSN)
+		if err != nil {
+			t.Fatalf("sql.Open() error = %v", err)
+		}
+		defer db.Close()
+		c, err := container.New(cfg, container.WithDB(db), container.WithJwtManager(fakeJwtManager{}), container.WithCognito(&fakeCognito{}))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if err := c.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		if isClosed(db) {
+			t.Error("injected database was closed")
+		}
+	})
+
+	t.Run("異常系: 解放のエラーをまとめて返す", func(t *testing.T) {
+		c, err := container.New(cfg, container.WithJwtManager(fakeJwtManager{}), container.WithCognito(&fakeCognito{}))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		errFirst, errSecond := errors.New("first"), errors.New("second")
+		c.OnClose(func() error { return errFirst })
+		c.OnClose(func() error { return errSecond })
+
+		err = c.Close()
+		if !errors.Is(err, errFirst) || !errors.Is(err, errSecond) {
+			t.Errorf("Close() error = %v, want both errors", err)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ユーザーのドメインイベント種別
+// 注意事項: tasks.Envelope の type としてSQSに送信されるため、変更すると受信側の互換性が失われる
+const (
+	// UserCreatedEventType はユーザーの作成を表すイベント種別
+	UserCreatedEventType = "user.created"
+
+	// UserUpdatedEventType はユーザー情報の更新を表すイベント種別
+	UserUpdatedEventType = "user.updated"
+)
+
+// UserEvent はユーザーのドメインイベントのペイロード
+// 意味: イベント発生時点のユーザー情報
+// 注意事項: イベントは重複や順序の入れ替わりがあり得るため、受信側は OccurredAt で新旧を判定する
+type UserEvent struct {
+	UserID     uuid.UUID `json:"user_id"`
+	Name       Name      `json:"name"`
+	Email      Email     `json:"email"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// NewUserEvent はユーザーの現在の状態からイベントのペイロードを生成する
+// 引数:
+//   - user: イベントの対象のユーザー
+//   - occurredAt: イベントの発生時刻
+//
+// 戻り値: イベントのペイロード
+func NewUserEvent(user *User, occurredAt time.Time) UserEvent {
+	return UserEvent{
+		UserID:     user.GetID(),
+		Name:       user.GetName(),
+		Email:      user.GetEmail(),
+		OccurredAt: occurredAt.UTC(),
+	}
+}
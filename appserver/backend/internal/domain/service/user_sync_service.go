@@ -7,6 +7,7 @@ import (
 	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/domain/model"
 	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/repository"
 	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/service/command"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/trace"
 )
 
 // UserSyncService はユーザー情報をCognitoとDBで同期するドメインサービスインターフェース
@@ -70,6 +71,9 @@ func NewUserSyncService(cognitoClient repository.CognitoClient, userCommand comm
 //   - ロールバック失敗時はCRITICALエラーとして記録
 //   - 現在はemailのみCognitoに同期(nameは標準属性にないためDBのみ)
 func (s *userSyncService) SyncUserUpdate(ctx context.Context, user *model.User, oldEmail model.Email) (*model.User, error) {
+	ctx, span := trace.Start(ctx, "UserSyncService.SyncUserUpdate", trace.WithAttributes(trace.String("user.id", user.GetID().String())))
+	defer span.End()
+
 	// Cognito更新用の属性マップを作成
 	// 注意: 現在の実装ではemailのみをCognitoに同期
 	// nameはCognitoの標準属性にないため、カスタム属性として保存する場合は別途実装が必要
@@ -80,26 +84,48 @@ func (s *userSyncService) SyncUserUpdate(ctx context.Context, user *model.User,
 	// Cognitoのユーザー属性を更新
 	// ビジネスルール: Cognito更新が失敗した場合、DB更新も行わない
 	if err := s.cognitoClient.UpdateUserAttributes(ctx, user.GetUserIDToken(), cognitoAttributes); err != nil {
+		span.RecordError(err)
 		return nil, fmt.Errorf("failed to update cognito user attributes: %w", err)
 	}
 
 	// DBのユーザー情報を更新
 	updatedUser, err := s.userCommand.UpdateUser(ctx, user)
 	if err != nil {
+		span.RecordError(err)
 		// DB更新失敗時、Cognitoをロールバック(Saga Pattern)
-		rollbackAttributes := map[string]string{
-			"email": string(oldEmail),
-		}
-
-		if rollbackErr := s.cognitoClient.UpdateUserAttributes(ctx, user.GetUserIDToken(), rollbackAttributes); rollbackErr != nil {
+		if rollbackErr := rollbackCognitoEmail(ctx, s.cognitoClient, user, oldEmail); rollbackErr != nil {
 			// ロールバック失敗は致命的エラー
 			// この場合、CognitoとDBでデータ不整合が発生している
+			span.SetAttributes(trace.Bool("saga.compensated", false))
 			return nil, fmt.Errorf("CRITICAL: failed to rollback cognito update - data inconsistency may occur (original error: %w, rollback error: %v)", err, rollbackErr)
 		}
 
 		// ロールバック成功時は通常のエラーを返す
+		span.SetAttributes(trace.Bool("saga.compensated", true))
 		return nil, fmt.Errorf("failed to update user in database (cognito rolled back successfully): %w", err)
 	}
 
 	return updatedUser, nil
 }
+
+// rollbackCognitoEmail はDB更新の失敗時にCognitoのメールアドレスを元に戻す(補償トランザクション)
+// 引数:
+//   - ctx: コンテキスト
+//   - cognitoClient: Cognito操作用のクライアント
+//   - user: 更新に失敗したユーザー情報
+//   - email: 元のメールアドレス
+// 戻り値: ロールバックのエラー情報
+// 注意事項: ロールバックの所要時間と失敗を追えるよう、"UserSyncService.compensate" スパンで囲む
+func rollbackCognitoEmail(ctx context.Context, cognitoClient repository.CognitoClient, user *model.User, email model.Email) error {
+	ctx, span := trace.Start(ctx, "UserSyncService.compensate", trace.WithAttributes(
+		trace.String("saga.step", "cognito.rollback"),
+		trace.String("user.id", user.GetID().String()),
+	))
+	defer span.End()
+
+	err := cognitoClient.UpdateUserAttributes(ctx, user.GetUserIDToken(), map[string]string{
+		"email": string(email),
+	})
+	span.RecordError(err)
+	return err
+}
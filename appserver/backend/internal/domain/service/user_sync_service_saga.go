@@ -9,6 +9,7 @@ import (
 	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/repository"
 	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/service/command"
 	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/service/query"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/trace"
 )
 
 // UserSyncServiceWithSaga はSagaパターンを使用したユーザー同期サービス
@@ -74,10 +75,14 @@ func NewUserSyncServiceWithSaga(
 //   - 補償トランザクション失敗時はログに記録し、手動リカバリが必要
 //   - べき等性を保証するため、ロールバックは元の値に戻す
 func (s *userSyncServiceWithSaga) SyncUserUpdate(ctx context.Context, user *model.User) (*model.User, error) {
+	ctx, span := trace.Start(ctx, "UserSyncServiceWithSaga.SyncUserUpdate", trace.WithAttributes(trace.String("user.id", user.GetID().String())))
+	defer span.End()
+
 	// ステップ1: 元のユーザー情報を取得(ロールバック用)
 	// これにより、Cognito更新後にDB更新が失敗した場合、元の状態に戻せる
 	originalUser, err := s.userQuery.GetUserById(ctx, user.GetID())
 	if err != nil {
+		span.RecordError(err)
 		return nil, fmt.Errorf("failed to get original user for rollback: %w", err)
 	}
 
@@ -86,27 +91,28 @@ func (s *userSyncServiceWithSaga) SyncUserUpdate(ctx context.Context, user *mode
 		"email": string(user.GetEmail()),
 	}
 	if err := s.cognitoClient.UpdateUserAttributes(ctx, user.GetUserIDToken(), cognitoAttributes); err != nil {
+		span.RecordError(err)
 		return nil, fmt.Errorf("failed to update cognito user attributes: %w", err)
 	}
 
 	// ステップ3: DBのユーザー情報を更新
 	updatedUser, err := s.userCommand.UpdateUser(ctx, user)
 	if err != nil {
+		span.RecordError(err)
 		// DB更新失敗 → 補償トランザクション(Cognitoロールバック)を実行
 		log.Printf("[Saga] DB update failed, starting compensating transaction (rollback Cognito)")
 
 		// 補償トランザクション: Cognitoを元の状態に戻す
-		originalAttributes := map[string]string{
-			"email": string(originalUser.GetEmail()),
-		}
-		if rollbackErr := s.cognitoClient.UpdateUserAttributes(ctx, user.GetUserIDToken(), originalAttributes); rollbackErr != nil {
+		if rollbackErr := rollbackCognitoEmail(ctx, s.cognitoClient, user, originalUser.GetEmail()); rollbackErr != nil {
 			// ロールバックも失敗 → 重大なエラー
 			// この場合、手動リカバリが必要
 			log.Printf("[Saga] CRITICAL: Compensating transaction failed! Manual recovery required. User ID: %s, Error: %v", user.GetID(), rollbackErr)
+			span.SetAttributes(trace.Bool("saga.compensated", false))
 			return nil, fmt.Errorf("failed to update user in database and rollback failed (CRITICAL - manual recovery required): db error=%w, rollback error=%v", err, rollbackErr)
 		}
 
 		log.Printf("[Saga] Compensating transaction succeeded: Cognito rolled back to original state")
+		span.SetAttributes(trace.Bool("saga.compensated", true))
 		return nil, fmt.Errorf("failed to update user in database (cognito rolled back successfully): %w", err)
 	}
 
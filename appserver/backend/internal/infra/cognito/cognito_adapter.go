@@ -2,9 +2,13 @@ package cognito
 
 import (
 	"context"
+	"maps"
+	"slices"
+	"strings"
 
 	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/repository"
 	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/cognito"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/trace"
 )
 
 // cognitoAdapter はCognitoClientインターフェースの実装
@@ -30,8 +34,19 @@ func NewCognitoAdapter(client cognito.Cognito) repository.CognitoClient {
 //   - userID: 更新対象のユーザーID (Cognito Username)
 //   - attributes: 更新する属性のマップ
 // 戻り値: エラー情報
-// 実装: pkg/aws/cognitoのAdminUpdateUserAttributesを呼び出す
-// 注意事項: エラーはそのまま上位層に伝播する
+// 実装: pkg/aws/cognitoのAdminUpdateUserAttributesをスパンで囲んで呼び出す
+// 注意事項:
+//   - エラーはそのまま上位層に伝播する
+//   - スパンには属性名のみを記録する(メールアドレスなどの値は記録しない)
 func (a *cognitoAdapter) UpdateUserAttributes(ctx context.Context, userID string, attributes map[string]string) error {
-	return a.client.AdminUpdateUserAttributes(ctx, userID, attributes)
+	names := slices.Sorted(maps.Keys(attributes))
+	ctx, span := trace.Start(ctx, "Cognito.AdminUpdateUserAttributes", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		trace.String("cognito.username", userID),
+		trace.String("cognito.attributes", strings.Join(names, ",")),
+	))
+	defer span.End()
+
+	err := a.client.AdminUpdateUserAttributes(ctx, userID, attributes)
+	span.RecordError(err)
+	return err
 }
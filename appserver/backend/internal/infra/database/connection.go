@@ -7,8 +7,17 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 )
 
-func Connect() *sql.DB {
-	db, err := sql.Open("mysql", "root:password@tcp(localhost:3306)/golang_learn")
+// Open はデータベースへの接続プールを生成する
+// 引数:
+//   - dsn: MySQLの接続文字列 (config.DatabaseConfig.DSN)
+//
+// 注意事項: 接続は最初のクエリまたは Ping で確立されるため、起動時にデータベースが停止していてもエラーにならない
+func Open(dsn string) (*sql.DB, error) {
+	return sql.Open("mysql", dsn)
+}
+
+func Connect(dsn string) *sql.DB {
+	db, err := Open(dsn)
 	if err != nil {
 		log.Fatalf("failed to connect to database: %v", err)
 	}
@@ -0,0 +1,100 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/config"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/container"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/domain/model"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/factory/userregistory"
+	usercommand "github.com/takeuchi-shogo/golang-learn/app/backend/internal/service/command"
+	userquery "github.com/takeuchi-shogo/golang-learn/app/backend/internal/service/query"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/cognito"
+)
+
+// MockUserCommand はテスト用のUserCommandモック
+type MockUserCommand struct {
+	CreateUserFunc func(ctx context.Context, user *model.User) (*model.User, error)
+	UpdateUserFunc func(ctx context.Context, user *model.User) (*model.User, error)
+}
+
+// CreateUser はユーザー作成のモック実装
+func (m *MockUserCommand) CreateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	if m.CreateUserFunc != nil {
+		return m.CreateUserFunc(ctx, user)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// UpdateUser はユーザー更新のモック実装
+func (m *MockUserCommand) UpdateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	if m.UpdateUserFunc != nil {
+		return m.UpdateUserFunc(ctx, user)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// fakeUserRegistory はデータベースの代わりにモックを返すUserRegistory
+type fakeUserRegistory struct {
+	query   userquery.UserQuery
+	command usercommand.UserCommand
+}
+
+func (f *fakeUserRegistory) UserQuery() userquery.UserQuery       { return f.query }
+func (f *fakeUserRegistory) UserCommand() usercommand.UserCommand { return f.command }
+
+// MockCognito はテスト用のCognitoモック
+// 注意事項: AdminUpdateUserAttributes 以外のメソッドは呼び出すとpanicする
+type MockCognito struct {
+	cognito.Cognito
+	AdminUpdateUserAttributesFunc func(ctx context.Context, userID string, attributes map[string]string) error
+}
+
+// AdminUpdateUserAttributes はユーザー属性更新のモック実装
+func (m *MockCognito) AdminUpdateUserAttributes(ctx context.Context, userID string, attributes map[string]string) error {
+	if m.AdminUpdateUserAttributesFunc != nil {
+		return m.AdminUpdateUserAttributesFunc(ctx, userID, attributes)
+	}
+	return nil
+}
+
+// newTestUserRegistory は任意のIDのユーザーを返し、更新を受け付けるUserRegistoryを生成するヘルパー
+func newTestUserRegistory() userregistory.UserRegistory {
+	return &fakeUserRegistory{
+		query: &MockUserQuery{
+			GetUserByIdFunc: func(ctx context.Context, id uuid.UUID) (*model.User, error) {
+				return &model.User{ID: id, UserIDToken: id.String(), Name: "Test User", Email: "test@example.com"}, nil
+			},
+		},
+		command: &MockUserCommand{
+			UpdateUserFunc: func(ctx context.Context, user *model.User) (*model.User, error) {
+				return user, nil
+			},
+		},
+	}
+}
+
+// newTestHandlers はデータベース、Cognito、JWKSに接続しないハンドラーを生成するヘルパー
+// 引数:
+//   - t: テストオブジェクト
+//   - opts: 差し替えるコンポーネント (デフォルトは newTestUserRegistory、MockCognito、MockJwtManager)
+//
+// 戻り値: *handlers
+// 注意事項: Containerはテストの終了時に閉じる
+func newTestHandlers(t *testing.T, opts ...container.Option) *handlers {
+	t.Helper()
+	defaults := []container.Option{
+		container.WithUserRegistory(newTestUserRegistory()),
+		container.WithCognito(&MockCognito{}),
+		container.WithJwtManager(&MockJwtManager{}),
+	}
+	c, err := container.New(config.Default(config.EnvDevelopment), append(defaults, opts...)...)
+	if err != nil {
+		t.Fatalf("container.New() error = %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return newHandlers(c)
+}
@@ -13,47 +13,72 @@ import (
 	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/controllers/dto"
 	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/domain/model"
 	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/domain/service"
-	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/factory"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/container"
 	infracognito "github.com/takeuchi-shogo/golang-learn/app/backend/internal/infra/cognito"
 	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/middleware"
 	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/repository"
 	httputil "github.com/takeuchi-shogo/golang-learn/app/backend/pkg/http"
 	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/auth/jwt"
-	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/cognito"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/metrics"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/trace"
 )
 
-// router は依存関係を組み立て、HTTPルーティングを設定する
+// handlers は各エンドポイントを処理する
+// コントローラーは起動時に Container のコンポーネントから一度だけ組み立て、すべてのリクエストで共有する
+type handlers struct {
+	jwtManager       jwt.JwtManager
+	userController   *controllers.UserController
+	signupController *authcontroller.SignupController
+	loginController  *authcontroller.LoginController
+}
+
+// newHandlers は c のコンポーネントからハンドラーを組み立てる
+func newHandlers(c *container.Container) *handlers {
+	users := c.Users()
+	userSyncService := service.NewUserSyncService(infracognito.NewCognitoAdapter(c.Cognito()), users.UserCommand())
+	return &handlers{
+		jwtManager: c.JwtManager(),
+		userController: controllers.NewUserControllerWithUpdate(
+			userapplication.NewGetUser(users.UserQuery()),
+			userapplication.NewUpdateUser(users.UserQuery(), userSyncService),
+		),
+		signupController: authcontroller.NewSignupController(c.Cognito(), c.JwtManager(), authapplication.NewSignupApplication(users.UserCommand())),
+		loginController:  authcontroller.NewLoginController(c.Cognito(), c.JwtManager()),
+	}
+}
+
+// router はHTTPルーティングを設定する
+// 引数:
+//   - h: 各エンドポイントのハンドラー
+//   - reg: GET /metricsで公開するメトリクスのRegistry
 // 実装:
 //   1. 各エンドポイントを定義
 //   2. 認証が必要なエンドポイントにはJwtVerifyミドルウェアを適用
+//   3. GET /metricsでregのメトリクスを公開
 // 注意事項:
 //   - PUT /users/{id}は認証必須(JwtVerifyミドルウェア適用)
 //   - 認証エンドポイント(signup, login)は認証不要
-func router() *http.ServeMux {
+func router(h *handlers, reg *metrics.Registry) *http.ServeMux {
 	mux := http.NewServeMux()
 
-	// JWT Manager の初期化(ミドルウェア用)
-	jwtManager, err := jwt.NewJwtManager("ap-northeast-1", "ap-northeast-1_local")
-	if err != nil {
-		// 初期化失敗は致命的エラー
-		panic("failed to initialize jwt manager: " + err.Error())
-	}
-
 	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("Hello, World!"))
 	})
 
 	// ルーティング設定
-	mux.HandleFunc("GET /users/{id}", userRouter)
+	mux.HandleFunc("GET /users/{id}", h.userRouter)
 
 	// 認証が必要なエンドポイント: ユーザー情報更新
 	// JwtVerifyミドルウェアを適用してContextにユーザー情報を追加
-	mux.Handle("PUT /users/{id}", middleware.JwtVerify(jwtManager)(http.HandlerFunc(updateUserRouter)))
+	mux.Handle("PUT /users/{id}", middleware.JwtVerify(h.jwtManager)(http.HandlerFunc(h.updateUserRouter)))
 
 	// 認証不要なエンドポイント
-	mux.HandleFunc("POST /auth/signup", authSignupRouter)
-	mux.HandleFunc("POST /auth/login", authLoginRouter)
+	mux.HandleFunc("POST /auth/signup", h.authSignupRouter)
+	mux.HandleFunc("POST /auth/login", h.authLoginRouter)
+
+	// メトリクスの公開
+	mux.Handle("GET /metrics", metrics.Handler(reg))
 	return mux
 }
 
@@ -63,11 +88,9 @@ func router() *http.ServeMux {
 //   - r: HTTPリクエスト
 // 実装:
 //   1. パスパラメータからユーザーIDを取得
-//   2. コントローラーを初期化
-//   3. ユーザー情報を取得
-//   4. レスポンスを返却
-func userRouter(w http.ResponseWriter, r *http.Request) {
-	userController := controllers.NewUserController(userapplication.NewGetUser(factory.NewFactory().GetUserRegistory().UserQuery()))
+//   2. ユーザー情報を取得
+//   3. レスポンスを返却
+func (h *handlers) userRouter(w http.ResponseWriter, r *http.Request) {
 	// リクエストパラメータの取得
 	idStr := r.PathValue("id")
 
@@ -79,7 +102,7 @@ func userRouter(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// コントローラー経由でビジネスロジックを実行
-	user, err := userController.Get(r.Context(), id)
+	user, err := h.userController.Get(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -98,17 +121,15 @@ func userRouter(w http.ResponseWriter, r *http.Request) {
 //   2. パスパラメータからユーザーIDを取得
 //   3. 本人確認(ログインユーザーIDと更新対象ユーザーIDが一致するか)
 //   4. リクエストボディから更新情報を取得してバリデーション
-//   5. 依存関係を組み立て
-//   6. コントローラーを初期化
-//   7. ユーザー情報を更新
-//   8. レスポンスを返却
+//   5. ユーザー情報を更新
+//   6. レスポンスを返却
 // 注意事項:
 //   - JWT認証が必須(ミドルウェアで事前に検証)
 //   - 他人のユーザー情報は更新できない(403エラー)
 //   - name, emailはnilの場合は更新しない
 //   - バリデーションエラーは400を返す
 //   - ユーザーが見つからない場合は404を返す
-func updateUserRouter(w http.ResponseWriter, r *http.Request) {
+func (h *handlers) updateUserRouter(w http.ResponseWriter, r *http.Request) {
 	// Contextからログインユーザー情報を取得
 	// 注意: JwtVerifyミドルウェアで既に検証済みのため、存在が保証されている
 	userInfo, ok := middleware.GetUserInfoFromContext(r.Context())
@@ -117,23 +138,6 @@ func updateUserRouter(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ファクトリーから依存関係を取得
-	f := factory.NewFactory()
-	userRegistory := f.GetUserRegistory()
-
-	// Cognitoクライアントの初期化
-	cognitoClient := cognito.New()
-	cognitoAdapter := infracognito.NewCognitoAdapter(cognitoClient)
-
-	// UserSyncServiceの初期化
-	userSyncService := service.NewUserSyncService(cognitoAdapter, userRegistory.UserCommand())
-
-	// コントローラーを初期化
-	userController := controllers.NewUserControllerWithUpdate(
-		userapplication.NewGetUser(userRegistory.UserQuery()),
-		userapplication.NewUpdateUser(userRegistory.UserQuery(), userSyncService),
-	)
-
 	// リクエストパラメータの取得
 	idStr := r.PathValue("id")
 
@@ -184,7 +188,7 @@ func updateUserRouter(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// コントローラー経由でビジネスロジックを実行
-	user, err := userController.Update(r.Context(), id, name, email)
+	user, err := h.userController.Update(r.Context(), id, name, email)
 	if err != nil {
 		// エラーの種類に応じてステータスコードを変更
 		if errors.Is(err, repository.ErrUserNotFound) {
@@ -199,14 +203,7 @@ func updateUserRouter(w http.ResponseWriter, r *http.Request) {
 	httputil.WriteJSON(w, user, http.StatusOK)
 }
 
-func authSignupRouter(w http.ResponseWriter, r *http.Request) {
-	cognitoClient := cognito.New()
-	jwtManager, err := jwt.NewJwtManager("ap-northeast-1", "ap-northeast-1_local")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	signupController := authcontroller.NewSignupController(cognitoClient, jwtManager, authapplication.NewSignupApplication(factory.NewFactory().GetUserRegistory().UserCommand()))
+func (h *handlers) authSignupRouter(w http.ResponseWriter, r *http.Request) {
 	type SignupRequest struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
@@ -216,8 +213,7 @@ func authSignupRouter(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	err = signupController.Signup(r.Context(), signupRequest.Email, signupRequest.Password)
-	if err != nil {
+	if err := h.signupController.Signup(r.Context(), signupRequest.Email, signupRequest.Password); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -226,26 +222,18 @@ func authSignupRouter(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "signup successful"})
 }
 
-func authLoginRouter(w http.ResponseWriter, r *http.Request) {
-	cognitoClient := cognito.New()
-	jwtManager, err := jwt.NewJwtManager("ap-northeast-1", "ap-northeast-1_local")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	loginController := authcontroller.NewLoginController(cognitoClient, jwtManager)
+func (h *handlers) authLoginRouter(w http.ResponseWriter, r *http.Request) {
 	type LoginRequest struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
 	}
 	var loginRequest LoginRequest
-	err = json.NewDecoder(r.Body).Decode(&loginRequest)
+	err := json.NewDecoder(r.Body).Decode(&loginRequest)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	authTokens, err := loginController.Login(r.Context(), loginRequest.Email, loginRequest.Password)
+	authTokens, err := h.loginController.Login(r.Context(), loginRequest.Email, loginRequest.Password)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -256,6 +244,10 @@ func authLoginRouter(w http.ResponseWriter, r *http.Request) {
 }
 
 // NewRouter はルーターを初期化する
-func NewRouter() *http.ServeMux {
-	return router()
+// ハンドラーは c のコンポーネント (データベースの接続プール、Cognitoクライアント、JwtManager) を共有する
+// リクエストの件数と処理時間はルートパターンごとに c.Metrics() へ記録し、GET /metricsで公開する
+// リクエストの traceparent ヘッダーを引き継いでサーバースパンを開始し、レスポンスの traceparent ヘッダーで返す
+// 注意事項: トレースのミドルウェアはリクエストを複製するため、ルートパターンを参照するメトリクスのミドルウェアより外側に置く
+func NewRouter(c *container.Container) http.Handler {
+	return trace.Middleware(metrics.NewHTTPMetrics(c.Metrics()).Middleware(router(newHandlers(c), c.Metrics())))
 }
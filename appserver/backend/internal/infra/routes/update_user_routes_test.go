@@ -7,16 +7,22 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/container"
 	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/controllers/dto"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/domain/model"
 	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/middleware"
 	jwtpkg "github.com/takeuchi-shogo/golang-learn/app/backend/pkg/auth/jwt"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/auth/jwt/jwttest"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/metrics"
 )
 
 // TestUpdateUserEndpoint_Success_WithAuth_OwnData は認証あり本人データ更新の正常系テスト
 // 実装: 有効なJWTトークンで本人のデータを更新できることを検証
 // 注意: リファクタリング後も既存の動作が保持されることを確認
+// 注意: データベースとCognitoはモックに差し替える
 func TestUpdateUserEndpoint_Success_WithAuth_OwnData(t *testing.T) {
 	// テスト用のユーザーID
 	testUserID := uuid.New()
@@ -47,16 +53,21 @@ func TestUpdateUserEndpoint_Success_WithAuth_OwnData(t *testing.T) {
 	rec := httptest.NewRecorder()
 
 	// エンドポイントを実行
-	// 注意: 実際のテストでは依存関係を注入する方法が必要
-	// ここでは概念的なテストケースとして示す
-	updateUserRouter(rec, req)
+	// データベースとCognitoの代わりにモックを注入する (newTestHandlers)
+	newTestHandlers(t).updateUserRouter(rec, req)
 
 	// ステータスコードの検証
-	// 注意: モックが完全に実装されていないため、実際のステータスは異なる可能性がある
-	// 完全な統合テストでは200 OKが期待される
-	if rec.Code != http.StatusOK && rec.Code != http.StatusInternalServerError {
-		t.Logf("Status code: %d (expected %d or %d during partial implementation)",
-			rec.Code, http.StatusOK, http.StatusInternalServerError)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d. Body: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	// 更新後のユーザー情報の検証
+	var user model.User
+	if err := json.NewDecoder(rec.Body).Decode(&user); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if user.ID != testUserID || string(user.Name) != newName {
+		t.Errorf("Expected user %s with name %q, got %s with name %q", testUserID, newName, user.ID, user.Name)
 	}
 }
 
@@ -94,7 +105,7 @@ func TestUpdateUserEndpoint_Forbidden_OthersData(t *testing.T) {
 	rec := httptest.NewRecorder()
 
 	// エンドポイントを実行
-	updateUserRouter(rec, req)
+	newTestHandlers(t).updateUserRouter(rec, req)
 
 	// ステータスコードの検証
 	if rec.Code != http.StatusForbidden {
@@ -138,7 +149,7 @@ func TestUpdateUserEndpoint_Unauthorized_NoAuth(t *testing.T) {
 
 	// JwtVerifyミドルウェアを適用したハンドラーを実行
 	mockJwtManager := &MockJwtManager{}
-	handler := middleware.JwtVerify(mockJwtManager)(http.HandlerFunc(updateUserRouter))
+	handler := middleware.JwtVerify(mockJwtManager)(http.HandlerFunc(newTestHandlers(t).updateUserRouter))
 
 	handler.ServeHTTP(rec, req)
 
@@ -178,7 +189,7 @@ func TestUpdateUserEndpoint_BadRequest_InvalidID(t *testing.T) {
 	rec := httptest.NewRecorder()
 
 	// エンドポイントを実行
-	updateUserRouter(rec, req)
+	newTestHandlers(t).updateUserRouter(rec, req)
 
 	// ステータスコードの検証
 	if rec.Code != http.StatusBadRequest {
@@ -209,7 +220,7 @@ func TestUpdateUserEndpoint_BadRequest_InvalidRequestBody(t *testing.T) {
 	rec := httptest.NewRecorder()
 
 	// エンドポイントを実行
-	updateUserRouter(rec, req)
+	newTestHandlers(t).updateUserRouter(rec, req)
 
 	// ステータスコードの検証
 	if rec.Code != http.StatusBadRequest {
@@ -247,10 +258,72 @@ func TestUpdateUserEndpoint_BadRequest_ValidationError(t *testing.T) {
 	rec := httptest.NewRecorder()
 
 	// エンドポイントを実行
-	updateUserRouter(rec, req)
+	newTestHandlers(t).updateUserRouter(rec, req)
 
 	// ステータスコードの検証
 	if rec.Code != http.StatusBadRequest {
 		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rec.Code)
 	}
 }
+
+// TestUpdateUserEndpoint_Success_WithSignedToken は署名付きトークンによる本人データ更新の正常系テスト
+// 実装: jwttest の発行者が署名したトークンで、ルーターの JwtVerify から更新までをオフラインで通して検証
+func TestUpdateUserEndpoint_Success_WithSignedToken(t *testing.T) {
+	testUserID := uuid.New()
+	newName := "Updated Name"
+
+	// Cognitoの代わりに、ローカルの発行者とそれを信頼する JwtManager を使用する
+	issuer, err := jwttest.New()
+	if err != nil {
+		t.Fatalf("jwttest.New() error = %v", err)
+	}
+	endpoint := issuer.Start()
+	defer issuer.Close()
+	jwtManager, err := jwtpkg.NewJwtManager(jwttest.Region, issuer.UserPoolID(), issuer.ClientID(), jwtpkg.WithEndpoint(endpoint))
+	if err != nil {
+		t.Fatalf("NewJwtManager() error = %v", err)
+	}
+	mux := router(newTestHandlers(t, container.WithJwtManager(jwtManager)), metrics.NewRegistry())
+
+	reqBody, err := json.Marshal(dto.UpdateUserRequest{Name: &newName})
+	if err != nil {
+		t.Fatalf("Failed to marshal request: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{
+			name:       "正常系: 本人のIDトークン",
+			token:      issuer.MustIDToken(testUserID.String()),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "異常系: 他人のIDトークン",
+			token:      issuer.MustIDToken(uuid.New().String()),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "異常系: 有効期限切れのIDトークン",
+			token:      issuer.MustIDToken(testUserID.String(), jwttest.WithExpiresIn(-time.Minute)),
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/users/"+testUserID.String(), bytes.NewReader(reqBody))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status code %d, got %d. Body: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	jwtpkg "github.com/takeuchi-shogo/golang-learn/app/backend/pkg/auth/jwt"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/auth/jwt/jwttest"
 )
 
 // MockJwtManager はテスト用のJWT Managerモック
@@ -237,3 +239,81 @@ func TestGetUserInfoFromContext_InvalidType(t *testing.T) {
 		t.Error("Expected nil user info")
 	}
 }
+
+// TestJwtVerify_SignedToken は jwttest の発行者が署名したトークンで JwtVerify をオフラインで検証するテスト
+// 実装: 実際の JwtManager を使用し、有効なトークンのみContextにユーザー情報が追加されることを検証
+func TestJwtVerify_SignedToken(t *testing.T) {
+	issuer, err := jwttest.New()
+	if err != nil {
+		t.Fatalf("jwttest.New() error = %v", err)
+	}
+	endpoint := issuer.Start()
+	defer issuer.Close()
+	manager, err := jwtpkg.NewJwtManager(jwttest.Region, issuer.UserPoolID(), issuer.ClientID(),
+		jwtpkg.WithEndpoint(endpoint),
+		jwtpkg.WithTokenTypes(jwtpkg.TokenTypeID, jwtpkg.TokenTypeAccess),
+	)
+	if err != nil {
+		t.Fatalf("NewJwtManager() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+		wantType   jwtpkg.TokenType
+	}{
+		{
+			name:       "正常系: IDトークン",
+			token:      issuer.MustIDToken("test-user-id", jwttest.WithClaim("email", "test@example.com")),
+			wantStatus: http.StatusOK,
+			wantType:   jwtpkg.TokenTypeID,
+		},
+		{
+			name:       "正常系: アクセストークン",
+			token:      issuer.MustAccessToken("test-user-id", jwttest.WithScopes("users/read")),
+			wantStatus: http.StatusOK,
+			wantType:   jwtpkg.TokenTypeAccess,
+		},
+		{
+			name:       "異常系: 有効期限切れ",
+			token:      issuer.MustIDToken("test-user-id", jwttest.WithExpiresIn(-time.Minute)),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "異常系: 発行者が異なる",
+			token:      issuer.MustIDToken("test-user-id", jwttest.WithClaim("iss", "https://example.com/other")),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "異常系: 署名アルゴリズムが異なる",
+			token:      issuer.MustIDToken("test-user-id", jwttest.WithSigningMethod(jwt.SigningMethodHS256)),
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *jwtpkg.UserInfo
+			handler := JwtVerify(manager)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = GetUserInfoFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status code %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got == nil || got.Sub != "test-user-id" || got.TokenType != tt.wantType {
+				t.Errorf("Expected user info for test-user-id (%s), got %+v", tt.wantType, got)
+			}
+		})
+	}
+}
@@ -5,9 +5,13 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/takeuchi-shogo/golang-learn/app/backend/internal/domain/model"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/trace"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/outbox"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks"
 )
 
 // userRepository はUserQueryインターフェースの実装
@@ -54,10 +58,21 @@ func NewUserRepository(db *sql.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
+// CreateUser はユーザーを作成し、同じトランザクションで "user.created" イベントをアウトボックスに書き込む
+// 注意事項: イベントはコミット後に outbox.Relay がSQSに送信する
 func (r *userRepository) CreateUser(ctx context.Context, user *model.User) (*model.User, error) {
-	query := "INSERT INTO users (id, name, email, user_id_token, auth_type) VALUES (?, ?, ?, ?, ?)"
-	_, err := r.db.ExecContext(ctx, query, user.ID, user.Name, user.Email, user.UserIDToken, model.AuthTypeCognito.String())
+	ctx, span := startSpan(ctx, "UserRepository.CreateUser", user.GetID())
+	defer span.End()
+
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		query := "INSERT INTO users (id, name, email, user_id_token, auth_type) VALUES (?, ?, ?, ?, ?)"
+		if _, err := tx.ExecContext(ctx, query, user.ID, user.Name, user.Email, user.UserIDToken, model.AuthTypeCognito.String()); err != nil {
+			return err
+		}
+		return insertUserEvent(ctx, tx, model.UserCreatedEventType, user)
+	})
 	if err != nil {
+		span.RecordError(err)
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
@@ -79,6 +94,9 @@ func (r *userRepository) CreateUser(ctx context.Context, user *model.User) (*mod
 // 実装: データベースから指定されたIDのユーザーを取得する
 // 注意事項: ユーザーが見つからない場合はnilとエラーを返す
 func (r *userRepository) GetUserById(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	ctx, span := startSpan(ctx, "UserRepository.GetUserById", id)
+	defer span.End()
+
 	query := "SELECT id, name, email, user_id_token FROM users WHERE id = ?"
 	row := r.db.QueryRowContext(ctx, query, id)
 
@@ -89,6 +107,7 @@ func (r *userRepository) GetUserById(ctx context.Context, id uuid.UUID) (*model.
 
 	err := row.Scan(&user.ID, &name, &email, &userIDToken)
 	if err != nil {
+		span.RecordError(err)
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserNotFound
 		}
@@ -105,23 +124,88 @@ func (r *userRepository) GetUserById(ctx context.Context, id uuid.UUID) (*model.
 // 戻り値:
 //   - *model.User: 更新されたユーザー情報
 //   - error: エラー情報
-// 実装: データベースのユーザー情報を更新する
+// 実装: データベースのユーザー情報を更新し、同じトランザクションで "user.updated" イベントをアウトボックスに書き込む
 // 注意事項: ユーザーIDは更新できない
 func (r *userRepository) UpdateUser(ctx context.Context, user *model.User) (*model.User, error) {
-	query := "UPDATE users SET name = ?, email = ? WHERE id = ?"
-	result, err := r.db.ExecContext(ctx, query, user.Name, user.Email, user.ID)
+	ctx, span := startSpan(ctx, "UserRepository.UpdateUser", user.GetID())
+	defer span.End()
+
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		query := "UPDATE users SET name = ?, email = ? WHERE id = ?"
+		result, err := tx.ExecContext(ctx, query, user.Name, user.Email, user.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+
+		if rowsAffected == 0 {
+			return ErrUserNotFound
+		}
+
+		return insertUserEvent(ctx, tx, model.UserUpdatedEventType, user)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to update user: %w", err)
+		span.RecordError(err)
+		return nil, err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	return user, nil
+}
+
+// withTx はトランザクション内で fn を実行し、fn がエラーを返した場合はロールバックする
+// 引数:
+//   - ctx: コンテキスト
+//   - fn: トランザクション内で実行する処理
+//
+// 戻り値: fn のエラー、またはトランザクションの開始・コミットのエラー
+func (r *userRepository) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-
-	if rowsAffected == 0 {
-		return nil, ErrUserNotFound
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to rollback transaction: %w", rollbackErr))
+		}
+		return err
 	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
 
-	return user, nil
+// startSpan はデータベース操作のスパンを開始する
+// 引数:
+//   - ctx: コンテキスト
+//   - name: スパン名 (例: "UserRepository.UpdateUser")
+//   - userID: 操作対象のユーザーID
+func startSpan(ctx context.Context, name string, userID uuid.UUID) (context.Context, *trace.Span) {
+	return trace.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		trace.String("db.system", "mysql"),
+		trace.String("user.id", userID.String()),
+	))
+}
+
+// insertUserEvent はユーザーのイベントをアウトボックスに書き込む
+// 注意事項: 同じユーザーのイベントを順に処理できるよう、ユーザーIDをメッセージグループIDにする
+// イベントのエンベロープにはリクエストのトレースIDを記録し、受信側のログと突き合わせられるようにする
+func insertUserEvent(ctx context.Context, tx *sql.Tx, eventType string, user *model.User) error {
+	var opts []tasks.EnvelopeOption
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		opts = append(opts, tasks.WithTraceID(sc.TraceID.String()))
+	}
+	envelope, err := tasks.NewEnvelope(eventType, model.NewUserEvent(user, time.Now()), opts...)
+	if err != nil {
+		return err
+	}
+	msg, err := outbox.NewMessage(envelope, user.GetID().String())
+	if err != nil {
+		return err
+	}
+	return outbox.Insert(ctx, tx, msg)
 }
@@ -0,0 +1,12 @@
+-- +migrate Up
+CREATE TABLE scheduler_runs (
+    name VARCHAR(255) NOT NULL,
+    fire_at DATETIME(3) NOT NULL COMMENT 'Scheduled fire time (UTC)',
+    owner VARCHAR(255) NOT NULL COMMENT 'Process that claimed the run (host:pid)',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (name, fire_at),
+    INDEX idx_fire_at (fire_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +migrate Down
+DROP TABLE IF EXISTS scheduler_runs;
@@ -0,0 +1,16 @@
+-- +migrate Up
+CREATE TABLE outbox (
+    seq BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY COMMENT 'Insertion order used by the relay',
+    id VARCHAR(36) NOT NULL UNIQUE COMMENT 'Event ID (tasks.Envelope ID)',
+    event_type VARCHAR(255) NOT NULL,
+    message_group_id VARCHAR(128) NOT NULL DEFAULT '' COMMENT 'FIFO message group (e.g. user ID)',
+    body TEXT NOT NULL COMMENT 'SQS message body (tasks.Envelope JSON)',
+    attempts INT NOT NULL DEFAULT 0 COMMENT 'Failed relay attempts',
+    last_error TEXT NULL,
+    created_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    sent_at DATETIME(3) NULL COMMENT 'NULL until relayed to SQS',
+    INDEX idx_sent_at_seq (sent_at, seq)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +migrate Down
+DROP TABLE IF EXISTS outbox;
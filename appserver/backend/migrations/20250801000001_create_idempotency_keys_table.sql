@@ -0,0 +1,13 @@
+-- +migrate Up
+CREATE TABLE idempotency_keys (
+    idempotency_key VARCHAR(255) NOT NULL PRIMARY KEY COMMENT 'Message dedup ID, envelope ID or message ID',
+    status ENUM('processing', 'completed') NOT NULL,
+    token VARCHAR(36) NOT NULL COMMENT 'Claim token of the current owner',
+    expires_at DATETIME(3) NOT NULL COMMENT 'Lease expiry while processing, retention expiry once completed (UTC)',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +migrate Down
+DROP TABLE IF EXISTS idempotency_keys;
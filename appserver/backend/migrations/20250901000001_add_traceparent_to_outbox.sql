@@ -0,0 +1,6 @@
+-- +migrate Up
+ALTER TABLE outbox
+    ADD COLUMN traceparent VARCHAR(55) NOT NULL DEFAULT '' COMMENT 'W3C traceparent of the request that wrote the event' AFTER body;
+
+-- +migrate Down
+ALTER TABLE outbox DROP COLUMN traceparent;
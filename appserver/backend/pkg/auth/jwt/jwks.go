@@ -0,0 +1,328 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/clock"
+)
+
+const (
+	// defaultJWKSTTL はキャッシュヘッダーがない場合にJWKSを保持する時間
+	defaultJWKSTTL = time.Hour
+	// minJWKSTTL と maxJWKSTTL はキャッシュヘッダーから求めた保持時間の範囲
+	minJWKSTTL = time.Minute
+	maxJWKSTTL = 24 * time.Hour
+	// defaultRefetchInterval は未知の kid による再取得の最小間隔
+	defaultRefetchInterval = 30 * time.Second
+	// defaultJWKSHTTPTimeout はJWKSの取得1回あたりの制限時間
+	defaultJWKSHTTPTimeout = 10 * time.Second
+	// maxJWKSBytes はJWKSのレスポンスの最大サイズ
+	maxJWKSBytes = 1 << 20
+)
+
+// ErrKeyNotFound はトークンの kid に対応する公開鍵がJWKSに存在しないことを表します。
+var ErrKeyNotFound = errors.New("jwt: signing key not found")
+
+// JWKSCache はJWKS (JSON Web Key Set) の公開鍵をキャッシュします。
+//   - 保持時間はレスポンスの Cache-Control (max-age) または Expires ヘッダーに従う
+//   - 保持時間を過ぎた後も、更新が完了するまでは古い公開鍵で検証を続ける (更新はバックグラウンドで行う)
+//   - 未知の kid のトークンを受け取った場合は、鍵のローテーションとみなして再取得する (WithRefetchInterval の間隔で制限)
+//
+// 複数のゴルーチンから同時に使用できます。
+type JWKSCache struct {
+	url             string
+	client          *http.Client
+	clock           clock.Clock
+	defaultTTL      time.Duration
+	refetchInterval time.Duration
+
+	// mu は keys と expiresAt を保護する
+	mu        sync.RWMutex
+	keys      map[string]*rsa.PublicKey
+	expiresAt time.Time
+
+	// fetchMu は取得を直列化し、lastFetch を保護する
+	fetchMu   sync.Mutex
+	lastFetch time.Time
+	// refreshing は保持時間切れによるバックグラウンドの更新が実行中かを表す
+	refreshing atomic.Bool
+}
+
+// JWKSOption はJWKSCacheのオプション関数型です。
+type JWKSOption func(*JWKSCache)
+
+// WithHTTPClient はJWKSの取得に使用するHTTPクライアントを設定します (デフォルト: タイムアウト10秒のクライアント)。
+// 注意事項: Timeout を設定したクライアントを渡すこと
+func WithHTTPClient(client *http.Client) JWKSOption {
+	return func(c *JWKSCache) {
+		c.client = client
+	}
+}
+
+// WithClock は保持時間の判定に使用する Clock を設定します (デフォルト: clock.New())。
+// テストでは clock.NewFake を渡して時間を進めます。
+func WithClock(clk clock.Clock) JWKSOption {
+	return func(c *JWKSCache) {
+		c.clock = clk
+	}
+}
+
+// WithDefaultTTL はキャッシュヘッダーがない場合の保持時間を設定します (デフォルト: 1時間)。
+func WithDefaultTTL(ttl time.Duration) JWKSOption {
+	if ttl <= 0 {
+		ttl = defaultJWKSTTL
+	}
+	return func(c *JWKSCache) {
+		c.defaultTTL = ttl
+	}
+}
+
+// WithRefetchInterval は未知の kid による再取得と、失敗した更新の再試行の最小間隔を設定します (デフォルト: 30秒)。
+// 不正な kid のトークンを大量に送られても、JWKSの取得が間隔を超えて増えないようにします。
+func WithRefetchInterval(interval time.Duration) JWKSOption {
+	if interval <= 0 {
+		interval = defaultRefetchInterval
+	}
+	return func(c *JWKSCache) {
+		c.refetchInterval = interval
+	}
+}
+
+// NewJWKSCache はJWKSCacheを生成します。
+// 公開鍵は最初の Key または Refresh の呼び出しで取得します。
+// 引数:
+//   - url: JWKSのURL (例: https://cognito-idp.<region>.amazonaws.com/<userPoolID>/.well-known/jwks.json)
+//   - opts: オプション
+func NewJWKSCache(url string, opts ...JWKSOption) *JWKSCache {
+	c := &JWKSCache{
+		url:             url,
+		client:          &http.Client{Timeout: defaultJWKSHTTPTimeout},
+		clock:           clock.New(),
+		defaultTTL:      defaultJWKSTTL,
+		refetchInterval: defaultRefetchInterval,
+		keys:            make(map[string]*rsa.PublicKey),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Key は kid に対応する公開鍵を返します。
+// 保持時間を過ぎている場合は古い公開鍵を返し、バックグラウンドで更新します。
+// kid が未知の場合は、前回の取得から WithRefetchInterval の間隔が空いていれば再取得してから探します。
+// 見つからない場合は ErrKeyNotFound を返します。
+func (c *JWKSCache) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	key, ok, expired := c.lookup(kid)
+	if ok {
+		if expired {
+			c.refreshAsync()
+		}
+		return key, nil
+	}
+
+	if err := c.refetch(ctx); err != nil {
+		return nil, err
+	}
+	if key, ok, _ = c.lookup(kid); !ok {
+		return nil, fmt.Errorf("%w: kid=%s", ErrKeyNotFound, kid)
+	}
+	return key, nil
+}
+
+// Refresh は間隔の制限なしにJWKSを取得し、公開鍵を置き換えます。
+// 取得に失敗した場合は、それまでの公開鍵を保持したままエラーを返します。
+func (c *JWKSCache) Refresh(ctx context.Context) error {
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+	return c.fetchLocked(ctx)
+}
+
+// Run は保持時間が切れるたびにJWKSを更新し、ctx がキャンセルされるまでブロックします。
+// 更新に失敗した場合は WithRefetchInterval の間隔で再試行します。
+// 使用例: go cache.Run(ctx)
+func (c *JWKSCache) Run(ctx context.Context) {
+	wait := c.untilExpiry()
+	for {
+		timer := c.clock.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+
+		if err := c.Refresh(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Warn("failed to refresh jwks", "url", c.url, "retryIn", c.refetchInterval, "error", err)
+			wait = c.refetchInterval
+			continue
+		}
+		wait = c.untilExpiry()
+	}
+}
+
+// lookup は kid の公開鍵と、保持時間を過ぎているかを返す
+func (c *JWKSCache) lookup(kid string) (*rsa.PublicKey, bool, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	key, ok := c.keys[kid]
+	return key, ok, !c.clock.Now().Before(c.expiresAt)
+}
+
+// untilExpiry は保持時間が切れるまでの時間を返す (切れている場合は0)
+func (c *JWKSCache) untilExpiry() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return max(c.expiresAt.Sub(c.clock.Now()), 0)
+}
+
+// refreshAsync はバックグラウンドでJWKSを更新する
+// 注意事項: 同時に実行する更新は1つまでとし、再取得の間隔の制限に従う
+func (c *JWKSCache) refreshAsync() {
+	if !c.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer c.refreshing.Store(false)
+		ctx, cancel := context.WithTimeout(context.Background(), defaultJWKSHTTPTimeout)
+		defer cancel()
+		if err := c.refetch(ctx); err != nil {
+			slog.Warn("failed to refresh expired jwks", "url", c.url, "error", err)
+		}
+	}()
+}
+
+// refetch は前回の取得から間隔が空いている場合のみJWKSを取得する
+// 同時に呼び出された場合は最初の1回のみ取得し、残りはその結果を使用する
+func (c *JWKSCache) refetch(ctx context.Context) error {
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+	if !c.lastFetch.IsZero() && c.clock.Now().Sub(c.lastFetch) < c.refetchInterval {
+		return nil
+	}
+	return c.fetchLocked(ctx)
+}
+
+// fetchLocked はJWKSを取得して公開鍵を置き換える
+// 注意事項: fetchMu を保持して呼び出すこと
+func (c *JWKSCache) fetchLocked(ctx context.Context) error {
+	now := c.clock.Now()
+	c.lastFetch = now
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	keys, err := parseJWKS(io.LimitReader(resp.Body, maxJWKSBytes))
+	if err != nil {
+		return err
+	}
+	ttl := cacheTTL(resp.Header, now, c.defaultTTL)
+
+	c.mu.Lock()
+	c.keys = keys
+	c.expiresAt = now.Add(ttl)
+	c.mu.Unlock()
+	return nil
+}
+
+// parseJWKS はJWKSからRSAの公開鍵を取り出す
+// 注意事項: RSA以外の鍵は無視する
+func parseJWKS(r io.Reader) (map[string]*rsa.PublicKey, error) {
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(r).Decode(&jwks); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, key := range jwks.Keys {
+		if key.Kty != "RSA" {
+			continue
+		}
+
+		nBytes, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode N (kid=%s): %w", key.Kid, err)
+		}
+		eBytes, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode E (kid=%s): %w", key.Kid, err)
+		}
+
+		var e int
+		for _, b := range eBytes {
+			e = e<<8 + int(b)
+		}
+		keys[key.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(nBytes),
+			E: e,
+		}
+	}
+	return keys, nil
+}
+
+// cacheTTL はレスポンスのキャッシュヘッダーから保持時間を求める
+//   - Cache-Control の no-store / no-cache は最小の保持時間とする
+//   - Cache-Control の max-age を Expires より優先する
+//   - どちらもない場合は fallback を使用する
+//
+// 結果は minJWKSTTL から maxJWKSTTL の範囲に収める
+func cacheTTL(h http.Header, now time.Time, fallback time.Duration) time.Duration {
+	ttl := fallback
+	found := false
+	for _, directive := range strings.Split(h.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(strings.ToLower(directive)), "=")
+		switch name {
+		case "no-store", "no-cache":
+			return minJWKSTTL
+		case "max-age":
+			if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil {
+				ttl, found = time.Duration(seconds)*time.Second, true
+			}
+		}
+	}
+	if !found {
+		if expires, err := http.ParseTime(h.Get("Expires")); err == nil {
+			// サーバーとの時刻のずれの影響を受けないよう、Date ヘッダーとの差を使用する
+			base := now
+			if date, err := http.ParseTime(h.Get("Date")); err == nil {
+				base = date
+			}
+			ttl = expires.Sub(base)
+		}
+	}
+	return min(max(ttl, minJWKSTTL), maxJWKSTTL)
+}
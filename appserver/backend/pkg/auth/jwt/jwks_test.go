@@ -0,0 +1,303 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/auth/jwt"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/clock"
+)
+
+const jwksPath = "/ap-northeast-1_test/.well-known/jwks.json"
+
+// jwksServer はテスト用のJWKSを返すサーバー
+type jwksServer struct {
+	*httptest.Server
+	mu           sync.Mutex
+	keys         map[string]*rsa.PrivateKey
+	cacheControl string
+	requests     atomic.Int32
+}
+
+// newJWKSServer は kids の鍵を公開するJWKSサーバーを起動する
+func newJWKSServer(t *testing.T, kids ...string) *jwksServer {
+	t.Helper()
+	s := &jwksServer{keys: make(map[string]*rsa.PrivateKey)}
+	s.rotate(t, kids...)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != jwksPath {
+			http.NotFound(w, r)
+			return
+		}
+		s.requests.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		type jwk struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Alg string `json:"alg"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		}
+		var set struct {
+			Keys []jwk `json:"keys"`
+		}
+		for kid, key := range s.keys {
+			set.Keys = append(set.Keys, jwk{
+				Kid: kid,
+				Kty: "RSA",
+				Alg: "RS256",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		if s.cacheControl != "" {
+			w.Header().Set("Cache-Control", s.cacheControl)
+		}
+		json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// rotate は公開する鍵を kids の新しい鍵に置き換える
+func (s *jwksServer) rotate(t *testing.T, kids ...string) {
+	t.Helper()
+	keys := make(map[string]*rsa.PrivateKey, len(kids))
+	for _, kid := range kids {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("rsa.GenerateKey() error = %v", err)
+		}
+		keys[kid] = key
+	}
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+}
+
+// key は kid の秘密鍵を返す
+func (s *jwksServer) key(kid string) *rsa.PrivateKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys[kid]
+}
+
+// waitRequests はJWKSの取得回数が want になるまで待つ
+func (s *jwksServer) waitRequests(t *testing.T, want int32) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for s.requests.Load() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("jwks requests = %d, want %d", s.requests.Load(), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestJWKSCache_Key は公開鍵のキャッシュと、未知の kid による再取得の制限を確認します。
+func TestJWKSCache_Key(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("正常系: 保持時間内は再取得しない", func(t *testing.T) {
+		server := newJWKSServer(t, "key-1")
+		cache := jwt.NewJWKSCache(server.URL + jwksPath)
+		for range 3 {
+			key, err := cache.Key(ctx, "key-1")
+			if err != nil {
+				t.Fatalf("Key() error = %v", err)
+			}
+			if !key.Equal(&server.key("key-1").PublicKey) {
+				t.Fatal("Key() returned a different public key")
+			}
+		}
+		if got := server.requests.Load(); got != 1 {
+			t.Errorf("jwks requests = %d, want 1", got)
+		}
+	})
+
+	t.Run("正常系: 未知の kid は鍵のローテーションとして再取得する", func(t *testing.T) {
+		server := newJWKSServer(t, "key-1")
+		clk := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+		cache := jwt.NewJWKSCache(server.URL+jwksPath, jwt.WithClock(clk), jwt.WithRefetchInterval(time.Minute))
+		if err := cache.Refresh(ctx); err != nil {
+			t.Fatalf("Refresh() error = %v", err)
+		}
+
+		server.rotate(t, "key-2")
+		// 前回の取得から間隔が空いていない場合は再取得しない
+		if _, err := cache.Key(ctx, "key-2"); !errors.Is(err, jwt.ErrKeyNotFound) {
+			t.Fatalf("Key() error = %v, want ErrKeyNotFound", err)
+		}
+		if got := server.requests.Load(); got != 1 {
+			t.Errorf("jwks requests = %d, want 1 (rate limited)", got)
+		}
+
+		clk.Advance(time.Minute)
+		key, err := cache.Key(ctx, "key-2")
+		if err != nil {
+			t.Fatalf("Key() after interval error = %v", err)
+		}
+		if !key.Equal(&server.key("key-2").PublicKey) {
+			t.Error("Key() returned a different public key")
+		}
+		// 存在しない kid が続いても、間隔内は再取得しない
+		if _, err := cache.Key(ctx, "unknown"); !errors.Is(err, jwt.ErrKeyNotFound) {
+			t.Errorf("Key(unknown) error = %v, want ErrKeyNotFound", err)
+		}
+		if got := server.requests.Load(); got != 2 {
+			t.Errorf("jwks requests = %d, want 2", got)
+		}
+	})
+
+	t.Run("正常系: 同時に未知の kid を受け取っても取得は1回", func(t *testing.T) {
+		server := newJWKSServer(t, "key-1")
+		cache := jwt.NewJWKSCache(server.URL + jwksPath)
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := cache.Key(ctx, "key-1"); err != nil {
+					t.Errorf("Key() error = %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		if got := server.requests.Load(); got != 1 {
+			t.Errorf("jwks requests = %d, want 1", got)
+		}
+	})
+
+	t.Run("正常系: Cache-Control の保持時間を過ぎると古い鍵を返しつつ更新する", func(t *testing.T) {
+		server := newJWKSServer(t, "key-1")
+		server.cacheControl = "public, max-age=300"
+		clk := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+		cache := jwt.NewJWKSCache(server.URL+jwksPath, jwt.WithClock(clk))
+		if _, err := cache.Key(ctx, "key-1"); err != nil {
+			t.Fatalf("Key() error = %v", err)
+		}
+
+		clk.Advance(299 * time.Second)
+		if _, err := cache.Key(ctx, "key-1"); err != nil {
+			t.Fatalf("Key() before expiry error = %v", err)
+		}
+		if got := server.requests.Load(); got != 1 {
+			t.Fatalf("jwks requests before expiry = %d, want 1", got)
+		}
+
+		clk.Advance(time.Second)
+		if _, err := cache.Key(ctx, "key-1"); err != nil {
+			t.Fatalf("Key() after expiry error = %v", err)
+		}
+		server.waitRequests(t, 2)
+	})
+
+	t.Run("異常系: 取得に失敗した場合はエラー", func(t *testing.T) {
+		server := newJWKSServer(t, "key-1")
+		cache := jwt.NewJWKSCache(server.URL + "/missing.json")
+		if _, err := cache.Key(ctx, "key-1"); err == nil || errors.Is(err, jwt.ErrKeyNotFound) {
+			t.Errorf("Key() error = %v, want fetch error", err)
+		}
+	})
+
+	t.Run("異常系: 制限時間内に応答しない場合はエラー", func(t *testing.T) {
+		release := make(chan struct{})
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer slow.Close()
+		defer close(release)
+		cache := jwt.NewJWKSCache(slow.URL+jwksPath, jwt.WithHTTPClient(&http.Client{Timeout: 50 * time.Millisecond}))
+		if err := cache.Refresh(ctx); err == nil {
+			t.Error("Refresh() error = nil, want timeout")
+		}
+	})
+}
+
+// TestJWKSCache_Run は保持時間が切れるたびにバックグラウンドで更新することを確認します。
+func TestJWKSCache_Run(t *testing.T) {
+	server := newJWKSServer(t, "key-1")
+	server.cacheControl = "max-age=600"
+	clk := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := jwt.NewJWKSCache(server.URL+jwksPath, jwt.WithClock(clk))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cache.Run(ctx)
+	}()
+	// 未取得の場合は即座に取得する
+	server.waitRequests(t, 1)
+
+	server.rotate(t, "key-2")
+	if err := clk.BlockUntil(ctx, 1); err != nil {
+		t.Fatalf("BlockUntil() error = %v", err)
+	}
+	clk.Advance(10 * time.Minute)
+	server.waitRequests(t, 2)
+
+	if _, err := cache.Key(context.Background(), "key-2"); err != nil {
+		t.Errorf("Key() after background refresh error = %v", err)
+	}
+	if got := server.requests.Load(); got != 2 {
+		t.Errorf("jwks requests = %d, want 2", got)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return after cancel")
+	}
+}
+
+// TestJwtManager_VerifyToken_KeyRotation は鍵のローテーション後も再起動せずに検証できることを確認します。
+func TestJwtManager_VerifyToken_KeyRotation(t *testing.T) {
+	server := newJWKSServer(t, "key-1")
+	manager, err := jwt.NewJwtManager("ap-northeast-1", "ap-northeast-1_test", "test-client",
+		jwt.WithEndpoint(server.URL),
+		jwt.WithJWKSOptions(jwt.WithHTTPClient(server.Client()), jwt.WithRefetchInterval(time.Nanosecond)),
+	)
+	if err != nil {
+		t.Fatalf("NewJwtManager() error = %v", err)
+	}
+
+	sign := func(kid string) string {
+		token := gojwt.NewWithClaims(gojwt.SigningMethodRS256, gojwt.MapClaims{
+			"iss":       server.URL + "/ap-northeast-1_test",
+			"sub":       "user-1",
+			"aud":       "test-client",
+			"email":     "user@example.com",
+			"token_use": "id",
+			"exp":       time.Now().Add(time.Hour).Unix(),
+		})
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(server.key(kid))
+		if err != nil {
+			t.Fatalf("SignedString() error = %v", err)
+		}
+		return signed
+	}
+
+	if _, err := manager.VerifyToken(context.Background(), sign("key-1")); err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+	server.rotate(t, "key-2")
+	if _, err := manager.VerifyToken(context.Background(), sign("key-2")); err != nil {
+		t.Errorf("VerifyToken() after rotation error = %v", err)
+	}
+}
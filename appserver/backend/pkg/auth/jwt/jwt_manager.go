@@ -2,13 +2,10 @@ package jwt
 
 import (
 	"context"
-	"crypto/rsa"
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
 	"log"
-	"math/big"
-	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -52,87 +49,101 @@ type JwtManager interface {
 	GetUserInfo(token *jwt.Token) (*UserInfo, error)
 }
 
+// TokenType はCognitoが発行するトークンの種類 (token_use クレーム)
+type TokenType string
+
+const (
+	// TokenTypeID はユーザーの属性を含むIDトークン (aud にアプリクライアントIDを持つ)
+	TokenTypeID TokenType = "id"
+	// TokenTypeAccess はAPIの認可に使用するアクセストークン (client_id と scope を持つ)
+	TokenTypeAccess TokenType = "access"
+)
+
 type jwtManager struct {
 	region     string
 	userPoolID string
+	clientID   string
 	authType   AuthType
 	issuer     string
-	keys       map[string]*rsa.PublicKey
+	// tokenTypes は受け付けるトークンの種類
+	tokenTypes []TokenType
+	jwks       *JWKSCache
+	// jwksOptions と refreshCtx は NewJwtManager でJWKSCacheを生成する際に使用する
+	jwksOptions []JWKSOption
+	refreshCtx  context.Context
 }
 
-func NewJwtManager(region, userPoolID string) (JwtManager, error) {
-	jwtManager := &jwtManager{
-		region:     region,
-		userPoolID: userPoolID,
-		// ローカル開発環境用
-		issuer: fmt.Sprintf("http://localhost:5050/%s", userPoolID),
-		// 本番環境用
-		// issuer:     fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s", region, userPoolID),
-		authType: AuthTypeCognito,
-		keys:     make(map[string]*rsa.PublicKey),
-	}
-	if err := jwtManager.fetchJWKS(); err != nil {
-		return nil, err
+// Option はJwtManagerのオプション関数型です。
+type Option func(*jwtManager)
+
+// WithEndpoint はCognito互換のエミュレータ (magnito など) のURLを設定します。
+// トークンの発行者は "<endpoint>/<userPoolID>" になり、JWKSもそこから取得します。
+// 空の場合は何もしません (実際のCognitoを使用する)。
+// 使用例: jwt.WithEndpoint("http://localhost:5050")
+func WithEndpoint(endpoint string) Option {
+	return func(v *jwtManager) {
+		if endpoint != "" {
+			v.issuer = fmt.Sprintf("%s/%s", strings.TrimSuffix(endpoint, "/"), v.userPoolID)
+		}
 	}
-	return jwtManager, nil
 }
 
-func (v *jwtManager) fetchJWKS() error {
-	// ローカル開発環境の場合はmagnitoを使用
-	url := fmt.Sprintf("http://localhost:5050/%s/.well-known/jwks.json", v.userPoolID)
-	// 本番環境の場合は以下を使用
-	// url := fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s/.well-known/jwks.json",
-	// 	v.region, v.userPoolID)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return fmt.Errorf("failed to fetch JWKS: %w", err)
+// WithTokenTypes は受け付けるトークンの種類を設定します (デフォルト: IDトークンのみ)。
+// SPAやマシンクライアントが送るアクセストークンを受け付ける場合は TokenTypeAccess を含めます。
+// 使用例: jwt.WithTokenTypes(jwt.TokenTypeID, jwt.TokenTypeAccess)
+func WithTokenTypes(types ...TokenType) Option {
+	return func(v *jwtManager) {
+		if len(types) > 0 {
+			v.tokenTypes = types
+		}
 	}
-	defer resp.Body.Close()
+}
 
-	var jwks struct {
-		Keys []struct {
-			Kid string `json:"kid"`
-			N   string `json:"n"`
-			E   string `json:"e"`
-			Kty string `json:"kty"`
-		} `json:"keys"`
+// WithJWKSOptions はJWKSのキャッシュのオプション (HTTPクライアント、保持時間など) を設定します。
+// 使用例: jwt.WithJWKSOptions(jwt.WithHTTPClient(client), jwt.WithRefetchInterval(time.Minute))
+func WithJWKSOptions(opts ...JWKSOption) Option {
+	return func(v *jwtManager) {
+		v.jwksOptions = append(v.jwksOptions, opts...)
 	}
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
-		return fmt.Errorf("failed to decode JWKS: %w", err)
+// WithBackgroundRefresh は ctx がキャンセルされるまで、保持時間が切れるたびにJWKSを更新するゴルーチンを起動します。
+// 指定しない場合も、保持時間を過ぎた後の最初の検証でバックグラウンドの更新を開始します。
+func WithBackgroundRefresh(ctx context.Context) Option {
+	return func(v *jwtManager) {
+		v.refreshCtx = ctx
 	}
+}
 
-	// RSA 公開鍵を生成
-	for _, key := range jwks.Keys {
-		if key.Kty != "RSA" {
-			continue
-		}
-
-		nBytes, err := base64.RawURLEncoding.DecodeString(key.N)
-		if err != nil {
-			return fmt.Errorf("failed to decode N: %w", err)
-		}
-
-		eBytes, err := base64.RawURLEncoding.DecodeString(key.E)
-		if err != nil {
-			return fmt.Errorf("failed to decode E: %w", err)
-		}
-
-		var e int
-		for _, b := range eBytes {
-			e = e<<8 + int(b)
-		}
-
-		pubKey := &rsa.PublicKey{
-			N: new(big.Int).SetBytes(nBytes),
-			E: e,
-		}
-
-		v.keys[key.Kid] = pubKey
+// NewJwtManager はJwtManagerを生成する
+// 引数:
+//   - region: ユーザープールのAWSリージョン
+//   - userPoolID: ユーザープールID
+//   - clientID: アプリクライアントID (IDトークンの aud、アクセストークンの client_id と照合する)
+//   - opts: オプション
+//
+// 実装: 発行者 (デフォルト: https://cognito-idp.<region>.amazonaws.com/<userPoolID>) のJWKSを取得する
+// 注意事項: 起動時にJWKSを取得できない場合はエラーを返す
+func NewJwtManager(region, userPoolID, clientID string, opts ...Option) (JwtManager, error) {
+	jwtManager := &jwtManager{
+		region:     region,
+		userPoolID: userPoolID,
+		clientID:   clientID,
+		issuer:     fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s", region, userPoolID),
+		authType:   AuthTypeCognito,
+		tokenTypes: []TokenType{TokenTypeID},
 	}
-
-	return nil
+	for _, opt := range opts {
+		opt(jwtManager)
+	}
+	jwtManager.jwks = NewJWKSCache(jwtManager.issuer+"/.well-known/jwks.json", jwtManager.jwksOptions...)
+	if err := jwtManager.jwks.Refresh(context.Background()); err != nil {
+		return nil, err
+	}
+	if jwtManager.refreshCtx != nil {
+		go jwtManager.jwks.Run(jwtManager.refreshCtx)
+	}
+	return jwtManager, nil
 }
 
 func (v *jwtManager) VerifyToken(ctx context.Context, tokenString string) (*jwt.Token, error) {
@@ -149,13 +160,8 @@ func (v *jwtManager) VerifyToken(ctx context.Context, tokenString string) (*jwt.
 			return nil, fmt.Errorf("kid header not found")
 		}
 
-		// 対応する公開鍵を取得
-		key, ok := v.keys[kid]
-		if !ok {
-			return nil, fmt.Errorf("key not found for kid: %s", kid)
-		}
-
-		return key, nil
+		// 対応する公開鍵を取得 (未知の kid の場合は鍵のローテーションとみなしてJWKSを再取得する)
+		return v.jwks.Key(ctx, kid)
 	})
 
 	if err != nil {
@@ -169,18 +175,28 @@ func (v *jwtManager) VerifyToken(ctx context.Context, tokenString string) (*jwt.
 	}
 
 	// Issuer チェック
-	// ローカル開発環境用
-	expectedIssuer := fmt.Sprintf("http://localhost:5050/%s", v.userPoolID)
-	// 本番環境用
-	// expectedIssuer := fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s",
-	// 	v.region, v.userPoolID)
-	if claims["iss"] != expectedIssuer {
+	if claims["iss"] != v.issuer {
 		return nil, fmt.Errorf("invalid issuer")
 	}
 
-	// Token Use チェック (ID Token であることを確認)
-	if claims["token_use"] != "id" {
-		return nil, fmt.Errorf("token is not an id token")
+	// Token Use チェック (受け付ける種類のトークンであることを確認)
+	tokenUse, _ := claims["token_use"].(string)
+	tokenType := TokenType(tokenUse)
+	if !slices.Contains(v.tokenTypes, tokenType) {
+		return nil, fmt.Errorf("token type %q is not accepted", tokenUse)
+	}
+
+	// アプリクライアントのチェック (IDトークンは aud、アクセストークンは client_id)
+	switch tokenType {
+	case TokenTypeID:
+		aud, err := claims.GetAudience()
+		if err != nil || !slices.Contains(aud, v.clientID) {
+			return nil, fmt.Errorf("invalid audience")
+		}
+	case TokenTypeAccess:
+		if claims["client_id"] != v.clientID {
+			return nil, fmt.Errorf("invalid client_id")
+		}
 	}
 
 	// 有効期限チェック
@@ -197,10 +213,13 @@ func (v *jwtManager) VerifyToken(ctx context.Context, tokenString string) (*jwt.
 // 引数:
 //   - token: 検証済みJWTトークン
 // 戻り値:
-//   - *UserInfo: ユーザー情報(Sub, Email)
+//   - *UserInfo: ユーザー情報(Sub, Email, Username, Scopes, Groups, AuthTime, TokenType)
 //   - error: エラー情報
-// 実装: トークンのクレームからsub(ユーザーID)とemailを抽出
-// 注意事項: クレームが不正な場合はエラーを返す
+// 実装: トークンのクレームからsub(ユーザーID)、email、ユーザー名、スコープ、グループ、認証時刻を抽出
+// 注意事項:
+//   - クレームが不正な場合はエラーを返す
+//   - emailはIDトークンでは必須、アクセストークンには含まれないため空になる
+//   - ユーザー名はIDトークンでは cognito:username、アクセストークンでは username から取得する
 func (v *jwtManager) GetUserInfo(token *jwt.Token) (*UserInfo, error) {
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
@@ -212,22 +231,69 @@ func (v *jwtManager) GetUserInfo(token *jwt.Token) (*UserInfo, error) {
 		return nil, fmt.Errorf("sub claim not found or invalid")
 	}
 
-	email, ok := claims["email"].(string)
-	if !ok {
-		return nil, fmt.Errorf("email claim not found or invalid")
+	tokenUse, _ := claims["token_use"].(string)
+	userInfo := &UserInfo{
+		Sub:       sub,
+		TokenType: TokenType(tokenUse),
 	}
 
-	return &UserInfo{
-		Sub:   sub,
-		Email: email,
-	}, nil
+	switch userInfo.TokenType {
+	case TokenTypeAccess:
+		userInfo.Username, _ = claims["username"].(string)
+		if scope, ok := claims["scope"].(string); ok {
+			userInfo.Scopes = strings.Fields(scope)
+		}
+	default:
+		email, ok := claims["email"].(string)
+		if !ok {
+			return nil, fmt.Errorf("email claim not found or invalid")
+		}
+		userInfo.Email = email
+		userInfo.Username, _ = claims["cognito:username"].(string)
+	}
+
+	if groups, ok := claims["cognito:groups"].([]interface{}); ok {
+		for _, group := range groups {
+			name, ok := group.(string)
+			if !ok {
+				return nil, fmt.Errorf("cognito:groups claim is invalid")
+			}
+			userInfo.Groups = append(userInfo.Groups, name)
+		}
+	}
+
+	if authTime, ok := claims["auth_time"].(float64); ok {
+		userInfo.AuthTime = time.Unix(int64(authTime), 0)
+	}
+
+	return userInfo, nil
 }
 
 // UserInfo はJWTトークンから取得したユーザー情報
-// 意味: 認証済みユーザーの基本情報を保持
+// 意味: 認証済みユーザーの基本情報と、認可に使用するスコープ・グループを保持
 type UserInfo struct {
 	// Sub はユーザーの一意識別子(Cognito User Pool内のユーザーID)
 	Sub string
-	// Email はユーザーのメールアドレス
+	// Email はユーザーのメールアドレス (アクセストークンの場合は空)
 	Email string
+	// Username はCognitoのユーザー名
+	Username string
+	// Scopes はアクセストークンのスコープ (IDトークンの場合は空)
+	Scopes []string
+	// Groups はユーザーが所属するCognitoのグループ (cognito:groups)
+	Groups []string
+	// AuthTime はユーザーが認証した時刻 (トークンの更新では変わらない)
+	AuthTime time.Time
+	// TokenType はトークンの種類 (IDトークン / アクセストークン)
+	TokenType TokenType
+}
+
+// HasScope はスコープを持つかを返します。
+func (u *UserInfo) HasScope(scope string) bool {
+	return slices.Contains(u.Scopes, scope)
+}
+
+// InGroup はグループに所属するかを返します。
+func (u *UserInfo) InGroup(group string) bool {
+	return slices.Contains(u.Groups, group)
 }
@@ -0,0 +1,243 @@
+package jwt_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/auth/jwt"
+)
+
+const testClientID = "test-client"
+
+// signToken は server の kid の鍵でトークンに署名するヘルパー
+func signToken(t *testing.T, server *jwksServer, kid string, claims gojwt.MapClaims) string {
+	t.Helper()
+	token := gojwt.NewWithClaims(gojwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(server.key(kid))
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	return signed
+}
+
+// idTokenClaims はCognitoのIDトークンのクレームを返す
+func idTokenClaims(issuer string) gojwt.MapClaims {
+	return gojwt.MapClaims{
+		"iss":              issuer,
+		"sub":              "user-1",
+		"aud":              testClientID,
+		"token_use":        "id",
+		"email":            "user@example.com",
+		"cognito:username": "user1",
+		"cognito:groups":   []string{"admin"},
+		"auth_time":        int64(1735689600),
+		"exp":              time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+// accessTokenClaims はCognitoのアクセストークンのクレームを返す
+func accessTokenClaims(issuer string) gojwt.MapClaims {
+	return gojwt.MapClaims{
+		"iss":            issuer,
+		"sub":            "user-1",
+		"client_id":      testClientID,
+		"token_use":      "access",
+		"username":       "user1",
+		"scope":          "openid users/read users/write",
+		"cognito:groups": []string{"admin", "staff"},
+		"auth_time":      int64(1735689600),
+		"exp":            time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+// TestJwtManager_VerifyToken はトークンの種類ごとにアプリクライアントを照合することを確認します。
+func TestJwtManager_VerifyToken(t *testing.T) {
+	server := newJWKSServer(t, "key-1")
+	issuer := server.URL + "/ap-northeast-1_test"
+	bothTypes := jwt.WithTokenTypes(jwt.TokenTypeID, jwt.TokenTypeAccess)
+
+	tests := []struct {
+		name    string
+		opts    []jwt.Option
+		claims  func(gojwt.MapClaims)
+		access  bool
+		wantErr bool
+	}{
+		{
+			name: "正常系: IDトークン",
+		},
+		{
+			name: "正常系: aud が配列のIDトークン",
+			claims: func(c gojwt.MapClaims) {
+				c["aud"] = []string{"other-client", testClientID}
+			},
+		},
+		{
+			name:   "正常系: アクセストークンを受け付ける設定",
+			opts:   []jwt.Option{bothTypes},
+			access: true,
+		},
+		{
+			name:    "異常系: デフォルトではアクセストークンを受け付けない",
+			access:  true,
+			wantErr: true,
+		},
+		{
+			name: "異常系: IDトークンの aud が別のアプリクライアント",
+			claims: func(c gojwt.MapClaims) {
+				c["aud"] = "other-client"
+			},
+			wantErr: true,
+		},
+		{
+			name: "異常系: IDトークンに aud がない",
+			claims: func(c gojwt.MapClaims) {
+				delete(c, "aud")
+			},
+			wantErr: true,
+		},
+		{
+			name:   "異常系: アクセストークンの client_id が別のアプリクライアント",
+			opts:   []jwt.Option{bothTypes},
+			access: true,
+			claims: func(c gojwt.MapClaims) {
+				c["client_id"] = "other-client"
+			},
+			wantErr: true,
+		},
+		{
+			name: "異常系: token_use が不明",
+			opts: []jwt.Option{bothTypes},
+			claims: func(c gojwt.MapClaims) {
+				c["token_use"] = "refresh"
+			},
+			wantErr: true,
+		},
+		{
+			name: "異常系: 発行者が異なる",
+			claims: func(c gojwt.MapClaims) {
+				c["iss"] = "https://cognito-idp.ap-northeast-1.amazonaws.com/other"
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]jwt.Option{jwt.WithEndpoint(server.URL)}, tt.opts...)
+			manager, err := jwt.NewJwtManager("ap-northeast-1", "ap-northeast-1_test", testClientID, opts...)
+			if err != nil {
+				t.Fatalf("NewJwtManager() error = %v", err)
+			}
+
+			claims := idTokenClaims(issuer)
+			if tt.access {
+				claims = accessTokenClaims(issuer)
+			}
+			if tt.claims != nil {
+				tt.claims(claims)
+			}
+
+			_, err = manager.VerifyToken(context.Background(), signToken(t, server, "key-1", claims))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestJwtManager_GetUserInfo はトークンの種類ごとのクレームからユーザー情報を取得することを確認します。
+func TestJwtManager_GetUserInfo(t *testing.T) {
+	server := newJWKSServer(t, "key-1")
+	issuer := server.URL + "/ap-northeast-1_test"
+	manager, err := jwt.NewJwtManager("ap-northeast-1", "ap-northeast-1_test", testClientID,
+		jwt.WithEndpoint(server.URL),
+		jwt.WithTokenTypes(jwt.TokenTypeID, jwt.TokenTypeAccess),
+	)
+	if err != nil {
+		t.Fatalf("NewJwtManager() error = %v", err)
+	}
+	authTime := time.Unix(1735689600, 0)
+
+	tests := []struct {
+		name    string
+		claims  gojwt.MapClaims
+		want    *jwt.UserInfo
+		wantErr bool
+	}{
+		{
+			name:   "正常系: IDトークン",
+			claims: idTokenClaims(issuer),
+			want: &jwt.UserInfo{
+				Sub:       "user-1",
+				Email:     "user@example.com",
+				Username:  "user1",
+				Groups:    []string{"admin"},
+				AuthTime:  authTime,
+				TokenType: jwt.TokenTypeID,
+			},
+		},
+		{
+			name:   "正常系: アクセストークン",
+			claims: accessTokenClaims(issuer),
+			want: &jwt.UserInfo{
+				Sub:       "user-1",
+				Username:  "user1",
+				Scopes:    []string{"openid", "users/read", "users/write"},
+				Groups:    []string{"admin", "staff"},
+				AuthTime:  authTime,
+				TokenType: jwt.TokenTypeAccess,
+			},
+		},
+		{
+			name: "異常系: IDトークンに email がない",
+			claims: func() gojwt.MapClaims {
+				c := idTokenClaims(issuer)
+				delete(c, "email")
+				return c
+			}(),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := manager.VerifyToken(context.Background(), signToken(t, server, "key-1", tt.claims))
+			if err != nil {
+				t.Fatalf("VerifyToken() error = %v", err)
+			}
+			got, err := manager.GetUserInfo(token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetUserInfo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !got.AuthTime.Equal(tt.want.AuthTime) {
+				t.Errorf("AuthTime = %v, want %v", got.AuthTime, tt.want.AuthTime)
+			}
+			got.AuthTime = tt.want.AuthTime
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetUserInfo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestUserInfo_HasScope_InGroup はスコープとグループによる認可の判定を確認します。
+func TestUserInfo_HasScope_InGroup(t *testing.T) {
+	info := &jwt.UserInfo{
+		Scopes: []string{"users/read"},
+		Groups: []string{"admin"},
+	}
+	if !info.HasScope("users/read") || info.HasScope("users/write") {
+		t.Errorf("HasScope() mismatch for scopes %v", info.Scopes)
+	}
+	if !info.InGroup("admin") || info.InGroup("staff") {
+		t.Errorf("InGroup() mismatch for groups %v", info.Groups)
+	}
+}
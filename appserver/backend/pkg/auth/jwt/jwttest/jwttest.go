@@ -0,0 +1,381 @@
+// Package jwttest はCognito互換のトークン発行者をプロセス内で実装するテスト・開発用のパッケージです。
+// RSAの鍵を生成してJWKSを配信し、CognitoのIDトークンとアクセストークンと同じ形のトークンを発行するため、
+// magnito や Cognito なしで jwt.JwtManager と JwtVerify ミドルウェアを実際の署名付きトークンで検証できます。
+// 有効期限切れ、発行者の不一致、署名アルゴリズムの不一致などのトークンも TokenOption で発行できます。
+//
+// 使用例:
+//
+//	issuer, err := jwttest.New()
+//	endpoint := issuer.Start()
+//	defer issuer.Close()
+//	manager, err := jwt.NewJwtManager(jwttest.Region, issuer.UserPoolID(), issuer.ClientID(), jwt.WithEndpoint(endpoint))
+//	token, err := issuer.IDToken("user-1", jwttest.WithClaim("email", "user@example.com"))
+package jwttest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// Region は発行者のユーザープールのリージョン
+	Region = "ap-northeast-1"
+
+	defaultUserPoolID = "ap-northeast-1_jwttest"
+	defaultClientID   = "jwttest-client"
+	defaultKeyID      = "jwttest-key-1"
+	defaultExpiresIn  = time.Hour
+	// jwksMaxAge はJWKSのレスポンスの Cache-Control の max-age (秒)
+	jwksMaxAge = 300
+	// rsaKeyBits はテスト用の鍵の長さ (Cognitoと同じ2048ビット)
+	rsaKeyBits = 2048
+	// hmacSecret は RSA 以外の署名アルゴリズムを指定した場合に使用する共通鍵
+	hmacSecret = "jwttest-hmac-secret"
+)
+
+// Option はIssuerの設定を変更する関数型です。
+type Option func(*Issuer)
+
+// WithUserPoolID はユーザープールIDを設定します (デフォルト: ap-northeast-1_jwttest)。
+func WithUserPoolID(userPoolID string) Option {
+	return func(i *Issuer) {
+		i.userPoolID = userPoolID
+	}
+}
+
+// WithClientID はアプリクライアントIDを設定します (デフォルト: jwttest-client)。
+// IDトークンの aud とアクセストークンの client_id に使用します。
+func WithClientID(clientID string) Option {
+	return func(i *Issuer) {
+		i.clientID = clientID
+	}
+}
+
+// WithEndpoint は発行者のURLを設定します。
+// Handler を既存のサーバーにマウントして使用する場合に、そのURLを指定します (Start を使用する場合は不要)。
+// トークンの iss は "<endpoint>/<userPoolID>" になります。
+func WithEndpoint(endpoint string) Option {
+	return func(i *Issuer) {
+		i.endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+}
+
+// Issuer はCognito互換のトークン発行者です。複数のゴルーチンから安全に使用できます。
+type Issuer struct {
+	userPoolID string
+	clientID   string
+
+	mu       sync.RWMutex
+	endpoint string
+	server   *httptest.Server
+	// keys はJWKSで公開する鍵、kid は署名に使用する現在の鍵
+	keys map[string]*rsa.PrivateKey
+	kid  string
+}
+
+// New はIssuerを生成するコンストラクタです。署名用のRSAの鍵を1つ生成します。
+// 引数:
+//   - opts: オプション
+//
+// 戻り値:
+//   - *Issuer: 発行者
+//   - error: 鍵の生成に失敗した場合のエラー
+func New(opts ...Option) (*Issuer, error) {
+	i := &Issuer{
+		userPoolID: defaultUserPoolID,
+		clientID:   defaultClientID,
+		keys:       make(map[string]*rsa.PrivateKey),
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	if err := i.RotateKey(defaultKeyID); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// UserPoolID はユーザープールIDを返します。
+func (i *Issuer) UserPoolID() string { return i.userPoolID }
+
+// ClientID はアプリクライアントIDを返します。
+func (i *Issuer) ClientID() string { return i.clientID }
+
+// Endpoint は発行者のURLを返します (jwt.WithEndpoint に渡す値)。
+func (i *Issuer) Endpoint() string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.endpoint
+}
+
+// IssuerURL はトークンの iss ("<endpoint>/<userPoolID>") を返します。
+func (i *Issuer) IssuerURL() string {
+	return i.Endpoint() + "/" + i.userPoolID
+}
+
+// Start はJWKSを配信するHTTPサーバーを起動し、そのURLを返します。
+// 注意事項: 使用後は Close を呼び出すこと
+func (i *Issuer) Start() string {
+	server := httptest.NewServer(i.Handler())
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.server = server
+	i.endpoint = server.URL
+	return i.endpoint
+}
+
+// Close は Start で起動したHTTPサーバーを停止します。
+func (i *Issuer) Close() {
+	i.mu.Lock()
+	server := i.server
+	i.server = nil
+	i.mu.Unlock()
+	if server != nil {
+		server.Close()
+	}
+}
+
+// RotateKey は kid のRSAの鍵を生成し、以降のトークンの署名に使用します。
+// それまでの鍵もJWKSで公開し続けるため、発行済みのトークンは引き続き検証できます。
+func (i *Issuer) RotateKey(kid string) error {
+	key, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	if err != nil {
+		return fmt.Errorf("failed to generate rsa key: %w", err)
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.keys[kid] = key
+	i.kid = kid
+	return nil
+}
+
+// RemoveKey は kid の鍵をJWKSから取り除きます。
+// 取り除いた鍵で署名したトークンは、JWKSの再取得後に検証できなくなります。
+func (i *Issuer) RemoveKey(kid string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.keys, kid)
+}
+
+// Handler は "GET /<userPoolID>/.well-known/jwks.json" でJWKSを返すハンドラーを返します。
+func (i *Issuer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /"+i.userPoolID+"/.well-known/jwks.json", i.serveJWKS)
+	return mux
+}
+
+// serveJWKS は公開中の鍵をJWKSとして返す
+func (i *Issuer) serveJWKS(w http.ResponseWriter, r *http.Request) {
+	type jwk struct {
+		Kid string `json:"kid"`
+		Kty string `json:"kty"`
+		Alg string `json:"alg"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	}
+	jwks := struct {
+		Keys []jwk `json:"keys"`
+	}{Keys: []jwk{}}
+
+	i.mu.RLock()
+	for kid, key := range i.keys {
+		jwks.Keys = append(jwks.Keys, jwk{
+			Kid: kid,
+			Kty: "RSA",
+			Alg: gojwt.SigningMethodRS256.Alg(),
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	i.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", jwksMaxAge))
+	json.NewEncoder(w).Encode(jwks)
+}
+
+// IDToken はCognitoのIDトークンと同じ形のトークンを発行します。
+// デフォルトのクレーム: iss, sub, aud (アプリクライアントID), token_use=id, email, cognito:username, auth_time, iat, exp (1時間後)
+// 引数:
+//   - sub: ユーザーID
+//   - opts: クレーム、有効期限、kid、署名アルゴリズムの変更
+func (i *Issuer) IDToken(sub string, opts ...TokenOption) (string, error) {
+	now := time.Now()
+	claims := gojwt.MapClaims{
+		"iss":              i.IssuerURL(),
+		"sub":              sub,
+		"aud":              i.clientID,
+		"token_use":        "id",
+		"email":            sub + "@example.com",
+		"email_verified":   true,
+		"cognito:username": sub,
+		"auth_time":        now.Unix(),
+		"iat":              now.Unix(),
+	}
+	return i.sign(claims, now, opts)
+}
+
+// AccessToken はCognitoのアクセストークンと同じ形のトークンを発行します。
+// デフォルトのクレーム: iss, sub, client_id (アプリクライアントID), token_use=access, scope=openid, username, auth_time, iat, exp (1時間後)
+// 引数:
+//   - sub: ユーザーID
+//   - opts: クレーム、有効期限、kid、署名アルゴリズムの変更
+func (i *Issuer) AccessToken(sub string, opts ...TokenOption) (string, error) {
+	now := time.Now()
+	claims := gojwt.MapClaims{
+		"iss":       i.IssuerURL(),
+		"sub":       sub,
+		"client_id": i.clientID,
+		"token_use": "access",
+		"scope":     "openid",
+		"username":  sub,
+		"auth_time": now.Unix(),
+		"iat":       now.Unix(),
+	}
+	return i.sign(claims, now, opts)
+}
+
+// MustIDToken は IDToken のエラー時にpanicする版です (テスト用)。
+func (i *Issuer) MustIDToken(sub string, opts ...TokenOption) string {
+	token, err := i.IDToken(sub, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return token
+}
+
+// MustAccessToken は AccessToken のエラー時にpanicする版です (テスト用)。
+func (i *Issuer) MustAccessToken(sub string, opts ...TokenOption) string {
+	token, err := i.AccessToken(sub, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return token
+}
+
+// sign はオプションを適用したクレームに署名する
+func (i *Issuer) sign(claims gojwt.MapClaims, now time.Time, opts []TokenOption) (string, error) {
+	i.mu.RLock()
+	o := tokenOptions{
+		expiresIn: defaultExpiresIn,
+		kid:       i.kid,
+		method:    gojwt.SigningMethodRS256,
+	}
+	i.mu.RUnlock()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	claims["exp"] = now.Add(o.expiresIn).Unix()
+	for name, value := range o.claims {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+
+	token := gojwt.NewWithClaims(o.method, claims)
+	token.Header["kid"] = o.kid
+
+	var key interface{}
+	switch o.method.(type) {
+	case *gojwt.SigningMethodRSA, *gojwt.SigningMethodRSAPSS:
+		i.mu.RLock()
+		rsaKey, ok := i.keys[o.kid]
+		i.mu.RUnlock()
+		if !ok {
+			// JWKSにない kid のトークンは、署名用に一時的な鍵を生成する
+			generated, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+			if err != nil {
+				return "", fmt.Errorf("failed to generate rsa key: %w", err)
+			}
+			rsaKey = generated
+		}
+		key = rsaKey
+	case *gojwt.SigningMethodHMAC:
+		key = []byte(hmacSecret)
+	default:
+		if o.method == gojwt.SigningMethodNone {
+			key = gojwt.UnsafeAllowNoneSignatureType
+		} else {
+			return "", fmt.Errorf("unsupported signing method: %s", o.method.Alg())
+		}
+	}
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return signed, nil
+}
+
+// tokenOptions は発行するトークンの設定
+type tokenOptions struct {
+	claims    map[string]interface{}
+	expiresIn time.Duration
+	kid       string
+	method    gojwt.SigningMethod
+}
+
+// TokenOption は発行するトークンを変更する関数型です。
+type TokenOption func(*tokenOptions)
+
+// WithClaim はクレームを追加・上書きします。value が nil の場合はクレームを削除します。
+// 使用例: jwttest.WithClaim("iss", "https://example.com") (発行者の不一致)、jwttest.WithClaim("aud", nil)
+func WithClaim(name string, value interface{}) TokenOption {
+	return func(o *tokenOptions) {
+		if o.claims == nil {
+			o.claims = make(map[string]interface{})
+		}
+		o.claims[name] = value
+	}
+}
+
+// WithGroups は cognito:groups クレームを設定します。
+func WithGroups(groups ...string) TokenOption {
+	return WithClaim("cognito:groups", groups)
+}
+
+// WithScopes はアクセストークンの scope クレームを設定します。
+func WithScopes(scopes ...string) TokenOption {
+	return WithClaim("scope", strings.Join(scopes, " "))
+}
+
+// WithExpiresIn は発行からの有効期間を設定します (デフォルト: 1時間)。
+// 負の値を指定すると有効期限切れのトークンを発行します。
+func WithExpiresIn(d time.Duration) TokenOption {
+	return func(o *tokenOptions) {
+		o.expiresIn = d
+	}
+}
+
+// WithKeyID は署名に使用する鍵の kid を設定します (デフォルト: 現在の鍵)。
+// JWKSにない kid を指定すると、公開されていない鍵で署名したトークンを発行します。
+func WithKeyID(kid string) TokenOption {
+	return func(o *tokenOptions) {
+		o.kid = kid
+	}
+}
+
+// WithSigningMethod は署名アルゴリズムを設定します (デフォルト: RS256)。
+// HMAC (HS256 など) は固定の共通鍵で、none は署名なしで発行します。
+// 使用例: jwttest.WithSigningMethod(gojwt.SigningMethodHS256) (署名アルゴリズムの不一致)
+func WithSigningMethod(method gojwt.SigningMethod) TokenOption {
+	return func(o *tokenOptions) {
+		o.method = method
+	}
+}
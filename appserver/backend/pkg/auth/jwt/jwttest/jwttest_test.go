@@ -0,0 +1,193 @@
+package jwttest_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/auth/jwt"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/auth/jwt/jwttest"
+)
+
+// newIssuer は起動済みのIssuerと、それを信頼するJwtManagerを生成するヘルパー
+func newIssuer(t *testing.T) (*jwttest.Issuer, jwt.JwtManager) {
+	t.Helper()
+	issuer, err := jwttest.New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	endpoint := issuer.Start()
+	t.Cleanup(issuer.Close)
+
+	manager, err := jwt.NewJwtManager(jwttest.Region, issuer.UserPoolID(), issuer.ClientID(),
+		jwt.WithEndpoint(endpoint),
+		jwt.WithTokenTypes(jwt.TokenTypeID, jwt.TokenTypeAccess),
+		jwt.WithJWKSOptions(jwt.WithRefetchInterval(time.Nanosecond)),
+	)
+	if err != nil {
+		t.Fatalf("NewJwtManager() error = %v", err)
+	}
+	return issuer, manager
+}
+
+// TestIssuer_VerifyToken は発行したトークンを JwtManager がオフラインで検証できることを確認します。
+func TestIssuer_VerifyToken(t *testing.T) {
+	issuer, manager := newIssuer(t)
+
+	tests := []struct {
+		name    string
+		mint    func() (string, error)
+		wantErr bool
+	}{
+		{
+			name: "正常系: IDトークン",
+			mint: func() (string, error) { return issuer.IDToken("user-1") },
+		},
+		{
+			name: "正常系: アクセストークン",
+			mint: func() (string, error) { return issuer.AccessToken("user-1") },
+		},
+		{
+			name:    "異常系: 有効期限切れ",
+			mint:    func() (string, error) { return issuer.IDToken("user-1", jwttest.WithExpiresIn(-time.Minute)) },
+			wantErr: true,
+		},
+		{
+			name: "異常系: 発行者が異なる",
+			mint: func() (string, error) {
+				return issuer.IDToken("user-1", jwttest.WithClaim("iss", "https://cognito-idp.ap-northeast-1.amazonaws.com/other"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "異常系: 署名アルゴリズムが HS256",
+			mint: func() (string, error) {
+				return issuer.IDToken("user-1", jwttest.WithSigningMethod(gojwt.SigningMethodHS256))
+			},
+			wantErr: true,
+		},
+		{
+			name: "異常系: 署名なし (alg=none)",
+			mint: func() (string, error) {
+				return issuer.IDToken("user-1", jwttest.WithSigningMethod(gojwt.SigningMethodNone))
+			},
+			wantErr: true,
+		},
+		{
+			name:    "異常系: JWKSにない鍵で署名",
+			mint:    func() (string, error) { return issuer.IDToken("user-1", jwttest.WithKeyID("unknown-key")) },
+			wantErr: true,
+		},
+		{
+			name:    "異常系: aud が別のアプリクライアント",
+			mint:    func() (string, error) { return issuer.IDToken("user-1", jwttest.WithClaim("aud", "other-client")) },
+			wantErr: true,
+		},
+		{
+			name:    "異常系: アクセストークンに client_id がない",
+			mint:    func() (string, error) { return issuer.AccessToken("user-1", jwttest.WithClaim("client_id", nil)) },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.mint()
+			if err != nil {
+				t.Fatalf("mint error = %v", err)
+			}
+			_, err = manager.VerifyToken(context.Background(), token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestIssuer_GetUserInfo は発行したトークンのクレームがユーザー情報に反映されることを確認します。
+func TestIssuer_GetUserInfo(t *testing.T) {
+	issuer, manager := newIssuer(t)
+
+	token := issuer.MustAccessToken("user-1",
+		jwttest.WithScopes("users/read", "users/write"),
+		jwttest.WithGroups("admin"),
+	)
+	verified, err := manager.VerifyToken(context.Background(), token)
+	if err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+	info, err := manager.GetUserInfo(verified)
+	if err != nil {
+		t.Fatalf("GetUserInfo() error = %v", err)
+	}
+	if info.Sub != "user-1" || info.Username != "user-1" || info.TokenType != jwt.TokenTypeAccess {
+		t.Errorf("GetUserInfo() = %+v", info)
+	}
+	if !reflect.DeepEqual(info.Scopes, []string{"users/read", "users/write"}) || !info.InGroup("admin") {
+		t.Errorf("Scopes = %v, Groups = %v", info.Scopes, info.Groups)
+	}
+}
+
+// TestIssuer_RotateKey は鍵のローテーション後も発行済みのトークンと新しいトークンを検証できることを確認します。
+func TestIssuer_RotateKey(t *testing.T) {
+	issuer, manager := newIssuer(t)
+	ctx := context.Background()
+
+	before := issuer.MustIDToken("user-1")
+	if err := issuer.RotateKey("jwttest-key-2"); err != nil {
+		t.Fatalf("RotateKey() error = %v", err)
+	}
+	after := issuer.MustIDToken("user-1")
+
+	if _, err := manager.VerifyToken(ctx, after); err != nil {
+		t.Errorf("VerifyToken(new key) error = %v", err)
+	}
+	if _, err := manager.VerifyToken(ctx, before); err != nil {
+		t.Errorf("VerifyToken(old key) error = %v", err)
+	}
+
+	// JWKSから取り除いた鍵は、再取得後に検証できなくなる
+	issuer.RemoveKey("jwttest-key-1")
+	removed := issuer.MustIDToken("user-1", jwttest.WithKeyID("jwttest-key-1"))
+	if _, err := manager.VerifyToken(ctx, removed); err == nil {
+		t.Error("VerifyToken(removed key) error = nil, want error")
+	}
+}
+
+// TestIssuer_Handler はJWKSのレスポンスを確認します。
+func TestIssuer_Handler(t *testing.T) {
+	issuer, err := jwttest.New(jwttest.WithUserPoolID("ap-northeast-1_dev"), jwttest.WithEndpoint("http://localhost:8080/"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got, want := issuer.IssuerURL(), "http://localhost:8080/ap-northeast-1_dev"; got != want {
+		t.Errorf("IssuerURL() = %q, want %q", got, want)
+	}
+	endpoint := issuer.Start()
+	defer issuer.Close()
+
+	resp, err := http.Get(endpoint + "/ap-northeast-1_dev/.well-known/jwks.json")
+	if err != nil {
+		t.Fatalf("GET jwks error = %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Cache-Control") == "" {
+		t.Fatalf("status = %d, Cache-Control = %q", resp.StatusCode, resp.Header.Get("Cache-Control"))
+	}
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if len(jwks.Keys) != 1 || jwks.Keys[0].Kid != "jwttest-key-1" || jwks.Keys[0].Kty != "RSA" {
+		t.Errorf("jwks = %+v", jwks)
+	}
+}
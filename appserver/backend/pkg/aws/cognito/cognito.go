@@ -46,35 +46,72 @@ type cognito struct {
 	client     *cognitoidentityprovider.Client
 	clientID   string
 	userPoolID string
+	// endpoint はエミュレータのURL (実際のCognitoの場合は空)
+	endpoint string
 }
 
-func New() Cognito {
-	// ローカル開発用のmagnito設定
-	// magnitoはローカルCognitoエミュレータ
-	// 重要: AWS SDK v2はリージョン名にバリデーションがあるため、
-	//       標準のAWSリージョン名を使用し、エンドポイントのみカスタマイズする
-	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(),
-		// 標準のAWSリージョン名を使用（SDK v2の要件）
-		awsconfig.WithRegion("ap-northeast-1"),
-		// magnitoのアクセスキーとシークレットキーを設定
-		awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-			"magnito-access-key", // Access Key ID (compose.ymlのCOGNITO_ACCESS_KEY)
-			"magnito-secret-key", // Secret Access Key (compose.ymlのCOGNITO_SECRET_KEY)
-			"",                   // Session Token (不要)
-		)),
-	)
+// options はNewのオプション
+type options struct {
+	endpoint        string
+	accessKeyID     string
+	secretAccessKey string
+}
+
+// Option はNewのオプション関数型です。
+type Option func(*options)
+
+// WithEndpoint はCognito互換のエミュレータ (magnito など) のURLを設定します。
+// 空の場合は何もしません (実際のCognitoに接続する)。
+func WithEndpoint(endpoint string) Option {
+	return func(o *options) {
+		o.endpoint = endpoint
+	}
+}
+
+// WithStaticCredentials は固定の認証情報を設定します (magnito の場合は compose.yml の COGNITO_ACCESS_KEY / COGNITO_SECRET_KEY)。
+// 空の場合は何もしません (AWS SDKのデフォルトの認証情報を使用する)。
+func WithStaticCredentials(accessKeyID, secretAccessKey string) Option {
+	return func(o *options) {
+		o.accessKeyID = accessKeyID
+		o.secretAccessKey = secretAccessKey
+	}
+}
+
+// New はCognitoクライアントを生成する
+// 引数:
+//   - region: ユーザープールのAWSリージョン
+//   - userPoolID: ユーザープールID
+//   - clientID: アプリクライアントID
+//   - opts: オプション
+//
+// 注意事項: magnitoを使用する場合も、AWS SDK v2はリージョン名にバリデーションがあるため
+// 標準のAWSリージョン名を指定し、エンドポイントのみ WithEndpoint で変更する
+func New(region, userPoolID, clientID string, opts ...Option) (Cognito, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	loadOpts := []func(*awsconfig.LoadOptions) error{awsconfig.WithRegion(region)}
+	if o.accessKeyID != "" {
+		loadOpts = append(loadOpts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(o.accessKeyID, o.secretAccessKey, ""),
+		))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), loadOpts...)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to load aws config: %w", err)
 	}
 	return &cognito{
-		client: cognitoidentityprovider.NewFromConfig(awsCfg, func(o *cognitoidentityprovider.Options) {
-			// magnitoのローカルエンドポイントを指定
-			// これにより、実際のAWSではなくローカルのmagnitoに接続される
-			o.BaseEndpoint = aws.String("http://localhost:5050")
+		client: cognitoidentityprovider.NewFromConfig(awsCfg, func(co *cognitoidentityprovider.Options) {
+			if o.endpoint != "" {
+				co.BaseEndpoint = aws.String(o.endpoint)
+			}
 		}),
-		clientID:   "magnito-client-name",  // compose.ymlのCOGNITO_USER_POOL_CLIENT_ID
-		userPoolID: "ap-northeast-1_local", // compose.ymlのCOGNITO_USER_POOL_ID
-	}
+		clientID:   clientID,
+		userPoolID: userPoolID,
+		endpoint:   o.endpoint,
+	}, nil
 }
 
 func newSignUpInput(clientID, email, password string) *cognitoidentityprovider.SignUpInput {
@@ -99,7 +136,7 @@ type SignUpResult struct {
 func (c *cognito) SignUp(ctx context.Context, userID, email, password string) (*SignUpResult, error) {
 	input := newSignUpInput(c.clientID, email, password)
 	log.Printf("SignUp request: ClientID=%s, Username=%s, Email=%s, Endpoint=%s",
-		c.clientID, email, email, "http://localhost:5050")
+		c.clientID, email, email, c.endpoint)
 
 	opt, err := c.client.SignUp(ctx, input)
 	if err != nil {
@@ -120,7 +157,7 @@ func (c *cognito) SignUp(ctx context.Context, userID, email, password string) (*
 
 		// その他のエラー（403など）の場合、magnitoやエンドポイントの問題の可能性
 		log.Printf("SignUp failed - Check magnito is running: docker compose ps")
-		log.Printf("SignUp failed - Check magnito config: ClientID=%s, Endpoint=%s", c.clientID, "http://localhost:5050")
+		log.Printf("SignUp failed - Check magnito config: ClientID=%s, Endpoint=%s", c.clientID, c.endpoint)
 		return nil, fmt.Errorf("failed to sign up (check magnito status): %w", err)
 	}
 
@@ -0,0 +1,266 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// MaxBatchEntries はSQSの1回のバッチリクエストに含められるエントリ数の上限です。
+// 受信時の MaxNumberOfMessages の上限でもあります。
+const MaxBatchEntries = 10
+
+// ErrDuplicateBatchEntryID はバッチ内でエントリIDが重複している場合のエラー
+var ErrDuplicateBatchEntryID = errors.New("duplicate batch entry id")
+
+// SendMessageBatchEntry は SendMessageBatch で送信する1件のメッセージです。
+type SendMessageBatchEntry struct {
+	// ID は結果と突き合わせるための識別子です (英数字・ハイフン・アンダースコア、80文字以内)。
+	// 空の場合はバッチ内のインデックスを使用します。
+	ID string
+	// Options は SendMessage と同じオプションです (WithMessageBody など)。
+	Options []SendMessageOptionFunc
+}
+
+// NewSendMessageBatchEntry は SendMessageBatchEntry を生成します。
+// 使用例: sqs.NewSendMessageBatchEntry(userID, sqs.WithMessageBody(body))
+func NewSendMessageBatchEntry(id string, options ...SendMessageOptionFunc) SendMessageBatchEntry {
+	return SendMessageBatchEntry{ID: id, Options: options}
+}
+
+// DeleteMessageBatchEntry は DeleteMessageBatch で削除する1件のメッセージです。
+type DeleteMessageBatchEntry struct {
+	// ID は結果と突き合わせるための識別子です。空の場合はバッチ内のインデックスを使用します。
+	ID string
+	// Options は DeleteMessage と同じオプションです (WithReceiptHandle)。
+	Options []DeleteMessageOptionFunc
+}
+
+// NewDeleteMessageBatchEntry は DeleteMessageBatchEntry を生成します。
+// 使用例: sqs.NewDeleteMessageBatchEntry(aws.ToString(msg.MessageId), sqs.WithReceiptHandle(aws.ToString(msg.ReceiptHandle)))
+func NewDeleteMessageBatchEntry(id string, options ...DeleteMessageOptionFunc) DeleteMessageBatchEntry {
+	return DeleteMessageBatchEntry{ID: id, Options: options}
+}
+
+// BatchEntryError はバッチ内で失敗した1件のエントリのエラーです。
+// SQSがエントリ単位で失敗を返した場合は Code と Message が、
+// リクエスト自体が失敗した場合 (通信エラーなど) は Err が設定されます。
+type BatchEntryError struct {
+	// ID は失敗したエントリの識別子です。
+	ID string
+	// Code はSQSのエラーコードです (例: InvalidParameterValue)。
+	Code string
+	// Message はSQSのエラーメッセージです。
+	Message string
+	// SenderFault は呼び出し側の誤り (再送しても成功しない) かどうかです。
+	SenderFault bool
+	// Err はリクエスト自体が失敗した場合のエラーです。
+	Err error
+}
+
+func (e *BatchEntryError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("batch entry %s: %v", e.ID, e.Err)
+	}
+	return fmt.Sprintf("batch entry %s: %s: %s (senderFault=%t)", e.ID, e.Code, e.Message, e.SenderFault)
+}
+
+func (e *BatchEntryError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable は再送すれば成功する可能性があるかどうかを返します。
+func (e *BatchEntryError) Retryable() bool {
+	return !e.SenderFault
+}
+
+// SendMessageBatchResult は SendMessageBatch の結果です。
+type SendMessageBatchResult struct {
+	// Successful は送信に成功したエントリです。
+	Successful []types.SendMessageBatchResultEntry
+	// Failed は送信に失敗したエントリです。
+	Failed []*BatchEntryError
+}
+
+// Err は失敗したエントリのエラーを errors.Join でまとめて返します。すべて成功した場合は nil です。
+func (r *SendMessageBatchResult) Err() error {
+	return joinBatchErrors(r.Failed)
+}
+
+// DeleteMessageBatchResult は DeleteMessageBatch の結果です。
+type DeleteMessageBatchResult struct {
+	// Successful は削除に成功したエントリのIDです。
+	Successful []string
+	// Failed は削除に失敗したエントリです。
+	Failed []*BatchEntryError
+}
+
+// Err は失敗したエントリのエラーを errors.Join でまとめて返します。すべて成功した場合は nil です。
+func (r *DeleteMessageBatchResult) Err() error {
+	return joinBatchErrors(r.Failed)
+}
+
+func joinBatchErrors(failed []*BatchEntryError) error {
+	errs := make([]error, len(failed))
+	for i, f := range failed {
+		errs[i] = f
+	}
+	return errors.Join(errs...)
+}
+
+// batchEntryIDs はエントリIDを決定します。空のIDはインデックスで補い、重複はエラーにします。
+func batchEntryIDs(n int, id func(i int) string) ([]string, error) {
+	ids := make([]string, n)
+	seen := make(map[string]bool, n)
+	for i := range ids {
+		ids[i] = id(i)
+		if ids[i] == "" {
+			ids[i] = strconv.Itoa(i)
+		}
+		if seen[ids[i]] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateBatchEntryID, ids[i])
+		}
+		seen[ids[i]] = true
+	}
+	return ids, nil
+}
+
+// chunks は [0, n) を MaxBatchEntries 件ずつの範囲に分割します。
+func chunks(n int) [][2]int {
+	var ranges [][2]int
+	for start := 0; start < n; start += MaxBatchEntries {
+		ranges = append(ranges, [2]int{start, min(start+MaxBatchEntries, n)})
+	}
+	return ranges
+}
+
+// failedEntries はリクエスト自体が失敗したチャンクのエントリを失敗として記録します。
+func failedEntries(ids []string, err error) []*BatchEntryError {
+	failed := make([]*BatchEntryError, len(ids))
+	for i, id := range ids {
+		failed[i] = &BatchEntryError{ID: id, Err: err}
+	}
+	return failed
+}
+
+func fromResultErrors(entries []types.BatchResultErrorEntry) []*BatchEntryError {
+	failed := make([]*BatchEntryError, len(entries))
+	for i, e := range entries {
+		failed[i] = &BatchEntryError{
+			ID:          aws.ToString(e.Id),
+			Code:        aws.ToString(e.Code),
+			Message:     aws.ToString(e.Message),
+			SenderFault: e.SenderFault,
+		}
+	}
+	return failed
+}
+
+func (s *sqsClient) SendMessageBatch(ctx context.Context, queueURL string, entries []SendMessageBatchEntry) (*SendMessageBatchResult, error) {
+	ids, err := batchEntryIDs(len(entries), func(i int) string { return entries[i].ID })
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SendMessageBatchResult{}
+	for _, r := range chunks(len(entries)) {
+		requestEntries := make([]types.SendMessageBatchRequestEntry, 0, r[1]-r[0])
+		for i := r[0]; i < r[1]; i++ {
+			// 単体の SendMessage と同じオプションを適用してからバッチのエントリに詰め替える
+			input := &sqs.SendMessageInput{}
+			for _, option := range entries[i].Options {
+				option(input)
+			}
+			injectTraceContext(ctx, input)
+			requestEntries = append(requestEntries, types.SendMessageBatchRequestEntry{
+				Id:                      aws.String(ids[i]),
+				MessageBody:             input.MessageBody,
+				DelaySeconds:            input.DelaySeconds,
+				MessageAttributes:       input.MessageAttributes,
+				MessageSystemAttributes: input.MessageSystemAttributes,
+				MessageDeduplicationId:  input.MessageDeduplicationId,
+				MessageGroupId:          input.MessageGroupId,
+			})
+		}
+
+		output, err := s.client.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+			QueueUrl: aws.String(queueURL),
+			Entries:  requestEntries,
+		})
+		if err != nil {
+			result.Failed = append(result.Failed, failedEntries(ids[r[0]:r[1]], err)...)
+			continue
+		}
+		result.Successful = append(result.Successful, output.Successful...)
+		result.Failed = append(result.Failed, fromResultErrors(output.Failed)...)
+	}
+	return result, nil
+}
+
+func (s *sqsClient) DeleteMessageBatch(ctx context.Context, queueURL string, entries []DeleteMessageBatchEntry) (*DeleteMessageBatchResult, error) {
+	ids, err := batchEntryIDs(len(entries), func(i int) string { return entries[i].ID })
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DeleteMessageBatchResult{}
+	for _, r := range chunks(len(entries)) {
+		requestEntries := make([]types.DeleteMessageBatchRequestEntry, 0, r[1]-r[0])
+		for i := r[0]; i < r[1]; i++ {
+			input := &sqs.DeleteMessageInput{}
+			for _, option := range entries[i].Options {
+				option(input)
+			}
+			requestEntries = append(requestEntries, types.DeleteMessageBatchRequestEntry{
+				Id:            aws.String(ids[i]),
+				ReceiptHandle: input.ReceiptHandle,
+			})
+		}
+
+		output, err := s.client.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+			QueueUrl: aws.String(queueURL),
+			Entries:  requestEntries,
+		})
+		if err != nil {
+			result.Failed = append(result.Failed, failedEntries(ids[r[0]:r[1]], err)...)
+			continue
+		}
+		for _, e := range output.Successful {
+			result.Successful = append(result.Successful, aws.ToString(e.Id))
+		}
+		result.Failed = append(result.Failed, fromResultErrors(output.Failed)...)
+	}
+	return result, nil
+}
+
+func (s *sqsClient) ReceiveMessageBatch(ctx context.Context, queueURL string, maxMessages int, options ...ReceiveMessageOptionFunc) ([]types.Message, error) {
+	var messages []types.Message
+	for len(messages) < maxMessages {
+		input := &sqs.ReceiveMessageInput{
+			QueueUrl: aws.String(queueURL),
+		}
+		for _, option := range options {
+			option(input)
+		}
+		input.MaxNumberOfMessages = int32(min(maxMessages-len(messages), MaxBatchEntries))
+		if len(messages) > 0 {
+			// 2回目以降はキューに残っている分だけを受け取り、ロングポーリングで待機しない
+			input.WaitTimeSeconds = 0
+		}
+
+		output, err := s.client.ReceiveMessage(ctx, input)
+		if err != nil {
+			return messages, err
+		}
+		if len(output.Messages) == 0 {
+			break
+		}
+		messages = append(messages, output.Messages...)
+	}
+	return messages, nil
+}
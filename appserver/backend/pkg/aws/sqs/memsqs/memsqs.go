@@ -0,0 +1,516 @@
+// Package memsqs はSQSAPIをプロセス内で実装するSQSエミュレーターです。
+// キュー、可視性タイムアウト、受信回数、DLQへのリドライブ、ロングポーリング、FIFOのメッセージグループを再現し、
+// Dockerやネットワークなしで Worker / SQSPoller の結合テストやローカル実行を行えるようにします。
+// 時刻は clock.Clock で制御でき、clock.Fake を渡すと可視性タイムアウトやロングポーリングを即座に進められます。
+//
+// 使用例:
+//
+//	server := memsqs.New(memsqs.WithClock(clk))
+//	server.MustCreateQueue("worker-queue-dlq")
+//	queueURL := server.MustCreateQueue("worker-queue", memsqs.WithRedrivePolicy("worker-queue-dlq", 3))
+//	client := sqs.NewSQSClientWithAPI(server)
+package memsqs
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+	"github.com/google/uuid"
+	sqspkg "github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/clock"
+)
+
+const (
+	defaultBaseURL           = "http://memsqs.local"
+	defaultVisibilityTimeout = 30 * time.Second
+	accountID                = "000000000000"
+	region                   = "ap-northeast-1"
+	senderID                 = "AIDAMEMSQS"
+	// deduplicationInterval はFIFOキューで同じ重複排除IDのメッセージを破棄する期間
+	deduplicationInterval = 5 * time.Minute
+	maxDelaySeconds       = 900
+	maxVisibilityTimeout  = 43200
+	maxWaitTimeSeconds    = 20
+)
+
+var _ sqspkg.SQSAPI = (*Server)(nil)
+
+// Option はServerの設定を変更する関数型です。
+type Option func(*Server)
+
+// WithClock は可視性タイムアウトや遅延、ロングポーリングに使用する時計を設定します (デフォルト: 実時間)。
+func WithClock(c clock.Clock) Option {
+	return func(s *Server) {
+		s.clock = c
+	}
+}
+
+// WithBaseURL はキューURLの接頭辞を設定します (デフォルト: http://memsqs.local)。
+// キューURLは "<baseURL>/000000000000/<キュー名>" です。
+func WithBaseURL(baseURL string) Option {
+	return func(s *Server) {
+		s.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// Server はプロセス内のSQSエミュレーターです。SQSAPI を実装し、複数のゴルーチンから安全に使用できます。
+type Server struct {
+	clock   clock.Clock
+	baseURL string
+
+	mu     sync.Mutex
+	queues map[string]*queue
+	urls   map[string]*queue
+	// changed はメッセージの追加・削除・可視性の変更時にクローズされ、ロングポーリング中の受信を起こす
+	changed chan struct{}
+	// seq はメッセージの送信順を表す通し番号 (FIFOキューのシーケンス番号にも使用する)
+	seq int64
+}
+
+// New はServerを生成するコンストラクタです。キューは CreateQueue で作成します。
+func New(opts ...Option) *Server {
+	s := &Server{
+		clock:   clock.New(),
+		baseURL: defaultBaseURL,
+		queues:  make(map[string]*queue),
+		urls:    make(map[string]*queue),
+		changed: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// QueueOption はキューの属性を設定する関数型です。
+type QueueOption func(*queueConfig)
+
+type queueConfig struct {
+	visibilityTimeout time.Duration
+	delay             time.Duration
+	deadLetterQueue   string
+	maxReceiveCount   int
+	contentBasedDedup bool
+}
+
+// WithVisibilityTimeout はキューの可視性タイムアウトを設定します (デフォルト: 30秒)。
+func WithVisibilityTimeout(d time.Duration) QueueOption {
+	return func(c *queueConfig) {
+		c.visibilityTimeout = d
+	}
+}
+
+// WithDelay はキューの配信遅延を設定します。送信したメッセージは d 経過後に受信可能になります。
+func WithDelay(d time.Duration) QueueOption {
+	return func(c *queueConfig) {
+		c.delay = d
+	}
+}
+
+// WithRedrivePolicy は maxReceiveCount 回受信しても削除されなかったメッセージを移動するDLQを設定します。
+// DLQは事前に作成しておく必要があり、FIFOキューのDLQはFIFOキューである必要があります。
+func WithRedrivePolicy(deadLetterQueue string, maxReceiveCount int) QueueOption {
+	return func(c *queueConfig) {
+		c.deadLetterQueue = deadLetterQueue
+		c.maxReceiveCount = maxReceiveCount
+	}
+}
+
+// WithContentBasedDeduplication はFIFOキューで重複排除IDを省略した場合に、本文のハッシュで重複を排除します。
+func WithContentBasedDeduplication() QueueOption {
+	return func(c *queueConfig) {
+		c.contentBasedDedup = true
+	}
+}
+
+// CreateQueue はキューを作成し、キューURLを返します。名前が ".fifo" で終わる場合はFIFOキューです。
+// 同じ名前のキューがある場合や、DLQが存在しない場合はエラーを返します。
+func (s *Server) CreateQueue(name string, opts ...QueueOption) (string, error) {
+	cfg := queueConfig{visibilityTimeout: defaultVisibilityTimeout}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.queues[name]; ok {
+		return "", &types.QueueNameExists{Message: aws.String(fmt.Sprintf("queue already exists: %s", name))}
+	}
+	q := newQueue(name, fmt.Sprintf("%s/%s/%s", s.baseURL, accountID, name), cfg)
+	if cfg.deadLetterQueue != "" {
+		dlq, ok := s.queues[cfg.deadLetterQueue]
+		if !ok {
+			return "", &types.QueueDoesNotExist{Message: aws.String(fmt.Sprintf("dead-letter queue does not exist: %s", cfg.deadLetterQueue))}
+		}
+		if dlq.fifo != q.fifo || cfg.maxReceiveCount < 1 {
+			return "", invalidParameter("dead-letter queue %s must be the same type as %s and maxReceiveCount must be positive", dlq.name, name)
+		}
+		q.dlq = dlq
+	}
+	s.queues[name] = q
+	s.urls[q.url] = q
+	return q.url, nil
+}
+
+// MustCreateQueue は CreateQueue と同じですが、エラーの場合はpanicします。テストの準備に使用します。
+func (s *Server) MustCreateQueue(name string, opts ...QueueOption) string {
+	url, err := s.CreateQueue(name, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return url
+}
+
+// QueueStats はキュー内のメッセージ数です。
+type QueueStats struct {
+	// Visible は受信可能なメッセージ数です (ApproximateNumberOfMessages)。
+	Visible int
+	// InFlight は受信済みで削除されていないメッセージ数です (ApproximateNumberOfMessagesNotVisible)。
+	InFlight int
+	// Delayed は配信遅延中のメッセージ数です (ApproximateNumberOfMessagesDelayed)。
+	Delayed int
+}
+
+// Total はキュー内のすべてのメッセージ数を返します。
+func (st QueueStats) Total() int {
+	return st.Visible + st.InFlight + st.Delayed
+}
+
+// Stats はキュー内のメッセージ数を返します。キューが存在しない場合はゼロ値です。
+func (s *Server) Stats(name string) QueueStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.queues[name]
+	if !ok {
+		return QueueStats{}
+	}
+	return q.stats(s.clock.Now())
+}
+
+// notifyLocked はロングポーリング中の受信を起こします。s.mu を保持して呼び出すこと。
+func (s *Server) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *Server) queueByURL(queueURL *string) (*queue, error) {
+	q, ok := s.urls[aws.ToString(queueURL)]
+	if !ok {
+		return nil, &types.QueueDoesNotExist{Message: aws.String(fmt.Sprintf("queue does not exist: %s", aws.ToString(queueURL)))}
+	}
+	return q, nil
+}
+
+// GetQueueUrl はキュー名からキューURLを返します。
+func (s *Server) GetQueueUrl(ctx context.Context, params *sqs.GetQueueUrlInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueUrlOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.queues[aws.ToString(params.QueueName)]
+	if !ok {
+		return nil, &types.QueueDoesNotExist{Message: aws.String(fmt.Sprintf("queue does not exist: %s", aws.ToString(params.QueueName)))}
+	}
+	return &sqs.GetQueueUrlOutput{QueueUrl: aws.String(q.url)}, nil
+}
+
+// SendMessage はメッセージをキューに追加します。
+// FIFOキューでは MessageGroupId が必須で、5分以内に同じ重複排除IDで送信したメッセージは追加せずに元のメッセージIDを返します。
+func (s *Server) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, err := s.queueByURL(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.sendLocked(q, params)
+	if err != nil {
+		return nil, err
+	}
+	return &sqs.SendMessageOutput{
+		MessageId:        aws.String(m.id),
+		MD5OfMessageBody: aws.String(m.md5),
+		SequenceNumber:   m.sequenceNumber(q.fifo),
+	}, nil
+}
+
+func (s *Server) sendLocked(q *queue, params *sqs.SendMessageInput) (*message, error) {
+	body := aws.ToString(params.MessageBody)
+	if body == "" {
+		return nil, apiError("MissingParameter", "the request must contain the parameter MessageBody")
+	}
+	if params.DelaySeconds < 0 || params.DelaySeconds > maxDelaySeconds {
+		return nil, invalidParameter("DelaySeconds must be between 0 and %d: %d", maxDelaySeconds, params.DelaySeconds)
+	}
+	now := s.clock.Now()
+	delay := q.cfg.delay
+	if params.DelaySeconds > 0 {
+		delay = time.Duration(params.DelaySeconds) * time.Second
+	}
+
+	m := &message{
+		id:         uuid.NewString(),
+		body:       body,
+		md5:        md5Hex(body),
+		attributes: params.MessageAttributes,
+		groupID:    aws.ToString(params.MessageGroupId),
+		sentAt:     now,
+		visibleAt:  now.Add(delay),
+	}
+	if q.fifo {
+		if m.groupID == "" {
+			return nil, apiError("MissingParameter", "the request must contain the parameter MessageGroupId")
+		}
+		if params.DelaySeconds > 0 {
+			return nil, invalidParameter("DelaySeconds is not supported on FIFO queues per message")
+		}
+		m.deduplicationID = aws.ToString(params.MessageDeduplicationId)
+		if m.deduplicationID == "" {
+			if !q.cfg.contentBasedDedup {
+				return nil, invalidParameter("the queue should either have ContentBasedDeduplication enabled or MessageDeduplicationId provided explicitly")
+			}
+			m.deduplicationID = contentHash(body)
+		}
+		if original, ok := q.deduplicated(m.deduplicationID, now); ok {
+			return original, nil
+		}
+	} else if params.MessageDeduplicationId != nil {
+		return nil, invalidParameter("MessageDeduplicationId is supported only for FIFO queues")
+	}
+
+	s.seq++
+	m.seq = s.seq
+	q.push(m, now)
+	s.notifyLocked()
+	return m, nil
+}
+
+// ReceiveMessage は受信可能なメッセージを最大 MaxNumberOfMessages 件受信します。
+// 受信可能なメッセージがない場合は WaitTimeSeconds の間、メッセージが届くか可視性タイムアウトが切れるまで待機します。
+// 受信したメッセージは可視性タイムアウトの間は他の受信者に返さず、受信回数が上限に達したメッセージはDLQに移動します。
+func (s *Server) ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	maxMessages := int(params.MaxNumberOfMessages)
+	if maxMessages == 0 {
+		maxMessages = 1
+	}
+	if maxMessages < 1 || maxMessages > sqspkg.MaxBatchEntries {
+		return nil, invalidParameter("MaxNumberOfMessages must be between 1 and %d: %d", sqspkg.MaxBatchEntries, maxMessages)
+	}
+	if params.WaitTimeSeconds < 0 || params.WaitTimeSeconds > maxWaitTimeSeconds {
+		return nil, invalidParameter("WaitTimeSeconds must be between 0 and %d: %d", maxWaitTimeSeconds, params.WaitTimeSeconds)
+	}
+	if params.VisibilityTimeout < 0 || params.VisibilityTimeout > maxVisibilityTimeout {
+		return nil, invalidParameter("VisibilityTimeout must be between 0 and %d: %d", maxVisibilityTimeout, params.VisibilityTimeout)
+	}
+	filter := newAttributeFilter(params)
+
+	deadline := s.clock.Now().Add(time.Duration(params.WaitTimeSeconds) * time.Second)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		s.mu.Lock()
+		q, err := s.queueByURL(params.QueueUrl)
+		if err != nil {
+			s.mu.Unlock()
+			return nil, err
+		}
+		now := s.clock.Now()
+		visibility := q.cfg.visibilityTimeout
+		if params.VisibilityTimeout > 0 {
+			visibility = time.Duration(params.VisibilityTimeout) * time.Second
+		}
+		received, moved := q.receive(now, maxMessages, visibility)
+		if moved {
+			s.notifyLocked()
+		}
+		messages := make([]types.Message, len(received))
+		for i, m := range received {
+			messages[i] = m.toMessage(q, filter)
+		}
+		changed := s.changed
+		wake := q.nextVisibleAt(now)
+		s.mu.Unlock()
+
+		if len(messages) > 0 || !now.Before(deadline) {
+			return &sqs.ReceiveMessageOutput{Messages: messages}, nil
+		}
+
+		// メッセージの追加・可視性の変更、次に可視になる時刻、待機の期限のいずれかまで待機する
+		if wake.IsZero() || deadline.Before(wake) {
+			wake = deadline
+		}
+		timer := s.clock.NewTimer(wake.Sub(now))
+		select {
+		case <-timer.C():
+		case <-changed:
+		case <-ctx.Done():
+		}
+		timer.Stop()
+	}
+}
+
+// DeleteMessage は受信したメッセージを削除します。最新の受信で得たレシートハンドルのみ有効です。
+func (s *Server) DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, err := s.queueByURL(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.deleteLocked(q, aws.ToString(params.ReceiptHandle)); err != nil {
+		return nil, err
+	}
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func (s *Server) deleteLocked(q *queue, receiptHandle string) error {
+	if !q.delete(receiptHandle) {
+		return &types.ReceiptHandleIsInvalid{Message: aws.String(fmt.Sprintf("receipt handle is invalid: %s", receiptHandle))}
+	}
+	// FIFOキューでは削除によって同じグループの次のメッセージが受信可能になる
+	s.notifyLocked()
+	return nil
+}
+
+// ChangeMessageVisibility は受信中のメッセージの可視性タイムアウトを現在時刻から VisibilityTimeout 秒後に変更します。
+// 0 を指定すると即座に受信可能になります。
+func (s *Server) ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+	if params.VisibilityTimeout < 0 || params.VisibilityTimeout > maxVisibilityTimeout {
+		return nil, invalidParameter("VisibilityTimeout must be between 0 and %d: %d", maxVisibilityTimeout, params.VisibilityTimeout)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, err := s.queueByURL(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	m := q.byReceiptHandle(aws.ToString(params.ReceiptHandle))
+	if m == nil {
+		return nil, &types.ReceiptHandleIsInvalid{Message: aws.String(fmt.Sprintf("receipt handle is invalid: %s", aws.ToString(params.ReceiptHandle)))}
+	}
+	if !m.inFlight(now) {
+		return nil, &types.MessageNotInflight{Message: aws.String(fmt.Sprintf("message is not in flight: %s", m.id))}
+	}
+	m.visibleAt = now.Add(time.Duration(params.VisibilityTimeout) * time.Second)
+	s.notifyLocked()
+	return &sqs.ChangeMessageVisibilityOutput{}, nil
+}
+
+// SendMessageBatch は最大10件のメッセージを送信します。エントリごとの失敗は Failed で返します。
+func (s *Server) SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+	ids := make([]string, len(params.Entries))
+	for i, e := range params.Entries {
+		ids[i] = aws.ToString(e.Id)
+	}
+	if err := validateBatch(ids); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, err := s.queueByURL(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	output := &sqs.SendMessageBatchOutput{}
+	for _, e := range params.Entries {
+		m, err := s.sendLocked(q, &sqs.SendMessageInput{
+			QueueUrl:                params.QueueUrl,
+			MessageBody:             e.MessageBody,
+			DelaySeconds:            e.DelaySeconds,
+			MessageAttributes:       e.MessageAttributes,
+			MessageSystemAttributes: e.MessageSystemAttributes,
+			MessageDeduplicationId:  e.MessageDeduplicationId,
+			MessageGroupId:          e.MessageGroupId,
+		})
+		if err != nil {
+			output.Failed = append(output.Failed, batchError(e.Id, err))
+			continue
+		}
+		output.Successful = append(output.Successful, types.SendMessageBatchResultEntry{
+			Id:               e.Id,
+			MessageId:        aws.String(m.id),
+			MD5OfMessageBody: aws.String(m.md5),
+			SequenceNumber:   m.sequenceNumber(q.fifo),
+		})
+	}
+	return output, nil
+}
+
+// DeleteMessageBatch は最大10件のメッセージを削除します。エントリごとの失敗は Failed で返します。
+func (s *Server) DeleteMessageBatch(ctx context.Context, params *sqs.DeleteMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) {
+	ids := make([]string, len(params.Entries))
+	for i, e := range params.Entries {
+		ids[i] = aws.ToString(e.Id)
+	}
+	if err := validateBatch(ids); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, err := s.queueByURL(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	output := &sqs.DeleteMessageBatchOutput{}
+	for _, e := range params.Entries {
+		if err := s.deleteLocked(q, aws.ToString(e.ReceiptHandle)); err != nil {
+			output.Failed = append(output.Failed, batchError(e.Id, err))
+			continue
+		}
+		output.Successful = append(output.Successful, types.DeleteMessageBatchResultEntry{Id: e.Id})
+	}
+	return output, nil
+}
+
+// validateBatch はバッチリクエストのエントリ数とIDの重複を検証します。
+func validateBatch(ids []string) error {
+	if len(ids) == 0 {
+		return &types.EmptyBatchRequest{Message: aws.String("there should be at least one entry in the request")}
+	}
+	if len(ids) > sqspkg.MaxBatchEntries {
+		return &types.TooManyEntriesInBatchRequest{Message: aws.String(fmt.Sprintf("maximum number of entries per request are %d: %d", sqspkg.MaxBatchEntries, len(ids)))}
+	}
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return &types.BatchEntryIdsNotDistinct{Message: aws.String(fmt.Sprintf("id %s repeated", id))}
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// batchError はエントリの失敗を BatchResultErrorEntry に変換します。メモリ上の処理のため失敗はすべて送信側の誤りです。
+func batchError(id *string, err error) types.BatchResultErrorEntry {
+	entry := types.BatchResultErrorEntry{Id: id, Code: aws.String("InternalError"), Message: aws.String(err.Error()), SenderFault: true}
+	if apiErr, ok := err.(smithy.APIError); ok {
+		entry.Code = aws.String(apiErr.ErrorCode())
+		entry.Message = aws.String(apiErr.ErrorMessage())
+	}
+	return entry
+}
+
+func apiError(code, message string) error {
+	return &smithy.GenericAPIError{Code: code, Message: message, Fault: smithy.FaultClient}
+}
+
+func invalidParameter(format string, args ...any) error {
+	return apiError("InvalidParameterValue", fmt.Sprintf(format, args...))
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,452 @@
+package memsqs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	sqspkg "github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs/memsqs"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/clock"
+)
+
+var epoch = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func newServer(t *testing.T) (*memsqs.Server, *clock.Fake, sqspkg.SQS) {
+	t.Helper()
+	clk := clock.NewFake(epoch)
+	server := memsqs.New(memsqs.WithClock(clk))
+	return server, clk, sqspkg.NewSQSClientWithAPI(server)
+}
+
+func bodies(messages []types.Message) []string {
+	got := make([]string, len(messages))
+	for i, m := range messages {
+		got[i] = aws.ToString(m.Body)
+	}
+	return got
+}
+
+func receive(t *testing.T, client sqspkg.SQS, queueURL string, options ...sqspkg.ReceiveMessageOptionFunc) []types.Message {
+	t.Helper()
+	messages, err := client.ReceiveMessages(context.Background(), queueURL, append([]sqspkg.ReceiveMessageOptionFunc{sqspkg.WithMaxMessages(10)}, options...)...)
+	if err != nil {
+		t.Fatalf("ReceiveMessages() error = %v", err)
+	}
+	return messages
+}
+
+func send(t *testing.T, client sqspkg.SQS, queueURL, body string, options ...sqspkg.SendMessageOptionFunc) {
+	t.Helper()
+	if _, err := client.SendMessage(context.Background(), queueURL, append([]sqspkg.SendMessageOptionFunc{sqspkg.WithMessageBody(body)}, options...)...); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+}
+
+// TestServer_VisibilityTimeout は受信中のメッセージが可視性タイムアウト後に再配信され、受信回数が増えることを確認します。
+func TestServer_VisibilityTimeout(t *testing.T) {
+	t.Parallel()
+
+	server, clk, client := newServer(t)
+	queueURL := server.MustCreateQueue("worker-queue", memsqs.WithVisibilityTimeout(30*time.Second))
+	send(t, client, queueURL, "m1")
+
+	first := receive(t, client, queueURL, sqspkg.WithApproximateReceiveCount())
+	if len(first) != 1 || sqspkg.ApproximateReceiveCount(first[0]) != 1 {
+		t.Fatalf("first receive = %v, want m1 with receive count 1", bodies(first))
+	}
+	if got := server.Stats("worker-queue"); got != (memsqs.QueueStats{InFlight: 1}) {
+		t.Errorf("Stats() = %+v, want 1 in flight", got)
+	}
+	// 可視性タイムアウトの間は受信できない
+	clk.Advance(29 * time.Second)
+	if got := receive(t, client, queueURL); len(got) != 0 {
+		t.Fatalf("received %v while in flight", bodies(got))
+	}
+
+	clk.Advance(time.Second)
+	second := receive(t, client, queueURL, sqspkg.WithApproximateReceiveCount())
+	if len(second) != 1 || sqspkg.ApproximateReceiveCount(second[0]) != 2 {
+		t.Fatalf("second receive = %v, want m1 with receive count 2", bodies(second))
+	}
+	// 古いレシートハンドルは無効になる
+	var invalid *types.ReceiptHandleIsInvalid
+	if err := client.DeleteMessage(context.Background(), queueURL, sqspkg.WithReceiptHandle(aws.ToString(first[0].ReceiptHandle))); !errors.As(err, &invalid) {
+		t.Errorf("DeleteMessage(stale handle) error = %v, want ReceiptHandleIsInvalid", err)
+	}
+	if err := client.DeleteMessage(context.Background(), queueURL, sqspkg.WithReceiptHandle(aws.ToString(second[0].ReceiptHandle))); err != nil {
+		t.Fatalf("DeleteMessage() error = %v", err)
+	}
+	if got := server.Stats("worker-queue").Total(); got != 0 {
+		t.Errorf("Stats().Total() = %d after delete, want 0", got)
+	}
+}
+
+// TestServer_ChangeMessageVisibility は可視性タイムアウトの延長と即時の再配信を確認します。
+func TestServer_ChangeMessageVisibility(t *testing.T) {
+	t.Parallel()
+
+	server, clk, client := newServer(t)
+	queueURL := server.MustCreateQueue("worker-queue", memsqs.WithVisibilityTimeout(30*time.Second))
+	send(t, client, queueURL, "m1")
+	received := receive(t, client, queueURL)
+	handle := aws.ToString(received[0].ReceiptHandle)
+
+	// 20秒後に60秒延長すると、受信から80秒後まで受信できない
+	clk.Advance(20 * time.Second)
+	if err := client.ChangeMessageVisibility(context.Background(), queueURL, handle, 60); err != nil {
+		t.Fatalf("ChangeMessageVisibility() error = %v", err)
+	}
+	clk.Advance(59 * time.Second)
+	if got := receive(t, client, queueURL); len(got) != 0 {
+		t.Fatalf("received %v before the extended timeout", bodies(got))
+	}
+
+	// 0 を指定すると即座に受信可能になる
+	if err := client.ChangeMessageVisibility(context.Background(), queueURL, handle, 0); err != nil {
+		t.Fatalf("ChangeMessageVisibility() error = %v", err)
+	}
+	var notInflight *types.MessageNotInflight
+	if err := client.ChangeMessageVisibility(context.Background(), queueURL, handle, 30); !errors.As(err, &notInflight) {
+		t.Errorf("ChangeMessageVisibility() error = %v, want MessageNotInflight", err)
+	}
+	if got := receive(t, client, queueURL); len(got) != 1 {
+		t.Fatalf("received %d messages after resetting visibility, want 1", len(got))
+	}
+}
+
+// TestServer_Redrive は受信回数の上限に達したメッセージがDLQに移動することを確認します。
+func TestServer_Redrive(t *testing.T) {
+	t.Parallel()
+
+	server, clk, client := newServer(t)
+	dlqURL := server.MustCreateQueue("worker-queue-dlq")
+	queueURL := server.MustCreateQueue("worker-queue", memsqs.WithVisibilityTimeout(10*time.Second), memsqs.WithRedrivePolicy("worker-queue-dlq", 2))
+	send(t, client, queueURL, "poison")
+
+	for i := 0; i < 2; i++ {
+		if got := receive(t, client, queueURL); len(got) != 1 {
+			t.Fatalf("receive %d = %v, want poison", i+1, bodies(got))
+		}
+		clk.Advance(10 * time.Second)
+	}
+	// 3回目の受信でDLQに移動し、受信者には返さない
+	if got := receive(t, client, queueURL); len(got) != 0 {
+		t.Fatalf("received %v after max receive count", bodies(got))
+	}
+	if got := server.Stats("worker-queue-dlq"); got.Visible != 1 {
+		t.Fatalf("DLQ Stats() = %+v, want 1 visible", got)
+	}
+
+	dead := receive(t, client, dlqURL, sqspkg.WithAllSystemAttributes())
+	if len(dead) != 1 || aws.ToString(dead[0].Body) != "poison" {
+		t.Fatalf("DLQ receive = %v, want poison", bodies(dead))
+	}
+	if got := dead[0].Attributes[string(types.MessageSystemAttributeNameDeadLetterQueueSourceArn)]; got != "arn:aws:sqs:ap-northeast-1:000000000000:worker-queue" {
+		t.Errorf("DeadLetterQueueSourceArn = %q", got)
+	}
+	if got := sqspkg.ApproximateReceiveCount(dead[0]); got != 1 {
+		t.Errorf("ApproximateReceiveCount() in DLQ = %d, want 1", got)
+	}
+}
+
+// TestServer_LongPolling はロングポーリング中の受信がメッセージの到着や可視性タイムアウトで起きることを確認します。
+func TestServer_LongPolling(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		prepare  func(t *testing.T, client sqspkg.SQS, queueURL string)
+		wake     func(t *testing.T, clk *clock.Fake, client sqspkg.SQS, queueURL string)
+		wantBody []string
+	}{
+		{
+			name: "正常系: 待機中に送信されたメッセージを受信する",
+			wake: func(t *testing.T, clk *clock.Fake, client sqspkg.SQS, queueURL string) {
+				send(t, client, queueURL, "m1")
+			},
+			wantBody: []string{"m1"},
+		},
+		{
+			name: "正常系: 待機中に可視性タイムアウトが切れたメッセージを受信する",
+			prepare: func(t *testing.T, client sqspkg.SQS, queueURL string) {
+				send(t, client, queueURL, "m1")
+				receive(t, client, queueURL)
+			},
+			wake: func(t *testing.T, clk *clock.Fake, client sqspkg.SQS, queueURL string) {
+				clk.Advance(5 * time.Second)
+			},
+			wantBody: []string{"m1"},
+		},
+		{
+			name: "正常系: 待機時間を過ぎると空で返る",
+			wake: func(t *testing.T, clk *clock.Fake, client sqspkg.SQS, queueURL string) {
+				clk.Advance(20 * time.Second)
+			},
+			wantBody: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server, clk, client := newServer(t)
+			queueURL := server.MustCreateQueue("worker-queue", memsqs.WithVisibilityTimeout(5*time.Second))
+			if tt.prepare != nil {
+				tt.prepare(t, client, queueURL)
+			}
+
+			done := make(chan []types.Message, 1)
+			go func() {
+				messages, _ := client.ReceiveMessages(context.Background(), queueURL, sqspkg.WithWaitTimeSeconds(20))
+				done <- messages
+			}()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if err := clk.BlockUntil(ctx, 1); err != nil {
+				t.Fatalf("receive did not start waiting: %v", err)
+			}
+			tt.wake(t, clk, client, queueURL)
+
+			select {
+			case got := <-done:
+				if fmt.Sprint(bodies(got)) != fmt.Sprint(tt.wantBody) {
+					t.Errorf("received %v, want %v", bodies(got), tt.wantBody)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("long polling receive did not return")
+			}
+		})
+	}
+}
+
+// TestServer_LongPollingCanceled はロングポーリング中にコンテキストが終了した場合にエラーを返すことを確認します。
+func TestServer_LongPollingCanceled(t *testing.T) {
+	t.Parallel()
+
+	server, _, client := newServer(t)
+	queueURL := server.MustCreateQueue("worker-queue")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.ReceiveMessages(ctx, queueURL, sqspkg.WithWaitTimeSeconds(20)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ReceiveMessages() error = %v, want DeadlineExceeded", err)
+	}
+}
+
+// TestServer_Delay は配信遅延中のメッセージが遅延後に受信可能になることを確認します。
+func TestServer_Delay(t *testing.T) {
+	t.Parallel()
+
+	server, clk, client := newServer(t)
+	queueURL := server.MustCreateQueue("worker-queue", memsqs.WithDelay(10*time.Second))
+	send(t, client, queueURL, "queue-delay")
+	send(t, client, queueURL, "message-delay", sqspkg.WithDelaySeconds(60))
+
+	if got := server.Stats("worker-queue"); got.Delayed != 2 {
+		t.Fatalf("Stats() = %+v, want 2 delayed", got)
+	}
+	clk.Advance(10 * time.Second)
+	first := receive(t, client, queueURL)
+	if got := bodies(first); fmt.Sprint(got) != "[queue-delay]" {
+		t.Fatalf("received %v after 10s, want [queue-delay]", got)
+	}
+	if err := client.DeleteMessage(context.Background(), queueURL, sqspkg.WithReceiptHandle(aws.ToString(first[0].ReceiptHandle))); err != nil {
+		t.Fatalf("DeleteMessage() error = %v", err)
+	}
+	clk.Advance(50 * time.Second)
+	if got := bodies(receive(t, client, queueURL)); fmt.Sprint(got) != "[message-delay]" {
+		t.Errorf("received %v after 60s, want [message-delay]", got)
+	}
+}
+
+// TestServer_FIFO はFIFOキューのグループごとの順序、受信中のグループの保留、重複排除を確認します。
+func TestServer_FIFO(t *testing.T) {
+	t.Parallel()
+
+	server, clk, client := newServer(t)
+	queueURL := server.MustCreateQueue("user-sync.fifo", memsqs.WithContentBasedDeduplication())
+	for _, m := range []struct{ body, group string }{{"a1", "a"}, {"b1", "b"}, {"a2", "a"}, {"b2", "b"}} {
+		send(t, client, queueURL, m.body, sqspkg.WithMessageGroupID(m.group))
+	}
+	// 5分以内の同じ本文は重複として破棄される
+	send(t, client, queueURL, "a1", sqspkg.WithMessageGroupID("a"))
+
+	first := receive(t, client, queueURL, sqspkg.WithMaxMessages(1), sqspkg.WithSystemAttributeNames(types.MessageSystemAttributeNameMessageGroupId))
+	if fmt.Sprint(bodies(first)) != "[a1]" || sqspkg.MessageGroupID(first[0]) != "a" {
+		t.Fatalf("first receive = %v, want [a1] in group a", bodies(first))
+	}
+	// グループ a は受信中のため、グループ b のメッセージだけが返る
+	if got := bodies(receive(t, client, queueURL)); fmt.Sprint(got) != "[b1 b2]" {
+		t.Fatalf("second receive = %v, want [b1 b2]", got)
+	}
+	if err := client.DeleteMessage(context.Background(), queueURL, sqspkg.WithReceiptHandle(aws.ToString(first[0].ReceiptHandle))); err != nil {
+		t.Fatalf("DeleteMessage() error = %v", err)
+	}
+	if got := bodies(receive(t, client, queueURL)); fmt.Sprint(got) != "[a2]" {
+		t.Fatalf("third receive = %v, want [a2]", got)
+	}
+
+	// 重複排除期間を過ぎれば同じ本文も受け付ける
+	clk.Advance(5 * time.Minute)
+	before := server.Stats("user-sync.fifo").Total()
+	send(t, client, queueURL, "a1", sqspkg.WithMessageGroupID("c"))
+	if got := server.Stats("user-sync.fifo").Total(); got != before+1 {
+		t.Errorf("Stats().Total() = %d, want %d after resending", got, before+1)
+	}
+}
+
+// TestServer_SendErrors は不正な送信がエラーになることを確認します。
+func TestServer_SendErrors(t *testing.T) {
+	t.Parallel()
+
+	server, _, client := newServer(t)
+	standardURL := server.MustCreateQueue("worker-queue")
+	fifoURL := server.MustCreateQueue("user-sync.fifo")
+
+	tests := []struct {
+		name     string
+		queueURL string
+		options  []sqspkg.SendMessageOptionFunc
+	}{
+		{name: "異常系: 存在しないキュー", queueURL: "http://memsqs.local/000000000000/unknown", options: []sqspkg.SendMessageOptionFunc{sqspkg.WithMessageBody("m")}},
+		{name: "異常系: 本文がない", queueURL: standardURL},
+		{name: "異常系: 標準キューに重複排除ID", queueURL: standardURL, options: []sqspkg.SendMessageOptionFunc{sqspkg.WithMessageBody("m"), sqspkg.WithMessageDeduplicationID("d")}},
+		{name: "異常系: FIFOキューにグループIDがない", queueURL: fifoURL, options: []sqspkg.SendMessageOptionFunc{sqspkg.WithMessageBody("m"), sqspkg.WithMessageDeduplicationID("d")}},
+		{name: "異常系: FIFOキューに重複排除IDがない", queueURL: fifoURL, options: []sqspkg.SendMessageOptionFunc{sqspkg.WithMessageBody("m"), sqspkg.WithMessageGroupID("g")}},
+		{name: "異常系: FIFOキューにメッセージ単位の遅延", queueURL: fifoURL, options: []sqspkg.SendMessageOptionFunc{sqspkg.WithMessageBody("m"), sqspkg.WithMessageGroupID("g"), sqspkg.WithMessageDeduplicationID("d"), sqspkg.WithDelaySeconds(5)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := client.SendMessage(context.Background(), tt.queueURL, tt.options...); err == nil {
+				t.Error("SendMessage() error = nil, want error")
+			}
+		})
+	}
+}
+
+// TestServer_Batch はバッチの送信・削除とエントリごとの失敗を確認します。
+func TestServer_Batch(t *testing.T) {
+	t.Parallel()
+
+	server, _, client := newServer(t)
+	queueURL := server.MustCreateQueue("worker-queue")
+
+	entries := make([]sqspkg.SendMessageBatchEntry, 12)
+	for i := range entries {
+		entries[i] = sqspkg.NewSendMessageBatchEntry(fmt.Sprintf("e%d", i), sqspkg.WithMessageBody(fmt.Sprintf("m%d", i)))
+	}
+	// 本文のないエントリはエントリ単位で失敗する
+	entries[11] = sqspkg.NewSendMessageBatchEntry("e11")
+	sent, err := client.SendMessageBatch(context.Background(), queueURL, entries)
+	if err != nil {
+		t.Fatalf("SendMessageBatch() error = %v", err)
+	}
+	if len(sent.Successful) != 11 || len(sent.Failed) != 1 || sent.Failed[0].ID != "e11" || sent.Failed[0].Code != "MissingParameter" {
+		t.Fatalf("SendMessageBatch() = %d successful, failed %+v", len(sent.Successful), sent.Failed)
+	}
+
+	received, err := client.ReceiveMessageBatch(context.Background(), queueURL, 20)
+	if err != nil || len(received) != 11 {
+		t.Fatalf("ReceiveMessageBatch() = %d messages, error = %v", len(received), err)
+	}
+	deletes := make([]sqspkg.DeleteMessageBatchEntry, 0, len(received)+1)
+	for _, m := range received {
+		deletes = append(deletes, sqspkg.NewDeleteMessageBatchEntry(aws.ToString(m.MessageId), sqspkg.WithReceiptHandle(aws.ToString(m.ReceiptHandle))))
+	}
+	deletes = append(deletes, sqspkg.NewDeleteMessageBatchEntry("stale", sqspkg.WithReceiptHandle("unknown")))
+	deleted, err := client.DeleteMessageBatch(context.Background(), queueURL, deletes)
+	if err != nil {
+		t.Fatalf("DeleteMessageBatch() error = %v", err)
+	}
+	if len(deleted.Successful) != 11 || len(deleted.Failed) != 1 || deleted.Failed[0].Code != "ReceiptHandleIsInvalid" {
+		t.Errorf("DeleteMessageBatch() = %d successful, failed %+v", len(deleted.Successful), deleted.Failed)
+	}
+	if got := server.Stats("worker-queue").Total(); got != 0 {
+		t.Errorf("Stats().Total() = %d, want 0", got)
+	}
+
+	// SQSAPI を直接呼び出した場合は上限を超えるバッチを拒否する
+	var tooMany *types.TooManyEntriesInBatchRequest
+	requestEntries := make([]types.SendMessageBatchRequestEntry, 11)
+	for i := range requestEntries {
+		requestEntries[i] = types.SendMessageBatchRequestEntry{Id: aws.String(fmt.Sprint(i)), MessageBody: aws.String("m")}
+	}
+	if _, err := server.SendMessageBatch(context.Background(), &sqs.SendMessageBatchInput{QueueUrl: aws.String(queueURL), Entries: requestEntries}); !errors.As(err, &tooMany) {
+		t.Errorf("SendMessageBatch(11 entries) error = %v, want TooManyEntriesInBatchRequest", err)
+	}
+}
+
+// TestServer_MessageAttributes は要求したメッセージ属性だけが返ることを確認します。
+func TestServer_MessageAttributes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options []sqspkg.ReceiveMessageOptionFunc
+		want    []string
+	}{
+		{name: "正常系: 要求しない場合は返さない", want: []string{}},
+		{name: "正常系: すべて", options: []sqspkg.ReceiveMessageOptionFunc{sqspkg.WithAllMessageAttributes()}, want: []string{"eventType", "trace.id", "trace.parent"}},
+		{name: "正常系: 接頭辞", options: []sqspkg.ReceiveMessageOptionFunc{sqspkg.WithMessageAttributeNames("trace.*")}, want: []string{"trace.id", "trace.parent"}},
+		{name: "正常系: 名前を指定", options: []sqspkg.ReceiveMessageOptionFunc{sqspkg.WithMessageAttributeNames("eventType")}, want: []string{"eventType"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server, _, client := newServer(t)
+			queueURL := server.MustCreateQueue("worker-queue")
+			send(t, client, queueURL, "m1",
+				sqspkg.WithStringMessageAttribute("eventType", "user.created"),
+				sqspkg.WithStringMessageAttribute("trace.id", "t1"),
+				sqspkg.WithStringMessageAttribute("trace.parent", "p1"),
+			)
+			messages := receive(t, client, queueURL, tt.options...)
+			got := []string{}
+			for _, name := range []string{"eventType", "trace.id", "trace.parent"} {
+				if _, ok := messages[0].MessageAttributes[name]; ok {
+					got = append(got, name)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("MessageAttributes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestServer_CreateQueue はキューの作成と取得のエラーを確認します。
+func TestServer_CreateQueue(t *testing.T) {
+	t.Parallel()
+
+	server, _, client := newServer(t)
+	url := server.MustCreateQueue("worker-queue")
+	if got, err := client.GetQueueURL(context.Background(), "worker-queue"); err != nil || got != url {
+		t.Errorf("GetQueueURL() = %s, %v, want %s", got, err, url)
+	}
+
+	var notExist *types.QueueDoesNotExist
+	if _, err := client.GetQueueURL(context.Background(), "unknown"); !errors.As(err, &notExist) {
+		t.Errorf("GetQueueURL(unknown) error = %v, want QueueDoesNotExist", err)
+	}
+	var exists *types.QueueNameExists
+	if _, err := server.CreateQueue("worker-queue"); !errors.As(err, &exists) {
+		t.Errorf("CreateQueue(duplicate) error = %v, want QueueNameExists", err)
+	}
+	if _, err := server.CreateQueue("orders", memsqs.WithRedrivePolicy("unknown-dlq", 3)); !errors.As(err, &notExist) {
+		t.Errorf("CreateQueue(unknown dlq) error = %v, want QueueDoesNotExist", err)
+	}
+	// FIFOキューのDLQはFIFOキューである必要がある
+	if _, err := server.CreateQueue("orders.fifo", memsqs.WithRedrivePolicy("worker-queue", 3)); err == nil {
+		t.Error("CreateQueue(fifo with standard dlq) error = nil, want error")
+	}
+}
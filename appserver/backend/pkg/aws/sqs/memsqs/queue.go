@@ -0,0 +1,283 @@
+package memsqs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/google/uuid"
+	sqspkg "github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs"
+)
+
+// queue は1つのキューのメッセージを送信順に保持します。Server.mu を保持して操作すること。
+type queue struct {
+	name string
+	url  string
+	arn  string
+	fifo bool
+	cfg  queueConfig
+	dlq  *queue
+
+	messages []*message
+	// dedup はFIFOキューの重複排除IDごとに、最初に送信したメッセージと送信時刻を保持する
+	dedup map[string]*message
+}
+
+// message はキュー内の1件のメッセージです。
+type message struct {
+	id              string
+	body            string
+	md5             string
+	attributes      map[string]types.MessageAttributeValue
+	groupID         string
+	deduplicationID string
+	seq             int64
+	sentAt          time.Time
+	// visibleAt より前は受信できない (配信遅延中、または受信中)
+	visibleAt      time.Time
+	receiveCount   int
+	firstReceiveAt time.Time
+	// receiptHandle は最新の受信で発行したレシートハンドル (未受信の場合は空)
+	receiptHandle string
+	// sourceARN はDLQに移動したメッセージの移動元のキューのARN
+	sourceARN string
+}
+
+func newQueue(name, url string, cfg queueConfig) *queue {
+	return &queue{
+		name:  name,
+		url:   url,
+		arn:   fmt.Sprintf("arn:aws:sqs:%s:%s:%s", region, accountID, name),
+		fifo:  sqspkg.IsFIFOQueue(name),
+		cfg:   cfg,
+		dedup: make(map[string]*message),
+	}
+}
+
+// push はメッセージを末尾に追加し、FIFOキューでは重複排除IDを記録します。
+func (q *queue) push(m *message, now time.Time) {
+	q.messages = append(q.messages, m)
+	if q.fifo {
+		q.dedup[m.deduplicationID] = m
+		// 期限切れの記録を削除して、重複排除IDの記録が増え続けないようにする
+		for id, original := range q.dedup {
+			if now.Sub(original.sentAt) >= deduplicationInterval {
+				delete(q.dedup, id)
+			}
+		}
+	}
+}
+
+// deduplicated は重複排除期間内に同じ重複排除IDで送信したメッセージを返します。
+func (q *queue) deduplicated(deduplicationID string, now time.Time) (*message, bool) {
+	original, ok := q.dedup[deduplicationID]
+	if !ok || now.Sub(original.sentAt) >= deduplicationInterval {
+		return nil, false
+	}
+	return original, true
+}
+
+// receive は受信可能なメッセージを送信順に最大 max 件取り出し、可視性タイムアウトを設定します。
+// 受信回数が maxReceiveCount に達しているメッセージは受信者に返さずDLQに移動します (移動した場合は moved が true)。
+// FIFOキューでは、受信中または配信遅延中のメッセージがあるグループのメッセージは返しません。
+func (q *queue) receive(now time.Time, max int, visibility time.Duration) (received []*message, moved bool) {
+	blocked := make(map[string]bool)
+	kept := q.messages[:0]
+	for _, m := range q.messages {
+		switch {
+		case len(received) >= max:
+		case q.fifo && blocked[m.groupID]:
+		case now.Before(m.visibleAt):
+			if q.fifo {
+				// グループ内の順序を守るため、先頭のメッセージが受信可能になるまで後続も返さない
+				blocked[m.groupID] = true
+			}
+		case q.dlq != nil && m.receiveCount >= q.cfg.maxReceiveCount:
+			q.dlq.pushDeadLetter(m, q.arn, now)
+			moved = true
+			continue
+		default:
+			m.receiveCount++
+			if m.firstReceiveAt.IsZero() {
+				m.firstReceiveAt = now
+			}
+			m.receiptHandle = uuid.NewString()
+			m.visibleAt = now.Add(visibility)
+			received = append(received, m)
+		}
+		kept = append(kept, m)
+	}
+	clear(q.messages[len(kept):])
+	q.messages = kept
+	return received, moved
+}
+
+// pushDeadLetter は受信回数の上限に達したメッセージをDLQの末尾に移動します。
+// メッセージIDと本文、属性は引き継ぎ、受信回数は引き継ぎません。
+func (q *queue) pushDeadLetter(m *message, sourceARN string, now time.Time) {
+	m.receiveCount = 0
+	m.firstReceiveAt = time.Time{}
+	m.receiptHandle = ""
+	m.visibleAt = now
+	m.sourceARN = sourceARN
+	q.messages = append(q.messages, m)
+}
+
+// delete はレシートハンドルに対応するメッセージを削除します。見つからない場合は false を返します。
+func (q *queue) delete(receiptHandle string) bool {
+	for i, m := range q.messages {
+		if receiptHandle != "" && m.receiptHandle == receiptHandle {
+			q.messages = append(q.messages[:i], q.messages[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// byReceiptHandle はレシートハンドルに対応するメッセージを返します。
+func (q *queue) byReceiptHandle(receiptHandle string) *message {
+	for _, m := range q.messages {
+		if receiptHandle != "" && m.receiptHandle == receiptHandle {
+			return m
+		}
+	}
+	return nil
+}
+
+// nextVisibleAt は現在受信できないメッセージのうち、最も早く受信可能になる時刻を返します (なければゼロ値)。
+func (q *queue) nextVisibleAt(now time.Time) time.Time {
+	var next time.Time
+	for _, m := range q.messages {
+		if now.Before(m.visibleAt) && (next.IsZero() || m.visibleAt.Before(next)) {
+			next = m.visibleAt
+		}
+	}
+	return next
+}
+
+func (q *queue) stats(now time.Time) QueueStats {
+	var st QueueStats
+	for _, m := range q.messages {
+		switch {
+		case m.inFlight(now):
+			st.InFlight++
+		case now.Before(m.visibleAt):
+			st.Delayed++
+		default:
+			st.Visible++
+		}
+	}
+	return st
+}
+
+// inFlight は受信済みで可視性タイムアウトが切れていないかどうかを返します。
+func (m *message) inFlight(now time.Time) bool {
+	return m.receiptHandle != "" && now.Before(m.visibleAt)
+}
+
+func (m *message) sequenceNumber(fifo bool) *string {
+	if !fifo {
+		return nil
+	}
+	return aws.String(fmt.Sprintf("%020d", m.seq))
+}
+
+// toMessage は受信者に返すメッセージを生成します。属性は要求されたものだけを含めます。
+func (m *message) toMessage(q *queue, filter attributeFilter) types.Message {
+	msg := types.Message{
+		MessageId:     aws.String(m.id),
+		ReceiptHandle: aws.String(m.receiptHandle),
+		Body:          aws.String(m.body),
+		MD5OfBody:     aws.String(m.md5),
+	}
+
+	system := map[types.MessageSystemAttributeName]string{
+		types.MessageSystemAttributeNameSenderId:                         senderID,
+		types.MessageSystemAttributeNameSentTimestamp:                    millis(m.sentAt),
+		types.MessageSystemAttributeNameApproximateReceiveCount:          strconv.Itoa(m.receiveCount),
+		types.MessageSystemAttributeNameApproximateFirstReceiveTimestamp: millis(m.firstReceiveAt),
+	}
+	if q.fifo {
+		system[types.MessageSystemAttributeNameMessageGroupId] = m.groupID
+		system[types.MessageSystemAttributeNameMessageDeduplicationId] = m.deduplicationID
+		system[types.MessageSystemAttributeNameSequenceNumber] = aws.ToString(m.sequenceNumber(true))
+	} else if m.groupID != "" {
+		system[types.MessageSystemAttributeNameMessageGroupId] = m.groupID
+	}
+	if m.sourceARN != "" {
+		system[types.MessageSystemAttributeNameDeadLetterQueueSourceArn] = m.sourceARN
+	}
+	for name, value := range system {
+		if filter.system(name) {
+			if msg.Attributes == nil {
+				msg.Attributes = make(map[string]string)
+			}
+			msg.Attributes[string(name)] = value
+		}
+	}
+
+	for name, value := range m.attributes {
+		if filter.message(name) {
+			if msg.MessageAttributes == nil {
+				msg.MessageAttributes = make(map[string]types.MessageAttributeValue)
+			}
+			msg.MessageAttributes[name] = value
+		}
+	}
+	return msg
+}
+
+// attributeFilter は受信時に要求された属性名です。
+type attributeFilter struct {
+	systemNames  map[string]bool
+	messageNames []string
+}
+
+func newAttributeFilter(params *sqs.ReceiveMessageInput) attributeFilter {
+	f := attributeFilter{systemNames: make(map[string]bool), messageNames: params.MessageAttributeNames}
+	for _, name := range params.MessageSystemAttributeNames {
+		f.systemNames[string(name)] = true
+	}
+	// 非推奨の AttributeNames で指定された場合も同様に扱う
+	for _, name := range params.AttributeNames {
+		f.systemNames[string(name)] = true
+	}
+	return f
+}
+
+func (f attributeFilter) system(name types.MessageSystemAttributeName) bool {
+	return f.systemNames[string(types.MessageSystemAttributeNameAll)] || f.systemNames[string(name)]
+}
+
+// message はメッセージ属性名が要求されたかどうかを返します。"All"、".*"、"接頭辞.*" を指定できます。
+func (f attributeFilter) message(name string) bool {
+	for _, pattern := range f.messageNames {
+		switch {
+		case pattern == "All" || pattern == ".*":
+			return true
+		case strings.HasSuffix(pattern, ".*") && strings.HasPrefix(name, strings.TrimSuffix(pattern, "*")):
+			return true
+		case pattern == name:
+			return true
+		}
+	}
+	return false
+}
+
+func millis(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
+
+func contentHash(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
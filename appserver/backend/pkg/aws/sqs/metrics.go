@@ -0,0 +1,123 @@
+package sqs
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/metrics"
+)
+
+// instrumentedClient は呼び出しの件数・エラー・処理時間を集計するSQSの実装です。
+type instrumentedClient struct {
+	next          SQS
+	requests      *metrics.CounterVec
+	errors        *metrics.CounterVec
+	duration      *metrics.HistogramVec
+	messages      *metrics.CounterVec
+	entryFailures *metrics.CounterVec
+}
+
+// NewInstrumentedClient は client の呼び出しを集計するSQSを返します。
+// 各メトリクスの operation ラベルはメソッド名 (ReceiveMessages, SendMessageBatch など) です。
+//   - sqs_requests_total{operation}: 呼び出し回数
+//   - sqs_request_errors_total{operation}: エラーを返した呼び出し回数
+//   - sqs_request_duration_seconds{operation}: 呼び出しの処理時間
+//   - sqs_messages_total{operation}: 受信・送信・削除に成功したメッセージ数
+//   - sqs_batch_entry_failures_total{operation}: バッチ操作で失敗したエントリ数
+//
+// 引数:
+//   - client: 集計対象のSQSクライアント
+//   - reg: メトリクスの登録先
+func NewInstrumentedClient(client SQS, reg *metrics.Registry) SQS {
+	return &instrumentedClient{
+		next:          client,
+		requests:      reg.CounterVec("sqs_requests_total", "Total number of SQS API calls by operation.", "operation"),
+		errors:        reg.CounterVec("sqs_request_errors_total", "Total number of failed SQS API calls by operation.", "operation"),
+		duration:      reg.HistogramVec("sqs_request_duration_seconds", "SQS API call latency in seconds by operation.", nil, "operation"),
+		messages:      reg.CounterVec("sqs_messages_total", "Total number of SQS messages received, sent or deleted by operation.", "operation"),
+		entryFailures: reg.CounterVec("sqs_batch_entry_failures_total", "Total number of failed entries in SQS batch operations.", "operation"),
+	}
+}
+
+// observe は呼び出し1回分の件数と処理時間、エラーを記録する
+func (c *instrumentedClient) observe(operation string, start time.Time, err error) {
+	c.requests.WithLabelValues(operation).Inc()
+	c.duration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		c.errors.WithLabelValues(operation).Inc()
+	}
+}
+
+func (c *instrumentedClient) ReceiveMessages(ctx context.Context, queueURL string, options ...ReceiveMessageOptionFunc) ([]types.Message, error) {
+	start := time.Now()
+	msgs, err := c.next.ReceiveMessages(ctx, queueURL, options...)
+	c.observe("ReceiveMessages", start, err)
+	c.messages.WithLabelValues("ReceiveMessages").Add(float64(len(msgs)))
+	return msgs, err
+}
+
+func (c *instrumentedClient) ReceiveMessageBatch(ctx context.Context, queueURL string, maxMessages int, options ...ReceiveMessageOptionFunc) ([]types.Message, error) {
+	start := time.Now()
+	msgs, err := c.next.ReceiveMessageBatch(ctx, queueURL, maxMessages, options...)
+	c.observe("ReceiveMessageBatch", start, err)
+	c.messages.WithLabelValues("ReceiveMessageBatch").Add(float64(len(msgs)))
+	return msgs, err
+}
+
+func (c *instrumentedClient) DeleteMessage(ctx context.Context, queueURL string, options ...DeleteMessageOptionFunc) error {
+	start := time.Now()
+	err := c.next.DeleteMessage(ctx, queueURL, options...)
+	c.observe("DeleteMessage", start, err)
+	if err == nil {
+		c.messages.WithLabelValues("DeleteMessage").Inc()
+	}
+	return err
+}
+
+func (c *instrumentedClient) DeleteMessageBatch(ctx context.Context, queueURL string, entries []DeleteMessageBatchEntry) (*DeleteMessageBatchResult, error) {
+	start := time.Now()
+	result, err := c.next.DeleteMessageBatch(ctx, queueURL, entries)
+	c.observe("DeleteMessageBatch", start, err)
+	if result != nil {
+		c.messages.WithLabelValues("DeleteMessageBatch").Add(float64(len(result.Successful)))
+		c.entryFailures.WithLabelValues("DeleteMessageBatch").Add(float64(len(result.Failed)))
+	}
+	return result, err
+}
+
+func (c *instrumentedClient) SendMessage(ctx context.Context, queueURL string, options ...SendMessageOptionFunc) (*sqs.SendMessageOutput, error) {
+	start := time.Now()
+	out, err := c.next.SendMessage(ctx, queueURL, options...)
+	c.observe("SendMessage", start, err)
+	if err == nil {
+		c.messages.WithLabelValues("SendMessage").Inc()
+	}
+	return out, err
+}
+
+func (c *instrumentedClient) SendMessageBatch(ctx context.Context, queueURL string, entries []SendMessageBatchEntry) (*SendMessageBatchResult, error) {
+	start := time.Now()
+	result, err := c.next.SendMessageBatch(ctx, queueURL, entries)
+	c.observe("SendMessageBatch", start, err)
+	if result != nil {
+		c.messages.WithLabelValues("SendMessageBatch").Add(float64(len(result.Successful)))
+		c.entryFailures.WithLabelValues("SendMessageBatch").Add(float64(len(result.Failed)))
+	}
+	return result, err
+}
+
+func (c *instrumentedClient) GetQueueURL(ctx context.Context, queueName string) (string, error) {
+	start := time.Now()
+	url, err := c.next.GetQueueURL(ctx, queueName)
+	c.observe("GetQueueURL", start, err)
+	return url, err
+}
+
+func (c *instrumentedClient) ChangeMessageVisibility(ctx context.Context, queueURL, receiptHandle string, visibilityTimeout int32) error {
+	start := time.Now()
+	err := c.next.ChangeMessageVisibility(ctx, queueURL, receiptHandle, visibilityTimeout)
+	c.observe("ChangeMessageVisibility", start, err)
+	return err
+}
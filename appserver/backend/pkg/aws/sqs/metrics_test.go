@@ -0,0 +1,67 @@
+package sqs_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	sqspkg "github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs/memsqs"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/metrics"
+)
+
+// TestNewInstrumentedClient は送信・受信・削除の呼び出し回数、エラー、メッセージ数が操作ごとに集計されることを確認します。
+func TestNewInstrumentedClient(t *testing.T) {
+	ctx := context.Background()
+	server := memsqs.New()
+	queueURL := server.MustCreateQueue("metrics-queue")
+	reg := metrics.NewRegistry()
+	client := sqspkg.NewInstrumentedClient(sqspkg.NewSQSClientWithAPI(server), reg)
+
+	if _, err := client.SendMessage(ctx, queueURL, sqspkg.WithMessageBody("a")); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	result, err := client.SendMessageBatch(ctx, queueURL, []sqspkg.SendMessageBatchEntry{
+		{Options: []sqspkg.SendMessageOptionFunc{sqspkg.WithMessageBody("b")}},
+		{Options: []sqspkg.SendMessageOptionFunc{sqspkg.WithMessageBody("c")}},
+	})
+	if err != nil || result.Err() != nil {
+		t.Fatalf("SendMessageBatch() error = %v, %v", err, result.Err())
+	}
+	msgs, err := client.ReceiveMessages(ctx, queueURL, sqspkg.WithMaxMessages(10))
+	if err != nil {
+		t.Fatalf("ReceiveMessages() error = %v", err)
+	}
+	if len(msgs) != 3 {
+		t.Fatalf("ReceiveMessages() got %d messages, want 3", len(msgs))
+	}
+	if err := client.DeleteMessage(ctx, queueURL, sqspkg.WithReceiptHandle(*msgs[0].ReceiptHandle)); err != nil {
+		t.Fatalf("DeleteMessage() error = %v", err)
+	}
+	if _, err := client.ReceiveMessages(ctx, "http://localhost/000000000000/missing-queue"); err == nil {
+		t.Fatal("ReceiveMessages() for missing queue error = nil, want error")
+	}
+
+	var b strings.Builder
+	if err := reg.WriteText(&b); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	got := b.String()
+	for _, want := range []string{
+		`sqs_requests_total{operation="SendMessage"} 1`,
+		`sqs_requests_total{operation="SendMessageBatch"} 1`,
+		`sqs_requests_total{operation="ReceiveMessages"} 2`,
+		`sqs_requests_total{operation="DeleteMessage"} 1`,
+		`sqs_request_errors_total{operation="ReceiveMessages"} 1`,
+		`sqs_messages_total{operation="SendMessage"} 1`,
+		`sqs_messages_total{operation="SendMessageBatch"} 2`,
+		`sqs_messages_total{operation="ReceiveMessages"} 3`,
+		`sqs_messages_total{operation="DeleteMessage"} 1`,
+		`sqs_batch_entry_failures_total{operation="SendMessageBatch"} 0`,
+		`sqs_request_duration_seconds_count{operation="ReceiveMessages"} 2`,
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("metrics does not contain %q\n%s", want, got)
+		}
+	}
+}
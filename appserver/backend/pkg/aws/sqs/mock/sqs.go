@@ -43,6 +43,20 @@ func (m *MockSQS) EXPECT() *MockSQSMockRecorder {
 	return m.recorder
 }
 
+// ChangeMessageVisibility mocks base method.
+func (m *MockSQS) ChangeMessageVisibility(ctx context.Context, queueURL, receiptHandle string, visibilityTimeout int32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeMessageVisibility", ctx, queueURL, receiptHandle, visibilityTimeout)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangeMessageVisibility indicates an expected call of ChangeMessageVisibility.
+func (mr *MockSQSMockRecorder) ChangeMessageVisibility(ctx, queueURL, receiptHandle, visibilityTimeout any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeMessageVisibility", reflect.TypeOf((*MockSQS)(nil).ChangeMessageVisibility), ctx, queueURL, receiptHandle, visibilityTimeout)
+}
+
 // DeleteMessage mocks base method.
 func (m *MockSQS) DeleteMessage(ctx context.Context, queueURL string, options ...sqs0.DeleteMessageOptionFunc) error {
 	m.ctrl.T.Helper()
@@ -62,6 +76,21 @@ func (mr *MockSQSMockRecorder) DeleteMessage(ctx, queueURL any, options ...any)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMessage", reflect.TypeOf((*MockSQS)(nil).DeleteMessage), varargs...)
 }
 
+// DeleteMessageBatch mocks base method.
+func (m *MockSQS) DeleteMessageBatch(ctx context.Context, queueURL string, entries []sqs0.DeleteMessageBatchEntry) (*sqs0.DeleteMessageBatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteMessageBatch", ctx, queueURL, entries)
+	ret0, _ := ret[0].(*sqs0.DeleteMessageBatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteMessageBatch indicates an expected call of DeleteMessageBatch.
+func (mr *MockSQSMockRecorder) DeleteMessageBatch(ctx, queueURL, entries any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMessageBatch", reflect.TypeOf((*MockSQS)(nil).DeleteMessageBatch), ctx, queueURL, entries)
+}
+
 // GetQueueURL mocks base method.
 func (m *MockSQS) GetQueueURL(ctx context.Context, queueName string) (string, error) {
 	m.ctrl.T.Helper()
@@ -77,6 +106,26 @@ func (mr *MockSQSMockRecorder) GetQueueURL(ctx, queueName any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQueueURL", reflect.TypeOf((*MockSQS)(nil).GetQueueURL), ctx, queueName)
 }
 
+// ReceiveMessageBatch mocks base method.
+func (m *MockSQS) ReceiveMessageBatch(ctx context.Context, queueURL string, maxMessages int, options ...sqs0.ReceiveMessageOptionFunc) ([]types.Message, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, queueURL, maxMessages}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ReceiveMessageBatch", varargs...)
+	ret0, _ := ret[0].([]types.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReceiveMessageBatch indicates an expected call of ReceiveMessageBatch.
+func (mr *MockSQSMockRecorder) ReceiveMessageBatch(ctx, queueURL, maxMessages any, options ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, queueURL, maxMessages}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceiveMessageBatch", reflect.TypeOf((*MockSQS)(nil).ReceiveMessageBatch), varargs...)
+}
+
 // ReceiveMessages mocks base method.
 func (m *MockSQS) ReceiveMessages(ctx context.Context, queueURL string, options ...sqs0.ReceiveMessageOptionFunc) ([]types.Message, error) {
 	m.ctrl.T.Helper()
@@ -117,6 +166,21 @@ func (mr *MockSQSMockRecorder) SendMessage(ctx, queueURL any, options ...any) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockSQS)(nil).SendMessage), varargs...)
 }
 
+// SendMessageBatch mocks base method.
+func (m *MockSQS) SendMessageBatch(ctx context.Context, queueURL string, entries []sqs0.SendMessageBatchEntry) (*sqs0.SendMessageBatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendMessageBatch", ctx, queueURL, entries)
+	ret0, _ := ret[0].(*sqs0.SendMessageBatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendMessageBatch indicates an expected call of SendMessageBatch.
+func (mr *MockSQSMockRecorder) SendMessageBatch(ctx, queueURL, entries any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessageBatch", reflect.TypeOf((*MockSQS)(nil).SendMessageBatch), ctx, queueURL, entries)
+}
+
 // MockSQSAPI is a mock of SQSAPI interface.
 type MockSQSAPI struct {
 	ctrl     *gomock.Controller
@@ -141,6 +205,26 @@ func (m *MockSQSAPI) EXPECT() *MockSQSAPIMockRecorder {
 	return m.recorder
 }
 
+// ChangeMessageVisibility mocks base method.
+func (m *MockSQSAPI) ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ChangeMessageVisibility", varargs...)
+	ret0, _ := ret[0].(*sqs.ChangeMessageVisibilityOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangeMessageVisibility indicates an expected call of ChangeMessageVisibility.
+func (mr *MockSQSAPIMockRecorder) ChangeMessageVisibility(ctx, params any, optFns ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeMessageVisibility", reflect.TypeOf((*MockSQSAPI)(nil).ChangeMessageVisibility), varargs...)
+}
+
 // DeleteMessage mocks base method.
 func (m *MockSQSAPI) DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
 	m.ctrl.T.Helper()
@@ -161,6 +245,26 @@ func (mr *MockSQSAPIMockRecorder) DeleteMessage(ctx, params any, optFns ...any)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMessage", reflect.TypeOf((*MockSQSAPI)(nil).DeleteMessage), varargs...)
 }
 
+// DeleteMessageBatch mocks base method.
+func (m *MockSQSAPI) DeleteMessageBatch(ctx context.Context, params *sqs.DeleteMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteMessageBatch", varargs...)
+	ret0, _ := ret[0].(*sqs.DeleteMessageBatchOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteMessageBatch indicates an expected call of DeleteMessageBatch.
+func (mr *MockSQSAPIMockRecorder) DeleteMessageBatch(ctx, params any, optFns ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMessageBatch", reflect.TypeOf((*MockSQSAPI)(nil).DeleteMessageBatch), varargs...)
+}
+
 // GetQueueUrl mocks base method.
 func (m *MockSQSAPI) GetQueueUrl(ctx context.Context, params *sqs.GetQueueUrlInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueUrlOutput, error) {
 	m.ctrl.T.Helper()
@@ -220,3 +324,23 @@ func (mr *MockSQSAPIMockRecorder) SendMessage(ctx, params any, optFns ...any) *g
 	varargs := append([]any{ctx, params}, optFns...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockSQSAPI)(nil).SendMessage), varargs...)
 }
+
+// SendMessageBatch mocks base method.
+func (m *MockSQSAPI) SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SendMessageBatch", varargs...)
+	ret0, _ := ret[0].(*sqs.SendMessageBatchOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendMessageBatch indicates an expected call of SendMessageBatch.
+func (mr *MockSQSAPIMockRecorder) SendMessageBatch(ctx, params any, optFns ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessageBatch", reflect.TypeOf((*MockSQSAPI)(nil).SendMessageBatch), varargs...)
+}
@@ -2,6 +2,9 @@ package sqs
 
 import (
 	"context"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
@@ -11,22 +14,43 @@ import (
 //go:generate mockgen -source=$GOFILE -destination=${GOPACKAGE}mock/$GOFILE -package=${GOPACKAGE}mock
 
 // SQS はSQSクライアントの外部公開用インターフェースです。
-// メッセージの送受信、削除、キューURL取得の機能を提供します。
+// メッセージの送受信、削除 (それぞれバッチを含む)、キューURL取得の機能を提供します。
 type SQS interface {
 	// ReceiveMessages はSQSからメッセージを受信するメソッドです。
 	// オプションで最大メッセージ数や待ち時間を指定できます。
 	ReceiveMessages(ctx context.Context, queueURL string, options ...ReceiveMessageOptionFunc) ([]types.Message, error)
 
+	// ReceiveMessageBatch は最大 maxMessages 件のメッセージを、10件ずつ繰り返し受信するメソッドです。
+	// キューが空になった時点で終了します。ロングポーリングは1回目の受信でのみ待機します。
+	// 途中で受信に失敗した場合は、それまでに受信したメッセージとエラーを返します。
+	ReceiveMessageBatch(ctx context.Context, queueURL string, maxMessages int, options ...ReceiveMessageOptionFunc) ([]types.Message, error)
+
 	// DeleteMessage はSQSのメッセージを削除するメソッドです。
 	DeleteMessage(ctx context.Context, queueURL string, options ...DeleteMessageOptionFunc) error
 
+	// DeleteMessageBatch は複数のメッセージをまとめて削除するメソッドです。
+	// 10件を超える場合は自動的に分割してリクエストします。
+	// エントリごとの失敗は結果の Failed で返し、エラーはエントリIDの重複など入力が不正な場合のみ返します。
+	DeleteMessageBatch(ctx context.Context, queueURL string, entries []DeleteMessageBatchEntry) (*DeleteMessageBatchResult, error)
+
 	// SendMessage はSQSにメッセージを送信するメソッドです。
 	// オプションでメッセージの内容や属性を指定できます。
+	// ctx にスパンがあり traceparent の属性を指定していない場合は、トレースコンテキストを属性に設定します。
 	SendMessage(ctx context.Context, queueURL string, options ...SendMessageOptionFunc) (*sqs.SendMessageOutput, error)
 
+	// SendMessageBatch は複数のメッセージをまとめて送信するメソッドです。
+	// 各エントリには SendMessage と同じオプションを指定でき、10件を超える場合は自動的に分割してリクエストします。
+	// エントリごとの失敗は結果の Failed で返し、エラーはエントリIDの重複など入力が不正な場合のみ返します。
+	// トレースコンテキストは SendMessage と同様にエントリごとに設定します。
+	SendMessageBatch(ctx context.Context, queueURL string, entries []SendMessageBatchEntry) (*SendMessageBatchResult, error)
+
 	// GetQueueURL はSQSのキューURLを取得するメソッドです。
 	// キュー名を指定すると、キューURLを取得できます。
 	GetQueueURL(ctx context.Context, queueName string) (string, error)
+
+	// ChangeMessageVisibility は受信済みメッセージの可視性タイムアウトを変更するメソッドです。
+	// 処理中のメッセージが再配信されないよう、現在時刻から visibilityTimeout 秒後まで延長します。
+	ChangeMessageVisibility(ctx context.Context, queueURL, receiptHandle string, visibilityTimeout int32) error
 }
 
 // SQSAPI はAWS SDK v2のSQSクライアントが実装すべき内部用インターフェースです。
@@ -35,7 +59,10 @@ type SQSAPI interface {
 	ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
 	DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
 	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+	SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error)
+	DeleteMessageBatch(ctx context.Context, params *sqs.DeleteMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error)
 	GetQueueUrl(ctx context.Context, params *sqs.GetQueueUrlInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueUrlOutput, error)
+	ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error)
 }
 
 // sqsClient はSQSインターフェースの実装です。
@@ -80,6 +107,84 @@ func WithWaitTimeSeconds(waitTimeSeconds int32) ReceiveMessageOptionFunc {
 	}
 }
 
+// WithVisibilityTimeout は受信したメッセージを他の受信者から隠す時間を設定します。
+// 引数:
+//   - visibilityTimeout: 可視性タイムアウト（秒）(0-43200)
+func WithVisibilityTimeout(visibilityTimeout int32) ReceiveMessageOptionFunc {
+	return func(input *sqs.ReceiveMessageInput) {
+		input.VisibilityTimeout = visibilityTimeout
+	}
+}
+
+// WithMessageAttributeNames は受信時に取得するメッセージ属性の名前を指定します。
+// 複数回指定した場合は追加されます。"All" または "." で終わる接頭辞も指定できます。
+// 引数:
+//   - names: メッセージ属性名
+func WithMessageAttributeNames(names ...string) ReceiveMessageOptionFunc {
+	return func(input *sqs.ReceiveMessageInput) {
+		input.MessageAttributeNames = appendUnique(input.MessageAttributeNames, names...)
+	}
+}
+
+// WithAllMessageAttributes は受信時にすべてのメッセージ属性を取得します。
+func WithAllMessageAttributes() ReceiveMessageOptionFunc {
+	return WithMessageAttributeNames("All")
+}
+
+// WithSystemAttributeNames は受信時に取得するシステム属性の名前を指定します。
+// 複数回指定した場合は追加されます。
+// 引数:
+//   - names: システム属性名 (types.MessageSystemAttributeNameSentTimestamp など)
+func WithSystemAttributeNames(names ...types.MessageSystemAttributeName) ReceiveMessageOptionFunc {
+	return func(input *sqs.ReceiveMessageInput) {
+		input.MessageSystemAttributeNames = appendUnique(input.MessageSystemAttributeNames, names...)
+	}
+}
+
+// WithAllSystemAttributes は受信時にすべてのシステム属性を取得します。
+func WithAllSystemAttributes() ReceiveMessageOptionFunc {
+	return WithSystemAttributeNames(types.MessageSystemAttributeNameAll)
+}
+
+// WithApproximateReceiveCount は受信時にメッセージの受信回数 (ApproximateReceiveCount) を取得します。
+// 取得した値は ApproximateReceiveCount で参照できます。
+func WithApproximateReceiveCount() ReceiveMessageOptionFunc {
+	return WithSystemAttributeNames(types.MessageSystemAttributeNameApproximateReceiveCount)
+}
+
+// appendUnique は重複しない値のみを追加します。
+func appendUnique[T comparable](values []T, add ...T) []T {
+	for _, v := range add {
+		if !slices.Contains(values, v) {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// ApproximateReceiveCount はメッセージの受信回数を返します。
+// 受信時に WithApproximateReceiveCount (または WithAllSystemAttributes) を指定していない場合は 0 を返します。
+func ApproximateReceiveCount(msg types.Message) int {
+	n, err := strconv.Atoi(msg.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)])
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// MessageGroupID はFIFOキューのメッセージのグループIDを返します。
+// 受信時に types.MessageSystemAttributeNameMessageGroupId を取得していない場合、
+// または標準キューのメッセージの場合は空文字を返します。
+func MessageGroupID(msg types.Message) string {
+	return msg.Attributes[string(types.MessageSystemAttributeNameMessageGroupId)]
+}
+
+// IsFIFOQueue はキュー名またはキューURLがFIFOキュー (".fifo" で終わる) かどうかを返します。
+// FIFOキューへの送信には WithMessageGroupID が必須です。
+func IsFIFOQueue(queue string) bool {
+	return strings.HasSuffix(queue, ".fifo")
+}
+
 func (s *sqsClient) ReceiveMessages(ctx context.Context, queueURL string, options ...ReceiveMessageOptionFunc) ([]types.Message, error) {
 	input := &sqs.ReceiveMessageInput{
 		QueueUrl: aws.String(queueURL),
@@ -133,6 +238,61 @@ func WithMessageBody(messageBody string) SendMessageOptionFunc {
 	}
 }
 
+// WithMessageAttribute はメッセージ属性を追加します。
+// 引数:
+//   - name: 属性名
+//   - value: 属性値 (DataType と StringValue または BinaryValue を設定する)
+func WithMessageAttribute(name string, value types.MessageAttributeValue) SendMessageOptionFunc {
+	return func(input *sqs.SendMessageInput) {
+		if input.MessageAttributes == nil {
+			input.MessageAttributes = make(map[string]types.MessageAttributeValue)
+		}
+		input.MessageAttributes[name] = value
+	}
+}
+
+// WithStringMessageAttribute は String 型のメッセージ属性を追加します。
+// 引数:
+//   - name: 属性名
+//   - value: 属性値
+func WithStringMessageAttribute(name, value string) SendMessageOptionFunc {
+	return WithMessageAttribute(name, types.MessageAttributeValue{
+		DataType:    aws.String("String"),
+		StringValue: aws.String(value),
+	})
+}
+
+// WithDelaySeconds はメッセージを受信可能にするまでの遅延時間を設定します。
+// FIFOキューではメッセージ単位の遅延は指定できません (キューの設定を使用します)。
+// 引数:
+//   - delaySeconds: 遅延時間（秒）(0-900)
+func WithDelaySeconds(delaySeconds int32) SendMessageOptionFunc {
+	return func(input *sqs.SendMessageInput) {
+		input.DelaySeconds = delaySeconds
+	}
+}
+
+// WithMessageGroupID はFIFOキューのメッセージグループIDを設定します。
+// 同じグループのメッセージは送信順に配信されます。FIFOキューでは必須です。
+// 引数:
+//   - groupID: メッセージグループID (ユーザーIDなど)
+func WithMessageGroupID(groupID string) SendMessageOptionFunc {
+	return func(input *sqs.SendMessageInput) {
+		input.MessageGroupId = aws.String(groupID)
+	}
+}
+
+// WithMessageDeduplicationID はFIFOキューの重複排除IDを設定します。
+// 5分以内に同じIDで送信したメッセージは1件のみ配信されます。
+// キューでコンテンツベースの重複排除が有効な場合は省略できます。
+// 引数:
+//   - deduplicationID: 重複排除ID
+func WithMessageDeduplicationID(deduplicationID string) SendMessageOptionFunc {
+	return func(input *sqs.SendMessageInput) {
+		input.MessageDeduplicationId = aws.String(deduplicationID)
+	}
+}
+
 func (s *sqsClient) SendMessage(ctx context.Context, queueURL string, options ...SendMessageOptionFunc) (*sqs.SendMessageOutput, error) {
 	input := &sqs.SendMessageInput{
 		QueueUrl: aws.String(queueURL),
@@ -140,6 +300,7 @@ func (s *sqsClient) SendMessage(ctx context.Context, queueURL string, options ..
 	for _, option := range options {
 		option(input)
 	}
+	injectTraceContext(ctx, input)
 	output, err := s.client.SendMessage(ctx, input)
 	if err != nil {
 		return nil, err
@@ -157,3 +318,16 @@ func (s *sqsClient) GetQueueURL(ctx context.Context, queueName string) (string,
 	}
 	return *output.QueueUrl, nil
 }
+
+func (s *sqsClient) ChangeMessageVisibility(ctx context.Context, queueURL, receiptHandle string, visibilityTimeout int32) error {
+	input := &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(queueURL),
+		ReceiptHandle:     aws.String(receiptHandle),
+		VisibilityTimeout: visibilityTimeout,
+	}
+	_, err := s.client.ChangeMessageVisibility(ctx, input)
+	if err != nil {
+		return err
+	}
+	return nil
+}
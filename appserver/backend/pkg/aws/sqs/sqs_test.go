@@ -3,6 +3,8 @@ package sqs_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -487,6 +489,74 @@ func TestGetQueueURL(t *testing.T) {
 	}
 }
 
+// TestChangeMessageVisibility は ChangeMessageVisibility メソッドのテストです。
+// 可視性タイムアウトの変更が正常に行われることと、エラーハンドリングが適切に行われることを確認します。
+func TestChangeMessageVisibility(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name              string
+		queueURL          string
+		receiptHandle     string
+		visibilityTimeout int32
+		setupMock         func(mockAPI *sqsmock.MockSQSAPI)
+		expectedError     bool
+	}{
+		{
+			name:              "正常系: 可視性タイムアウトを延長できる",
+			queueURL:          "https://sqs.ap-northeast-1.amazonaws.com/123456789012/test-queue",
+			receiptHandle:     "receipt-handle-1",
+			visibilityTimeout: 60,
+			setupMock: func(mockAPI *sqsmock.MockSQSAPI) {
+				mockAPI.EXPECT().
+					ChangeMessageVisibility(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+						// パラメータが正しく設定されているか確認
+						if aws.ToString(params.QueueUrl) != "https://sqs.ap-northeast-1.amazonaws.com/123456789012/test-queue" {
+							t.Errorf("unexpected QueueUrl: %v", aws.ToString(params.QueueUrl))
+						}
+						if aws.ToString(params.ReceiptHandle) != "receipt-handle-1" {
+							t.Errorf("expected ReceiptHandle to be 'receipt-handle-1', got %v", aws.ToString(params.ReceiptHandle))
+						}
+						if params.VisibilityTimeout != 60 {
+							t.Errorf("expected VisibilityTimeout to be 60, got %d", params.VisibilityTimeout)
+						}
+						return &sqs.ChangeMessageVisibilityOutput{}, nil
+					})
+			},
+			expectedError: false,
+		},
+		{
+			name:              "異常系: レシートハンドルが無効な場合にエラーを返す",
+			queueURL:          "https://sqs.ap-northeast-1.amazonaws.com/123456789012/test-queue",
+			receiptHandle:     "expired-handle",
+			visibilityTimeout: 60,
+			setupMock: func(mockAPI *sqsmock.MockSQSAPI) {
+				mockAPI.EXPECT().
+					ChangeMessageVisibility(gomock.Any(), gomock.Any()).
+					Return(nil, errors.New("receipt handle is invalid"))
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockAPI := sqsmock.NewMockSQSAPI(ctrl)
+			tt.setupMock(mockAPI)
+			client := sqspkg.NewSQSClientWithAPI(mockAPI)
+
+			err := client.ChangeMessageVisibility(context.Background(), tt.queueURL, tt.receiptHandle, tt.visibilityTimeout)
+			if (err != nil) != tt.expectedError {
+				t.Errorf("expected error: %v, got: %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
 // TestWithMaxMessages は WithMaxMessages オプション関数のテストです。
 func TestWithMaxMessages(t *testing.T) {
 	t.Parallel()
@@ -561,6 +631,43 @@ func TestWithWaitTimeSeconds(t *testing.T) {
 	}
 }
 
+// TestWithVisibilityTimeout は WithVisibilityTimeout オプション関数のテストです。
+func TestWithVisibilityTimeout(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name              string
+		visibilityTimeout int32
+		expected          int32
+	}{
+		{
+			name:              "正常系: VisibilityTimeoutに0を設定できる",
+			visibilityTimeout: 0,
+			expected:          0,
+		},
+		{
+			name:              "正常系: VisibilityTimeoutに30を設定できる",
+			visibilityTimeout: 30,
+			expected:          30,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			input := &sqs.ReceiveMessageInput{}
+			option := sqspkg.WithVisibilityTimeout(tt.visibilityTimeout)
+			option(input)
+
+			if input.VisibilityTimeout != tt.expected {
+				t.Errorf("expected VisibilityTimeout to be %d, got %d", tt.expected, input.VisibilityTimeout)
+			}
+		})
+	}
+}
+
 // TestWithReceiptHandle は WithReceiptHandle オプション関数のテストです。
 func TestWithReceiptHandle(t *testing.T) {
 	t.Parallel()
@@ -657,3 +764,479 @@ func TestNewSQSClientWithAPI(t *testing.T) {
 		}
 	})
 }
+
+// sendEntries は n 件の送信エントリを生成します。
+func sendEntries(n int) []sqspkg.SendMessageBatchEntry {
+	entries := make([]sqspkg.SendMessageBatchEntry, n)
+	for i := range entries {
+		entries[i] = sqspkg.NewSendMessageBatchEntry(fmt.Sprintf("user-%d", i), sqspkg.WithMessageBody(fmt.Sprintf("body-%d", i)))
+	}
+	return entries
+}
+
+// succeedSendBatch は受け取ったエントリをすべて成功として返すモックの振る舞いです。
+func succeedSendBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+	output := &sqs.SendMessageBatchOutput{}
+	for _, e := range params.Entries {
+		output.Successful = append(output.Successful, types.SendMessageBatchResultEntry{Id: e.Id, MessageId: aws.String("msg-" + aws.ToString(e.Id))})
+	}
+	return output, nil
+}
+
+// TestSendMessageBatch は SendMessageBatch メソッドのテストです。
+// 10件ごとに分割して送信されることと、エントリごとの失敗が結果に反映されることを確認します。
+func TestSendMessageBatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		entries        []sqspkg.SendMessageBatchEntry
+		setupMock      func(mockAPI *sqsmock.MockSQSAPI)
+		wantSuccessful int
+		wantFailed     []string
+		expectedError  error
+	}{
+		{
+			name:    "正常系: 10件を超えるエントリは分割して送信される",
+			entries: sendEntries(23),
+			setupMock: func(mockAPI *sqsmock.MockSQSAPI) {
+				var sizes []int
+				mockAPI.EXPECT().
+					SendMessageBatch(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+						sizes = append(sizes, len(params.Entries))
+						if aws.ToString(params.QueueUrl) != "https://sqs.ap-northeast-1.amazonaws.com/123456789012/test-queue" {
+							t.Errorf("unexpected QueueUrl: %v", aws.ToString(params.QueueUrl))
+						}
+						// オプションがエントリに反映されているか確認
+						first := params.Entries[0]
+						if want := "body-" + strings.TrimPrefix(aws.ToString(first.Id), "user-"); aws.ToString(first.MessageBody) != want {
+							t.Errorf("expected MessageBody to be %s, got %s", want, aws.ToString(first.MessageBody))
+						}
+						if len(sizes) == 3 && fmt.Sprint(sizes) != "[10 10 3]" {
+							t.Errorf("expected chunks [10 10 3], got %v", sizes)
+						}
+						return succeedSendBatch(ctx, params)
+					}).
+					Times(3)
+			},
+			wantSuccessful: 23,
+		},
+		{
+			name:    "異常系: エントリごとの失敗が結果に含まれる",
+			entries: sendEntries(3),
+			setupMock: func(mockAPI *sqsmock.MockSQSAPI) {
+				mockAPI.EXPECT().
+					SendMessageBatch(gomock.Any(), gomock.Any()).
+					Return(&sqs.SendMessageBatchOutput{
+						Successful: []types.SendMessageBatchResultEntry{{Id: aws.String("user-0")}, {Id: aws.String("user-2")}},
+						Failed: []types.BatchResultErrorEntry{
+							{Id: aws.String("user-1"), Code: aws.String("InvalidParameterValue"), Message: aws.String("message too long"), SenderFault: true},
+						},
+					}, nil)
+			},
+			wantSuccessful: 2,
+			wantFailed:     []string{"user-1"},
+		},
+		{
+			name:    "異常系: リクエストが失敗したチャンクのエントリはすべて失敗になる",
+			entries: sendEntries(12),
+			setupMock: func(mockAPI *sqsmock.MockSQSAPI) {
+				gomock.InOrder(
+					mockAPI.EXPECT().SendMessageBatch(gomock.Any(), gomock.Any()).DoAndReturn(succeedSendBatch),
+					mockAPI.EXPECT().SendMessageBatch(gomock.Any(), gomock.Any()).Return(nil, errors.New("connection reset")),
+				)
+			},
+			wantSuccessful: 10,
+			wantFailed:     []string{"user-10", "user-11"},
+		},
+		{
+			name:          "異常系: エントリIDが重複している場合は送信しない",
+			entries:       []sqspkg.SendMessageBatchEntry{sqspkg.NewSendMessageBatchEntry("a"), sqspkg.NewSendMessageBatchEntry("a")},
+			setupMock:     func(mockAPI *sqsmock.MockSQSAPI) {},
+			expectedError: sqspkg.ErrDuplicateBatchEntryID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockAPI := sqsmock.NewMockSQSAPI(ctrl)
+			tt.setupMock(mockAPI)
+			client := sqspkg.NewSQSClientWithAPI(mockAPI)
+
+			// テスト実行
+			result, err := client.SendMessageBatch(context.Background(), "https://sqs.ap-northeast-1.amazonaws.com/123456789012/test-queue", tt.entries)
+
+			// エラーチェック
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error: %v, got: %v", tt.expectedError, err)
+			}
+			if err != nil {
+				return
+			}
+			if len(result.Successful) != tt.wantSuccessful {
+				t.Errorf("expected %d successful entries, got %d", tt.wantSuccessful, len(result.Successful))
+			}
+			assertFailedIDs(t, result.Failed, tt.wantFailed)
+			if (result.Err() != nil) != (len(tt.wantFailed) > 0) {
+				t.Errorf("unexpected Err(): %v", result.Err())
+			}
+		})
+	}
+}
+
+func assertFailedIDs(t *testing.T, failed []*sqspkg.BatchEntryError, want []string) {
+	t.Helper()
+	got := make([]string, len(failed))
+	for i, f := range failed {
+		got[i] = f.ID
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected failed entries %v, got %v", want, got)
+	}
+}
+
+// TestBatchEntryError はエントリごとのエラーの判定を確認します。
+func TestBatchEntryError(t *testing.T) {
+	t.Parallel()
+
+	errNetwork := errors.New("connection reset")
+	tests := []struct {
+		name          string
+		err           *sqspkg.BatchEntryError
+		wantRetryable bool
+		wantCause     error
+	}{
+		{name: "正常系: 送信側の誤りは再送しない", err: &sqspkg.BatchEntryError{ID: "a", Code: "InvalidParameterValue", SenderFault: true}, wantRetryable: false},
+		{name: "正常系: SQS側の失敗は再送できる", err: &sqspkg.BatchEntryError{ID: "a", Code: "InternalError"}, wantRetryable: true},
+		{name: "正常系: リクエストの失敗は原因を辿れる", err: &sqspkg.BatchEntryError{ID: "a", Err: errNetwork}, wantRetryable: true, wantCause: errNetwork},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.err.Retryable(); got != tt.wantRetryable {
+				t.Errorf("expected Retryable() to be %v, got %v", tt.wantRetryable, got)
+			}
+			result := &sqspkg.SendMessageBatchResult{Failed: []*sqspkg.BatchEntryError{tt.err}}
+			var entryErr *sqspkg.BatchEntryError
+			if !errors.As(result.Err(), &entryErr) || entryErr.ID != "a" {
+				t.Errorf("expected Err() to contain *BatchEntryError, got %v", result.Err())
+			}
+			if tt.wantCause != nil && !errors.Is(result.Err(), tt.wantCause) {
+				t.Errorf("expected Err() to wrap %v", tt.wantCause)
+			}
+		})
+	}
+}
+
+// TestDeleteMessageBatch は DeleteMessageBatch メソッドのテストです。
+func TestDeleteMessageBatch(t *testing.T) {
+	t.Parallel()
+
+	entries := make([]sqspkg.DeleteMessageBatchEntry, 15)
+	for i := range entries {
+		// IDを省略した場合はインデックスが使われる
+		entries[i] = sqspkg.NewDeleteMessageBatchEntry("", sqspkg.WithReceiptHandle(fmt.Sprintf("receipt-%d", i)))
+	}
+
+	tests := []struct {
+		name           string
+		setupMock      func(mockAPI *sqsmock.MockSQSAPI)
+		wantSuccessful []string
+		wantFailed     []string
+	}{
+		{
+			name: "正常系: 分割して削除し、IDを省略したエントリはインデックスで識別できる",
+			setupMock: func(mockAPI *sqsmock.MockSQSAPI) {
+				mockAPI.EXPECT().
+					DeleteMessageBatch(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, params *sqs.DeleteMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) {
+						output := &sqs.DeleteMessageBatchOutput{}
+						for _, e := range params.Entries {
+							if want := "receipt-" + aws.ToString(e.Id); aws.ToString(e.ReceiptHandle) != want {
+								t.Errorf("expected ReceiptHandle to be %s, got %s", want, aws.ToString(e.ReceiptHandle))
+							}
+							if aws.ToString(e.Id) == "12" {
+								output.Failed = append(output.Failed, types.BatchResultErrorEntry{Id: e.Id, Code: aws.String("ReceiptHandleIsInvalid"), SenderFault: true})
+								continue
+							}
+							output.Successful = append(output.Successful, types.DeleteMessageBatchResultEntry{Id: e.Id})
+						}
+						return output, nil
+					}).
+					Times(2)
+			},
+			wantSuccessful: []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "13", "14"},
+			wantFailed:     []string{"12"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockAPI := sqsmock.NewMockSQSAPI(ctrl)
+			tt.setupMock(mockAPI)
+			client := sqspkg.NewSQSClientWithAPI(mockAPI)
+
+			// テスト実行
+			result, err := client.DeleteMessageBatch(context.Background(), "https://sqs.ap-northeast-1.amazonaws.com/123456789012/test-queue", entries)
+
+			// エラーチェック
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if fmt.Sprint(result.Successful) != fmt.Sprint(tt.wantSuccessful) {
+				t.Errorf("expected successful entries %v, got %v", tt.wantSuccessful, result.Successful)
+			}
+			assertFailedIDs(t, result.Failed, tt.wantFailed)
+		})
+	}
+}
+
+// TestReceiveMessageBatch は ReceiveMessageBatch メソッドのテストです。
+// 指定した件数に達するか、キューが空になるまで繰り返し受信することを確認します。
+func TestReceiveMessageBatch(t *testing.T) {
+	t.Parallel()
+
+	messages := func(n int) []types.Message {
+		msgs := make([]types.Message, n)
+		for i := range msgs {
+			msgs[i] = types.Message{MessageId: aws.String(fmt.Sprintf("msg-%d", i))}
+		}
+		return msgs
+	}
+
+	tests := []struct {
+		name          string
+		maxMessages   int
+		responses     [][]types.Message
+		responseErr   error
+		wantRequests  []int32
+		wantMessages  int
+		expectedError bool
+	}{
+		{
+			name:         "正常系: 上限に達するまで受信する",
+			maxMessages:  25,
+			responses:    [][]types.Message{messages(10), messages(10), messages(5)},
+			wantRequests: []int32{10, 10, 5},
+			wantMessages: 25,
+		},
+		{
+			name:         "正常系: キューが空になった時点で終了する",
+			maxMessages:  30,
+			responses:    [][]types.Message{messages(10), messages(4), nil},
+			wantRequests: []int32{10, 10, 10},
+			wantMessages: 14,
+		},
+		{
+			name:          "異常系: 途中で失敗した場合は受信済みのメッセージとエラーを返す",
+			maxMessages:   20,
+			responses:     [][]types.Message{messages(10)},
+			responseErr:   errors.New("throttled"),
+			wantRequests:  []int32{10, 10},
+			wantMessages:  10,
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockAPI := sqsmock.NewMockSQSAPI(ctrl)
+			var requests []int32
+			mockAPI.EXPECT().
+				ReceiveMessage(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+					// ロングポーリングは1回目のみ
+					wantWait := int32(0)
+					if len(requests) == 0 {
+						wantWait = 20
+					}
+					if params.WaitTimeSeconds != wantWait {
+						t.Errorf("expected WaitTimeSeconds to be %d, got %d", wantWait, params.WaitTimeSeconds)
+					}
+					requests = append(requests, params.MaxNumberOfMessages)
+					if len(requests) > len(tt.responses) {
+						return nil, tt.responseErr
+					}
+					return &sqs.ReceiveMessageOutput{Messages: tt.responses[len(requests)-1]}, nil
+				}).
+				Times(len(tt.wantRequests))
+			client := sqspkg.NewSQSClientWithAPI(mockAPI)
+
+			// テスト実行
+			got, err := client.ReceiveMessageBatch(context.Background(), "https://sqs.ap-northeast-1.amazonaws.com/123456789012/test-queue", tt.maxMessages, sqspkg.WithWaitTimeSeconds(20))
+
+			// エラーチェック
+			if (err != nil) != tt.expectedError {
+				t.Errorf("expected error: %v, got: %v", tt.expectedError, err)
+			}
+			if len(got) != tt.wantMessages {
+				t.Errorf("expected %d messages, got %d", tt.wantMessages, len(got))
+			}
+			if fmt.Sprint(requests) != fmt.Sprint(tt.wantRequests) {
+				t.Errorf("expected MaxNumberOfMessages %v, got %v", tt.wantRequests, requests)
+			}
+		})
+	}
+}
+
+// TestSendMessageAttributeOptions はメッセージ属性・遅延・FIFO用のオプションが送信内容に反映されることを確認します。
+func TestSendMessageAttributeOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options []sqspkg.SendMessageOptionFunc
+		check   func(t *testing.T, params *sqs.SendMessageInput)
+	}{
+		{
+			name: "正常系: メッセージ属性を追加できる",
+			options: []sqspkg.SendMessageOptionFunc{
+				sqspkg.WithStringMessageAttribute("eventType", "user.updated"),
+				sqspkg.WithMessageAttribute("version", types.MessageAttributeValue{DataType: aws.String("Number"), StringValue: aws.String("2")}),
+			},
+			check: func(t *testing.T, params *sqs.SendMessageInput) {
+				if got := params.MessageAttributes["eventType"]; aws.ToString(got.DataType) != "String" || aws.ToString(got.StringValue) != "user.updated" {
+					t.Errorf("unexpected eventType attribute: %+v", got)
+				}
+				if got := params.MessageAttributes["version"]; aws.ToString(got.DataType) != "Number" || aws.ToString(got.StringValue) != "2" {
+					t.Errorf("unexpected version attribute: %+v", got)
+				}
+			},
+		},
+		{
+			name:    "正常系: 遅延時間を設定できる",
+			options: []sqspkg.SendMessageOptionFunc{sqspkg.WithDelaySeconds(60)},
+			check: func(t *testing.T, params *sqs.SendMessageInput) {
+				if params.DelaySeconds != 60 {
+					t.Errorf("expected DelaySeconds to be 60, got %d", params.DelaySeconds)
+				}
+			},
+		},
+		{
+			name:    "正常系: FIFOキューのグループIDと重複排除IDを設定できる",
+			options: []sqspkg.SendMessageOptionFunc{sqspkg.WithMessageGroupID("user-1"), sqspkg.WithMessageDeduplicationID("event-1")},
+			check: func(t *testing.T, params *sqs.SendMessageInput) {
+				if aws.ToString(params.MessageGroupId) != "user-1" || aws.ToString(params.MessageDeduplicationId) != "event-1" {
+					t.Errorf("unexpected MessageGroupId/MessageDeduplicationId: %s, %s", aws.ToString(params.MessageGroupId), aws.ToString(params.MessageDeduplicationId))
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockAPI := sqsmock.NewMockSQSAPI(ctrl)
+			mockAPI.EXPECT().
+				SendMessage(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+					tt.check(t, params)
+					return &sqs.SendMessageOutput{}, nil
+				})
+			client := sqspkg.NewSQSClientWithAPI(mockAPI)
+
+			// テスト実行
+			if _, err := client.SendMessage(context.Background(), "https://sqs.ap-northeast-1.amazonaws.com/123456789012/test-queue", tt.options...); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// TestReceiveAttributeOptions は受信時に取得する属性のオプションを確認します。
+func TestReceiveAttributeOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name                 string
+		options              []sqspkg.ReceiveMessageOptionFunc
+		wantMessageAttrs     []string
+		wantSystemAttributes []types.MessageSystemAttributeName
+	}{
+		{
+			name:             "正常系: すべてのメッセージ属性を取得する",
+			options:          []sqspkg.ReceiveMessageOptionFunc{sqspkg.WithAllMessageAttributes()},
+			wantMessageAttrs: []string{"All"},
+		},
+		{
+			name:                 "正常系: 受信回数とグループIDを重複なく追加できる",
+			options:              []sqspkg.ReceiveMessageOptionFunc{sqspkg.WithApproximateReceiveCount(), sqspkg.WithSystemAttributeNames(types.MessageSystemAttributeNameMessageGroupId, types.MessageSystemAttributeNameApproximateReceiveCount)},
+			wantSystemAttributes: []types.MessageSystemAttributeName{types.MessageSystemAttributeNameApproximateReceiveCount, types.MessageSystemAttributeNameMessageGroupId},
+		},
+		{
+			name:                 "正常系: すべてのシステム属性と指定したメッセージ属性を取得する",
+			options:              []sqspkg.ReceiveMessageOptionFunc{sqspkg.WithAllSystemAttributes(), sqspkg.WithMessageAttributeNames("traceparent", "eventType")},
+			wantMessageAttrs:     []string{"traceparent", "eventType"},
+			wantSystemAttributes: []types.MessageSystemAttributeName{types.MessageSystemAttributeNameAll},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			input := &sqs.ReceiveMessageInput{}
+			for _, option := range tt.options {
+				option(input)
+			}
+			if fmt.Sprint(input.MessageAttributeNames) != fmt.Sprint(tt.wantMessageAttrs) {
+				t.Errorf("expected MessageAttributeNames %v, got %v", tt.wantMessageAttrs, input.MessageAttributeNames)
+			}
+			if fmt.Sprint(input.MessageSystemAttributeNames) != fmt.Sprint(tt.wantSystemAttributes) {
+				t.Errorf("expected MessageSystemAttributeNames %v, got %v", tt.wantSystemAttributes, input.MessageSystemAttributeNames)
+			}
+		})
+	}
+}
+
+// TestMessageSystemAttributes は受信したメッセージのシステム属性の参照を確認します。
+func TestMessageSystemAttributes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		attributes       map[string]string
+		wantReceiveCount int
+		wantGroupID      string
+	}{
+		{
+			name:             "正常系: 受信回数とグループIDを参照できる",
+			attributes:       map[string]string{"ApproximateReceiveCount": "3", "MessageGroupId": "user-1"},
+			wantReceiveCount: 3,
+			wantGroupID:      "user-1",
+		},
+		{
+			name:             "正常系: 属性を取得していない場合はゼロ値",
+			attributes:       nil,
+			wantReceiveCount: 0,
+			wantGroupID:      "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			msg := types.Message{Attributes: tt.attributes}
+			if got := sqspkg.ApproximateReceiveCount(msg); got != tt.wantReceiveCount {
+				t.Errorf("expected ApproximateReceiveCount() to be %d, got %d", tt.wantReceiveCount, got)
+			}
+			if got := sqspkg.MessageGroupID(msg); got != tt.wantGroupID {
+				t.Errorf("expected MessageGroupID() to be %s, got %s", tt.wantGroupID, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package sqs
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/trace"
+)
+
+// TraceParentAttribute はトレースコンテキストを伝播するメッセージ属性の名前です。
+const TraceParentAttribute = trace.TraceParentHeader
+
+// WithTraceParent は traceparent をメッセージ属性に設定します。空の場合は何もしません。
+// アウトボックスのように、送信時とは別のコンテキストで記録したトレースを引き継ぐ場合に使用します。
+// 引数:
+//   - traceParent: traceparent の文字列 (trace.TraceParentFromContext の戻り値)
+func WithTraceParent(traceParent string) SendMessageOptionFunc {
+	return func(input *sqs.SendMessageInput) {
+		if traceParent != "" {
+			WithStringMessageAttribute(TraceParentAttribute, traceParent)(input)
+		}
+	}
+}
+
+// WithTraceParentAttribute は受信時にトレースコンテキストのメッセージ属性を取得します。
+func WithTraceParentAttribute() ReceiveMessageOptionFunc {
+	return WithMessageAttributeNames(TraceParentAttribute)
+}
+
+// injectTraceContext は traceparent が未設定の場合に、ctx のスパンをメッセージ属性に設定する
+// SendMessage と SendMessageBatch は送信したメッセージを処理するタスクが同じトレースを引き継げるよう、自動的に呼び出す
+func injectTraceContext(ctx context.Context, input *sqs.SendMessageInput) {
+	if _, ok := input.MessageAttributes[TraceParentAttribute]; ok {
+		return
+	}
+	WithTraceParent(trace.TraceParentFromContext(ctx))(input)
+}
+
+// ContextWithMessageTrace はメッセージ属性の traceparent を親として ctx に設定します。
+// 属性がない、または形式が不正な場合は ctx をそのまま返します。
+// 注意事項: 受信時に WithTraceParentAttribute (または WithAllMessageAttributes) を指定すること
+func ContextWithMessageTrace(ctx context.Context, msg types.Message) context.Context {
+	attr, ok := msg.MessageAttributes[TraceParentAttribute]
+	if !ok {
+		return ctx
+	}
+	sc, err := trace.ParseTraceParent(aws.ToString(attr.StringValue))
+	if err != nil {
+		return ctx
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, sc)
+}
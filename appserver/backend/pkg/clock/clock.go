@@ -0,0 +1,54 @@
+// Package clock は現在時刻とタイマーを抽象化し、テストで時間を制御できるようにします。
+package clock
+
+import "time"
+
+// Clock は現在時刻の取得とタイマーの生成を行うインターフェースです。
+// 本番では New が返す実時間の実装を、テストでは Fake を使用します。
+type Clock interface {
+	// Now は現在時刻を返します。
+	Now() time.Time
+	// After は d 経過後に現在時刻を送信するチャネルを返します。
+	After(d time.Duration) <-chan time.Time
+	// NewTimer は d 経過後に発火するタイマーを生成します。
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer は Clock が生成するタイマーです。
+type Timer interface {
+	// C は発火時に時刻を受信するチャネルを返します。
+	C() <-chan time.Time
+	// Stop はタイマーを停止します。発火前に停止できた場合は true を返します。
+	Stop() bool
+}
+
+// New は実時間の Clock を返します。
+func New() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{t: time.NewTimer(d)}
+}
+
+type realTimer struct {
+	t *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.t.Stop()
+}
@@ -0,0 +1,147 @@
+package clock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Fake はテスト用の Clock です。時刻は Advance / Set を呼んだときにのみ進みます。
+// 使用例:
+//
+//	clk := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+//	go scheduler.Run(ctx)
+//	clk.BlockUntil(ctx, 1) // スケジューラーがタイマーを待機するまで待つ
+//	clk.Advance(time.Minute)
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeTimer
+	// changed は待機中のタイマーの数が変わったときにクローズし、BlockUntil を起こす
+	changed chan struct{}
+}
+
+// NewFake は指定した時刻から始まる Fake を生成します。
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now, changed: make(chan struct{})}
+}
+
+// Now は現在の疑似時刻を返します。
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// After は疑似時刻が d 進んだときに発火するチャネルを返します。
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// NewTimer は疑似時刻が d 進んだときに発火するタイマーを生成します。
+// d が0以下の場合は即座に発火します。
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{fake: f, deadline: f.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- f.now
+		return t
+	}
+	f.waiters = append(f.waiters, t)
+	f.notifyLocked()
+	return t
+}
+
+// Advance は疑似時刻を d 進め、期限を迎えたタイマーを発火させます。
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.setLocked(f.now.Add(d))
+}
+
+// Set は疑似時刻を t に設定し、期限を迎えたタイマーを発火させます。
+// 現在より前の時刻を指定した場合、時刻は戻りますがタイマーは発火しません。
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.setLocked(t)
+}
+
+func (f *Fake) setLocked(t time.Time) {
+	f.now = t
+	remaining := f.waiters[:0]
+	fired := false
+	for _, w := range f.waiters {
+		if w.deadline.After(t) {
+			remaining = append(remaining, w)
+			continue
+		}
+		w.c <- t
+		fired = true
+	}
+	clear(f.waiters[len(remaining):])
+	f.waiters = remaining
+	if fired {
+		f.notifyLocked()
+	}
+}
+
+// Waiters は発火を待っているタイマーの数を返します。
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+// BlockUntil は発火を待っているタイマーが n 個以上になるまで待機します。
+// 別のゴルーチンがタイマーを待ち始めたことを確認してから Advance するために使用します。
+func (f *Fake) BlockUntil(ctx context.Context, n int) error {
+	for {
+		f.mu.Lock()
+		if len(f.waiters) >= n {
+			f.mu.Unlock()
+			return nil
+		}
+		changed := f.changed
+		f.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (f *Fake) notifyLocked() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *Fake) stop(t *fakeTimer) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, w := range f.waiters {
+		if w == t {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			f.notifyLocked()
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	fake     *Fake
+	deadline time.Time
+	c        chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	return t.fake.stop(t)
+}
@@ -0,0 +1,94 @@
+package clock_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/clock"
+)
+
+var epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// TestFake_Advance は期限を迎えたタイマーだけが発火することを確認します。
+func TestFake_Advance(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		delay     time.Duration
+		advance   time.Duration
+		wantFired bool
+	}{
+		{name: "正常系: 期限ちょうどで発火する", delay: time.Minute, advance: time.Minute, wantFired: true},
+		{name: "正常系: 期限を過ぎると発火する", delay: time.Minute, advance: time.Hour, wantFired: true},
+		{name: "正常系: 期限前は発火しない", delay: time.Minute, advance: 59 * time.Second, wantFired: false},
+		{name: "正常系: 0以下の待機は即座に発火する", delay: 0, advance: 0, wantFired: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			clk := clock.NewFake(epoch)
+			c := clk.After(tt.delay)
+			clk.Advance(tt.advance)
+
+			select {
+			case got := <-c:
+				if !tt.wantFired {
+					t.Fatalf("timer fired unexpectedly at %v", got)
+				}
+				if want := epoch.Add(tt.advance); !got.Equal(want) {
+					t.Errorf("fired at %v, want %v", got, want)
+				}
+			default:
+				if tt.wantFired {
+					t.Fatal("timer did not fire")
+				}
+			}
+			if got := clk.Now(); !got.Equal(epoch.Add(tt.advance)) {
+				t.Errorf("Now() = %v, want %v", got, epoch.Add(tt.advance))
+			}
+		})
+	}
+}
+
+// TestFake_StopAndBlockUntil は停止したタイマーが発火せず、BlockUntil が待機中のタイマー数を待つことを確認します。
+func TestFake_StopAndBlockUntil(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewFake(epoch)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go func() {
+		clk.NewTimer(time.Minute)
+		clk.NewTimer(2 * time.Minute)
+	}()
+	if err := clk.BlockUntil(ctx, 2); err != nil {
+		t.Fatalf("BlockUntil() error = %v", err)
+	}
+
+	timer := clk.NewTimer(time.Second)
+	if !timer.Stop() {
+		t.Error("Stop() = false, want true for a pending timer")
+	}
+	if timer.Stop() {
+		t.Error("Stop() = true, want false for an already stopped timer")
+	}
+	clk.Advance(time.Minute)
+	select {
+	case <-timer.C():
+		t.Error("stopped timer fired")
+	default:
+	}
+	if got := clk.Waiters(); got != 1 {
+		t.Errorf("Waiters() = %d, want 1", got)
+	}
+
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer shortCancel()
+	if err := clk.BlockUntil(shortCtx, 5); err == nil {
+		t.Error("BlockUntil() error = nil, want context deadline")
+	}
+}
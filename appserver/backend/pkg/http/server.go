@@ -1,23 +1,290 @@
 package http
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
-func NewServer(addr string, handler *http.ServeMux) error {
-	server := &http.Server{
-		Addr:         addr,
-		Handler:      handler,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  120 * time.Second,
+const (
+	defaultAddr              = ":8080"
+	defaultReadTimeout       = 10 * time.Second
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultWriteTimeout      = 10 * time.Second
+	defaultIdleTimeout       = 120 * time.Second
+	defaultShutdownTimeout   = 30 * time.Second
+	defaultReadinessTimeout  = 2 * time.Second
+
+	// HealthzPath は生存確認のパス (プロセスが応答できれば常に200を返す)
+	HealthzPath = "/healthz"
+	// ReadyzPath は準備完了確認のパス (停止処理中、または依存先の確認に失敗した場合は503を返す)
+	ReadyzPath = "/readyz"
+)
+
+// ReadinessCheck はリクエストを受け付けられるかを確認する関数型です。
+// 依存先 (データベースなど) が利用できない場合はエラーを返します。
+type ReadinessCheck func(ctx context.Context) error
+
+// readinessCheck は名前付きの ReadinessCheck
+type readinessCheck struct {
+	name  string
+	check ReadinessCheck
+}
+
+// Server はシグナルを受けて処理中のリクエストの完了を待ってから停止するHTTPサーバーです。
+// GET /healthz と GET /readyz をハンドラーより優先して応答します。
+type Server struct {
+	server           *http.Server
+	certFile         string
+	keyFile          string
+	shutdownTimeout  time.Duration
+	drainDelay       time.Duration
+	readinessTimeout time.Duration
+	checks           []readinessCheck
+	// draining は Shutdown が呼ばれた後 true になり、/readyz が503を返す
+	draining     atomic.Bool
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
+// ServerOption はServerのオプション関数型です。
+type ServerOption func(*Server)
+
+// WithAddr は待ち受けるアドレスを設定します (デフォルト: ":8080")。
+func WithAddr(addr string) ServerOption {
+	return func(s *Server) {
+		s.server.Addr = addr
+	}
+}
+
+// WithReadTimeout はリクエスト全体 (ボディを含む) の読み込みの制限時間を設定します (デフォルト: 10秒)。
+func WithReadTimeout(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.server.ReadTimeout = d
+	}
+}
+
+// WithReadHeaderTimeout はリクエストヘッダーの読み込みの制限時間を設定します (デフォルト: 5秒)。
+func WithReadHeaderTimeout(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.server.ReadHeaderTimeout = d
+	}
+}
+
+// WithWriteTimeout はレスポンスの書き込みの制限時間を設定します (デフォルト: 10秒)。
+func WithWriteTimeout(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.server.WriteTimeout = d
+	}
+}
+
+// WithIdleTimeout はキープアライブの接続を次のリクエストまで維持する時間を設定します (デフォルト: 120秒)。
+func WithIdleTimeout(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.server.IdleTimeout = d
+	}
+}
+
+// WithMaxHeaderBytes はリクエストヘッダーの最大サイズを設定します (デフォルト: http.DefaultMaxHeaderBytes)。
+func WithMaxHeaderBytes(n int) ServerOption {
+	return func(s *Server) {
+		s.server.MaxHeaderBytes = n
+	}
+}
+
+// WithTLS は証明書と秘密鍵のファイルを設定し、HTTPSで待ち受けます。
+func WithTLS(certFile, keyFile string) ServerOption {
+	return func(s *Server) {
+		s.certFile = certFile
+		s.keyFile = keyFile
+	}
+}
+
+// WithShutdownTimeout は処理中のリクエストの完了を待つ時間を設定します (デフォルト: 30秒)。
+// 制限時間を過ぎた場合は残りの接続を強制的に閉じます。
+func WithShutdownTimeout(d time.Duration) ServerOption {
+	if d <= 0 {
+		d = defaultShutdownTimeout
+	}
+	return func(s *Server) {
+		s.shutdownTimeout = d
+	}
+}
+
+// WithDrainDelay は停止処理の開始から新しいリクエストの受け付けを止めるまでの待機時間を設定します (デフォルト: 0)。
+// この間 /readyz は503を返すため、ロードバランサーが振り分け先から外すまでの猶予として使用します。
+func WithDrainDelay(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.drainDelay = max(d, 0)
+	}
+}
+
+// WithReadinessCheck は /readyz で実行する確認を追加します。
+// 使用例: http.WithReadinessCheck("database", db.PingContext)
+func WithReadinessCheck(name string, check ReadinessCheck) ServerOption {
+	return func(s *Server) {
+		s.checks = append(s.checks, readinessCheck{name: name, check: check})
+	}
+}
+
+// WithReadinessTimeout は /readyz の確認1件あたりの制限時間を設定します (デフォルト: 2秒)。
+func WithReadinessTimeout(d time.Duration) ServerOption {
+	if d <= 0 {
+		d = defaultReadinessTimeout
+	}
+	return func(s *Server) {
+		s.readinessTimeout = d
+	}
+}
+
+// NewServer はServerを生成する
+// 引数:
+//   - handler: アプリケーションのハンドラー (/healthz と /readyz 以外のリクエストを処理する)
+//   - opts: オプション
+func NewServer(handler http.Handler, opts ...ServerOption) *Server {
+	s := &Server{
+		server: &http.Server{
+			Addr:              defaultAddr,
+			ReadTimeout:       defaultReadTimeout,
+			ReadHeaderTimeout: defaultReadHeaderTimeout,
+			WriteTimeout:      defaultWriteTimeout,
+			IdleTimeout:       defaultIdleTimeout,
+		},
+		shutdownTimeout:  defaultShutdownTimeout,
+		readinessTimeout: defaultReadinessTimeout,
 	}
-	log.Printf("server is listening on %s", addr)
-	if err := server.ListenAndServe(); err != nil {
-		return fmt.Errorf("failed to listen and serve: %w", err)
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+HealthzPath, s.healthz)
+	mux.HandleFunc("GET "+ReadyzPath, s.readyz)
+	mux.Handle("/", handler)
+	s.server.Handler = mux
+	return s
+}
+
+// Handler は /healthz と /readyz を含むハンドラーを返します。
+func (s *Server) Handler() http.Handler {
+	return s.server.Handler
+}
+
+// Start は設定したアドレスで待ち受け、SIGINT / SIGTERM を受信するか ctx がキャンセルされるまでブロックします。
+// 停止時は Shutdown を呼び出し、処理中のリクエストの完了を待ってから戻ります。
+func (s *Server) Start(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen (addr=%s): %w", s.server.Addr, err)
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve は ln で待ち受ける点を除き Start と同じです。
+// 注意事項: ln は Serve の終了時に閉じられる
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("server is listening", "addr", ln.Addr().String(), "tls", s.certFile != "")
+		var err error
+		if s.certFile != "" {
+			err = s.server.ServeTLS(ln, s.certFile, s.keyFile)
+		} else {
+			err = s.server.Serve(ln)
+		}
+		serveErr <- err
+	}()
+
+	select {
+	case err := <-serveErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			// 他のゴルーチンから Shutdown が呼ばれた
+			return nil
+		}
+		return fmt.Errorf("failed to serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	slog.Info("server is shutting down", "drainDelay", s.drainDelay, "timeout", s.shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.drainDelay+s.shutdownTimeout)
+	defer cancel()
+	if err := s.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve: %w", err)
 	}
 	return nil
 }
+
+// Shutdown は /readyz を503に切り替え、WithDrainDelay の時間が経過した後に新しい接続の受け付けを止めて
+// 処理中のリクエストの完了を待ちます。
+// ctx の期限までに完了しない場合は残りの接続を強制的に閉じ、ctx のエラーを返します。
+// 注意事項: 2回目以降の呼び出しは1回目の結果を返す
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shutdownOnce.Do(func() {
+		s.draining.Store(true)
+		if s.drainDelay > 0 {
+			select {
+			case <-time.After(s.drainDelay):
+			case <-ctx.Done():
+			}
+		}
+		if err := s.server.Shutdown(ctx); err != nil {
+			s.server.Close()
+			s.shutdownErr = fmt.Errorf("failed to shutdown server: %w", err)
+		}
+	})
+	return s.shutdownErr
+}
+
+// healthStatus は /healthz と /readyz のレスポンス
+type healthStatus struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// healthz はプロセスが応答できることを返す
+// 注意事項: 停止処理中も200を返し、処理中のリクエストが終わる前にプロセスが再起動されないようにする
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, healthStatus{Status: "ok"}, http.StatusOK)
+}
+
+// readyz は新しいリクエストを受け付けられるかを返す
+// 停止処理中、またはいずれかの確認に失敗した場合は503を返す
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	if s.draining.Load() {
+		WriteJSON(w, healthStatus{Status: "draining"}, http.StatusServiceUnavailable)
+		return
+	}
+
+	res := healthStatus{Status: "ok"}
+	status := http.StatusOK
+	if len(s.checks) > 0 {
+		res.Checks = make(map[string]string, len(s.checks))
+	}
+	for _, c := range s.checks {
+		ctx, cancel := context.WithTimeout(r.Context(), s.readinessTimeout)
+		err := c.check(ctx)
+		cancel()
+		if err != nil {
+			res.Status = "unavailable"
+			res.Checks[c.name] = err.Error()
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		res.Checks[c.name] = "ok"
+	}
+	WriteJSON(w, res, status)
+}
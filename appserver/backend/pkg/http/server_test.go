@@ -0,0 +1,184 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	httputil "github.com/takeuchi-shogo/golang-learn/app/backend/pkg/http"
+)
+
+// TestServer_Readyz は /readyz が確認の結果と停止処理の状態に応じて応答することを確認します。
+func TestServer_Readyz(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     []httputil.ServerOption
+		shutdown   bool
+		wantStatus int
+		wantBody   string
+		wantChecks map[string]string
+	}{
+		{
+			name:       "正常系: 確認がない場合は200",
+			wantStatus: http.StatusOK,
+			wantBody:   "ok",
+		},
+		{
+			name:       "正常系: すべての確認に成功した場合は200",
+			checks:     []httputil.ServerOption{httputil.WithReadinessCheck("database", func(ctx context.Context) error { return nil })},
+			wantStatus: http.StatusOK,
+			wantBody:   "ok",
+			wantChecks: map[string]string{"database": "ok"},
+		},
+		{
+			name: "異常系: データベースの確認に失敗した場合は503",
+			checks: []httputil.ServerOption{
+				httputil.WithReadinessCheck("database", func(ctx context.Context) error { return errors.New("connection refused") }),
+				httputil.WithReadinessCheck("cache", func(ctx context.Context) error { return nil }),
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   "unavailable",
+			wantChecks: map[string]string{"database": "connection refused", "cache": "ok"},
+		},
+		{
+			name:       "異常系: 停止処理中は503",
+			checks:     []httputil.ServerOption{httputil.WithReadinessCheck("database", func(ctx context.Context) error { return nil })},
+			shutdown:   true,
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   "draining",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := httputil.NewServer(http.NotFoundHandler(), tt.checks...)
+			if tt.shutdown {
+				if err := s.Shutdown(context.Background()); err != nil {
+					t.Fatalf("Shutdown() error = %v", err)
+				}
+			}
+
+			rec := httptest.NewRecorder()
+			s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, httputil.ReadyzPath, nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			var got struct {
+				Status string            `json:"status"`
+				Checks map[string]string `json:"checks"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if got.Status != tt.wantBody {
+				t.Errorf("status = %q, want %q", got.Status, tt.wantBody)
+			}
+			for name, want := range tt.wantChecks {
+				if got.Checks[name] != want {
+					t.Errorf("checks[%s] = %q, want %q", name, got.Checks[name], want)
+				}
+			}
+		})
+	}
+}
+
+// TestServer_Healthz は /healthz が停止処理中も200を返し、それ以外のパスはハンドラーに委譲することを確認します。
+func TestServer_Healthz(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	s := httputil.NewServer(handler)
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{name: "正常系: 停止処理中も生存確認は200", path: httputil.HealthzPath, wantStatus: http.StatusOK},
+		{name: "正常系: それ以外のパスはハンドラーが処理する", path: "/users/1", wantStatus: http.StatusTeapot},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// TestServer_Serve は停止時に処理中のリクエストの完了を待ってから戻ることを確認します。
+func TestServer_Serve(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	})
+	s := httputil.NewServer(handler, httputil.WithShutdownTimeout(5*time.Second))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.Serve(ctx, ln)
+	}()
+
+	type result struct {
+		body string
+		err  error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		res, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err != nil {
+			resCh <- result{err: err}
+			return
+		}
+		defer res.Body.Close()
+		b, err := io.ReadAll(res.Body)
+		resCh <- result{body: string(b), err: err}
+	}()
+	<-started
+
+	// 処理中のリクエストがある状態で停止を開始する
+	cancel()
+	select {
+	case err := <-serveErr:
+		t.Fatalf("Serve() returned before in-flight request completed: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	res := <-resCh
+	if res.err != nil || res.body != "done" {
+		t.Errorf("in-flight request = %q, %v, want done", res.body, res.err)
+	}
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve() did not return after shutdown")
+	}
+
+	if _, err := net.DialTimeout("tcp", ln.Addr().String(), time.Second); err == nil {
+		t.Error("server still accepts connections after shutdown")
+	}
+}
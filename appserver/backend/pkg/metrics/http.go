@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// unmatchedRoute はどのルートにも一致しなかったリクエストのラベル値
+// パスをそのままラベルにすると系列が際限なく増えるため、まとめて集計する
+const unmatchedRoute = "unmatched"
+
+// HTTPMetrics はHTTPリクエストの件数と処理時間をルートパターンごとに集計します。
+type HTTPMetrics struct {
+	requests *CounterVec
+	duration *HistogramVec
+	inFlight *Gauge
+}
+
+// NewHTTPMetrics はHTTPリクエストのメトリクスを reg に登録します。
+//   - http_requests_total{route, code}: 処理したリクエスト数
+//   - http_request_duration_seconds{route}: リクエストの処理時間
+//   - http_requests_in_flight: 処理中のリクエスト数
+func NewHTTPMetrics(reg *Registry) *HTTPMetrics {
+	return &HTTPMetrics{
+		requests: reg.CounterVec("http_requests_total", "Total number of HTTP requests by route pattern and status code.", "route", "code"),
+		duration: reg.HistogramVec("http_request_duration_seconds", "HTTP request latency in seconds by route pattern.", nil, "route"),
+		inFlight: reg.GaugeVec("http_requests_in_flight", "Number of HTTP requests currently being served.").WithLabelValues(),
+	}
+}
+
+// Middleware は next で処理したリクエストを集計するハンドラーを返します。
+// ルートは http.ServeMux が一致したパターン (例: "GET /users/{id}") で集計するため、next には ServeMux を渡します。
+func (m *HTTPMetrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		rw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		// ServeMux はルーティング時に r.Pattern を設定する
+		route := r.Pattern
+		if route == "" {
+			route = unmatchedRoute
+		}
+		m.requests.WithLabelValues(route, strconv.Itoa(rw.status)).Inc()
+		m.duration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// statusRecorder はレスポンスのステータスコードを記録する
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap は http.ResponseController が元の ResponseWriter の機能 (Flush など) を使えるようにする
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
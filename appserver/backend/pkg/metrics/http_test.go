@@ -0,0 +1,50 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/metrics"
+)
+
+// TestHTTPMetrics_Middleware はリクエストをルートパターンとステータスコードごとに集計することを確認します。
+func TestHTTPMetrics_Middleware(t *testing.T) {
+	reg := metrics.NewRegistry()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("PUT /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	handler := metrics.NewHTTPMetrics(reg).Middleware(mux)
+
+	requests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/users/1"},
+		{http.MethodGet, "/users/2"},
+		{http.MethodPut, "/users/1"},
+		{http.MethodGet, "/unknown/path"},
+	}
+	for _, r := range requests {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(r.method, r.path, nil))
+	}
+
+	got := scrape(t, reg)
+	for _, want := range []string{
+		`http_requests_total{route="GET /users/{id}",code="200"} 2`,
+		`http_requests_total{route="PUT /users/{id}",code="403"} 1`,
+		`http_requests_total{route="unmatched",code="404"} 1`,
+		`http_request_duration_seconds_count{route="GET /users/{id}"} 2`,
+		`http_request_duration_seconds_count{route="PUT /users/{id}"} 1`,
+		"http_requests_in_flight 0",
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("metrics does not contain %q\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+// Package metrics はカウンター・ゲージ・ヒストグラムを集計し、Prometheusのテキスト形式で出力します。
+// 外部のライブラリやサービスに依存せず、Registry に登録したメトリクスを Handler で /metrics として公開します。
+//
+// 使用例:
+//
+//	reg := metrics.NewRegistry()
+//	requests := reg.CounterVec("app_requests_total", "Total number of requests.", "route")
+//	requests.WithLabelValues("GET /users/{id}").Inc()
+//	mux.Handle("GET /metrics", metrics.Handler(reg))
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets は処理時間(秒)を計測するヒストグラムのデフォルトのバケットです。
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultRegistry はプロセス全体で共有するRegistryです。
+var DefaultRegistry = NewRegistry()
+
+var (
+	metricNamePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNamePattern  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// Kind はメトリクスの種類です。
+type Kind string
+
+const (
+	// KindCounter は単調増加する値です。
+	KindCounter Kind = "counter"
+	// KindGauge は増減する値です。
+	KindGauge Kind = "gauge"
+	// KindHistogram はバケットごとの観測数と合計です。
+	KindHistogram Kind = "histogram"
+)
+
+// Registry はメトリクスを名前で管理します。
+// 同じ名前・種類・ラベルで登録した場合は登録済みのメトリクスを返すため、複数の箇所から同じメトリクスを共有できます。
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// NewRegistry は空のRegistryを生成します。
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// CounterVec はカウンターを登録します。
+// 注意事項: 同じ名前で種類やラベルが異なるメトリクスが登録済みの場合、または名前が不正な場合はpanicする
+func (r *Registry) CounterVec(name, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{family: r.register(name, help, KindCounter, labelNames, nil)}
+}
+
+// GaugeVec はゲージを登録します。
+// 注意事項: 同じ名前で種類やラベルが異なるメトリクスが登録済みの場合、または名前が不正な場合はpanicする
+func (r *Registry) GaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	return &GaugeVec{family: r.register(name, help, KindGauge, labelNames, nil)}
+}
+
+// HistogramVec はヒストグラムを登録します。buckets が空の場合は DefaultBuckets を使用します。
+// +Inf のバケットは自動的に追加されます。
+// 注意事項: 同じ名前で種類やラベル、バケットが異なるメトリクスが登録済みの場合、または名前が不正な場合はpanicする
+func (r *Registry) HistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	buckets = slices.Compact(buckets)
+	if math.IsInf(buckets[len(buckets)-1], 1) {
+		buckets = buckets[:len(buckets)-1]
+	}
+	return &HistogramVec{family: r.register(name, help, KindHistogram, labelNames, buckets)}
+}
+
+// register はメトリクスを登録し、同じ定義で登録済みの場合はそれを返す
+func (r *Registry) register(name, help string, kind Kind, labelNames []string, buckets []float64) *family {
+	if !metricNamePattern.MatchString(name) {
+		panic(fmt.Sprintf("metrics: invalid metric name %q", name))
+	}
+	for _, label := range labelNames {
+		if !labelNamePattern.MatchString(label) || strings.HasPrefix(label, "__") {
+			panic(fmt.Sprintf("metrics: invalid label name %q (metric=%s)", label, name))
+		}
+		if kind == KindHistogram && label == "le" {
+			panic(fmt.Sprintf("metrics: label name \"le\" is reserved for histogram buckets (metric=%s)", name))
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if f, ok := r.families[name]; ok {
+		if f.kind != kind || !slices.Equal(f.labelNames, labelNames) || !slices.Equal(f.buckets, buckets) {
+			panic(fmt.Sprintf("metrics: metric %s is already registered with a different definition", name))
+		}
+		return f
+	}
+	f := &family{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: slices.Clone(labelNames),
+		buckets:    buckets,
+		series:     make(map[string]*series),
+	}
+	r.families[name] = f
+	return f
+}
+
+// snapshot は登録済みのメトリクスの現在値を名前順に返す
+func (r *Registry) snapshot() []familySnapshot {
+	r.mu.Lock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.Unlock()
+
+	slices.SortFunc(families, func(a, b *family) int { return strings.Compare(a.name, b.name) })
+	snapshots := make([]familySnapshot, 0, len(families))
+	for _, f := range families {
+		snapshots = append(snapshots, f.snapshot())
+	}
+	return snapshots
+}
+
+// family は同じ名前のメトリクスをラベルの組み合わせごとに保持する
+type family struct {
+	name       string
+	help       string
+	kind       Kind
+	labelNames []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series はラベルの組み合わせ1つ分の値
+type series struct {
+	labelValues []string
+	value       float64
+	// fn は収集時に値を取得する関数 (GaugeVec.Func で設定した場合のみ)
+	fn func() float64
+	// counts はバケットごとの観測数 (累積ではない)。最後の要素は +Inf のバケット
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// seriesFor はラベル値に対応する系列を返し、存在しない場合は作成する
+func (f *family) seriesFor(labelValues []string) *series {
+	if len(labelValues) != len(f.labelNames) {
+		panic(fmt.Sprintf("metrics: metric %s expects %d label values, got %d", f.name, len(f.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: slices.Clone(labelValues)}
+		if f.kind == KindHistogram {
+			s.counts = make([]uint64, len(f.buckets)+1)
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// update は系列の値をロックを取得して更新する
+func (f *family) update(s *series, fn func(s *series)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fn(s)
+}
+
+// familySnapshot は出力用にコピーしたメトリクスの値
+type familySnapshot struct {
+	name       string
+	help       string
+	kind       Kind
+	labelNames []string
+	buckets    []float64
+	series     []series
+}
+
+// snapshot は系列の値をラベル値の順にコピーする
+// 関数で値を取得する系列は、関数内でのロックと競合しないようロックを解放してから呼び出す
+func (f *family) snapshot() familySnapshot {
+	f.mu.Lock()
+	list := make([]series, 0, len(f.series))
+	for _, s := range f.series {
+		c := *s
+		c.counts = slices.Clone(s.counts)
+		list = append(list, c)
+	}
+	f.mu.Unlock()
+
+	for i := range list {
+		if list[i].fn != nil {
+			list[i].value = list[i].fn()
+		}
+	}
+	slices.SortFunc(list, func(a, b series) int { return slices.Compare(a.labelValues, b.labelValues) })
+	return familySnapshot{
+		name:       f.name,
+		help:       f.help,
+		kind:       f.kind,
+		labelNames: f.labelNames,
+		buckets:    f.buckets,
+		series:     list,
+	}
+}
+
+// CounterVec はラベルの組み合わせごとのカウンターです。
+type CounterVec struct {
+	family *family
+}
+
+// WithLabelValues はラベル値に対応するカウンターを返します。ラベル値は登録時のラベル名の順に指定します。
+func (v *CounterVec) WithLabelValues(labelValues ...string) *Counter {
+	return &Counter{family: v.family, series: v.family.seriesFor(labelValues)}
+}
+
+// Counter は単調増加する値です。
+type Counter struct {
+	family *family
+	series *series
+}
+
+// Inc は値を1増やします。
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add は値を delta 増やします。
+// 注意事項: カウンターは減らせないため、delta が負の場合はpanicする
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic(fmt.Sprintf("metrics: counter %s cannot decrease", c.family.name))
+	}
+	c.family.update(c.series, func(s *series) { s.value += delta })
+}
+
+// GaugeVec はラベルの組み合わせごとのゲージです。
+type GaugeVec struct {
+	family *family
+}
+
+// WithLabelValues はラベル値に対応するゲージを返します。ラベル値は登録時のラベル名の順に指定します。
+func (v *GaugeVec) WithLabelValues(labelValues ...string) *Gauge {
+	return &Gauge{family: v.family, series: v.family.seriesFor(labelValues)}
+}
+
+// Func はラベル値に対応するゲージの値を、収集のたびに fn で取得するよう設定します。
+// キューの長さなど、他のコンポーネントが保持している値の公開に使用します。
+// 注意事項: fn は /metrics の取得ごとに呼び出されるため、短時間で終わる処理にすること
+func (v *GaugeVec) Func(fn func() float64, labelValues ...string) {
+	v.family.update(v.family.seriesFor(labelValues), func(s *series) { s.fn = fn })
+}
+
+// Gauge は増減する値です。
+type Gauge struct {
+	family *family
+	series *series
+}
+
+// Set は値を設定します。
+func (g *Gauge) Set(value float64) {
+	g.family.update(g.series, func(s *series) { s.value = value })
+}
+
+// Add は値を delta 増やします。delta が負の場合は減らします。
+func (g *Gauge) Add(delta float64) {
+	g.family.update(g.series, func(s *series) { s.value += delta })
+}
+
+// Inc は値を1増やします。
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+// Dec は値を1減らします。
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+// HistogramVec はラベルの組み合わせごとのヒストグラムです。
+type HistogramVec struct {
+	family *family
+}
+
+// WithLabelValues はラベル値に対応するヒストグラムを返します。ラベル値は登録時のラベル名の順に指定します。
+func (v *HistogramVec) WithLabelValues(labelValues ...string) *Histogram {
+	return &Histogram{family: v.family, series: v.family.seriesFor(labelValues)}
+}
+
+// Histogram は観測値の分布です。
+type Histogram struct {
+	family *family
+	series *series
+}
+
+// Observe は値を観測し、値以上の上限を持つ最小のバケットに加えます。
+func (h *Histogram) Observe(value float64) {
+	i, _ := slices.BinarySearch(h.family.buckets, value)
+	h.family.update(h.series, func(s *series) {
+		s.counts[i]++
+		s.sum += value
+		s.count++
+	})
+}
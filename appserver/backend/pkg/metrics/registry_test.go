@@ -0,0 +1,194 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/metrics"
+)
+
+// scrape は reg をテキスト形式で出力した結果を返します。
+func scrape(t *testing.T, reg *metrics.Registry) string {
+	t.Helper()
+	var b strings.Builder
+	if err := reg.WriteText(&b); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	return b.String()
+}
+
+// TestRegistry_WriteText はメトリクスの種類ごとの出力形式を確認します。
+func TestRegistry_WriteText(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(reg *metrics.Registry)
+		want  string
+	}{
+		{
+			name: "正常系: カウンターをラベル値の順に出力する",
+			setup: func(reg *metrics.Registry) {
+				c := reg.CounterVec("jobs_total", "Total jobs.", "type", "status")
+				c.WithLabelValues("mail", "failed").Inc()
+				c.WithLabelValues("mail", "succeeded").Add(2)
+				c.WithLabelValues("health", "succeeded").Inc()
+			},
+			want: `# HELP jobs_total Total jobs.
+# TYPE jobs_total counter
+jobs_total{type="health",status="succeeded"} 1
+jobs_total{type="mail",status="failed"} 1
+jobs_total{type="mail",status="succeeded"} 2
+`,
+		},
+		{
+			name: "正常系: ゲージの増減と関数で取得する値を出力する",
+			setup: func(reg *metrics.Registry) {
+				g := reg.GaugeVec("queue_length", "Queue length.", "queue")
+				g.WithLabelValues("a").Set(5)
+				g.WithLabelValues("a").Dec()
+				g.WithLabelValues("b").Add(1.5)
+				g.Func(func() float64 { return 42 }, "c")
+			},
+			want: `# HELP queue_length Queue length.
+# TYPE queue_length gauge
+queue_length{queue="a"} 4
+queue_length{queue="b"} 1.5
+queue_length{queue="c"} 42
+`,
+		},
+		{
+			name: "正常系: ヒストグラムは累積のバケットと合計、件数を出力する",
+			setup: func(reg *metrics.Registry) {
+				h := reg.HistogramVec("duration_seconds", "Duration.", []float64{1, 0.1}, "op")
+				h.WithLabelValues("send").Observe(0.05)
+				h.WithLabelValues("send").Observe(0.1)
+				h.WithLabelValues("send").Observe(0.5)
+				h.WithLabelValues("send").Observe(3)
+			},
+			want: `# HELP duration_seconds Duration.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{op="send",le="0.1"} 2
+duration_seconds_bucket{op="send",le="1"} 3
+duration_seconds_bucket{op="send",le="+Inf"} 4
+duration_seconds_sum{op="send"} 3.65
+duration_seconds_count{op="send"} 4
+`,
+		},
+		{
+			name: "正常系: ラベルのないメトリクスは名前順に出力し、系列のないメトリクスは出力しない",
+			setup: func(reg *metrics.Registry) {
+				reg.GaugeVec("z_in_flight", "In flight.").WithLabelValues().Set(1)
+				reg.CounterVec("a_total", "A.").WithLabelValues().Inc()
+				reg.CounterVec("unused_total", "Unused.", "type")
+			},
+			want: `# HELP a_total A.
+# TYPE a_total counter
+a_total 1
+# HELP z_in_flight In flight.
+# TYPE z_in_flight gauge
+z_in_flight 1
+`,
+		},
+		{
+			name: "正常系: ラベル値とヘルプをエスケープする",
+			setup: func(reg *metrics.Registry) {
+				reg.CounterVec("escaped_total", "Line1\nC:\\path", "value").WithLabelValues("a\"b\\c\nd").Inc()
+			},
+			want: `# HELP escaped_total Line1\nC:\\path
+# TYPE escaped_total counter
+escaped_total{value="a\"b\\c\nd"} 1
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := metrics.NewRegistry()
+			tt.setup(reg)
+			if got := scrape(t, reg); got != tt.want {
+				t.Errorf("WriteText() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRegistry_SharedRegistration は同じ定義での登録が同じメトリクスを共有することを確認します。
+func TestRegistry_SharedRegistration(t *testing.T) {
+	reg := metrics.NewRegistry()
+	reg.CounterVec("shared_total", "Shared.", "worker").WithLabelValues("a").Inc()
+	reg.CounterVec("shared_total", "Shared.", "worker").WithLabelValues("a").Inc()
+
+	if got := scrape(t, reg); !strings.Contains(got, `shared_total{worker="a"} 2`) {
+		t.Errorf("WriteText() = %s, want shared counter value 2", got)
+	}
+}
+
+// TestRegistry_InvalidUsage は不正な登録や操作がpanicすることを確認します。
+func TestRegistry_InvalidUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(reg *metrics.Registry)
+	}{
+		{
+			name: "異常系: 同じ名前で種類が異なる",
+			fn: func(reg *metrics.Registry) {
+				reg.CounterVec("conflict", "Conflict.")
+				reg.GaugeVec("conflict", "Conflict.")
+			},
+		},
+		{
+			name: "異常系: 同じ名前でラベルが異なる",
+			fn: func(reg *metrics.Registry) {
+				reg.CounterVec("conflict_total", "Conflict.", "a")
+				reg.CounterVec("conflict_total", "Conflict.", "b")
+			},
+		},
+		{
+			name: "異常系: 不正なメトリクス名",
+			fn:   func(reg *metrics.Registry) { reg.CounterVec("invalid-name", "Invalid.") },
+		},
+		{
+			name: "異常系: ヒストグラムで予約済みのラベル名",
+			fn:   func(reg *metrics.Registry) { reg.HistogramVec("latency_seconds", "Latency.", nil, "le") },
+		},
+		{
+			name: "異常系: ラベル値の数が一致しない",
+			fn:   func(reg *metrics.Registry) { reg.CounterVec("labels_total", "Labels.", "a", "b").WithLabelValues("a") },
+		},
+		{
+			name: "異常系: カウンターを減らす",
+			fn:   func(reg *metrics.Registry) { reg.CounterVec("negative_total", "Negative.").WithLabelValues().Add(-1) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			tt.fn(metrics.NewRegistry())
+		})
+	}
+}
+
+// TestHandler はハンドラーがテキスト形式とContent-Typeで応答することを確認します。
+func TestHandler(t *testing.T) {
+	reg := metrics.NewRegistry()
+	reg.CounterVec("requests_total", "Requests.").WithLabelValues().Inc()
+
+	rec := httptest.NewRecorder()
+	metrics.Handler(reg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Content-Type"); got != metrics.ContentType {
+		t.Errorf("Content-Type = %q, want %q", got, metrics.ContentType)
+	}
+	if !strings.Contains(rec.Body.String(), "requests_total 1\n") {
+		t.Errorf("body = %s, want requests_total 1", rec.Body.String())
+	}
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ContentType はPrometheusのテキスト形式のContent-Typeです。
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// WriteText は登録済みのメトリクスをPrometheusのテキスト形式で w に書き込みます。
+// メトリクスは名前順、系列はラベル値の順に出力します。
+func (r *Registry) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range r.snapshot() {
+		if len(f.series) == 0 {
+			continue
+		}
+		bw.WriteString("# HELP " + f.name + " " + helpEscaper.Replace(f.help) + "\n")
+		bw.WriteString("# TYPE " + f.name + " " + string(f.kind) + "\n")
+		for _, s := range f.series {
+			if f.kind != KindHistogram {
+				writeSample(bw, f.name, f.labelNames, s.labelValues, "", "", s.value)
+				continue
+			}
+			var cumulative uint64
+			for i, upper := range f.buckets {
+				cumulative += s.counts[i]
+				writeSample(bw, f.name+"_bucket", f.labelNames, s.labelValues, "le", formatFloat(upper), float64(cumulative))
+			}
+			writeSample(bw, f.name+"_bucket", f.labelNames, s.labelValues, "le", "+Inf", float64(s.count))
+			writeSample(bw, f.name+"_sum", f.labelNames, s.labelValues, "", "", s.sum)
+			writeSample(bw, f.name+"_count", f.labelNames, s.labelValues, "", "", float64(s.count))
+		}
+	}
+	return bw.Flush()
+}
+
+// writeSample は1行分のサンプルを書き込む。extraName が空でない場合は末尾にラベルを追加する (ヒストグラムの le)
+func writeSample(w *bufio.Writer, name string, labelNames, labelValues []string, extraName, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labelNames) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, label := range labelNames {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(label + `="` + labelValueEscaper.Replace(labelValues[i]) + `"`)
+		}
+		if extraName != "" {
+			if len(labelNames) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extraName + `="` + extraValue + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Handler は reg のメトリクスをPrometheusのテキスト形式で返すハンドラーを返します。
+func Handler(reg *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		reg.WriteText(w)
+	})
+}
@@ -0,0 +1,183 @@
+// Package trace はW3C Trace Context (traceparent) 形式でトレースを伝播し、処理の区間をスパンとして記録します。
+// OpenTelemetryと同じ考え方 (トレースID・スパンID・親子関係) で、外部のライブラリに依存せずに動作します。
+// スパンは Tracer に設定した Exporter に送られます (ローカルでは JSONExporter で標準出力に出力します)。
+//
+// 使用例:
+//
+//	trace.SetDefaultTracer(trace.NewTracer(trace.NewJSONExporter(os.Stdout), trace.WithServiceName("backend-api")))
+//
+//	ctx, span := trace.Start(ctx, "UserRepository.UpdateUser", trace.WithAttributes(trace.String("user.id", id)))
+//	defer span.End()
+//	if err := update(ctx); err != nil {
+//		span.RecordError(err)
+//		return err
+//	}
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TraceParentHeader はトレースコンテキストを伝播するHTTPヘッダー・SQSメッセージ属性の名前です。
+const TraceParentHeader = "traceparent"
+
+// traceParentVersion は対応している traceparent のバージョン
+const traceParentVersion = "00"
+
+// flagSampled は traceparent の trace-flags で記録対象であることを表すビット
+const flagSampled = 0x01
+
+// ErrInvalidTraceParent は traceparent の形式が不正な場合のエラーです。
+var ErrInvalidTraceParent = errors.New("invalid traceparent")
+
+// TraceID はトレース全体を識別する16バイトのIDです。
+type TraceID [16]byte
+
+// String は16進数の文字列を返します。
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid はIDがすべて0でないかどうかを返します。
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+// SpanID はスパンを識別する8バイトのIDです。
+type SpanID [8]byte
+
+// String は16進数の文字列を返します。
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid はIDがすべて0でないかどうかを返します。
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// SpanContext はプロセスをまたいで伝播するスパンの識別情報です。
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	// Sampled はスパンを記録するかどうかです。記録しない場合もIDは伝播します。
+	Sampled bool
+	// Remote は traceparent から復元した、他のプロセスのスパンかどうかです。
+	Remote bool
+}
+
+// IsValid はトレースIDとスパンIDが設定されているかどうかを返します。
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// TraceParent は traceparent の文字列 (例: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01") を返します。
+// 無効な SpanContext の場合は空文字を返します。
+func (sc SpanContext) TraceParent() string {
+	if !sc.IsValid() {
+		return ""
+	}
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return traceParentVersion + "-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceParent は traceparent の文字列から SpanContext を復元します。
+// 復元した SpanContext は Remote として扱われます。
+// 戻り値:
+//   - SpanContext: 復元したスパンの識別情報
+//   - error: 形式が不正、またはIDがすべて0の場合は ErrInvalidTraceParent
+func ParseTraceParent(s string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	// 将来のバージョンは末尾にフィールドが追加される可能性があるため、バージョン00以外は先頭4つのみ解釈する
+	if len(parts) < 4 || (parts[0] == traceParentVersion && len(parts) != 4) {
+		return SpanContext{}, fmt.Errorf("%w: %q", ErrInvalidTraceParent, s)
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if len(version) != 2 || version == "ff" || len(traceID) != 32 || len(spanID) != 16 || len(flags) != 2 {
+		return SpanContext{}, fmt.Errorf("%w: %q", ErrInvalidTraceParent, s)
+	}
+
+	var sc SpanContext
+	if !decodeLowerHex(sc.TraceID[:], traceID) || !decodeLowerHex(sc.SpanID[:], spanID) || !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("%w: %q", ErrInvalidTraceParent, s)
+	}
+	var flagBits [1]byte
+	if !decodeLowerHex(flagBits[:], flags) {
+		return SpanContext{}, fmt.Errorf("%w: %q", ErrInvalidTraceParent, s)
+	}
+	sc.Sampled = flagBits[0]&flagSampled != 0
+	sc.Remote = true
+	return sc, nil
+}
+
+// decodeLowerHex は小文字の16進数のみを受け付けてデコードする (W3C Trace Context の仕様)
+func decodeLowerHex(dst []byte, s string) bool {
+	if strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpan は span を ctx に設定します。以降に ctx から開始したスパンは span の子になります。
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// ContextWithRemoteSpanContext は他のプロセスから受け取った sc を親として ctx に設定します。
+// 無効な sc の場合は ctx をそのまま返します。
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	if !sc.IsValid() {
+		return ctx
+	}
+	sc.Remote = true
+	return ContextWithSpan(ctx, &Span{spanContext: sc, ended: true})
+}
+
+// SpanFromContext は ctx に設定されたスパンを返します。設定されていない場合は nil を返します。
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// SpanContextFromContext は ctx に設定されたスパンの SpanContext を返します。
+// 設定されていない場合は無効な SpanContext を返します。
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	return SpanContext{}
+}
+
+// TraceParentFromContext は ctx のスパンを伝播する traceparent の文字列を返します。スパンがない場合は空文字を返します。
+func TraceParentFromContext(ctx context.Context) string {
+	return SpanContextFromContext(ctx).TraceParent()
+}
+
+// newTraceID はランダムなトレースIDを生成する
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+// newSpanID はランダムなスパンIDを生成する
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
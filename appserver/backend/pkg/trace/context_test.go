@@ -0,0 +1,91 @@
+package trace_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/trace"
+)
+
+// TestParseTraceParent は traceparent の解析と、不正な形式の検出を確認します。
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantTraceID string
+		wantSpanID  string
+		wantSampled bool
+		wantErr     bool
+	}{
+		{
+			name:        "正常系: 記録対象のトレース",
+			input:       "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			wantTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantSpanID:  "00f067aa0ba902b7",
+			wantSampled: true,
+		},
+		{
+			name:        "正常系: 記録対象でないトレース",
+			input:       "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			wantTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantSpanID:  "00f067aa0ba902b7",
+			wantSampled: false,
+		},
+		{
+			name:        "正常系: 将来のバージョンは先頭4つのフィールドを解釈する",
+			input:       "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			wantTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantSpanID:  "00f067aa0ba902b7",
+			wantSampled: true,
+		},
+		{name: "異常系: 空文字", input: "", wantErr: true},
+		{name: "異常系: フィールドが足りない", input: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", wantErr: true},
+		{name: "異常系: バージョン00で余分なフィールドがある", input: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", wantErr: true},
+		{name: "異常系: 無効なバージョン", input: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", wantErr: true},
+		{name: "異常系: トレースIDがすべて0", input: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", wantErr: true},
+		{name: "異常系: スパンIDがすべて0", input: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", wantErr: true},
+		{name: "異常系: 大文字の16進数", input: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", wantErr: true},
+		{name: "異常系: トレースIDの長さが不正", input: "00-4bf92f3577b34da6-00f067aa0ba902b7-01", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := trace.ParseTraceParent(tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, trace.ErrInvalidTraceParent) {
+					t.Fatalf("ParseTraceParent() error = %v, want ErrInvalidTraceParent", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTraceParent() error = %v", err)
+			}
+			if sc.TraceID.String() != tt.wantTraceID || sc.SpanID.String() != tt.wantSpanID || sc.Sampled != tt.wantSampled {
+				t.Errorf("ParseTraceParent() = %s/%s sampled=%v, want %s/%s sampled=%v",
+					sc.TraceID, sc.SpanID, sc.Sampled, tt.wantTraceID, tt.wantSpanID, tt.wantSampled)
+			}
+			if !sc.Remote {
+				t.Error("ParseTraceParent() Remote = false, want true")
+			}
+		})
+	}
+}
+
+// TestSpanContext_TraceParent は SpanContext から traceparent を生成し、解析で元に戻ることを確認します。
+func TestSpanContext_TraceParent(t *testing.T) {
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := trace.ParseTraceParent(traceParent)
+	if err != nil {
+		t.Fatalf("ParseTraceParent() error = %v", err)
+	}
+	if got := sc.TraceParent(); got != traceParent {
+		t.Errorf("TraceParent() = %s, want %s", got, traceParent)
+	}
+	if got := (trace.SpanContext{}).TraceParent(); got != "" {
+		t.Errorf("TraceParent() of invalid SpanContext = %q, want empty", got)
+	}
+	if got := trace.TraceParentFromContext(context.Background()); got != "" {
+		t.Errorf("TraceParentFromContext() without span = %q, want empty", got)
+	}
+}
@@ -0,0 +1,95 @@
+package trace
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// JSONExporter はスパンを1行1件のJSONで書き込むExporterです。ローカルでの確認に使用します。
+type JSONExporter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewJSONExporter は w にスパンを書き込むJSONExporterを生成します (標準出力の場合は os.Stdout)。
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{encoder: json.NewEncoder(w)}
+}
+
+// jsonSpan はJSONExporterが出力するスパンの形式
+type jsonSpan struct {
+	Name          string         `json:"name"`
+	Kind          SpanKind       `json:"kind"`
+	TraceID       string         `json:"trace_id"`
+	SpanID        string         `json:"span_id"`
+	ParentSpanID  string         `json:"parent_span_id,omitempty"`
+	ServiceName   string         `json:"service_name,omitempty"`
+	StartTime     time.Time      `json:"start_time"`
+	EndTime       time.Time      `json:"end_time"`
+	DurationMs    float64        `json:"duration_ms"`
+	Attributes    map[string]any `json:"attributes,omitempty"`
+	Status        Status         `json:"status"`
+	StatusMessage string         `json:"status_message,omitempty"`
+}
+
+// Export はスパンをJSONで書き込みます。書き込みに失敗した場合はログに記録します。
+func (e *JSONExporter) Export(span SpanData) {
+	out := jsonSpan{
+		Name:          span.Name,
+		Kind:          span.Kind,
+		TraceID:       span.TraceID.String(),
+		SpanID:        span.SpanID.String(),
+		ServiceName:   span.ServiceName,
+		StartTime:     span.StartTime,
+		EndTime:       span.EndTime,
+		DurationMs:    float64(span.Duration().Microseconds()) / 1000,
+		Attributes:    span.Attributes,
+		Status:        span.Status,
+		StatusMessage: span.StatusMessage,
+	}
+	if span.ParentSpanID.IsValid() {
+		out.ParentSpanID = span.ParentSpanID.String()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.encoder.Encode(out); err != nil {
+		slog.Warn("failed to export span", "name", span.Name, "error", err)
+	}
+}
+
+// MemoryExporter は終了したスパンをメモリに保持するExporterです。テストでスパンを確認するために使用します。
+type MemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// NewMemoryExporter は空のMemoryExporterを生成します。
+func NewMemoryExporter() *MemoryExporter {
+	return &MemoryExporter{}
+}
+
+// Export はスパンを保持します。
+func (e *MemoryExporter) Export(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Spans は保持しているスパンを終了した順に返します。
+func (e *MemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slices.Clone(e.spans)
+}
+
+// Reset は保持しているスパンを削除します。
+func (e *MemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
@@ -0,0 +1,64 @@
+package trace
+
+import (
+	"net/http"
+)
+
+// Middleware はリクエストごとにサーバースパンを開始するハンドラーを返します。
+//   - リクエストの traceparent ヘッダーが有効な場合は、呼び出し元のトレースを引き継ぐ
+//   - レスポンスの traceparent ヘッダーにサーバースパンを設定し、呼び出し元がトレースを特定できるようにする
+//   - スパン名は http.ServeMux が一致したパターン (例: "PUT /users/{id}") で、ステータスコードが5xxの場合は失敗とする
+//
+// 注意事項: next には ServeMux (またはそれを包むハンドラー) を渡し、ルーティング後の r.Pattern を参照できるようにする
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if sc, err := ParseTraceParent(r.Header.Get(TraceParentHeader)); err == nil {
+			ctx = ContextWithRemoteSpanContext(ctx, sc)
+		}
+		ctx, span := Start(ctx, r.Method, WithSpanKind(SpanKindServer), WithAttributes(
+			String("http.method", r.Method),
+			String("http.target", r.URL.Path),
+		))
+		defer span.End()
+		w.Header().Set(TraceParentHeader, span.SpanContext().TraceParent())
+
+		rw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		r = r.WithContext(ctx)
+		next.ServeHTTP(rw, r)
+
+		if r.Pattern != "" {
+			span.SetName(r.Pattern)
+			span.SetAttributes(String("http.route", r.Pattern))
+		}
+		span.SetAttributes(Int("http.status_code", rw.status))
+		if rw.status >= http.StatusInternalServerError {
+			span.SetStatus(StatusError, http.StatusText(rw.status))
+		}
+	})
+}
+
+// statusRecorder はレスポンスのステータスコードを記録する
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap は http.ResponseController が元の ResponseWriter の機能 (Flush など) を使えるようにする
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,80 @@
+package trace_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/trace"
+)
+
+// TestMiddleware はリクエストの traceparent を引き継ぎ、レスポンスで返すことを確認します。
+func TestMiddleware(t *testing.T) {
+	exporter := trace.NewMemoryExporter()
+	prev := trace.DefaultTracer()
+	trace.SetDefaultTracer(trace.NewTracer(exporter))
+	t.Cleanup(func() { trace.SetDefaultTracer(prev) })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		// ハンドラー内で開始したスパンはサーバースパンの子になる
+		_, span := trace.Start(r.Context(), "UserSyncService.SyncUserUpdate")
+		span.End()
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	handler := trace.Middleware(mux)
+
+	tests := []struct {
+		name        string
+		traceParent string
+		wantRemote  bool
+	}{
+		{name: "正常系: 呼び出し元のトレースを引き継ぐ", traceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", wantRemote: true},
+		{name: "正常系: traceparent がない場合は新しいトレースを開始する"},
+		{name: "異常系: 不正な traceparent は無視して新しいトレースを開始する", traceParent: "invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter.Reset()
+			req := httptest.NewRequest(http.MethodPut, "/users/1", nil)
+			if tt.traceParent != "" {
+				req.Header.Set(trace.TraceParentHeader, tt.traceParent)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			spans := exporter.Spans()
+			if len(spans) != 2 {
+				t.Fatalf("exported %d spans, want 2", len(spans))
+			}
+			inner, server := spans[0], spans[1]
+			if server.Name != "PUT /users/{id}" || server.Kind != trace.SpanKindServer {
+				t.Errorf("server span = %s (%s), want PUT /users/{id} (server)", server.Name, server.Kind)
+			}
+			if server.Status != trace.StatusError || server.Attributes["http.status_code"] != http.StatusInternalServerError {
+				t.Errorf("server span status = %s attributes = %v", server.Status, server.Attributes)
+			}
+			if inner.ParentSpanID != server.SpanID || inner.TraceID != server.TraceID {
+				t.Errorf("inner span parent = %s/%s, want %s/%s", inner.TraceID, inner.ParentSpanID, server.TraceID, server.SpanID)
+			}
+
+			remote, err := trace.ParseTraceParent(tt.traceParent)
+			if tt.wantRemote {
+				if err != nil || server.TraceID != remote.TraceID || server.ParentSpanID != remote.SpanID {
+					t.Errorf("server span = %s/%s, want parent %s", server.TraceID, server.ParentSpanID, tt.traceParent)
+				}
+			} else if server.ParentSpanID.IsValid() {
+				t.Errorf("server span ParentSpanID = %s, want invalid", server.ParentSpanID)
+			}
+
+			got, err := trace.ParseTraceParent(rec.Header().Get(trace.TraceParentHeader))
+			if err != nil {
+				t.Fatalf("response traceparent error = %v", err)
+			}
+			if got.TraceID != server.TraceID || got.SpanID != server.SpanID {
+				t.Errorf("response traceparent = %s, want server span %s/%s", got.TraceParent(), server.TraceID, server.SpanID)
+			}
+		})
+	}
+}
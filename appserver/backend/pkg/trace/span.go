@@ -0,0 +1,276 @@
+package trace
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SpanKind はスパンが表す処理の種類です。
+type SpanKind string
+
+const (
+	// SpanKindInternal はプロセス内の処理です (デフォルト)。
+	SpanKindInternal SpanKind = "internal"
+	// SpanKindServer は受信したリクエストの処理です。
+	SpanKindServer SpanKind = "server"
+	// SpanKindClient は外部サービス (Cognito, DBなど) の呼び出しです。
+	SpanKindClient SpanKind = "client"
+	// SpanKindProducer はメッセージの送信です。
+	SpanKindProducer SpanKind = "producer"
+	// SpanKindConsumer は受信したメッセージの処理です。
+	SpanKindConsumer SpanKind = "consumer"
+)
+
+// Status はスパンの処理結果です。
+type Status string
+
+const (
+	// StatusUnset は結果が設定されていないことを表します (成功として扱います)。
+	StatusUnset Status = "unset"
+	// StatusOK は成功を表します。
+	StatusOK Status = "ok"
+	// StatusError は失敗を表します。
+	StatusError Status = "error"
+)
+
+// Attribute はスパンに付与するキーと値です。
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String は文字列の Attribute を生成します。
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int は整数の Attribute を生成します。
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool は真偽値の Attribute を生成します。
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// SpanData は終了したスパンの記録で、Exporter に渡されます。
+type SpanData struct {
+	Name          string
+	Kind          SpanKind
+	TraceID       TraceID
+	SpanID        SpanID
+	ParentSpanID  SpanID
+	ServiceName   string
+	StartTime     time.Time
+	EndTime       time.Time
+	Attributes    map[string]any
+	Status        Status
+	StatusMessage string
+}
+
+// Duration はスパンの処理時間を返します。
+func (d SpanData) Duration() time.Duration {
+	return d.EndTime.Sub(d.StartTime)
+}
+
+// Exporter は終了したスパンを出力するインターフェースです。
+type Exporter interface {
+	// Export は終了したスパンを出力します。スパンの終了処理から同期的に呼び出されるため、時間のかかる処理は避けます。
+	Export(span SpanData)
+}
+
+// Option はTracerの設定を変更する関数型です。
+type Option func(*Tracer)
+
+// WithServiceName はスパンに記録するサービス名を設定します。
+func WithServiceName(name string) Option {
+	return func(t *Tracer) {
+		t.serviceName = name
+	}
+}
+
+// Tracer はスパンを開始し、終了したスパンを Exporter に送ります。
+type Tracer struct {
+	exporter    Exporter
+	serviceName string
+}
+
+// NewTracer はTracerを生成するコンストラクタです。
+// 引数:
+//   - exporter: 終了したスパンの出力先 (nil の場合はIDの伝播のみ行い、スパンを出力しない)
+//   - opts: オプション
+func NewTracer(exporter Exporter, opts ...Option) *Tracer {
+	t := &Tracer{exporter: exporter}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+var defaultTracer atomic.Pointer[Tracer]
+
+func init() {
+	defaultTracer.Store(NewTracer(nil))
+}
+
+// SetDefaultTracer は Start で使用するTracerを設定します。プロセスの起動時に呼び出します。
+// 設定しない場合はトレースコンテキストの伝播のみ行い、スパンは出力しません。
+func SetDefaultTracer(t *Tracer) {
+	defaultTracer.Store(t)
+}
+
+// DefaultTracer は Start で使用するTracerを返します。
+func DefaultTracer() *Tracer {
+	return defaultTracer.Load()
+}
+
+// StartOption はスパンの開始時の設定を変更する関数型です。
+type StartOption func(*startConfig)
+
+type startConfig struct {
+	kind       SpanKind
+	attributes []Attribute
+}
+
+// WithSpanKind はスパンの種類を設定します (デフォルト: SpanKindInternal)。
+func WithSpanKind(kind SpanKind) StartOption {
+	return func(c *startConfig) {
+		c.kind = kind
+	}
+}
+
+// WithAttributes はスパンの開始時に付与する属性を設定します。
+func WithAttributes(attrs ...Attribute) StartOption {
+	return func(c *startConfig) {
+		c.attributes = append(c.attributes, attrs...)
+	}
+}
+
+// Start はデフォルトのTracerでスパンを開始します。Tracer.Start を参照してください。
+func Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	return DefaultTracer().Start(ctx, name, opts...)
+}
+
+// Start はスパンを開始し、スパンを設定したコンテキストを返します。
+// ctx にスパンがある場合はその子として同じトレースに属し、ない場合は新しいトレースを開始します。
+// 親が記録対象でない場合 (traceparent の sampled フラグが0) は、子のスパンも出力しません。
+// 注意事項: 返したスパンは必ず End で終了すること
+func (t *Tracer) Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	config := startConfig{kind: SpanKindInternal}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	sc := SpanContext{SpanID: newSpanID(), Sampled: true}
+	var parentSpanID SpanID
+	if parent := SpanContextFromContext(ctx); parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Sampled = parent.Sampled
+		parentSpanID = parent.SpanID
+	} else {
+		sc.TraceID = newTraceID()
+	}
+
+	span := &Span{
+		tracer:       t,
+		spanContext:  sc,
+		name:         name,
+		kind:         config.kind,
+		parentSpanID: parentSpanID,
+		start:        time.Now(),
+		attributes:   make(map[string]any, len(config.attributes)),
+		status:       StatusUnset,
+	}
+	span.SetAttributes(config.attributes...)
+	return ContextWithSpan(ctx, span), span
+}
+
+// Span は処理の区間です。
+type Span struct {
+	tracer       *Tracer
+	spanContext  SpanContext
+	kind         SpanKind
+	parentSpanID SpanID
+	start        time.Time
+
+	mu            sync.Mutex
+	name          string
+	attributes    map[string]any
+	status        Status
+	statusMessage string
+	ended         bool
+}
+
+// SpanContext はスパンの識別情報を返します。
+func (s *Span) SpanContext() SpanContext {
+	return s.spanContext
+}
+
+// SetName はスパン名を変更します。HTTPのルートパターンなど、処理の途中で名前が決まる場合に使用します。
+func (s *Span) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.name = name
+}
+
+// SetAttributes はスパンに属性を付与します。同じキーの属性は上書きします。
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	for _, attr := range attrs {
+		s.attributes[attr.Key] = attr.Value
+	}
+}
+
+// SetStatus はスパンの処理結果を設定します。
+func (s *Span) SetStatus(status Status, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.status = status
+	s.statusMessage = message
+}
+
+// RecordError はスパンを失敗として、エラーのメッセージを記録します。err が nil の場合は何もしません。
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.SetStatus(StatusError, err.Error())
+}
+
+// End はスパンを終了し、記録対象であれば Exporter に送ります。2回目以降の呼び出しは無視します。
+func (s *Span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	data := SpanData{
+		Name:          s.name,
+		Kind:          s.kind,
+		TraceID:       s.spanContext.TraceID,
+		SpanID:        s.spanContext.SpanID,
+		ParentSpanID:  s.parentSpanID,
+		ServiceName:   s.tracer.serviceName,
+		StartTime:     s.start,
+		EndTime:       time.Now(),
+		Attributes:    s.attributes,
+		Status:        s.status,
+		StatusMessage: s.statusMessage,
+	}
+	s.mu.Unlock()
+
+	if s.spanContext.Sampled && s.tracer.exporter != nil {
+		s.tracer.exporter.Export(data)
+	}
+}
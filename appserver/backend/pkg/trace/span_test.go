@@ -0,0 +1,133 @@
+package trace_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/trace"
+)
+
+// TestTracer_Start はスパンの親子関係と、記録対象の判定を確認します。
+func TestTracer_Start(t *testing.T) {
+	exporter := trace.NewMemoryExporter()
+	tracer := trace.NewTracer(exporter, trace.WithServiceName("test-service"))
+
+	t.Run("正常系: 子のスパンは親と同じトレースに属する", func(t *testing.T) {
+		exporter.Reset()
+		ctx, parent := tracer.Start(context.Background(), "parent", trace.WithSpanKind(trace.SpanKindServer))
+		_, child := tracer.Start(ctx, "child", trace.WithAttributes(trace.String("user.id", "u1")))
+		child.RecordError(errors.New("db error"))
+		child.End()
+		parent.End()
+
+		spans := exporter.Spans()
+		if len(spans) != 2 {
+			t.Fatalf("exported %d spans, want 2", len(spans))
+		}
+		gotChild, gotParent := spans[0], spans[1]
+		if gotChild.TraceID != gotParent.TraceID {
+			t.Errorf("child TraceID = %s, want %s", gotChild.TraceID, gotParent.TraceID)
+		}
+		if gotChild.ParentSpanID != gotParent.SpanID {
+			t.Errorf("child ParentSpanID = %s, want %s", gotChild.ParentSpanID, gotParent.SpanID)
+		}
+		if gotParent.ParentSpanID.IsValid() {
+			t.Errorf("root ParentSpanID = %s, want invalid", gotParent.ParentSpanID)
+		}
+		if gotChild.Status != trace.StatusError || gotChild.StatusMessage != "db error" {
+			t.Errorf("child status = %s (%s), want error (db error)", gotChild.Status, gotChild.StatusMessage)
+		}
+		if gotChild.Attributes["user.id"] != "u1" || gotChild.Kind != trace.SpanKindInternal {
+			t.Errorf("child attributes = %v kind = %s", gotChild.Attributes, gotChild.Kind)
+		}
+		if gotParent.ServiceName != "test-service" || gotParent.Kind != trace.SpanKindServer {
+			t.Errorf("parent service = %s kind = %s", gotParent.ServiceName, gotParent.Kind)
+		}
+	})
+
+	t.Run("正常系: 他のプロセスのスパンを親として引き継ぐ", func(t *testing.T) {
+		exporter.Reset()
+		remote, _ := trace.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		ctx := trace.ContextWithRemoteSpanContext(context.Background(), remote)
+		_, span := tracer.Start(ctx, "consumer", trace.WithSpanKind(trace.SpanKindConsumer))
+		span.End()
+
+		spans := exporter.Spans()
+		if len(spans) != 1 {
+			t.Fatalf("exported %d spans, want 1 (remote parent must not be exported)", len(spans))
+		}
+		if spans[0].TraceID != remote.TraceID || spans[0].ParentSpanID != remote.SpanID {
+			t.Errorf("span = %s/%s, want parent %s/%s", spans[0].TraceID, spans[0].ParentSpanID, remote.TraceID, remote.SpanID)
+		}
+	})
+
+	t.Run("正常系: 記録対象でない親の子は出力せず、IDのみ伝播する", func(t *testing.T) {
+		exporter.Reset()
+		remote, _ := trace.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+		ctx, span := tracer.Start(trace.ContextWithRemoteSpanContext(context.Background(), remote), "unsampled")
+		span.End()
+
+		if spans := exporter.Spans(); len(spans) != 0 {
+			t.Errorf("exported %d spans, want 0", len(spans))
+		}
+		sc := trace.SpanContextFromContext(ctx)
+		if sc.TraceID != remote.TraceID || sc.Sampled {
+			t.Errorf("SpanContext = %s sampled=%v, want %s sampled=false", sc.TraceID, sc.Sampled, remote.TraceID)
+		}
+	})
+
+	t.Run("正常系: 2回目の End は無視する", func(t *testing.T) {
+		exporter.Reset()
+		_, span := tracer.Start(context.Background(), "twice")
+		span.End()
+		span.SetAttributes(trace.Int("ignored", 1))
+		span.End()
+
+		spans := exporter.Spans()
+		if len(spans) != 1 {
+			t.Fatalf("exported %d spans, want 1", len(spans))
+		}
+		if _, ok := spans[0].Attributes["ignored"]; ok {
+			t.Error("attribute set after End must be ignored")
+		}
+	})
+}
+
+// TestJSONExporter はスパンを1行1件のJSONで出力することを確認します。
+func TestJSONExporter(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := trace.NewTracer(trace.NewJSONExporter(&buf), trace.WithServiceName("backend-api"))
+	ctx, parent := tracer.Start(context.Background(), "PUT /users/{id}", trace.WithSpanKind(trace.SpanKindServer))
+	_, child := tracer.Start(ctx, "Cognito.AdminUpdateUserAttributes", trace.WithAttributes(trace.String("cognito.attributes", "email")))
+	child.End()
+	parent.End()
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %s", len(lines), buf.String())
+	}
+	var got struct {
+		Name         string         `json:"name"`
+		Kind         string         `json:"kind"`
+		TraceID      string         `json:"trace_id"`
+		ParentSpanID string         `json:"parent_span_id"`
+		ServiceName  string         `json:"service_name"`
+		Attributes   map[string]any `json:"attributes"`
+		Status       string         `json:"status"`
+	}
+	if err := json.Unmarshal(lines[0], &got); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if got.Name != "Cognito.AdminUpdateUserAttributes" || got.Kind != "internal" || got.ServiceName != "backend-api" || got.Status != "unset" {
+		t.Errorf("span = %+v", got)
+	}
+	if got.TraceID != parent.SpanContext().TraceID.String() || got.ParentSpanID != parent.SpanContext().SpanID.String() {
+		t.Errorf("trace_id/parent_span_id = %s/%s, want %s/%s", got.TraceID, got.ParentSpanID, parent.SpanContext().TraceID, parent.SpanContext().SpanID)
+	}
+	if got.Attributes["cognito.attributes"] != "email" {
+		t.Errorf("attributes = %v", got.Attributes)
+	}
+}
@@ -1,9 +1,319 @@
 package worker
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+var (
+	// ErrNoRoute はジョブを振り分ける先のメンバーが見つからない場合のエラー
+	ErrNoRoute = errors.New("no worker routes the job")
+	// ErrUnknownMember は登録されていないメンバー名を指定した場合のエラー
+	ErrUnknownMember = errors.New("unknown cluster member")
+	// ErrDuplicateMember は同じ名前のメンバーを登録した場合のエラー
+	ErrDuplicateMember = errors.New("cluster member already exists")
+)
+
+const defaultClusterName = "default-cluster"
+
+// ClusterOption はWorkerClusterの設定を変更する関数型です。
+type ClusterOption func(*clusterOptions)
+
+type clusterOptions struct {
+	name          string
+	members       []clusterMember
+	partitions    int
+	partitionOpts []WorkerOption
+	routes        map[string]string
+	defaultMember string
+}
+
+type clusterMember struct {
+	name   string
+	worker Worker
+}
+
+// WithClusterName はクラスター名を設定します。パーティションのWorker名の接頭辞にも使用します。
+func WithClusterName(name string) ClusterOption {
+	return func(o *clusterOptions) {
+		o.name = name
+	}
+}
+
+// WithMember は名前付きのWorkerをメンバーとして追加します (例: キューごと、タスク種別ごとのWorker)。
+func WithMember(name string, w Worker) ClusterOption {
+	return func(o *clusterOptions) {
+		o.members = append(o.members, clusterMember{name: name, worker: w})
+	}
+}
+
+// WithPartitions はキーで振り分けるパーティションを n 個作成します。
+// 各パーティションは WithSerialExecution のWorkerで、opts はすべてのパーティションに適用します。
+// WithRoutingKey を指定したジョブはキーのハッシュでパーティションが決まり、同じキーのジョブは投入順に実行されます。
+// パーティションのメンバー名は "<クラスター名>-partition-<番号>" です。
+func WithPartitions(n int, opts ...WorkerOption) ClusterOption {
+	return func(o *clusterOptions) {
+		o.partitions = n
+		o.partitionOpts = opts
+	}
+}
+
+// WithRoute はタスク種別 (TypedTask) のジョブを振り分けるメンバーを設定します。
+func WithRoute(taskType, member string) ClusterOption {
+	return func(o *clusterOptions) {
+		if o.routes == nil {
+			o.routes = make(map[string]string)
+		}
+		o.routes[taskType] = member
+	}
+}
+
+// WithDefaultMember はどのルートにも一致しないジョブを振り分けるメンバーを設定します。
+func WithDefaultMember(member string) ClusterOption {
+	return func(o *clusterOptions) {
+		o.defaultMember = member
+	}
+}
+
+// WorkerCluster は複数の名前付きWorkerをまとめて起動・停止し、ジョブを振り分けます。
+// 振り分けは次の順に判定します。
+//  1. WithRoutingKey を指定したジョブは、キーのハッシュで決まるパーティション
+//  2. 先頭のタスクの種別に WithRoute が設定されていれば、そのメンバー
+//  3. WithDefaultMember のメンバー
 type WorkerCluster struct {
-	workers []*worker
+	name          string
+	members       map[string]Worker
+	order         []string
+	partitions    []string
+	routes        map[string]string
+	defaultMember string
+}
+
+// NewWorkerCluster はWorkerClusterを生成するコンストラクタです。
+// メンバー名の重複や、存在しないメンバーへのルートがある場合はエラーを返します。
+func NewWorkerCluster(opts ...ClusterOption) (*WorkerCluster, error) {
+	options := clusterOptions{name: defaultClusterName}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.partitions < 0 {
+		return nil, fmt.Errorf("partitions must not be negative: %d", options.partitions)
+	}
+
+	c := &WorkerCluster{
+		name:          options.name,
+		members:       make(map[string]Worker),
+		routes:        options.routes,
+		defaultMember: options.defaultMember,
+	}
+	for _, m := range options.members {
+		if err := c.addMember(m.name, m.worker); err != nil {
+			return nil, err
+		}
+	}
+	for i := 0; i < options.partitions; i++ {
+		name := fmt.Sprintf("%s-partition-%d", options.name, i)
+		// パーティション共通のオプションの後に適用し、名前と逐次実行を上書きされないようにする
+		workerOpts := append(append([]WorkerOption(nil), options.partitionOpts...), WithName(name), WithSerialExecution())
+		if err := c.addMember(name, NewWorker(workerOpts...)); err != nil {
+			return nil, err
+		}
+		c.partitions = append(c.partitions, name)
+	}
+
+	for taskType, member := range c.routes {
+		if _, ok := c.members[member]; !ok {
+			return nil, fmt.Errorf("%w: %s (route for taskType=%s)", ErrUnknownMember, member, taskType)
+		}
+	}
+	if c.defaultMember != "" {
+		if _, ok := c.members[c.defaultMember]; !ok {
+			return nil, fmt.Errorf("%w: %s (default member)", ErrUnknownMember, c.defaultMember)
+		}
+	}
+	return c, nil
+}
+
+func (c *WorkerCluster) addMember(name string, w Worker) error {
+	if _, ok := c.members[name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateMember, name)
+	}
+	c.members[name] = w
+	c.order = append(c.order, name)
+	return nil
+}
+
+// Name はクラスター名を返します。
+func (c *WorkerCluster) Name() string {
+	return c.name
+}
+
+// Members はメンバー名を登録順 (パーティションは最後) に返します。
+func (c *WorkerCluster) Members() []string {
+	return append([]string(nil), c.order...)
+}
+
+// Member は名前でメンバーのWorkerを返します。SQSPoller などに個別に渡す場合に使用します。
+func (c *WorkerCluster) Member(name string) (Worker, bool) {
+	w, ok := c.members[name]
+	return w, ok
+}
+
+// Route はジョブを振り分けるメンバー名を返します。
+func (c *WorkerCluster) Route(tasks []Task, opts ...JobOption) (string, error) {
+	options := newJobOptions(opts)
+	if options.key != "" {
+		if len(c.partitions) == 0 {
+			return "", fmt.Errorf("%w: routing key %q is set but the cluster has no partitions", ErrNoRoute, options.key)
+		}
+		return c.partitions[partitionIndex(options.key, len(c.partitions))], nil
+	}
+	if len(tasks) > 0 {
+		if member, ok := c.routes[taskTypeOf(tasks[0])]; ok {
+			return member, nil
+		}
+	}
+	if c.defaultMember != "" {
+		return c.defaultMember, nil
+	}
+	taskType := ""
+	if len(tasks) > 0 {
+		taskType = taskTypeOf(tasks[0])
+	}
+	return "", fmt.Errorf("%w (taskType=%s)", ErrNoRoute, taskType)
+}
+
+// partitionIndex はキーのFNV-1aハッシュからパーティション番号を決めます。
+func partitionIndex(key string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
+
+// Submit はジョブを振り分け先のメンバーに追加し、完了を待機するための JobHandle を返します。
+// 使用例: c.Submit(ctx, []worker.Task{task}, worker.WithRoutingKey(userID))
+func (c *WorkerCluster) Submit(ctx context.Context, tasks []Task, opts ...JobOption) (*JobHandle, error) {
+	member, err := c.Route(tasks, opts...)
+	if err != nil {
+		return nil, err
+	}
+	handle, err := c.members[member].Submit(ctx, tasks, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit job (member=%s): %w", member, err)
+	}
+	return handle, nil
+}
+
+// AddJob はタスク種別で振り分けたメンバーにジョブを追加します。キューが満杯の場合は空きができるまで待機します。
+func (c *WorkerCluster) AddJob(tasks ...Task) error {
+	member, err := c.Route(tasks)
+	if err != nil {
+		return err
+	}
+	return c.members[member].AddJob(tasks...)
+}
+
+// AddJobAsync はタスク種別で振り分けたメンバーにジョブを追加します。キューが満杯の場合は ErrJobQueueFull を返します。
+func (c *WorkerCluster) AddJobAsync(tasks ...Task) error {
+	member, err := c.Route(tasks)
+	if err != nil {
+		return err
+	}
+	return c.members[member].AddJobAsync(tasks...)
+}
+
+// Run はすべてのメンバーを起動します。起動に失敗したメンバーのエラーをまとめて返します。
+func (c *WorkerCluster) Run(ctx context.Context) error {
+	var errs []error
+	for _, name := range c.order {
+		if err := c.members[name].Run(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to run member (member=%s): %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown はすべてのメンバーを並行にグレースフルシャットダウンし、すべての完了を待機します。
+// ctx の期限までに停止しなかったメンバーのエラーをまとめて返します。
+func (c *WorkerCluster) Shutdown(ctx context.Context) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, name := range c.order {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.members[name].Shutdown(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("failed to shutdown member (member=%s): %w", name, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// MemberStatus はメンバーの状態です。
+type MemberStatus struct {
+	Name   string
+	Status WorkerStatus
+	Stats  PoolStats
+}
+
+// ClusterStatus はクラスター全体の状態です。
+type ClusterStatus struct {
+	// Status は全メンバーの状態をまとめたものです。
+	// 全メンバーが同じ状態の場合はその状態、異なる場合 (一部のみ停止など) は WorkerStatusDraining です。
+	Status WorkerStatus
+	// QueueDepth は全メンバーのキューで待機しているジョブ数の合計です。
+	QueueDepth int
+	// Active は全メンバーで実行中のジョブ数の合計です。
+	Active int
+	// Members はメンバーごとの状態です (Members() と同じ順)。
+	Members []MemberStatus
+}
+
+// Status はクラスター全体の状態を返します。
+func (c *WorkerCluster) Status() ClusterStatus {
+	status := ClusterStatus{Members: make([]MemberStatus, 0, len(c.order))}
+	for i, name := range c.order {
+		w := c.members[name]
+		member := MemberStatus{Name: name, Status: w.Status(), Stats: w.Stats()}
+		status.Members = append(status.Members, member)
+		status.QueueDepth += member.Stats.QueueDepth
+		status.Active += member.Stats.Active
+		if i == 0 {
+			status.Status = member.Status
+		} else if status.Status != member.Status {
+			status.Status = WorkerStatusDraining
+		}
+	}
+	return status
+}
+
+// QueueDepth は全メンバーのキューで待機しているジョブ数の合計を返します。
+func (c *WorkerCluster) QueueDepth() int {
+	depth := 0
+	for _, w := range c.members {
+		for _, n := range w.QueueLengths() {
+			depth += n
+		}
+	}
+	return depth
 }
 
-func NewWorkerCluster(workers []*worker) *WorkerCluster {
-	return &WorkerCluster{workers: workers}
+// IsRunning は全メンバーが実行中かどうかを返します。
+func (c *WorkerCluster) IsRunning() bool {
+	for _, w := range c.members {
+		if !w.IsRunning() {
+			return false
+		}
+	}
+	return len(c.members) > 0
 }
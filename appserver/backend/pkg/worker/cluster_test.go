@@ -0,0 +1,306 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks"
+)
+
+func typed(taskType string) worker.Task {
+	return &typedTask{taskType: taskType, fn: func(ctx context.Context) error { return nil }}
+}
+
+// TestWorkerCluster_Route はタスク種別・キー・デフォルトによる振り分けを確認します。
+func TestWorkerCluster_Route(t *testing.T) {
+	t.Parallel()
+
+	c, err := worker.NewWorkerCluster(
+		worker.WithClusterName("app"),
+		worker.WithMember("mail", worker.NewWorker()),
+		worker.WithMember("general", worker.NewWorker()),
+		worker.WithPartitions(4),
+		worker.WithRoute("mail.send", "mail"),
+		worker.WithDefaultMember("general"),
+	)
+	if err != nil {
+		t.Fatalf("NewWorkerCluster() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		tasks []worker.Task
+		opts  []worker.JobOption
+		want  string
+	}{
+		{name: "正常系: タスク種別のルート", tasks: []worker.Task{typed("mail.send")}, want: "mail"},
+		{name: "正常系: ルートのない種別はデフォルト", tasks: []worker.Task{typed("health.check")}, want: "general"},
+		{name: "正常系: 種別のないタスクはデフォルト", tasks: []worker.Task{noopTask()}, want: "general"},
+		{name: "正常系: キーはタスク種別より優先", tasks: []worker.Task{typed("mail.send")}, opts: []worker.JobOption{worker.WithRoutingKey("user-1")}, want: "partition"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := c.Route(tt.tasks, tt.opts...)
+			if err != nil {
+				t.Fatalf("Route() error = %v", err)
+			}
+			if tt.want == "partition" {
+				// 同じキーは常に同じパーティションに振り分けられる
+				again, _ := c.Route(nil, tt.opts...)
+				if got != again {
+					t.Errorf("Route() = %s then %s for the same key", got, again)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Route() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	want := []string{"mail", "general", "app-partition-0", "app-partition-1", "app-partition-2", "app-partition-3"}
+	if got := c.Members(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Members() = %v, want %v", got, want)
+	}
+	// キーが異なればパーティションが分散する
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		member, _ := c.Route(nil, worker.WithRoutingKey(fmt.Sprintf("user-%d", i)))
+		seen[member] = true
+	}
+	if len(seen) != 4 {
+		t.Errorf("keys were routed to %d partitions, want 4", len(seen))
+	}
+}
+
+// TestWorkerCluster_RouteErrors は振り分け先がない場合のエラーを確認します。
+func TestWorkerCluster_RouteErrors(t *testing.T) {
+	t.Parallel()
+
+	c, err := worker.NewWorkerCluster(
+		worker.WithMember("mail", worker.NewWorker()),
+		worker.WithRoute("mail.send", "mail"),
+	)
+	if err != nil {
+		t.Fatalf("NewWorkerCluster() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		tasks []worker.Task
+		opts  []worker.JobOption
+	}{
+		{name: "異常系: ルートもデフォルトもない", tasks: []worker.Task{typed("health.check")}},
+		{name: "異常系: パーティションがないのにキーを指定", tasks: []worker.Task{typed("mail.send")}, opts: []worker.JobOption{worker.WithRoutingKey("user-1")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := c.Submit(context.Background(), tt.tasks, tt.opts...); !errors.Is(err, worker.ErrNoRoute) {
+				t.Errorf("Submit() error = %v, want ErrNoRoute", err)
+			}
+			if err := c.AddJobAsync(tt.tasks...); len(tt.opts) == 0 && !errors.Is(err, worker.ErrNoRoute) {
+				t.Errorf("AddJobAsync() error = %v, want ErrNoRoute", err)
+			}
+		})
+	}
+}
+
+// TestNewWorkerCluster_Invalid は不正な構成がエラーになることを確認します。
+func TestNewWorkerCluster_Invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		opts    []worker.ClusterOption
+		wantErr error
+	}{
+		{
+			name:    "異常系: メンバー名の重複",
+			opts:    []worker.ClusterOption{worker.WithMember("a", worker.NewWorker()), worker.WithMember("a", worker.NewWorker())},
+			wantErr: worker.ErrDuplicateMember,
+		},
+		{
+			name:    "異常系: 存在しないメンバーへのルート",
+			opts:    []worker.ClusterOption{worker.WithMember("a", worker.NewWorker()), worker.WithRoute("mail.send", "b")},
+			wantErr: worker.ErrUnknownMember,
+		},
+		{
+			name:    "異常系: 存在しないデフォルトメンバー",
+			opts:    []worker.ClusterOption{worker.WithDefaultMember("b")},
+			wantErr: worker.ErrUnknownMember,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := worker.NewWorkerCluster(tt.opts...); !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewWorkerCluster() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestWorkerCluster_PartitionOrdering は同じキーのジョブが投入順に実行されることを確認します。
+// 失敗して再試行されるジョブがあっても、後続のジョブは追い越しません。
+func TestWorkerCluster_PartitionOrdering(t *testing.T) {
+	t.Parallel()
+
+	c, err := worker.NewWorkerCluster(
+		worker.WithPartitions(3, worker.WithRetryPolicy(fastRetry), worker.WithMaxWorkerJobs(1000)),
+	)
+	if err != nil {
+		t.Fatalf("NewWorkerCluster() error = %v", err)
+	}
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var mu sync.Mutex
+	executed := make(map[string][]int)
+	var failOnce sync.Once
+	task := func(key string, seq int) worker.Task {
+		return tasks.NewTask(func(ctx context.Context) error {
+			if seq == 3 {
+				failed := false
+				failOnce.Do(func() { failed = true })
+				if failed {
+					return errors.New("transient")
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			executed[key] = append(executed[key], seq)
+			return nil
+		})
+	}
+
+	const users, jobsPerUser = 10, 30
+	for seq := 0; seq < jobsPerUser; seq++ {
+		for u := 0; u < users; u++ {
+			key := fmt.Sprintf("user-%d", u)
+			if _, err := c.Submit(context.Background(), []worker.Task{task(key, seq)}, worker.WithRoutingKey(key)); err != nil {
+				t.Fatalf("Submit() error = %v", err)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	for u := 0; u < users; u++ {
+		key := fmt.Sprintf("user-%d", u)
+		got := executed[key]
+		if len(got) != jobsPerUser {
+			t.Fatalf("%s executed %d jobs, want %d", key, len(got), jobsPerUser)
+		}
+		for i, seq := range got {
+			if seq != i {
+				t.Fatalf("%s executed out of order: %v", key, got)
+			}
+		}
+	}
+}
+
+// TestWorkerCluster_Status は全メンバーの起動・停止と状態の集計を確認します。
+func TestWorkerCluster_Status(t *testing.T) {
+	t.Parallel()
+
+	mail := worker.NewWorker(worker.WithMaxWorkerJobs(10))
+	general := worker.NewWorker(worker.WithMaxWorkerJobs(10))
+	c, err := worker.NewWorkerCluster(
+		worker.WithMember("mail", mail),
+		worker.WithMember("general", general),
+		worker.WithRoute("mail.send", "mail"),
+		worker.WithDefaultMember("general"),
+	)
+	if err != nil {
+		t.Fatalf("NewWorkerCluster() error = %v", err)
+	}
+
+	// Run 前のためジョブはキューに留まる
+	for i := 0; i < 3; i++ {
+		if err := c.AddJob(typed("mail.send")); err != nil {
+			t.Fatalf("AddJob() error = %v", err)
+		}
+	}
+	if err := c.AddJobAsync(noopTask()); err != nil {
+		t.Fatalf("AddJobAsync() error = %v", err)
+	}
+
+	status := c.Status()
+	if status.Status != worker.WorkerStatusCreated {
+		t.Errorf("Status = %s, want created", status.Status)
+	}
+	if status.QueueDepth != 4 || c.QueueDepth() != 4 {
+		t.Errorf("QueueDepth = %d / %d, want 4", status.QueueDepth, c.QueueDepth())
+	}
+	if status.Members[0].Name != "mail" || status.Members[0].Stats.QueueDepth != 3 {
+		t.Errorf("Members[0] = %+v, want mail with 3 queued jobs", status.Members[0])
+	}
+
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !c.IsRunning() || c.Status().Status != worker.WorkerStatusRunning {
+		t.Errorf("Status = %s, want running", c.Status().Status)
+	}
+	if err := c.Run(context.Background()); !errors.Is(err, worker.ErrWorkerAlreadyRunning) {
+		t.Errorf("Run() error = %v, want ErrWorkerAlreadyRunning", err)
+	}
+
+	// 一部のメンバーだけ停止した場合は draining として報告する
+	if err := general.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if got := c.Status().Status; got != worker.WorkerStatusDraining || c.IsRunning() {
+		t.Errorf("Status = %s, want draining", got)
+	}
+
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	status = c.Status()
+	if status.Status != worker.WorkerStatusStopped || status.QueueDepth != 0 {
+		t.Errorf("Status = %s, QueueDepth = %d, want stopped and empty", status.Status, status.QueueDepth)
+	}
+	if w, ok := c.Member("mail"); !ok || w != mail {
+		t.Error("Member(mail) did not return the registered worker")
+	}
+}
+
+// TestWorker_SerialExecution は逐次実行のWorkerの同時実行数が1に固定されることを確認します。
+func TestWorker_SerialExecution(t *testing.T) {
+	t.Parallel()
+
+	w := worker.NewWorker(worker.WithSerialExecution(), worker.WithRunningWorkers(10), worker.WithAutoscaling(worker.AutoscalePolicy{}))
+	if got := w.Resize(5); got != 1 {
+		t.Errorf("Resize(5) = %d, want 1", got)
+	}
+	stats := w.Stats()
+	if stats.Size != 1 || stats.Max != 1 {
+		t.Errorf("Stats() = %+v, want size and max 1", stats)
+	}
+
+	probe := &concurrencyProbe{}
+	w.Run(context.Background())
+	for i := 0; i < 5; i++ {
+		w.AddJob(probe.task(5 * time.Millisecond))
+	}
+	w.Shutdown(context.Background())
+	if got := probe.peak.Load(); got != 1 {
+		t.Errorf("max concurrency = %d, want 1", got)
+	}
+}
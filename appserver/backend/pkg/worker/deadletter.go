@@ -0,0 +1,276 @@
+package worker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs"
+)
+
+// ErrNotDeadLetter はメッセージ本文がデッドレターレコードではない場合のエラー
+// SQSのリドライブポリシーで移動された元のメッセージなどが該当します。
+var ErrNotDeadLetter = errors.New("message is not a dead letter record")
+
+// DeadLetter は最終的に失敗したタスクの記録です。
+// 元のメッセージ本文(エンベロープ)を保持しているため、原因を解消した後に再投入できます。
+type DeadLetter struct {
+	// ID はデッドレターの識別子です。
+	ID string `json:"id"`
+	// Worker はタスクを実行したWorkerの名前です。
+	Worker string `json:"worker"`
+	// TaskType はタスク種別名です (TypedTask を実装していない場合は空)。
+	TaskType string `json:"task_type,omitempty"`
+	// Body は元のメッセージ本文です (DeadLetterSource を実装していない場合は空)。
+	Body string `json:"body,omitempty"`
+	// MessageGroupID はFIFOキューから受信したメッセージのグループIDです (DeadLetterGroupSource を実装していない場合は空)。
+	// 再投入時に元と同じグループで送信するために使用します。
+	MessageGroupID string `json:"message_group_id,omitempty"`
+	// Error は最後の試行のエラーメッセージです。
+	Error string `json:"error"`
+	// ErrorChain はエラーをUnwrapして得られるメッセージの一覧です (外側から順)。
+	ErrorChain []string `json:"error_chain,omitempty"`
+	// Attempts は実行した試行回数です。
+	Attempts int `json:"attempts"`
+	// Stack はタスクがpanicした場合のスタックトレースです。
+	Stack string `json:"stack,omitempty"`
+	// Permanent は再試行不可能なエラーで失敗したかどうかです。
+	Permanent bool `json:"permanent"`
+	// FailedAt は失敗が確定した時刻です。
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// Marshal はデッドレターをメッセージ本文用のJSON文字列に変換します。
+func (d DeadLetter) Marshal() (string, error) {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal dead letter (id=%s): %w", d.ID, err)
+	}
+	return string(b), nil
+}
+
+// DecodeDeadLetter はメッセージ本文をDeadLetterに変換します。
+// デッドレターレコードとして解釈できない場合は ErrNotDeadLetter をラップしたエラーを返します。
+func DecodeDeadLetter(body string) (*DeadLetter, error) {
+	var d DeadLetter
+	if err := json.Unmarshal([]byte(body), &d); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotDeadLetter, err)
+	}
+	if d.ID == "" || d.FailedAt.IsZero() {
+		return nil, fmt.Errorf("%w: id or failed_at is missing", ErrNotDeadLetter)
+	}
+	return &d, nil
+}
+
+// DeadLetterSink は最終的に失敗したタスクの送り先です。
+type DeadLetterSink interface {
+	Send(ctx context.Context, deadLetter DeadLetter) error
+}
+
+// DeadLetterSource は再投入用の元のメッセージ本文を提供するTaskです。
+// 実装したタスクがデッドレターになると、その本文が DeadLetter.Body に保存されます。
+type DeadLetterSource interface {
+	Task
+	DeadLetterBody() (string, error)
+}
+
+// DeadLetterGroupSource はFIFOキューのメッセージグループIDを提供するTaskです。
+// 実装したタスクがデッドレターになると、そのグループIDが DeadLetter.MessageGroupID に保存されます。
+type DeadLetterGroupSource interface {
+	Task
+	DeadLetterMessageGroupID() string
+}
+
+// WithDeadLetterSink は再試行を使い切った、または PermanentError で失敗したタスクの送り先を設定します。
+func WithDeadLetterSink(sink DeadLetterSink) WorkerOption {
+	return func(w *worker) {
+		w.deadLetterSink = sink
+	}
+}
+
+// newDeadLetter はタスクの最終結果からDeadLetterを生成します。
+func newDeadLetter(workerName string, result TaskResult) DeadLetter {
+	d := DeadLetter{
+		ID:         uuid.NewString(),
+		Worker:     workerName,
+		TaskType:   result.TaskType,
+		Error:      result.Err.Error(),
+		ErrorChain: errorChain(result.Err),
+		Attempts:   result.Attempts,
+		Permanent:  IsPermanent(result.Err),
+		FailedAt:   time.Now().UTC(),
+	}
+	var panicErr *PanicError
+	if errors.As(result.Err, &panicErr) {
+		d.Stack = string(panicErr.Stack)
+	}
+	if source, ok := result.Task.(DeadLetterSource); ok {
+		body, err := source.DeadLetterBody()
+		if err != nil {
+			d.ErrorChain = append(d.ErrorChain, fmt.Sprintf("failed to get original body: %v", err))
+		}
+		d.Body = body
+	}
+	if source, ok := result.Task.(DeadLetterGroupSource); ok {
+		d.MessageGroupID = source.DeadLetterMessageGroupID()
+	}
+	return d
+}
+
+// errorChain はエラーを深さ優先でUnwrapし、各エラーのメッセージを返します。
+// errors.Join などで複数のエラーをラップしている場合はすべてを辿ります。
+func errorChain(err error) []string {
+	var chain []string
+	var walk func(err error)
+	walk = func(err error) {
+		if err == nil {
+			return
+		}
+		chain = append(chain, err.Error())
+		switch e := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, inner := range e.Unwrap() {
+				walk(inner)
+			}
+		case interface{ Unwrap() error }:
+			walk(e.Unwrap())
+		}
+	}
+	walk(err)
+	return chain
+}
+
+// SQSDeadLetterSink はデッドレターをSQSキューに送信するDeadLetterSinkです。
+// メッセージ本文は DeadLetter を Marshal したJSONです。
+// FIFOキューの場合は元のメッセージグループID (なければデッドレターのID) をグループIDに、デッドレターのIDを重複排除IDにします。
+type SQSDeadLetterSink struct {
+	client   sqs.SQS
+	queueURL string
+}
+
+// NewSQSDeadLetterSink はSQSDeadLetterSinkを生成するコンストラクタです。
+// 引数:
+//   - client: SQSクライアント
+//   - queueURL: デッドレターキューのURL
+func NewSQSDeadLetterSink(client sqs.SQS, queueURL string) *SQSDeadLetterSink {
+	return &SQSDeadLetterSink{client: client, queueURL: queueURL}
+}
+
+// Send はデッドレターをキューに送信します。
+func (s *SQSDeadLetterSink) Send(ctx context.Context, deadLetter DeadLetter) error {
+	body, err := deadLetter.Marshal()
+	if err != nil {
+		return err
+	}
+	options := []sqs.SendMessageOptionFunc{sqs.WithMessageBody(body)}
+	if sqs.IsFIFOQueue(s.queueURL) {
+		groupID := deadLetter.MessageGroupID
+		if groupID == "" {
+			groupID = deadLetter.ID
+		}
+		options = append(options, sqs.WithMessageGroupID(groupID), sqs.WithMessageDeduplicationID(deadLetter.ID))
+	}
+	if _, err := s.client.SendMessage(ctx, s.queueURL, options...); err != nil {
+		return fmt.Errorf("failed to send dead letter (id=%s): %w", deadLetter.ID, err)
+	}
+	return nil
+}
+
+// MemoryDeadLetterSink はデッドレターをメモリに保持するDeadLetterSinkです。
+// 主にテストで使用します。
+type MemoryDeadLetterSink struct {
+	mu          sync.Mutex
+	deadLetters []DeadLetter
+}
+
+// NewMemoryDeadLetterSink は空のMemoryDeadLetterSinkを生成します。
+func NewMemoryDeadLetterSink() *MemoryDeadLetterSink {
+	return &MemoryDeadLetterSink{}
+}
+
+// Send はデッドレターを保存します。
+func (s *MemoryDeadLetterSink) Send(ctx context.Context, deadLetter DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deadLetters = append(s.deadLetters, deadLetter)
+	return nil
+}
+
+// List は保存されたデッドレターを受信順に返します。
+func (s *MemoryDeadLetterSink) List() []DeadLetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]DeadLetter(nil), s.deadLetters...)
+}
+
+// FileDeadLetterSink はデッドレターをJSON Lines形式でファイルに追記するDeadLetterSinkです。
+// ローカル開発やテストでの確認に使用します。
+type FileDeadLetterSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileDeadLetterSink はFileDeadLetterSinkを生成します。
+// ファイルが存在しない場合は最初の Send で作成されます。
+func NewFileDeadLetterSink(path string) *FileDeadLetterSink {
+	return &FileDeadLetterSink{path: path}
+}
+
+// Send はデッドレターをファイルの末尾に1行追記します。
+func (s *FileDeadLetterSink) Send(ctx context.Context, deadLetter DeadLetter) error {
+	body, err := deadLetter.Marshal()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open dead letter file (path=%s): %w", s.path, err)
+	}
+	if _, err := f.WriteString(body + "\n"); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write dead letter (path=%s): %w", s.path, err)
+	}
+	return f.Close()
+}
+
+// List はファイルに保存されたデッドレターを記録順に返します。
+// ファイルが存在しない場合は空のスライスを返します。
+func (s *FileDeadLetterSink) List() ([]DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.Open(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open dead letter file (path=%s): %w", s.path, err)
+	}
+	defer f.Close()
+
+	var deadLetters []DeadLetter
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		d, err := DecodeDeadLetter(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode dead letter (path=%s): %w", s.path, err)
+		}
+		deadLetters = append(deadLetters, *d)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dead letter file (path=%s): %w", s.path, err)
+	}
+	return deadLetters, nil
+}
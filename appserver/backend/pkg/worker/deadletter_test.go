@@ -0,0 +1,261 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awssqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqspkg "github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs"
+	sqsmock "github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs/mock"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks"
+	"go.uber.org/mock/gomock"
+)
+
+// TestWorker_DeadLetter は最終的に失敗したタスクだけがデッドレターになることを確認します。
+func TestWorker_DeadLetter(t *testing.T) {
+	t.Parallel()
+
+	errInvalid := errors.New("invalid input")
+	tests := []struct {
+		name              string
+		task              worker.Task
+		expectDeadLetter  bool
+		expectedAttempts  int
+		expectedPermanent bool
+		expectedChain     []string
+	}{
+		{
+			name:             "正常系: 成功したタスクはデッドレターにならない",
+			task:             failingTask(1, errors.New("temporary")),
+			expectDeadLetter: false,
+		},
+		{
+			name:             "異常系: 再試行を使い切ったタスクはデッドレターになる",
+			task:             failingTask(10, fmt.Errorf("sync user: %w", errors.New("timeout"))),
+			expectDeadLetter: true,
+			expectedAttempts: 3,
+			expectedChain:    []string{"sync user: timeout", "timeout"},
+		},
+		{
+			name:              "異常系: PermanentErrorのタスクは即座にデッドレターになる",
+			task:              failingTask(10, worker.Permanent(errInvalid)),
+			expectDeadLetter:  true,
+			expectedAttempts:  1,
+			expectedPermanent: true,
+			expectedChain:     []string{"permanent error: invalid input", "invalid input"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			sink := worker.NewMemoryDeadLetterSink()
+			recorder := &resultRecorder{}
+			w := worker.NewWorker(
+				worker.WithName("dlq-worker"),
+				worker.WithRetryPolicy(fastRetry),
+				worker.WithDeadLetterSink(sink),
+				worker.WithResultHook(recorder.hook),
+			)
+			w.Run(context.Background())
+			w.AddJob(tt.task)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if err := w.Shutdown(ctx); err != nil {
+				t.Fatalf("failed to shutdown: %v", err)
+			}
+
+			deadLetters := sink.List()
+			if !tt.expectDeadLetter {
+				if len(deadLetters) != 0 {
+					t.Fatalf("expected no dead letters, got %d", len(deadLetters))
+				}
+				return
+			}
+			if len(deadLetters) != 1 {
+				t.Fatalf("expected 1 dead letter, got %d", len(deadLetters))
+			}
+			d := deadLetters[0]
+			if d.Worker != "dlq-worker" {
+				t.Errorf("expected worker dlq-worker, got %s", d.Worker)
+			}
+			if d.Attempts != tt.expectedAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.expectedAttempts, d.Attempts)
+			}
+			if d.Permanent != tt.expectedPermanent {
+				t.Errorf("expected permanent=%v, got %v", tt.expectedPermanent, d.Permanent)
+			}
+			if fmt.Sprint(d.ErrorChain) != fmt.Sprint(tt.expectedChain) {
+				t.Errorf("expected error chain %v, got %v", tt.expectedChain, d.ErrorChain)
+			}
+			if results := recorder.all(); len(results) != 1 || !results[0].DeadLettered {
+				t.Errorf("expected result to be marked as dead lettered, got %+v", results)
+			}
+		})
+	}
+}
+
+// TestWorker_DeadLetterKeepsEnvelope はエンベロープのタスクが元の本文ごと保存されることを確認します。
+func TestWorker_DeadLetterKeepsEnvelope(t *testing.T) {
+	t.Parallel()
+
+	registry := tasks.NewRegistry()
+	tasks.MustRegister(registry, "user.sync", func(ctx context.Context, p struct{ UserID string }) error {
+		return worker.Permanent(errors.New("user not found"))
+	})
+	envelope, err := tasks.NewEnvelope("user.sync", map[string]string{"UserID": "u-1"})
+	if err != nil {
+		t.Fatalf("failed to create envelope: %v", err)
+	}
+	task, err := registry.TaskFromEnvelope(envelope)
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	sink := worker.NewMemoryDeadLetterSink()
+	w := worker.NewWorker(worker.WithDeadLetterSink(sink))
+	w.Run(context.Background())
+	w.AddJob(task)
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	deadLetters := sink.List()
+	if len(deadLetters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(deadLetters))
+	}
+	if deadLetters[0].TaskType != "user.sync" {
+		t.Errorf("expected task type user.sync, got %s", deadLetters[0].TaskType)
+	}
+	restored, err := tasks.DecodeEnvelope(deadLetters[0].Body)
+	if err != nil {
+		t.Fatalf("failed to decode original envelope: %v", err)
+	}
+	if restored.ID != envelope.ID {
+		t.Errorf("expected envelope id %s, got %s", envelope.ID, restored.ID)
+	}
+}
+
+// TestFileDeadLetterSink はファイルに保存したデッドレターを読み戻せることを確認します。
+func TestFileDeadLetterSink(t *testing.T) {
+	t.Parallel()
+
+	sink := worker.NewFileDeadLetterSink(filepath.Join(t.TempDir(), "dlq.jsonl"))
+
+	// ファイル作成前は空
+	deadLetters, err := sink.List()
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	if len(deadLetters) != 0 {
+		t.Fatalf("expected no dead letters, got %d", len(deadLetters))
+	}
+
+	failedAt := time.Date(2025, 11, 25, 0, 0, 0, 0, time.UTC)
+	for _, id := range []string{"dl-1", "dl-2"} {
+		if err := sink.Send(context.Background(), worker.DeadLetter{ID: id, Error: "failed", Attempts: 3, FailedAt: failedAt}); err != nil {
+			t.Fatalf("failed to send: %v", err)
+		}
+	}
+
+	deadLetters, err = sink.List()
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	if len(deadLetters) != 2 || deadLetters[0].ID != "dl-1" || deadLetters[1].ID != "dl-2" {
+		t.Fatalf("expected dl-1 and dl-2 in order, got %+v", deadLetters)
+	}
+	if !deadLetters[0].FailedAt.Equal(failedAt) {
+		t.Errorf("expected failed_at %v, got %v", failedAt, deadLetters[0].FailedAt)
+	}
+}
+
+// TestSQSDeadLetterSink はFIFOキューのDLQにはグループIDと重複排除IDを付けて送信することを確認します。
+func TestSQSDeadLetterSink(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		queueURL    string
+		deadLetter  worker.DeadLetter
+		wantGroupID string
+		wantDedupID string
+	}{
+		{
+			name:       "正常系: 標準キューには本文のみ送信する",
+			queueURL:   "http://localhost:4566/000000000000/worker-queue-dlq",
+			deadLetter: worker.DeadLetter{ID: "dl-1", MessageGroupID: "user-1"},
+		},
+		{
+			name:        "正常系: FIFOキューには元のグループIDで送信する",
+			queueURL:    "http://localhost:4566/000000000000/user-sync-queue-dlq.fifo",
+			deadLetter:  worker.DeadLetter{ID: "dl-1", MessageGroupID: "user-1"},
+			wantGroupID: "user-1",
+			wantDedupID: "dl-1",
+		},
+		{
+			name:        "正常系: グループIDがない場合はデッドレターのIDを使う",
+			queueURL:    "http://localhost:4566/000000000000/user-sync-queue-dlq.fifo",
+			deadLetter:  worker.DeadLetter{ID: "dl-1"},
+			wantGroupID: "dl-1",
+			wantDedupID: "dl-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockSQS := sqsmock.NewMockSQS(ctrl)
+			mockSQS.EXPECT().
+				SendMessage(gomock.Any(), tt.queueURL, gomock.Any()).
+				DoAndReturn(func(ctx context.Context, queueURL string, options ...sqspkg.SendMessageOptionFunc) (*awssqs.SendMessageOutput, error) {
+					input := &awssqs.SendMessageInput{}
+					for _, option := range options {
+						option(input)
+					}
+					if got := aws.ToString(input.MessageGroupId); got != tt.wantGroupID {
+						t.Errorf("expected MessageGroupId %q, got %q", tt.wantGroupID, got)
+					}
+					if got := aws.ToString(input.MessageDeduplicationId); got != tt.wantDedupID {
+						t.Errorf("expected MessageDeduplicationId %q, got %q", tt.wantDedupID, got)
+					}
+					return &awssqs.SendMessageOutput{}, nil
+				})
+
+			sink := worker.NewSQSDeadLetterSink(mockSQS, tt.queueURL)
+			if err := sink.Send(context.Background(), tt.deadLetter); err != nil {
+				t.Fatalf("failed to send: %v", err)
+			}
+		})
+	}
+}
+
+// TestDecodeDeadLetter は元のエンベロープとデッドレターレコードを判別できることを確認します。
+func TestDecodeDeadLetter(t *testing.T) {
+	t.Parallel()
+
+	if _, err := worker.DecodeDeadLetter(`{"version":1,"id":"e-1","type":"user.sync"}`); !errors.Is(err, worker.ErrNotDeadLetter) {
+		t.Errorf("expected ErrNotDeadLetter for envelope, got %v", err)
+	}
+	if _, err := worker.DecodeDeadLetter("not json"); !errors.Is(err, worker.ErrNotDeadLetter) {
+		t.Errorf("expected ErrNotDeadLetter for invalid json, got %v", err)
+	}
+	d, err := worker.DecodeDeadLetter(`{"id":"dl-1","error":"failed","attempts":1,"failed_at":"2025-11-25T00:00:00Z"}`)
+	if err != nil {
+		t.Fatalf("failed to decode dead letter: %v", err)
+	}
+	if d.ID != "dl-1" {
+		t.Errorf("expected id dl-1, got %s", d.ID)
+	}
+}
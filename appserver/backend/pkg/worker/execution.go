@@ -0,0 +1,107 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// ErrTaskTimeout はタスクの実行が制限時間を超えた場合のエラー
+// 使用例: errors.Is(result.Err, worker.ErrTaskTimeout)
+var ErrTaskTimeout = errors.New("task execution timed out")
+
+// PanicError はタスクの実行中に発生したpanicを表すエラーです。
+// panicはWorker内で回復され、通常の失敗として再試行やデッドレターの対象になります。
+type PanicError struct {
+	// Value は recover() で得られた値です。
+	Value any
+	// Stack はpanic発生時のスタックトレースです。
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Value)
+}
+
+// Unwrap はpanicの値がerrorの場合にそのエラーを返します。
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// WithTaskTimeout はすべてのタスクの1回の試行あたりの制限時間を設定します。
+// 制限時間は Run に渡したコンテキストから派生し、超過したタスクは ErrTaskTimeout で失敗します。
+// 0以下の場合は制限しません(デフォルト)。
+func WithTaskTimeout(timeout time.Duration) WorkerOption {
+	return func(w *worker) {
+		w.taskTimeout = max(timeout, 0)
+	}
+}
+
+// WithTaskTypeTimeout は特定のタスク種別の1回の試行あたりの制限時間を設定します。
+// TypedTask を実装したタスクにのみ適用され、WithTaskTimeout より優先されます。
+func WithTaskTypeTimeout(taskType string, timeout time.Duration) WorkerOption {
+	return func(w *worker) {
+		if w.taskTimeouts == nil {
+			w.taskTimeouts = make(map[string]time.Duration)
+		}
+		w.taskTimeouts[taskType] = max(timeout, 0)
+	}
+}
+
+// taskTimeoutFor はタスク種別に適用する制限時間を返す
+func (w *worker) taskTimeoutFor(taskType string) time.Duration {
+	if timeout, ok := w.taskTimeouts[taskType]; ok {
+		return timeout
+	}
+	return w.taskTimeout
+}
+
+// executeOnce はタスクを1回実行します。
+// 制限時間を超えた場合はタスクの終了を待たずに ErrTaskTimeout を返し、同時実行枠を解放します。
+// 注意事項: Goではゴルーチンを強制終了できないため、コンテキストを無視するタスクはバックグラウンドで実行され続ける
+func executeOnce(ctx context.Context, task Task, timeout time.Duration) error {
+	if timeout <= 0 {
+		return safeExecute(ctx, task)
+	}
+
+	taskCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- safeExecute(taskCtx, task)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil && ctx.Err() == nil && errors.Is(taskCtx.Err(), context.DeadlineExceeded) {
+			// コンテキストに従って終了したタスクも制限時間超過として扱う
+			return timeoutError(timeout, err)
+		}
+		return err
+	case <-taskCtx.Done():
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return timeoutError(timeout, context.DeadlineExceeded)
+	}
+}
+
+func timeoutError(timeout time.Duration, err error) error {
+	return fmt.Errorf("%w (timeout=%s): %w", ErrTaskTimeout, timeout, err)
+}
+
+// safeExecute はタスクを実行し、panicを PanicError に変換します。
+func safeExecute(ctx context.Context, task Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return task.Execute(ctx)
+}
@@ -0,0 +1,202 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks"
+)
+
+// TestWorker_PanicIsolation はタスクのpanicが PanicError として通常の失敗扱いになり、
+// Workerが後続のジョブを処理し続けることを確認します。
+func TestWorker_PanicIsolation(t *testing.T) {
+	t.Parallel()
+
+	recorder := &resultRecorder{}
+	sink := worker.NewMemoryDeadLetterSink()
+	w := worker.NewWorker(
+		worker.WithRetryPolicy(fastRetry),
+		worker.WithResultHook(recorder.hook),
+		worker.WithDeadLetterSink(sink),
+	)
+	w.Run(context.Background())
+
+	w.AddJob(&typedTask{taskType: "panic", fn: func(ctx context.Context) error {
+		panic("something went wrong")
+	}})
+	w.AddJob(&typedTask{taskType: "ok", fn: func(ctx context.Context) error { return nil }})
+
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	results := map[string]worker.TaskResult{}
+	for _, result := range recorder.all() {
+		results[result.TaskType] = result
+	}
+	if !results["ok"].Succeeded() {
+		t.Errorf("expected the job after the panic to succeed, got %v", results["ok"].Err)
+	}
+
+	panicked := results["panic"]
+	var panicErr *worker.PanicError
+	if !errors.As(panicked.Err, &panicErr) {
+		t.Fatalf("expected PanicError, got %v", panicked.Err)
+	}
+	if panicErr.Value != "something went wrong" {
+		t.Errorf("expected panic value to be kept, got %v", panicErr.Value)
+	}
+	if !strings.Contains(string(panicErr.Stack), "TestWorker_PanicIsolation") {
+		t.Errorf("expected stack trace to contain the panicking function, got %s", panicErr.Stack)
+	}
+	// panicも通常の失敗と同様に再試行される
+	if panicked.Attempts != fastRetry.MaxAttempts {
+		t.Errorf("expected %d attempts, got %d", fastRetry.MaxAttempts, panicked.Attempts)
+	}
+	deadLetters := sink.List()
+	if len(deadLetters) != 1 || deadLetters[0].Stack == "" {
+		t.Errorf("expected a dead letter with stack trace, got %+v", deadLetters)
+	}
+}
+
+// TestPanicError_Unwrap はerrorの値でpanicした場合に元のエラーを辿れることを確認します。
+func TestPanicError_Unwrap(t *testing.T) {
+	t.Parallel()
+
+	cause := errors.New("nil map")
+	err := error(&worker.PanicError{Value: cause})
+	if !errors.Is(err, cause) {
+		t.Errorf("expected PanicError to unwrap to the panic value")
+	}
+	if errors.Unwrap(&worker.PanicError{Value: "not an error"}) != nil {
+		t.Errorf("expected nil for non-error panic value")
+	}
+}
+
+// TestWorker_TaskTimeout は制限時間を超えたタスクが ErrTaskTimeout で失敗することを確認します。
+func TestWorker_TaskTimeout(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		fn   func(release <-chan struct{}) func(ctx context.Context) error
+	}{
+		{
+			name: "異常系: コンテキストに従って終了するタスク",
+			fn: func(release <-chan struct{}) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				}
+			},
+		},
+		{
+			name: "異常系: コンテキストを無視してハングするタスク",
+			fn: func(release <-chan struct{}) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					<-release
+					return nil
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			release := make(chan struct{})
+			defer close(release)
+
+			recorder := &resultRecorder{}
+			w := worker.NewWorker(
+				worker.WithRunningWorkers(1),
+				worker.WithTaskTimeout(20*time.Millisecond),
+				worker.WithResultHook(recorder.hook),
+			)
+			w.Run(context.Background())
+			w.AddJob(&typedTask{taskType: "hung", fn: tt.fn(release)})
+			// 同時実行枠が1つでも、タイムアウト後に次のジョブが実行される
+			w.AddJob(&typedTask{taskType: "next", fn: func(ctx context.Context) error { return nil }})
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if err := w.Shutdown(ctx); err != nil {
+				t.Fatalf("failed to shutdown: %v", err)
+			}
+
+			results := map[string]worker.TaskResult{}
+			for _, result := range recorder.all() {
+				results[result.TaskType] = result
+			}
+			hung := results["hung"]
+			if !errors.Is(hung.Err, worker.ErrTaskTimeout) || !errors.Is(hung.Err, context.DeadlineExceeded) {
+				t.Errorf("expected ErrTaskTimeout wrapping DeadlineExceeded, got %v", hung.Err)
+			}
+			if !results["next"].Succeeded() {
+				t.Errorf("expected next job to succeed, got %v", results["next"].Err)
+			}
+		})
+	}
+}
+
+// TestWorker_TaskTypeTimeout はタスク種別ごとの制限時間が優先されることを確認します。
+func TestWorker_TaskTypeTimeout(t *testing.T) {
+	t.Parallel()
+
+	recorder := &resultRecorder{}
+	w := worker.NewWorker(
+		worker.WithTaskTimeout(10*time.Millisecond),
+		worker.WithTaskTypeTimeout("slow", time.Second),
+		worker.WithResultHook(recorder.hook),
+	)
+	w.Run(context.Background())
+
+	sleep := func(ctx context.Context) error {
+		select {
+		case <-time.After(50 * time.Millisecond):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	w.AddJob(&typedTask{taskType: "slow", fn: sleep}, &typedTask{taskType: "fast", fn: sleep})
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	results := map[string]worker.TaskResult{}
+	for _, result := range recorder.all() {
+		results[result.TaskType] = result
+	}
+	if !results["slow"].Succeeded() {
+		t.Errorf("expected slow task to succeed within its own timeout, got %v", results["slow"].Err)
+	}
+	if !errors.Is(results["fast"].Err, worker.ErrTaskTimeout) {
+		t.Errorf("expected fast task to time out, got %v", results["fast"].Err)
+	}
+}
+
+// TestWorker_TaskTimeoutDisabled は制限時間を設定しない場合にタスクの完了を待つことを確認します。
+func TestWorker_TaskTimeoutDisabled(t *testing.T) {
+	t.Parallel()
+
+	recorder := &resultRecorder{}
+	w := worker.NewWorker(worker.WithResultHook(recorder.hook))
+	w.Run(context.Background())
+	w.AddJob(tasks.NewTask(func(ctx context.Context) error {
+		time.Sleep(30 * time.Millisecond)
+		return nil
+	}))
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+	if results := recorder.all(); len(results) != 1 || !results[0].Succeeded() {
+		t.Errorf("expected task to succeed, got %+v", results)
+	}
+}
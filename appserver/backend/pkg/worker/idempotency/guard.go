@@ -0,0 +1,239 @@
+// Package idempotency は再配信や重複送信されたメッセージの二重実行を防ぎます。
+// SQSは少なくとも1回の配信のため、同じメッセージが複数回、または複数のプロセスに同時に届くことがあります。
+// Guard はタスクの実行前にキー (重複排除ID、エンベロープID、メッセージID) の実行権を Store で取得し、
+// 処理済みのキーは実行せずに成功として扱います。
+//
+// 使用例:
+//
+//	guard := idempotency.New(idempotency.NewSQLStore(db), idempotency.WithTTL(24*time.Hour))
+//	poller := worker.NewSQSPoller(client, w, queueURL, guard.MessageHandler(registry.MessageHandler()))
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/clock"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks"
+)
+
+const (
+	defaultTTL          = 24 * time.Hour
+	defaultLease        = 5 * time.Minute
+	defaultPollInterval = time.Second
+)
+
+// KeyFunc はメッセージから重複判定のキーを取り出す関数型です。
+type KeyFunc func(msg types.Message) string
+
+// Option はGuardの設定を変更する関数型です。
+type Option func(*Guard)
+
+// WithTTL は処理済みのキーを保持する期間を設定します (デフォルト: 24時間)。
+// この期間内に届いた同じキーのメッセージは実行しません。
+func WithTTL(ttl time.Duration) Option {
+	return func(g *Guard) {
+		if ttl > 0 {
+			g.ttl = ttl
+		}
+	}
+}
+
+// WithLease は処理中のキーの実行権を保持する期間を設定します (デフォルト: 5分)。
+// 実行中のプロセスが停止した場合は、この期間が過ぎると他のプロセスが実行権を引き継ぎます。
+// 注意事項: タスクのタイムアウト (再試行を含む) より長くすること
+func WithLease(lease time.Duration) Option {
+	return func(g *Guard) {
+		if lease > 0 {
+			g.lease = lease
+		}
+	}
+}
+
+// WithPollInterval は他のプロセスが処理中の場合に、完了を確認する間隔を設定します (デフォルト: 1秒)。
+func WithPollInterval(interval time.Duration) Option {
+	return func(g *Guard) {
+		if interval > 0 {
+			g.pollInterval = interval
+		}
+	}
+}
+
+// WithClock は処理中のキーの確認の待機に使用する Clock を設定します (デフォルト: 実時間)。
+func WithClock(c clock.Clock) Option {
+	return func(g *Guard) {
+		g.clock = c
+	}
+}
+
+// WithKeyFunc は MessageHandler でメッセージからキーを取り出す関数を設定します (デフォルト: MessageKey)。
+func WithKeyFunc(fn KeyFunc) Option {
+	return func(g *Guard) {
+		g.keyFunc = fn
+	}
+}
+
+// Guard はキーごとにタスクを1回だけ実行させます。
+type Guard struct {
+	store        Store
+	ttl          time.Duration
+	lease        time.Duration
+	pollInterval time.Duration
+	clock        clock.Clock
+	keyFunc      KeyFunc
+}
+
+// New はGuardを生成するコンストラクタです。
+// 引数:
+//   - store: 実行権と処理済みのキーを記録するStore
+//   - opts: オプション
+func New(store Store, opts ...Option) *Guard {
+	g := &Guard{
+		store:        store,
+		ttl:          defaultTTL,
+		lease:        defaultLease,
+		pollInterval: defaultPollInterval,
+		clock:        clock.New(),
+		keyFunc:      MessageKey,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// MessageHandler はメッセージから変換したTaskを、キーごとに1回だけ実行するTaskで包むハンドラーを返します。
+func (g *Guard) MessageHandler(next worker.MessageHandler) worker.MessageHandler {
+	return func(msg types.Message) (worker.Task, error) {
+		task, err := next(msg)
+		if err != nil {
+			return nil, err
+		}
+		return g.Wrap(g.keyFunc(msg), task), nil
+	}
+}
+
+// Wrap は task をキーごとに1回だけ実行するTaskで包みます。キーが空の場合は task をそのまま返します。
+//
+// 包んだTaskは実行のたびに実行権を取得し、以下のように動作します:
+//   - 取得できた場合は task を実行し、成功すれば処理済みに、失敗すれば実行権を解放して再試行や再配信で実行できるようにする
+//   - 処理済みの場合は task を実行せずに成功とする
+//   - 他のプロセスが処理中の場合は、完了するか実行権が解放・期限切れになるまで待機する
+//     (失敗として扱うとデッドレターに送られるため)
+func (g *Guard) Wrap(key string, task worker.Task) worker.Task {
+	if key == "" {
+		return task
+	}
+	return &guardedTask{guard: g, key: key, task: task}
+}
+
+// MessageKey はメッセージの重複判定のキーを返します。
+// FIFOキューの重複排除ID、エンベロープのID、メッセージIDの順に、最初に見つかったものを使用します。
+// 重複排除IDは受信時に MessageDeduplicationId のシステム属性を要求した場合のみ取得できます。
+// エンベロープのIDはアウトボックスからの重複送信やDLQからの再投入でも変わらないため、メッセージIDより優先します。
+func MessageKey(msg types.Message) string {
+	if id := msg.Attributes[string(types.MessageSystemAttributeNameMessageDeduplicationId)]; id != "" {
+		return "dedup:" + id
+	}
+	if e, err := tasks.DecodeEnvelope(aws.ToString(msg.Body)); err == nil && e.ID != "" {
+		return "envelope:" + e.ID
+	}
+	return "message:" + aws.ToString(msg.MessageId)
+}
+
+// guardedTask は Guard.Wrap で包んだTaskです。
+type guardedTask struct {
+	guard *Guard
+	key   string
+	task  worker.Task
+}
+
+var (
+	_ worker.TypedTask        = (*guardedTask)(nil)
+	_ worker.TaskCompleter    = (*guardedTask)(nil)
+	_ worker.DeadLetterSource = (*guardedTask)(nil)
+)
+
+// Execute は実行権を取得できた場合のみ元のTaskを実行します。
+func (t *guardedTask) Execute(ctx context.Context) error {
+	claim, err := t.claim(ctx)
+	if err != nil {
+		return err
+	}
+	if claim.Status == StatusCompleted {
+		slog.Info("skip duplicate task", "key", t.key, "taskType", t.TaskType())
+		return nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.release(claim)
+			panic(r)
+		}
+	}()
+	if err := t.task.Execute(ctx); err != nil {
+		t.release(claim)
+		return err
+	}
+	// 処理済みの記録に失敗してもタスクは成功しているため、エラーにせず再試行させない (この場合、以降の再配信は重複として検知できない)
+	if err := t.guard.store.Complete(context.WithoutCancel(ctx), claim, t.guard.ttl); err != nil {
+		slog.Warn("failed to record completed task", "key", t.key, "taskType", t.TaskType(), "error", err)
+	}
+	return nil
+}
+
+// claim は実行権を取得します。他のプロセスが処理中の場合は、結果が確定するまで待機します。
+func (t *guardedTask) claim(ctx context.Context) (Claim, error) {
+	for {
+		claim, err := t.guard.store.Claim(ctx, t.key, t.guard.lease)
+		if err != nil {
+			return Claim{}, err
+		}
+		if claim.Status != StatusInProgress {
+			return claim, nil
+		}
+		slog.Debug("waiting for duplicate task in progress", "key", t.key, "taskType", t.TaskType())
+		select {
+		case <-t.guard.clock.After(t.guard.pollInterval):
+		case <-ctx.Done():
+			return Claim{}, ctx.Err()
+		}
+	}
+}
+
+// release は失敗したタスクの実行権を解放します。
+// タスクのタイムアウトでコンテキストが終了していても解放できるよう、キャンセルされないコンテキストを使用します。
+func (t *guardedTask) release(claim Claim) {
+	if err := t.guard.store.Release(context.Background(), claim); err != nil && !errors.Is(err, ErrClaimLost) {
+		slog.Warn("failed to release idempotency claim", "key", t.key, "taskType", t.TaskType(), "error", err)
+	}
+}
+
+// TaskType は元のTaskの種別名を返します。
+func (t *guardedTask) TaskType() string {
+	if typed, ok := t.task.(worker.TypedTask); ok {
+		return typed.TaskType()
+	}
+	return ""
+}
+
+// DeadLetterBody は元のTaskが DeadLetterSource の場合にその本文を返します。
+func (t *guardedTask) DeadLetterBody() (string, error) {
+	if source, ok := t.task.(worker.DeadLetterSource); ok {
+		return source.DeadLetterBody()
+	}
+	return "", nil
+}
+
+// OnComplete は元のTaskが TaskCompleter の場合に最終結果を通知します。
+func (t *guardedTask) OnComplete(ctx context.Context, result worker.TaskResult) {
+	if completer, ok := t.task.(worker.TaskCompleter); ok {
+		result.Task = t.task
+		completer.OnComplete(ctx, result)
+	}
+}
@@ -0,0 +1,349 @@
+package idempotency_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	sqspkg "github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs/memsqs"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/clock"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/idempotency"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks"
+)
+
+var epoch = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// TestMemoryStore は実行権の取得・完了・解放と期限切れを確認します。
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		// prepare は最初の実行権に対する操作と経過時間
+		prepare func(t *testing.T, store *idempotency.MemoryStore, clk *clock.Fake, claim idempotency.Claim)
+		want    idempotency.Status
+	}{
+		{
+			name:    "正常系: 処理中のキーは取得できない",
+			prepare: func(t *testing.T, store *idempotency.MemoryStore, clk *clock.Fake, claim idempotency.Claim) {},
+			want:    idempotency.StatusInProgress,
+		},
+		{
+			name: "正常系: 処理済みのキーは取得できない",
+			prepare: func(t *testing.T, store *idempotency.MemoryStore, clk *clock.Fake, claim idempotency.Claim) {
+				if err := store.Complete(context.Background(), claim, time.Hour); err != nil {
+					t.Fatalf("Complete() error = %v", err)
+				}
+				clk.Advance(59 * time.Minute)
+			},
+			want: idempotency.StatusCompleted,
+		},
+		{
+			name: "正常系: 解放したキーは再び取得できる",
+			prepare: func(t *testing.T, store *idempotency.MemoryStore, clk *clock.Fake, claim idempotency.Claim) {
+				if err := store.Release(context.Background(), claim); err != nil {
+					t.Fatalf("Release() error = %v", err)
+				}
+			},
+			want: idempotency.StatusAcquired,
+		},
+		{
+			name: "正常系: リース期限の切れたキーは引き継げる",
+			prepare: func(t *testing.T, store *idempotency.MemoryStore, clk *clock.Fake, claim idempotency.Claim) {
+				clk.Advance(time.Minute)
+			},
+			want: idempotency.StatusAcquired,
+		},
+		{
+			name: "正常系: 保持期限の切れた処理済みのキーは再び取得できる",
+			prepare: func(t *testing.T, store *idempotency.MemoryStore, clk *clock.Fake, claim idempotency.Claim) {
+				if err := store.Complete(context.Background(), claim, time.Hour); err != nil {
+					t.Fatalf("Complete() error = %v", err)
+				}
+				clk.Advance(time.Hour)
+			},
+			want: idempotency.StatusAcquired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			clk := clock.NewFake(epoch)
+			store := idempotency.NewMemoryStore(idempotency.WithMemoryStoreClock(clk))
+			claim, err := store.Claim(context.Background(), "key", time.Minute)
+			if err != nil || claim.Status != idempotency.StatusAcquired || claim.Token == "" {
+				t.Fatalf("Claim() = %+v, %v, want acquired", claim, err)
+			}
+			tt.prepare(t, store, clk, claim)
+
+			got, err := store.Claim(context.Background(), "key", time.Minute)
+			if err != nil {
+				t.Fatalf("Claim() error = %v", err)
+			}
+			if got.Status != tt.want {
+				t.Errorf("Claim() status = %s, want %s", got.Status, tt.want)
+			}
+		})
+	}
+}
+
+// TestMemoryStore_ClaimLost は引き継がれた実行権を元の実行者が完了できないことを確認します。
+func TestMemoryStore_ClaimLost(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewFake(epoch)
+	store := idempotency.NewMemoryStore(idempotency.WithMemoryStoreClock(clk))
+	stale, _ := store.Claim(context.Background(), "key", time.Minute)
+	clk.Advance(time.Minute)
+	current, _ := store.Claim(context.Background(), "key", time.Minute)
+
+	if err := store.Complete(context.Background(), stale, time.Hour); !errors.Is(err, idempotency.ErrClaimLost) {
+		t.Errorf("Complete(stale) error = %v, want ErrClaimLost", err)
+	}
+	// 古い実行権の解放で、現在の実行権が失われない
+	if err := store.Release(context.Background(), stale); err != nil {
+		t.Fatalf("Release(stale) error = %v", err)
+	}
+	if err := store.Complete(context.Background(), current, time.Hour); err != nil {
+		t.Errorf("Complete(current) error = %v", err)
+	}
+}
+
+// TestGuard_Wrap は同じキーのタスクが1回だけ実行され、失敗した場合は再実行できることを確認します。
+func TestGuard_Wrap(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		// errs は各実行で元のタスクが返すエラー
+		errs         []error
+		wantExecuted int
+		wantErrs     []bool
+	}{
+		{
+			name:         "正常系: 成功したキーは再実行しない",
+			errs:         []error{nil, nil, nil},
+			wantExecuted: 1,
+			wantErrs:     []bool{false, false, false},
+		},
+		{
+			name:         "正常系: 失敗したキーは再実行する",
+			errs:         []error{errors.New("failed"), nil, nil},
+			wantExecuted: 2,
+			wantErrs:     []bool{true, false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			guard := idempotency.New(idempotency.NewMemoryStore())
+			executed := 0
+			for i, wantErr := range tt.wantErrs {
+				task := guard.Wrap("key", tasks.NewTask(func(ctx context.Context) error {
+					executed++
+					return tt.errs[executed-1]
+				}))
+				if err := task.Execute(context.Background()); (err != nil) != wantErr {
+					t.Errorf("Execute() #%d error = %v, wantErr %v", i+1, err, wantErr)
+				}
+			}
+			if executed != tt.wantExecuted {
+				t.Errorf("executed %d times, want %d", executed, tt.wantExecuted)
+			}
+		})
+	}
+}
+
+// TestGuard_ConcurrentDuplicates は同時に届いた重複が1回だけ実行され、他は完了を待って成功することを確認します。
+func TestGuard_ConcurrentDuplicates(t *testing.T) {
+	t.Parallel()
+
+	guard := idempotency.New(idempotency.NewMemoryStore(), idempotency.WithPollInterval(time.Millisecond))
+	var executed atomic.Int32
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- guard.Wrap("key", tasks.NewTask(func(ctx context.Context) error {
+				executed.Add(1)
+				time.Sleep(20 * time.Millisecond)
+				return nil
+			})).Execute(context.Background())
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Execute() error = %v", err)
+		}
+	}
+	if got := executed.Load(); got != 1 {
+		t.Errorf("executed %d times, want 1", got)
+	}
+}
+
+// TestGuard_LeaseTakeover は処理中のまま停止した実行者の実行権を、リース期限後に引き継ぐことを確認します。
+func TestGuard_LeaseTakeover(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewFake(epoch)
+	store := idempotency.NewMemoryStore(idempotency.WithMemoryStoreClock(clk))
+	guard := idempotency.New(store, idempotency.WithClock(clk), idempotency.WithLease(time.Minute), idempotency.WithPollInterval(10*time.Second))
+
+	// 別のプロセスが実行権を取得したまま停止した状態
+	if _, err := store.Claim(context.Background(), "key", time.Minute); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+
+	done := make(chan error, 1)
+	executed := make(chan struct{}, 1)
+	go func() {
+		done <- guard.Wrap("key", tasks.NewTask(func(ctx context.Context) error {
+			executed <- struct{}{}
+			return nil
+		})).Execute(context.Background())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for elapsed := time.Duration(0); elapsed < time.Minute; elapsed += 10 * time.Second {
+		if err := clk.BlockUntil(ctx, 1); err != nil {
+			t.Fatalf("task did not wait for the claim in progress: %v", err)
+		}
+		select {
+		case <-executed:
+			t.Fatalf("task executed after %s, before the lease expired", elapsed)
+		default:
+		}
+		clk.Advance(10 * time.Second)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("task did not take over the expired claim")
+	}
+	if len(executed) != 1 {
+		t.Error("task was not executed after taking over the claim")
+	}
+}
+
+// TestMessageKey はメッセージから重複判定のキーを取り出す優先順位を確認します。
+func TestMessageKey(t *testing.T) {
+	t.Parallel()
+
+	e, err := tasks.NewEnvelope("user.created", map[string]string{"user_id": "u1"})
+	if err != nil {
+		t.Fatalf("NewEnvelope() error = %v", err)
+	}
+	body, _ := e.Marshal()
+
+	tests := []struct {
+		name string
+		msg  types.Message
+		want string
+	}{
+		{
+			name: "正常系: 重複排除IDを優先する",
+			msg: types.Message{
+				MessageId:  aws.String("m1"),
+				Body:       aws.String(body),
+				Attributes: map[string]string{string(types.MessageSystemAttributeNameMessageDeduplicationId): "d1"},
+			},
+			want: "dedup:d1",
+		},
+		{
+			name: "正常系: エンベロープのID",
+			msg:  types.Message{MessageId: aws.String("m1"), Body: aws.String(body)},
+			want: "envelope:" + e.ID,
+		},
+		{
+			name: "正常系: エンベロープでない本文はメッセージID",
+			msg:  types.Message{MessageId: aws.String("m1"), Body: aws.String("plain text")},
+			want: "message:m1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := idempotency.MessageKey(tt.msg); got != tt.want {
+				t.Errorf("MessageKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestGuard_MessageHandler は同じエンベロープのメッセージが重複して届いても1回だけ処理され、
+// いずれのメッセージも削除されることを確認します。
+func TestGuard_MessageHandler(t *testing.T) {
+	t.Parallel()
+
+	server := memsqs.New()
+	queueURL := server.MustCreateQueue("worker-queue")
+	client := sqspkg.NewSQSClientWithAPI(server)
+
+	var executed atomic.Int32
+	registry := tasks.NewRegistry()
+	tasks.MustRegister(registry, "user.created", func(ctx context.Context, payload struct{}) error {
+		executed.Add(1)
+		return nil
+	})
+
+	e, err := tasks.NewEnvelope("user.created", struct{}{})
+	if err != nil {
+		t.Fatalf("NewEnvelope() error = %v", err)
+	}
+	body, _ := e.Marshal()
+	// アウトボックスの再送などで同じイベントが2回送信された状態
+	for range 2 {
+		if _, err := client.SendMessage(context.Background(), queueURL, sqspkg.WithMessageBody(body)); err != nil {
+			t.Fatalf("SendMessage() error = %v", err)
+		}
+	}
+
+	w := worker.NewWorker()
+	w.Run(context.Background())
+	defer w.Shutdown(context.Background())
+
+	guard := idempotency.New(idempotency.NewMemoryStore(), idempotency.WithPollInterval(time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- worker.NewSQSPoller(client, w, queueURL, guard.MessageHandler(registry.MessageHandler())).Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.After(2 * time.Second)
+	for server.Stats("worker-queue").Total() != 0 {
+		select {
+		case <-deadline:
+			t.Fatalf("messages were not deleted: %+v", server.Stats("worker-queue"))
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	if got := executed.Load(); got != 1 {
+		t.Errorf("handler executed %d times, want 1", got)
+	}
+}
@@ -0,0 +1,121 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+)
+
+const (
+	// mysqlErrDuplicateEntry は一意制約違反を表すMySQLのエラー番号
+	mysqlErrDuplicateEntry = 1062
+	// maxClaimAttempts は期限切れの行の削除と競合した場合に取得をやり直す回数の上限
+	maxClaimAttempts = 3
+)
+
+// SQLStore は idempotency_keys テーブルでキーを管理するStoreです。
+// 実行権の取得は主キーの一意制約と条件付きUPDATEで判定するため、複数のプロセスが同時に同じキーを受信しても1つだけが実行します。
+// 注意事項: テーブルは migrations/20250801000001_create_idempotency_keys_table.sql で作成する
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore はSQLStoreを生成するコンストラクタです。
+func NewSQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+// Claim は行をINSERTして実行権を取得します。
+// 行が既に存在する場合は、期限切れであれば実行権を引き継ぎ、そうでなければ行の状態を返します。
+func (s *SQLStore) Claim(ctx context.Context, key string, lease time.Duration) (Claim, error) {
+	for range maxClaimAttempts {
+		now := time.Now().UTC()
+		token := uuid.NewString()
+		_, err := s.db.ExecContext(ctx,
+			"INSERT INTO idempotency_keys (idempotency_key, status, token, expires_at) VALUES (?, 'processing', ?, ?)",
+			key, token, now.Add(lease),
+		)
+		if err == nil {
+			return Claim{Key: key, Status: StatusAcquired, Token: token}, nil
+		}
+		var mysqlErr *mysql.MySQLError
+		if !errors.As(err, &mysqlErr) || mysqlErr.Number != mysqlErrDuplicateEntry {
+			return Claim{}, fmt.Errorf("failed to claim idempotency key (key=%s): %w", key, err)
+		}
+
+		// 期限切れの行は条件付きUPDATEで引き継ぐ (同時に引き継ごうとしても更新できるのは1つだけ)
+		result, err := s.db.ExecContext(ctx,
+			"UPDATE idempotency_keys SET status = 'processing', token = ?, expires_at = ? WHERE idempotency_key = ? AND expires_at <= ?",
+			token, now.Add(lease), key, now,
+		)
+		if err != nil {
+			return Claim{}, fmt.Errorf("failed to take over idempotency key (key=%s): %w", key, err)
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return Claim{}, fmt.Errorf("failed to get rows affected: %w", err)
+		} else if n == 1 {
+			return Claim{Key: key, Status: StatusAcquired, Token: token}, nil
+		}
+
+		var status string
+		err = s.db.QueryRowContext(ctx, "SELECT status FROM idempotency_keys WHERE idempotency_key = ?", key).Scan(&status)
+		if errors.Is(err, sql.ErrNoRows) {
+			// 確認するまでの間に解放された場合は取得をやり直す
+			continue
+		}
+		if err != nil {
+			return Claim{}, fmt.Errorf("failed to get idempotency key status (key=%s): %w", key, err)
+		}
+		if status == "completed" {
+			return Claim{Key: key, Status: StatusCompleted}, nil
+		}
+		return Claim{Key: key, Status: StatusInProgress}, nil
+	}
+	return Claim{Key: key, Status: StatusInProgress}, nil
+}
+
+// Complete は行を処理済みにし、保持期限を設定します。
+func (s *SQLStore) Complete(ctx context.Context, claim Claim, ttl time.Duration) error {
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE idempotency_keys SET status = 'completed', expires_at = ? WHERE idempotency_key = ? AND token = ?",
+		time.Now().UTC().Add(ttl), claim.Key, claim.Token,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key (key=%s): %w", claim.Key, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if n == 0 {
+		return ErrClaimLost
+	}
+	return nil
+}
+
+// Release は処理中の行を削除します。
+func (s *SQLStore) Release(ctx context.Context, claim Claim) error {
+	_, err := s.db.ExecContext(ctx,
+		"DELETE FROM idempotency_keys WHERE idempotency_key = ? AND token = ? AND status = 'processing'",
+		claim.Key, claim.Token,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key (key=%s): %w", claim.Key, err)
+	}
+	return nil
+}
+
+// Prune は before より前に期限切れになった行を削除し、削除した件数を返します。
+// 期限切れの行は Claim で引き継がれるまで残るため、定期的に呼び出して削除します。
+func (s *SQLStore) Prune(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at < ?", before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune idempotency keys (before=%s): %w", before.Format(time.RFC3339), err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,147 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/clock"
+)
+
+// memoryPruneInterval はMemoryStoreが期限切れのキーをまとめて削除する間隔
+const memoryPruneInterval = time.Minute
+
+// ErrClaimLost は実行権のリース期限が切れ、他の実行者に実行権が移った場合のエラーです。
+var ErrClaimLost = errors.New("idempotency claim lost")
+
+// Status は実行権の取得結果です。
+type Status int
+
+const (
+	// StatusAcquired は実行権を取得したことを表します。タスクを実行してください。
+	StatusAcquired Status = iota + 1
+	// StatusInProgress は他の実行者が同じキーを処理中であることを表します。
+	StatusInProgress
+	// StatusCompleted は同じキーが処理済みであることを表します。タスクは実行しません。
+	StatusCompleted
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusAcquired:
+		return "acquired"
+	case StatusInProgress:
+		return "in_progress"
+	case StatusCompleted:
+		return "completed"
+	}
+	return "unknown"
+}
+
+// Claim は実行権の取得結果です。
+type Claim struct {
+	Key    string
+	Status Status
+	// Token は取得した実行権の識別子で、Status が StatusAcquired の場合のみ設定されます。
+	// リース期限切れで他の実行者に実行権が移った後に、元の実行者が完了や解放を記録しないために使用します。
+	Token string
+}
+
+// Store は処理済みのキーを記録し、同じキーの二重実行を防ぐインターフェースです。
+type Store interface {
+	// Claim はキーの実行権を取得します。
+	// 未処理のキー、またはリース期限や保持期限を過ぎたキーの場合は StatusAcquired を返し、lease の間は実行権を保持します。
+	Claim(ctx context.Context, key string, lease time.Duration) (Claim, error)
+	// Complete は実行権を処理済みにし、ttl の間は同じキーを StatusCompleted とします。
+	// 実行権が他の実行者に移っていた場合は ErrClaimLost を返します。
+	Complete(ctx context.Context, claim Claim, ttl time.Duration) error
+	// Release は処理中の実行権を解放し、同じキーを再び実行できるようにします。
+	Release(ctx context.Context, claim Claim) error
+}
+
+// MemoryStore はキーをメモリで管理するStoreです。
+// 同一プロセス内の重複のみを排除できます。複数プロセスで重複を排除する場合は SQLStore を使用します。
+type MemoryStore struct {
+	clock clock.Clock
+
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastPrune time.Time
+}
+
+type memoryEntry struct {
+	status    Status
+	token     string
+	expiresAt time.Time
+}
+
+// MemoryStoreOption はMemoryStoreの設定を変更する関数型です。
+type MemoryStoreOption func(*MemoryStore)
+
+// WithMemoryStoreClock は期限の判定に使用する Clock を設定します (デフォルト: 実時間)。
+func WithMemoryStoreClock(c clock.Clock) MemoryStoreOption {
+	return func(s *MemoryStore) {
+		s.clock = c
+	}
+}
+
+// NewMemoryStore は空のMemoryStoreを生成します。
+func NewMemoryStore(opts ...MemoryStoreOption) *MemoryStore {
+	s := &MemoryStore{clock: clock.New(), entries: make(map[string]*memoryEntry)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Claim はキーの実行権を取得します。
+func (s *MemoryStore) Claim(ctx context.Context, key string, lease time.Duration) (Claim, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
+	s.pruneLocked(now)
+	if e, ok := s.entries[key]; ok && now.Before(e.expiresAt) {
+		return Claim{Key: key, Status: e.status}, nil
+	}
+	token := uuid.NewString()
+	s.entries[key] = &memoryEntry{status: StatusInProgress, token: token, expiresAt: now.Add(lease)}
+	return Claim{Key: key, Status: StatusAcquired, Token: token}, nil
+}
+
+// Complete は実行権を処理済みにします。
+func (s *MemoryStore) Complete(ctx context.Context, claim Claim, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[claim.Key]
+	if !ok || e.token != claim.Token {
+		return ErrClaimLost
+	}
+	e.status = StatusCompleted
+	e.expiresAt = s.clock.Now().Add(ttl)
+	return nil
+}
+
+// Release は処理中の実行権を解放します。
+func (s *MemoryStore) Release(ctx context.Context, claim Claim) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[claim.Key]; ok && e.token == claim.Token && e.status == StatusInProgress {
+		delete(s.entries, claim.Key)
+	}
+	return nil
+}
+
+// pruneLocked は期限切れのキーを memoryPruneInterval ごとに削除し、キーが増え続けないようにします。
+func (s *MemoryStore) pruneLocked(now time.Time) {
+	if now.Sub(s.lastPrune) < memoryPruneInterval {
+		return
+	}
+	s.lastPrune = now
+	for key, e := range s.entries {
+		if !now.Before(e.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ErrJobDiscarded はジョブが実行されずに破棄された場合のエラー
+// Run に渡したコンテキストのキャンセルや、Run 前の Shutdown でキューに残っていたジョブが該当します。
+var ErrJobDiscarded = errors.New("job was discarded before execution")
+
+// JobResult はジョブに含まれるタスクの最終結果の一覧です。
+type JobResult struct {
+	// JobID はジョブの識別子です。
+	JobID string
+	// Tasks は投入した順のタスクの最終結果です。
+	Tasks []TaskResult
+}
+
+// Succeeded はすべてのタスクが成功したかどうかを返します。
+func (r JobResult) Succeeded() bool {
+	for _, t := range r.Tasks {
+		if !t.Succeeded() {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed は失敗したタスクの結果を返します。
+func (r JobResult) Failed() []TaskResult {
+	var failed []TaskResult
+	for _, t := range r.Tasks {
+		if !t.Succeeded() {
+			failed = append(failed, t)
+		}
+	}
+	return failed
+}
+
+// Err は失敗したタスクのエラーを errors.Join でまとめて返します。すべて成功した場合は nil です。
+// errors.Is / errors.As で個々のタスクのエラーを判定できます。
+func (r JobResult) Err() error {
+	var errs []error
+	for i, t := range r.Tasks {
+		if t.Err != nil {
+			errs = append(errs, fmt.Errorf("task %d (taskType=%s): %w", i, t.TaskType, t.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// JobCallback はジョブの完了通知を受け取る関数型です。
+type JobCallback func(result JobResult)
+
+// JobHandle は Submit で投入したジョブの完了を待機・通知するためのハンドルです。
+// 使用例:
+//
+//	handle, err := w.Submit(ctx, []worker.Task{task})
+//	if err != nil {
+//		return err
+//	}
+//	result, err := handle.Wait(ctx) // err はコンテキストの終了、またはタスクのエラー
+type JobHandle struct {
+	id   string
+	done chan struct{}
+
+	mu        sync.Mutex
+	result    JobResult
+	completed bool
+	onSuccess []JobCallback
+	onFailure []JobCallback
+}
+
+func newJobHandle(id string) *JobHandle {
+	return &JobHandle{id: id, done: make(chan struct{})}
+}
+
+// ID はジョブの識別子を返します。
+func (h *JobHandle) ID() string {
+	return h.id
+}
+
+// Done はジョブが完了したときにクローズされるチャネルを返します。
+func (h *JobHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Wait はジョブの完了を待機し、結果を返します。
+// コンテキストが先に終了した場合はゼロ値とコンテキストのエラーを返します (ジョブの実行は継続します)。
+// ジョブが完了した場合は、失敗したタスクがあれば JobResult.Err() のエラーを併せて返します。
+func (h *JobHandle) Wait(ctx context.Context) (JobResult, error) {
+	select {
+	case <-h.done:
+		result, _ := h.Result()
+		return result, result.Err()
+	case <-ctx.Done():
+		return JobResult{}, ctx.Err()
+	}
+}
+
+// Result は完了したジョブの結果を返します。未完了の場合は false を返します。
+func (h *JobHandle) Result() (JobResult, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.result, h.completed
+}
+
+// OnSuccess はすべてのタスクが成功した場合に呼び出すコールバックを登録します。
+// 完了済みの場合は登録時に呼び出し元のゴルーチンで即座に呼び出します。
+// 未完了の場合はジョブを実行したゴルーチンで呼び出すため、時間のかかる処理は別のゴルーチンで行ってください。
+func (h *JobHandle) OnSuccess(fn JobCallback) *JobHandle {
+	h.register(fn, true)
+	return h
+}
+
+// OnFailure はいずれかのタスクが失敗した場合 (破棄された場合を含む) に呼び出すコールバックを登録します。
+// 呼び出されるゴルーチンは OnSuccess と同じです。
+func (h *JobHandle) OnFailure(fn JobCallback) *JobHandle {
+	h.register(fn, false)
+	return h
+}
+
+func (h *JobHandle) register(fn JobCallback, onSuccess bool) {
+	h.mu.Lock()
+	if !h.completed {
+		if onSuccess {
+			h.onSuccess = append(h.onSuccess, fn)
+		} else {
+			h.onFailure = append(h.onFailure, fn)
+		}
+		h.mu.Unlock()
+		return
+	}
+	result := h.result
+	h.mu.Unlock()
+
+	if result.Succeeded() == onSuccess {
+		h.invoke(fn, result)
+	}
+}
+
+// complete は結果を確定し、待機中の呼び出し元とコールバックに通知します。2回目以降の呼び出しは無視します。
+func (h *JobHandle) complete(tasks []TaskResult) {
+	h.mu.Lock()
+	if h.completed {
+		h.mu.Unlock()
+		return
+	}
+	h.result = JobResult{JobID: h.id, Tasks: tasks}
+	h.completed = true
+	callbacks := h.onFailure
+	if h.result.Succeeded() {
+		callbacks = h.onSuccess
+	}
+	h.onSuccess, h.onFailure = nil, nil
+	result := h.result
+	h.mu.Unlock()
+
+	close(h.done)
+	for _, fn := range callbacks {
+		h.invoke(fn, result)
+	}
+}
+
+// invoke はコールバックを呼び出します。コールバックのpanicはWorkerを止めないよう回復してログに記録します。
+func (h *JobHandle) invoke(fn JobCallback, result JobResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("job callback panicked", "jobID", h.id, "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+	fn(result)
+}
+
+// discard は実行されなかったジョブを ErrJobDiscarded で完了させます。
+func (j job) discard() {
+	if j.handle == nil {
+		return
+	}
+	results := make([]TaskResult, len(j.task))
+	for i, task := range j.task {
+		results[i] = TaskResult{Task: task, TaskType: taskTypeOf(task), Err: ErrJobDiscarded}
+	}
+	j.handle.complete(results)
+}
+
+// JobOption は Submit で投入するジョブの設定を変更する関数型です。
+type JobOption func(*jobOptions)
+
+type jobOptions struct {
+	id       string
+	priority Priority
+	nonBlock bool
+	key      string
+}
+
+// WithJobPriority はジョブの優先度を設定します (デフォルト: PriorityNormal)。
+func WithJobPriority(priority Priority) JobOption {
+	return func(o *jobOptions) {
+		o.priority = priority
+	}
+}
+
+// WithJobID はジョブの識別子を設定します (デフォルト: UUID)。ログや呼び出し元での突き合わせに使用します。
+func WithJobID(id string) JobOption {
+	return func(o *jobOptions) {
+		o.id = id
+	}
+}
+
+// WithNonBlocking はキューが満杯の場合に待機せず ErrJobQueueFull を返すようにします。
+func WithNonBlocking() JobOption {
+	return func(o *jobOptions) {
+		o.nonBlock = true
+	}
+}
+
+// WithRoutingKey は WorkerCluster でジョブを振り分けるキーを設定します (ユーザーIDなど)。
+// 同じキーのジョブは同じパーティションで投入順に実行されます。単体のWorkerでは無視されます。
+func WithRoutingKey(key string) JobOption {
+	return func(o *jobOptions) {
+		o.key = key
+	}
+}
+
+// newJobOptions はオプションを適用した設定を返します。
+func newJobOptions(opts []JobOption) jobOptions {
+	options := jobOptions{priority: PriorityNormal}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// Submit はジョブをキューに追加し、完了を待機するための JobHandle を返します。
+// キューが満杯の場合は空きができるか ctx が終了するまで待機します (WithNonBlocking の場合は ErrJobQueueFull)。
+// ctx はキューへの追加にのみ使用し、タスクの実行には Run に渡したコンテキストを使用します。
+func (w *worker) Submit(ctx context.Context, tasks []Task, opts ...JobOption) (*JobHandle, error) {
+	options := newJobOptions(opts)
+	if options.id == "" {
+		options.id = uuid.NewString()
+	}
+
+	handle := newJobHandle(options.id)
+	if err := w.enqueue(ctx, options.priority, job{task: tasks, handle: handle}, !options.nonBlock); err != nil {
+		return nil, err
+	}
+	return handle, nil
+}
+
+// enqueue はジョブを優先度のレーンに追加します。
+// block が true の場合はレーンに空きができるか、停止処理が始まるか、ctx が終了するまで待機します。
+func (w *worker) enqueue(ctx context.Context, priority Priority, j job, block bool) error {
+	if !priority.valid() {
+		return fmt.Errorf("%w: %d", ErrInvalidPriority, priority)
+	}
+	w.state.sendMu.RLock()
+	defer w.state.sendMu.RUnlock()
+	if !w.state.acceptsJobs() {
+		return ErrWorkerStopped
+	}
+	if !block {
+		select {
+		case w.lanes.queues[priority] <- j:
+			return nil
+		default:
+			return ErrJobQueueFull
+		}
+	}
+	select {
+	case w.lanes.queues[priority] <- j:
+		return nil
+	case <-w.state.draining:
+		return ErrWorkerStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,297 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks"
+)
+
+// TestWorker_SubmitWait は Wait でタスクごとの結果とエラーを受け取れることを確認します。
+func TestWorker_SubmitWait(t *testing.T) {
+	t.Parallel()
+
+	errBoom := errors.New("boom")
+	tests := []struct {
+		name          string
+		tasks         []worker.Task
+		wantSucceeded bool
+		wantErrs      []error
+		wantAttempts  []int
+	}{
+		{
+			name:          "正常系: すべて成功",
+			tasks:         []worker.Task{noopTask(), noopTask()},
+			wantSucceeded: true,
+			wantErrs:      []error{nil, nil},
+			wantAttempts:  []int{1, 1},
+		},
+		{
+			name:          "正常系: 再試行で成功した試行回数が分かる",
+			tasks:         []worker.Task{failingTask(2, errBoom)},
+			wantSucceeded: true,
+			wantErrs:      []error{nil},
+			wantAttempts:  []int{3},
+		},
+		{
+			name:          "異常系: 一部のタスクが失敗",
+			tasks:         []worker.Task{noopTask(), failingTask(5, worker.Permanent(errBoom))},
+			wantSucceeded: false,
+			wantErrs:      []error{nil, errBoom},
+			wantAttempts:  []int{1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			w := worker.NewWorker(worker.WithRetryPolicy(fastRetry))
+			w.Run(context.Background())
+			defer w.Shutdown(context.Background())
+
+			handle, err := w.Submit(context.Background(), tt.tasks, worker.WithJobID("job-1"))
+			if err != nil {
+				t.Fatalf("Submit() error = %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			result, err := handle.Wait(ctx)
+
+			if result.JobID != "job-1" || handle.ID() != "job-1" {
+				t.Errorf("JobID = %q, ID() = %q, want job-1", result.JobID, handle.ID())
+			}
+			if result.Succeeded() != tt.wantSucceeded {
+				t.Errorf("Succeeded() = %v, want %v", result.Succeeded(), tt.wantSucceeded)
+			}
+			if tt.wantSucceeded && err != nil {
+				t.Errorf("Wait() error = %v, want nil", err)
+			}
+			if len(result.Tasks) != len(tt.tasks) {
+				t.Fatalf("len(Tasks) = %d, want %d", len(result.Tasks), len(tt.tasks))
+			}
+			for i, r := range result.Tasks {
+				if !errors.Is(r.Err, tt.wantErrs[i]) || (tt.wantErrs[i] == nil) != (r.Err == nil) {
+					t.Errorf("Tasks[%d].Err = %v, want %v", i, r.Err, tt.wantErrs[i])
+				}
+				if r.Attempts != tt.wantAttempts[i] {
+					t.Errorf("Tasks[%d].Attempts = %d, want %d", i, r.Attempts, tt.wantAttempts[i])
+				}
+				if tt.wantErrs[i] != nil && !errors.Is(err, tt.wantErrs[i]) {
+					t.Errorf("Wait() error = %v, want it to wrap %v", err, tt.wantErrs[i])
+				}
+			}
+			if got := len(result.Failed()); got != len(tt.tasks)-countNil(tt.wantErrs) {
+				t.Errorf("len(Failed()) = %d", got)
+			}
+		})
+	}
+}
+
+func countNil(errs []error) int {
+	n := 0
+	for _, err := range errs {
+		if err == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// TestJobHandle_Callbacks は成功・失敗に応じたコールバックが一度だけ呼び出されることを確認します。
+func TestJobHandle_Callbacks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		task        worker.Task
+		wantSuccess int
+		wantFailure int
+	}{
+		{name: "正常系: 成功時は OnSuccess", task: noopTask(), wantSuccess: 2, wantFailure: 0},
+		{name: "異常系: 失敗時は OnFailure", task: failingTask(1, errors.New("boom")), wantSuccess: 0, wantFailure: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			// 実行前にコールバックを登録するため、Run 前に投入する
+			w := worker.NewWorker()
+
+			var mu sync.Mutex
+			success, failure := 0, 0
+			count := func(n *int) worker.JobCallback {
+				return func(result worker.JobResult) {
+					mu.Lock()
+					defer mu.Unlock()
+					*n++
+				}
+			}
+
+			handle, err := w.Submit(context.Background(), []worker.Task{tt.task})
+			if err != nil {
+				t.Fatalf("Submit() error = %v", err)
+			}
+			handle.OnSuccess(count(&success)).OnFailure(count(&failure))
+
+			w.Run(context.Background())
+			<-handle.Done()
+			// 完了後に登録したコールバックは即座に呼び出される
+			handle.OnSuccess(count(&success)).OnFailure(count(&failure))
+			if err := w.Shutdown(context.Background()); err != nil {
+				t.Fatalf("Shutdown() error = %v", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if success != tt.wantSuccess || failure != tt.wantFailure {
+				t.Errorf("success = %d, failure = %d, want %d, %d", success, failure, tt.wantSuccess, tt.wantFailure)
+			}
+		})
+	}
+}
+
+// TestJobHandle_CallbackPanic はコールバックのpanicがWorkerを止めないことを確認します。
+func TestJobHandle_CallbackPanic(t *testing.T) {
+	t.Parallel()
+
+	w := worker.NewWorker()
+	handle, err := w.Submit(context.Background(), []worker.Task{noopTask()})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	handle.OnSuccess(func(worker.JobResult) { panic("callback failed") })
+	w.Run(context.Background())
+	<-handle.Done()
+
+	next, err := w.Submit(context.Background(), []worker.Task{noopTask()})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := next.Wait(ctx); err != nil {
+		t.Errorf("Wait() error = %v", err)
+	}
+	w.Shutdown(context.Background())
+}
+
+// TestJobHandle_WaitTimeout は完了前に Wait のコンテキストが終了した場合にエラーを返すことを確認します。
+func TestJobHandle_WaitTimeout(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	w := worker.NewWorker()
+	w.Run(context.Background())
+	handle, err := w.Submit(context.Background(), []worker.Task{tasks.NewTask(func(ctx context.Context) error {
+		<-release
+		return nil
+	})})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := handle.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, want DeadlineExceeded", err)
+	}
+	if _, ok := handle.Result(); ok {
+		t.Error("Result() ok = true before completion")
+	}
+
+	// ジョブは Wait のタイムアウト後も実行を継続する
+	close(release)
+	if _, err := handle.Wait(context.Background()); err != nil {
+		t.Errorf("Wait() error = %v after release", err)
+	}
+	w.Shutdown(context.Background())
+}
+
+// TestWorker_SubmitDiscarded は実行されずに破棄されたジョブが ErrJobDiscarded で完了することを確認します。
+func TestWorker_SubmitDiscarded(t *testing.T) {
+	t.Parallel()
+
+	w := worker.NewWorker()
+	handle, err := w.Submit(context.Background(), []worker.Task{noopTask(), noopTask()}, worker.WithJobPriority(worker.PriorityLow))
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	// Run 前の Shutdown はキューに残ったジョブを破棄する
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	result, err := handle.Wait(context.Background())
+	if !errors.Is(err, worker.ErrJobDiscarded) {
+		t.Errorf("Wait() error = %v, want ErrJobDiscarded", err)
+	}
+	for i, r := range result.Tasks {
+		if r.Attempts != 0 || !errors.Is(r.Err, worker.ErrJobDiscarded) {
+			t.Errorf("Tasks[%d] = %+v, want discarded without attempts", i, r)
+		}
+	}
+}
+
+// TestWorker_SubmitErrors は投入できない場合のエラーを確認します。
+func TestWorker_SubmitErrors(t *testing.T) {
+	t.Parallel()
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		opts    []worker.JobOption
+		prepare func(w worker.Worker)
+		wantErr error
+	}{
+		{
+			name:    "異常系: 不正な優先度",
+			ctx:     context.Background(),
+			opts:    []worker.JobOption{worker.WithJobPriority(worker.Priority(99))},
+			wantErr: worker.ErrInvalidPriority,
+		},
+		{
+			name:    "異常系: 停止後",
+			ctx:     context.Background(),
+			prepare: func(w worker.Worker) { w.Shutdown(context.Background()) },
+			wantErr: worker.ErrWorkerStopped,
+		},
+		{
+			name:    "異常系: キューが満杯で待機中にコンテキストが終了",
+			ctx:     canceled,
+			prepare: func(w worker.Worker) { w.AddJob(noopTask()) },
+			wantErr: context.Canceled,
+		},
+		{
+			name:    "異常系: キューが満杯で WithNonBlocking",
+			ctx:     context.Background(),
+			opts:    []worker.JobOption{worker.WithNonBlocking()},
+			prepare: func(w worker.Worker) { w.AddJob(noopTask()) },
+			wantErr: worker.ErrJobQueueFull,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			// Run 前のため、キューの容量 (1) を超えると満杯になる
+			w := worker.NewWorker(worker.WithMaxWorkerJobs(1))
+			if tt.prepare != nil {
+				tt.prepare(w)
+			}
+			handle, err := w.Submit(tt.ctx, []worker.Task{noopTask()}, tt.opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Submit() error = %v, want %v", err, tt.wantErr)
+			}
+			if handle != nil {
+				t.Error("Submit() returned a handle on error")
+			}
+		})
+	}
+}
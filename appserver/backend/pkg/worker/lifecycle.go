@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ライフサイクルの不正な遷移で返されるエラー
+var (
+	// ErrWorkerStopped は停止処理を開始した(または停止した)Workerを操作した場合のエラー
+	ErrWorkerStopped = errors.New("worker is stopped")
+
+	// ErrWorkerAlreadyRunning は起動済みのWorkerを再度 Run した場合のエラー
+	ErrWorkerAlreadyRunning = errors.New("worker is already running")
+)
+
+type WorkerStatus int32
+
+const (
+	// WorkerStatusCreated は生成直後で Run される前の状態です。ジョブの追加は可能です。
+	WorkerStatusCreated WorkerStatus = iota
+	// WorkerStatusRunning はジョブを処理している状態です。
+	WorkerStatusRunning
+	// WorkerStatusDraining は停止処理中の状態です。新しいジョブは受け付けず、キューに残ったジョブを処理します。
+	WorkerStatusDraining
+	// WorkerStatusStopped はすべてのジョブの処理を終えて停止した状態です。
+	WorkerStatusStopped
+)
+
+func (w WorkerStatus) String() string {
+	switch w {
+	case WorkerStatusCreated:
+		return "created"
+	case WorkerStatusRunning:
+		return "running"
+	case WorkerStatusDraining:
+		return "draining"
+	case WorkerStatusStopped:
+		return "stopped"
+	}
+	return "unknown"
+}
+
+func (w WorkerStatus) IsRunning() bool {
+	return w == WorkerStatusRunning
+}
+
+func (w WorkerStatus) IsDraining() bool {
+	return w == WorkerStatusDraining
+}
+
+func (w WorkerStatus) IsStopped() bool {
+	return w == WorkerStatusStopped
+}
+
+// lifecycle はWorkerの状態遷移 (created → running → draining → stopped) を管理します。
+// 状態はアトミックに遷移し、後戻りしません。
+type lifecycle struct {
+	status atomic.Int32
+	// sendMu はジョブキューへの送信(RLock)とクローズ(Lock)を排他し、クローズ済みチャネルへの送信を防ぐ
+	sendMu sync.RWMutex
+	// draining は停止処理の開始時にクローズされ、キューの空きを待っている AddJob を解放する
+	draining chan struct{}
+	// stopped は停止完了時にクローズされる
+	stopped chan struct{}
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{
+		draining: make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+func (l *lifecycle) load() WorkerStatus {
+	return WorkerStatus(l.status.Load())
+}
+
+// transition は状態が from の場合のみ to に遷移し、遷移できたかどうかを返します。
+func (l *lifecycle) transition(from, to WorkerStatus) bool {
+	return l.status.CompareAndSwap(int32(from), int32(to))
+}
+
+// acceptsJobs はジョブを受け付けられる状態かどうかを返します。
+func (l *lifecycle) acceptsJobs() bool {
+	status := l.load()
+	return status == WorkerStatusCreated || status == WorkerStatusRunning
+}
+
+// markStopped は停止状態に遷移し、停止完了を通知します。
+func (l *lifecycle) markStopped() {
+	l.status.Store(int32(WorkerStatusStopped))
+	close(l.stopped)
+}
@@ -0,0 +1,259 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks"
+)
+
+// このファイルのテストは go test -race で実行することを前提としています。
+
+func noopTask() worker.Task {
+	return tasks.NewTask(func(ctx context.Context) error { return nil })
+}
+
+// TestWorker_LifecycleTransitions は created → running → stopped の遷移と不正な遷移のエラーを確認します。
+func TestWorker_LifecycleTransitions(t *testing.T) {
+	t.Parallel()
+
+	w := worker.NewWorker()
+	if got := w.Status(); got != worker.WorkerStatusCreated {
+		t.Fatalf("expected created, got %s", got)
+	}
+
+	if err := w.Run(context.Background()); err != nil {
+		t.Fatalf("failed to run: %v", err)
+	}
+	if got := w.Status(); got != worker.WorkerStatusRunning || !w.IsRunning() {
+		t.Fatalf("expected running, got %s", got)
+	}
+	if err := w.Run(context.Background()); !errors.Is(err, worker.ErrWorkerAlreadyRunning) {
+		t.Errorf("expected ErrWorkerAlreadyRunning, got %v", err)
+	}
+
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+	if got := w.Status(); got != worker.WorkerStatusStopped || w.IsRunning() {
+		t.Fatalf("expected stopped, got %s", got)
+	}
+	if err := w.Run(context.Background()); !errors.Is(err, worker.ErrWorkerStopped) {
+		t.Errorf("expected ErrWorkerStopped on run after shutdown, got %v", err)
+	}
+	// 2回目の Shutdown も安全に完了する
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Errorf("expected second shutdown to succeed, got %v", err)
+	}
+}
+
+// TestWorker_AddJobAfterShutdown は停止後のジョブ追加がpanicせずにエラーを返すことを確認します。
+func TestWorker_AddJobAfterShutdown(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		start bool
+	}{
+		{name: "異常系: Run後にShutdownしたWorker", start: true},
+		{name: "異常系: Runせずに Shutdown したWorker", start: false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := worker.NewWorker()
+			if tt.start {
+				w.Run(context.Background())
+			}
+			if err := w.Shutdown(context.Background()); err != nil {
+				t.Fatalf("failed to shutdown: %v", err)
+			}
+			if got := w.Status(); got != worker.WorkerStatusStopped {
+				t.Errorf("expected stopped, got %s", got)
+			}
+			if err := w.AddJob(noopTask()); !errors.Is(err, worker.ErrWorkerStopped) {
+				t.Errorf("expected ErrWorkerStopped from AddJob, got %v", err)
+			}
+			if err := w.AddJobAsync(noopTask()); !errors.Is(err, worker.ErrWorkerStopped) {
+				t.Errorf("expected ErrWorkerStopped from AddJobAsync, got %v", err)
+			}
+		})
+	}
+}
+
+// TestWorker_ShutdownDrainsQueue は Shutdown がキューに残ったジョブを処理してから停止することを確認します。
+func TestWorker_ShutdownDrainsQueue(t *testing.T) {
+	t.Parallel()
+
+	w := worker.NewWorker(worker.WithRunningWorkers(1), worker.WithMaxWorkerJobs(20))
+	// Run 前に追加したジョブも処理される
+	var executed atomic.Int32
+	for i := 0; i < 20; i++ {
+		if err := w.AddJob(tasks.NewTask(func(ctx context.Context) error {
+			executed.Add(1)
+			return nil
+		})); err != nil {
+			t.Fatalf("failed to add job: %v", err)
+		}
+	}
+	w.Run(context.Background())
+
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+	if got := executed.Load(); got != 20 {
+		t.Errorf("expected 20 executed jobs, got %d", got)
+	}
+}
+
+// TestWorker_ShutdownReleasesBlockedAddJob はキューの空きを待っている AddJob が停止処理で解放されることを確認します。
+func TestWorker_ShutdownReleasesBlockedAddJob(t *testing.T) {
+	t.Parallel()
+
+	// Run 前のWorkerはジョブを取り出さないため、1件でキューが満杯になる
+	w := worker.NewWorker(worker.WithMaxWorkerJobs(1))
+	if err := w.AddJob(noopTask()); err != nil {
+		t.Fatalf("failed to add job: %v", err)
+	}
+
+	addErr := make(chan error, 1)
+	go func() { addErr <- w.AddJob(noopTask()) }()
+	select {
+	case err := <-addErr:
+		t.Fatalf("expected AddJob to block on a full queue, got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+	select {
+	case err := <-addErr:
+		if !errors.Is(err, worker.ErrWorkerStopped) {
+			t.Errorf("expected ErrWorkerStopped, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("blocked AddJob was not released by shutdown")
+	}
+}
+
+// TestWorker_ShutdownTimeout は実行中のジョブが終わらない場合に Shutdown がコンテキストのエラーを返すことを確認します。
+func TestWorker_ShutdownTimeout(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	w := worker.NewWorker()
+	w.Run(context.Background())
+	w.AddJob(tasks.NewTask(func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := w.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+	if got := w.Status(); got != worker.WorkerStatusDraining {
+		t.Errorf("expected draining, got %s", got)
+	}
+}
+
+// TestWorker_RunContextCanceled は Run のコンテキストがキャンセルされると停止することを確認します。
+func TestWorker_RunContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := worker.NewWorker()
+	w.Run(ctx)
+	cancel()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Second)
+	defer shutdownCancel()
+	if err := w.Shutdown(shutdownCtx); err != nil {
+		t.Fatalf("failed to wait for stop: %v", err)
+	}
+	if err := w.AddJob(noopTask()); !errors.Is(err, worker.ErrWorkerStopped) {
+		t.Errorf("expected ErrWorkerStopped, got %v", err)
+	}
+}
+
+// TestWorker_ConcurrentAddShutdownStatus は AddJob/AddJobAsync/Status と Shutdown を並行に呼び出しても
+// データ競合やpanicが起きず、受け付けたジョブがすべて実行されることを確認します。
+func TestWorker_ConcurrentAddShutdownStatus(t *testing.T) {
+	t.Parallel()
+
+	for round := 0; round < 20; round++ {
+		w := worker.NewWorker(worker.WithRunningWorkers(4), worker.WithMaxWorkerJobs(8))
+		w.Run(context.Background())
+
+		var accepted, executed atomic.Int32
+		task := tasks.NewTask(func(ctx context.Context) error {
+			executed.Add(1)
+			return nil
+		})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(3)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					if err := w.AddJob(task); err == nil {
+						accepted.Add(1)
+					} else if !errors.Is(err, worker.ErrWorkerStopped) {
+						t.Errorf("unexpected AddJob error: %v", err)
+					}
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					if err := w.AddJobAsync(task); err == nil {
+						accepted.Add(1)
+					} else if !errors.Is(err, worker.ErrWorkerStopped) && !errors.Is(err, worker.ErrJobQueueFull) {
+						t.Errorf("unexpected AddJobAsync error: %v", err)
+					}
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					_ = w.Status().String()
+					_ = w.IsRunning()
+					_ = w.QueueLengths()
+				}
+			}()
+		}
+
+		shutdownErrs := make(chan error, 2)
+		for i := 0; i < 2; i++ {
+			go func() { shutdownErrs <- w.Shutdown(context.Background()) }()
+		}
+		wg.Wait()
+		for i := 0; i < 2; i++ {
+			if err := <-shutdownErrs; err != nil {
+				t.Fatalf("failed to shutdown: %v", err)
+			}
+		}
+
+		if w.Status() != worker.WorkerStatusStopped {
+			t.Fatalf("expected stopped, got %s", w.Status())
+		}
+		if accepted.Load() != executed.Load() {
+			t.Fatalf("round %d: accepted %d jobs but executed %d", round, accepted.Load(), executed.Load())
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"context"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/metrics"
+)
+
+// untypedTaskLabel は TypedTask を実装していないタスクの task_type ラベル値
+const untypedTaskLabel = "untyped"
+
+// WithMetrics はWorkerのメトリクスを reg に登録します。
+// 複数のWorkerで同じ reg を指定した場合は、worker ラベル (WithName の値) で区別します。
+//   - worker_queue_length{worker, priority}: 優先度ごとのキューで待機しているジョブ数
+//   - worker_tasks_in_flight{worker}: 実行中のジョブ数
+//   - worker_pool_size{worker}: 同時実行数の上限
+//   - worker_tasks_total{worker, task_type, status}: 最終結果が確定したタスク数 (status は succeeded または failed)
+//   - worker_task_duration_seconds{worker, task_type}: タスクの処理時間 (再試行を含む)
+//   - worker_tasks_dead_lettered_total{worker, task_type}: デッドレターに送信したタスク数
+func WithMetrics(reg *metrics.Registry) WorkerOption {
+	return func(w *worker) {
+		w.metrics = reg
+	}
+}
+
+// registerMetrics はキューの長さと実行中のジョブ数を収集時に取得するゲージと、タスクの結果を集計するフックを登録する
+func (w *worker) registerMetrics(reg *metrics.Registry) {
+	queueLength := reg.GaugeVec("worker_queue_length", "Number of jobs waiting in the worker queue by priority.", "worker", "priority")
+	for _, p := range Priorities() {
+		queueLength.Func(func() float64 { return float64(len(w.lanes.queues[p])) }, w.name, p.String())
+	}
+	reg.GaugeVec("worker_tasks_in_flight", "Number of jobs currently being executed by the worker.", "worker").
+		Func(func() float64 { return float64(w.Stats().Active) }, w.name)
+	reg.GaugeVec("worker_pool_size", "Current concurrency limit of the worker pool.", "worker").
+		Func(func() float64 { return float64(w.Stats().Size) }, w.name)
+
+	tasks := reg.CounterVec("worker_tasks_total", "Total number of tasks completed by the worker by task type and status.", "worker", "task_type", "status")
+	duration := reg.HistogramVec("worker_task_duration_seconds", "Task execution time in seconds including retries.", nil, "worker", "task_type")
+	deadLettered := reg.CounterVec("worker_tasks_dead_lettered_total", "Total number of failed tasks sent to the dead letter sink.", "worker", "task_type")
+	w.resultHooks = append(w.resultHooks, func(ctx context.Context, result TaskResult) {
+		taskType := result.TaskType
+		if taskType == "" {
+			taskType = untypedTaskLabel
+		}
+		status := "succeeded"
+		if !result.Succeeded() {
+			status = "failed"
+		}
+		tasks.WithLabelValues(w.name, taskType, status).Inc()
+		duration.WithLabelValues(w.name, taskType).Observe(result.Duration.Seconds())
+		if result.DeadLettered {
+			deadLettered.WithLabelValues(w.name, taskType).Inc()
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/metrics"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks"
+)
+
+// TestWithMetrics はタスクの結果と処理時間がタスク種別ごとに集計され、
+// キューの長さと実行中のジョブ数が取得時点の値で出力されることを確認します。
+func TestWithMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	w := worker.NewWorker(
+		worker.WithName("metrics-worker"),
+		worker.WithSerialExecution(),
+		worker.WithRetryPolicy(worker.RetryPolicy{MaxAttempts: 1}),
+		worker.WithMetrics(reg),
+	)
+	if err := w.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	defer w.Shutdown(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	handle, err := w.Submit(ctx, []worker.Task{
+		&typedTask{taskType: "mail", fn: func(ctx context.Context) error { return nil }},
+		&typedTask{taskType: "mail", fn: func(ctx context.Context) error { return nil }},
+		&typedTask{taskType: "mail", fn: func(ctx context.Context) error { return errors.New("failed") }},
+		tasks.NewTask(func(ctx context.Context) error { return nil }),
+	})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	// 失敗したタスクを含むため、Wait のエラーではなく完了のみを待つ
+	select {
+	case <-handle.Done():
+	case <-ctx.Done():
+		t.Fatal("timeout waiting for job")
+	}
+
+	// 実行中のジョブが終わるまで、後から投入したジョブはキューで待機する
+	started := make(chan struct{})
+	release := make(chan struct{})
+	if err := w.AddJob(tasks.NewTask(func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})); err != nil {
+		t.Fatalf("AddJob() error = %v", err)
+	}
+	<-started
+	if err := w.AddJobWithPriority(worker.PriorityLow, tasks.NewTask(func(ctx context.Context) error { return nil })); err != nil {
+		t.Fatalf("AddJobWithPriority() error = %v", err)
+	}
+
+	var b strings.Builder
+	if err := reg.WriteText(&b); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	close(release)
+
+	got := b.String()
+	for _, want := range []string{
+		`worker_tasks_total{worker="metrics-worker",task_type="mail",status="succeeded"} 2`,
+		`worker_tasks_total{worker="metrics-worker",task_type="mail",status="failed"} 1`,
+		`worker_tasks_total{worker="metrics-worker",task_type="untyped",status="succeeded"} 1`,
+		`worker_task_duration_seconds_count{worker="metrics-worker",task_type="mail"} 3`,
+		`worker_tasks_in_flight{worker="metrics-worker"} 1`,
+		`worker_queue_length{worker="metrics-worker",priority="low"} 1`,
+		`worker_queue_length{worker="metrics-worker",priority="high"} 0`,
+		`worker_pool_size{worker="metrics-worker"} 1`,
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("metrics does not contain %q\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Package outbox はトランザクショナルアウトボックスでイベントをSQSに確実に送信します。
+// イベントは業務データの更新と同じトランザクションで outbox テーブルに書き込み (Insert)、
+// Relay が未送信の行をSQSに送信して送信済みにします。
+// DBの更新とSQSへの送信の間でプロセスが停止してもイベントは失われず、少なくとも1回は送信されます (重複はあり得ます)。
+//
+// 使用例:
+//
+//	tx, _ := db.BeginTx(ctx, nil)
+//	tx.ExecContext(ctx, "INSERT INTO users ...")
+//	msg, _ := outbox.NewMessage(envelope, userID)
+//	outbox.Insert(ctx, tx, msg)
+//	tx.Commit()
+//
+//	relay := outbox.NewRelay(outbox.NewSQLStore(db), client, queueURL)
+//	s.Schedule("outbox.relay", scheduler.Every(5*time.Second), relay)
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/trace"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks"
+)
+
+// Message は outbox テーブルの1行で、SQSに送信する1件のイベントです。
+type Message struct {
+	// ID はイベントID (エンベロープのID) で、FIFOキューの重複排除IDにも使用します。
+	ID string
+	// EventType はイベント種別 (エンベロープの type) です。
+	EventType string
+	// MessageGroupID はFIFOキューのメッセージグループIDです (ユーザーIDなど)。
+	MessageGroupID string
+	// Body はSQSメッセージの本文 (エンベロープのJSON) です。
+	Body string
+	// Attempts は送信に失敗した回数です。
+	Attempts int
+	// TraceParent はイベントを書き込んだ処理のトレースコンテキストです。
+	// 送信時にメッセージ属性に設定し、受信したタスクが同じトレースを引き継げるようにします。
+	TraceParent string
+}
+
+// NewMessage はエンベロープから outbox の行を生成します。
+// 引数:
+//   - e: 送信するイベントのエンベロープ
+//   - groupID: FIFOキューのメッセージグループID (同じグループのイベントは送信順に処理される)
+func NewMessage(e *tasks.Envelope, groupID string) (Message, error) {
+	body, err := e.Marshal()
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{ID: e.ID, EventType: e.Type, MessageGroupID: groupID, Body: body}, nil
+}
+
+// Insert は outbox の行を tx で書き込みます。
+// 業務データの更新と同じトランザクションで呼び出し、コミットされた場合のみイベントが送信されるようにします。
+// msg.TraceParent が空の場合は ctx のスパンのトレースコンテキストを記録します。
+// 注意事項: テーブルは migrations/20250701000001_create_outbox_table.sql (traceparent 列は 20250901000001) で作成する
+func Insert(ctx context.Context, tx *sql.Tx, msg Message) error {
+	if msg.TraceParent == "" {
+		msg.TraceParent = trace.TraceParentFromContext(ctx)
+	}
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO outbox (id, event_type, message_group_id, body, traceparent) VALUES (?, ?, ?, ?, ?)",
+		msg.ID, msg.EventType, msg.MessageGroupID, msg.Body, msg.TraceParent,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert outbox message (id=%s, type=%s): %w", msg.ID, msg.EventType, err)
+	}
+	return nil
+}
+
+// Store は未送信のイベントの取得と送信結果の記録を行うインターフェースです。
+type Store interface {
+	// Pending は未送信のイベントを書き込み順に最大 limit 件返します。
+	Pending(ctx context.Context, limit int) ([]Message, error)
+	// MarkSent はイベントを送信済みにします。
+	MarkSent(ctx context.Context, ids []string, sentAt time.Time) error
+	// MarkFailed は送信の失敗を記録します。イベントは未送信のまま残り、次回の Relay で再送されます。
+	MarkFailed(ctx context.Context, id string, cause error) error
+}
+
+// SQLStore は outbox テーブルでイベントを管理するStoreです。
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore はSQLStoreを生成するコンストラクタです。
+func NewSQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+// Pending は sent_at が NULL の行を seq の順に返します。
+func (s *SQLStore) Pending(ctx context.Context, limit int) ([]Message, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, event_type, message_group_id, body, traceparent, attempts FROM outbox WHERE sent_at IS NULL ORDER BY seq LIMIT ?",
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []Message
+	for rows.Next() {
+		var msg Message
+		if err := rows.Scan(&msg.ID, &msg.EventType, &msg.MessageGroupID, &msg.Body, &msg.TraceParent, &msg.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pending outbox messages: %w", err)
+	}
+	return messages, nil
+}
+
+// MarkSent は行の sent_at を設定します。
+func (s *SQLStore) MarkSent(ctx context.Context, ids []string, sentAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := make([]any, 0, len(ids)+1)
+	args = append(args, sentAt.UTC())
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	query := "UPDATE outbox SET sent_at = ? WHERE id IN (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+	if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to mark outbox messages as sent (count=%d): %w", len(ids), err)
+	}
+	return nil
+}
+
+// MarkFailed は行の attempts を増やし、last_error を記録します。
+func (s *SQLStore) MarkFailed(ctx context.Context, id string, cause error) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE outbox SET attempts = attempts + 1, last_error = ? WHERE id = ?",
+		cause.Error(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox message as failed (id=%s): %w", id, err)
+	}
+	return nil
+}
+
+// Prune は before より前に送信済みになった行を削除し、削除した件数を返します。
+// 送信済みの行は残り続けるため、定期的に呼び出して削除します。
+func (s *SQLStore) Prune(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM outbox WHERE sent_at < ?", before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune outbox messages (before=%s): %w", before.Format(time.RFC3339), err)
+	}
+	return result.RowsAffected()
+}
+
+// MemoryStore はイベントをメモリに保持するStoreです。
+// 主にテストで使用します。
+type MemoryStore struct {
+	mu       sync.Mutex
+	messages []*memoryMessage
+}
+
+type memoryMessage struct {
+	Message
+	sent      bool
+	lastError error
+}
+
+// NewMemoryStore は空のMemoryStoreを生成します。
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Add はイベントを未送信として追加します (Insert に相当)。
+func (s *MemoryStore) Add(messages ...Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, msg := range messages {
+		s.messages = append(s.messages, &memoryMessage{Message: msg})
+	}
+}
+
+// Pending は未送信のイベントを追加順に返します。
+func (s *MemoryStore) Pending(ctx context.Context, limit int) ([]Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var messages []Message
+	for _, m := range s.messages {
+		if len(messages) >= limit {
+			break
+		}
+		if !m.sent {
+			messages = append(messages, m.Message)
+		}
+	}
+	return messages, nil
+}
+
+// MarkSent はイベントを送信済みにします。
+func (s *MemoryStore) MarkSent(ctx context.Context, ids []string, sentAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sent := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		sent[id] = true
+	}
+	for _, m := range s.messages {
+		if sent[m.ID] {
+			m.sent = true
+		}
+	}
+	return nil
+}
+
+// MarkFailed は送信の失敗を記録します。
+func (s *MemoryStore) MarkFailed(ctx context.Context, id string, cause error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range s.messages {
+		if m.ID == id {
+			m.Attempts++
+			m.lastError = cause
+			return nil
+		}
+	}
+	return fmt.Errorf("outbox message not found (id=%s)", id)
+}
+
+// LastError は最後に記録された送信エラーを返します (送信に失敗していない場合は nil)。
+func (s *MemoryStore) LastError(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range s.messages {
+		if m.ID == id {
+			return m.lastError
+		}
+	}
+	return nil
+}
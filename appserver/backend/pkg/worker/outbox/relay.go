@@ -0,0 +1,161 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker"
+)
+
+const (
+	// TaskType はRelayのタスク種別名です。
+	TaskType = "outbox.relay"
+
+	// EventTypeAttribute はイベント種別を設定するSQSのメッセージ属性名です。
+	EventTypeAttribute = "eventType"
+
+	defaultRelayBatchSize = 100
+)
+
+// RelayOption はRelayの設定を変更する関数型です。
+type RelayOption func(*Relay)
+
+// WithRelayBatchSize は1回の読み出しで取得するイベント数を設定します (デフォルト: 100)。
+func WithRelayBatchSize(n int) RelayOption {
+	return func(r *Relay) {
+		if n > 0 {
+			r.batchSize = n
+		}
+	}
+}
+
+// Relay は未送信のイベントをSQSに送信して送信済みにする worker.Task です。
+// Scheduler で定期的に投入して使用します。
+//
+// 送信に成功したイベントは送信済みにし、失敗したイベントは失敗を記録して次回の実行で再送します。
+// SQSへの送信後、送信済みにする前にプロセスが停止した場合も再送するため、イベントは重複して届くことがあります。
+// 受信側はイベントIDで重複を排除してください。
+//
+// キューURLが ".fifo" で終わる場合は、メッセージグループIDと重複排除ID (イベントID) を付けて送信します。
+// 注意事項: 同じグループの先のイベントだけが送信に失敗した場合、再送されたイベントは後続より後に届きます。
+type Relay struct {
+	store     Store
+	client    sqs.SQS
+	queueURL  string
+	fifo      bool
+	batchSize int
+}
+
+var (
+	_ worker.Task      = (*Relay)(nil)
+	_ worker.TypedTask = (*Relay)(nil)
+)
+
+// NewRelay はRelayを生成するコンストラクタです。
+// 引数:
+//   - store: イベントの読み出し元
+//   - client: SQSクライアント
+//   - queueURL: 送信先のキューURL
+//   - opts: オプション
+func NewRelay(store Store, client sqs.SQS, queueURL string, opts ...RelayOption) *Relay {
+	r := &Relay{
+		store:     store,
+		client:    client,
+		queueURL:  queueURL,
+		fifo:      sqs.IsFIFOQueue(queueURL),
+		batchSize: defaultRelayBatchSize,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Execute は未送信のイベントがなくなるまで送信します (Drain を参照)。
+func (r *Relay) Execute(ctx context.Context) error {
+	_, err := r.Drain(ctx)
+	return err
+}
+
+// TaskType はタスク種別名を返します。
+func (r *Relay) TaskType() string {
+	return TaskType
+}
+
+// Drain は未送信のイベントをバッチサイズずつ送信し、送信済みにした件数を返します。
+// 送信に失敗したイベントがあった場合は、同じイベントを繰り返し送信しないよう、そのバッチで終了します。
+// イベントの送信失敗はエラーにせず、Storeの読み書きに失敗した場合のみエラーを返します。
+func (r *Relay) Drain(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		messages, err := r.store.Pending(ctx, r.batchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(messages) == 0 {
+			return total, nil
+		}
+
+		sent, failed, err := r.send(ctx, messages)
+		total += sent
+		if err != nil {
+			return total, err
+		}
+		if failed > 0 || len(messages) < r.batchSize {
+			return total, nil
+		}
+	}
+}
+
+// send はイベントをバッチで送信し、結果をStoreに記録します。
+func (r *Relay) send(ctx context.Context, messages []Message) (sent, failed int, err error) {
+	entries := make([]sqs.SendMessageBatchEntry, len(messages))
+	for i, msg := range messages {
+		entries[i] = sqs.NewSendMessageBatchEntry(msg.ID, r.sendOptions(msg)...)
+	}
+	result, err := r.client.SendMessageBatch(ctx, r.queueURL, entries)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to send outbox messages: %w", err)
+	}
+
+	// 送信済みの記録に失敗した場合は、次回の実行で再送される (少なくとも1回の送信)
+	ids := make([]string, 0, len(result.Successful))
+	for _, entry := range result.Successful {
+		ids = append(ids, aws.ToString(entry.Id))
+	}
+	if err := r.store.MarkSent(ctx, ids, time.Now()); err != nil {
+		return 0, 0, err
+	}
+	for _, entryErr := range result.Failed {
+		slog.Warn("failed to relay outbox message", "id", entryErr.ID, "queueURL", r.queueURL, "error", entryErr)
+		if err := r.store.MarkFailed(ctx, entryErr.ID, entryErr); err != nil {
+			return len(ids), len(result.Failed), err
+		}
+	}
+	return len(ids), len(result.Failed), nil
+}
+
+func (r *Relay) sendOptions(msg Message) []sqs.SendMessageOptionFunc {
+	options := []sqs.SendMessageOptionFunc{
+		sqs.WithMessageBody(msg.Body),
+		sqs.WithStringMessageAttribute(EventTypeAttribute, msg.EventType),
+		// 書き込んだ処理のトレースを引き継ぐ (Relay 自身のトレースより優先する)
+		sqs.WithTraceParent(msg.TraceParent),
+	}
+	if r.fifo {
+		groupID := msg.MessageGroupID
+		if groupID == "" {
+			// グループIDのないイベントはイベント種別ごとに順序を保つ
+			groupID = msg.EventType
+		}
+		options = append(options, sqs.WithMessageGroupID(groupID), sqs.WithMessageDeduplicationID(msg.ID))
+	}
+	return options
+}
@@ -0,0 +1,174 @@
+package outbox_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sqspkg "github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs/memsqs"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/clock"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/outbox"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks"
+)
+
+func newMessage(t *testing.T, eventType, groupID string) outbox.Message {
+	t.Helper()
+	e, err := tasks.NewEnvelope(eventType, map[string]string{"user_id": groupID})
+	if err != nil {
+		t.Fatalf("NewEnvelope() error = %v", err)
+	}
+	msg, err := outbox.NewMessage(e, groupID)
+	if err != nil {
+		t.Fatalf("NewMessage() error = %v", err)
+	}
+	return msg
+}
+
+// TestRelay_Drain は未送信のイベントがSQSに送信され、送信済みになることを確認します。
+func TestRelay_Drain(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		queueName string
+		count     int
+		batchSize int
+	}{
+		{name: "正常系: 標準キュー", queueName: "user-sync-queue", count: 3, batchSize: 100},
+		{name: "正常系: FIFOキュー", queueName: "user-sync-queue.fifo", count: 3, batchSize: 100},
+		{name: "正常系: バッチサイズを超える件数", queueName: "user-sync-queue", count: 25, batchSize: 10},
+		{name: "正常系: 未送信のイベントがない", queueName: "user-sync-queue", count: 0, batchSize: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := memsqs.New(memsqs.WithClock(clock.NewFake(time.Now())))
+			queueURL := server.MustCreateQueue(tt.queueName)
+			client := sqspkg.NewSQSClientWithAPI(server)
+
+			store := outbox.NewMemoryStore()
+			want := make([]outbox.Message, tt.count)
+			for i := range want {
+				want[i] = newMessage(t, "user.updated", fmt.Sprintf("user-%d", i%2))
+				store.Add(want[i])
+			}
+
+			relay := outbox.NewRelay(store, client, queueURL, outbox.WithRelayBatchSize(tt.batchSize))
+			sent, err := relay.Drain(context.Background())
+			if err != nil {
+				t.Fatalf("Drain() error = %v", err)
+			}
+			if sent != tt.count {
+				t.Errorf("Drain() sent = %d, want %d", sent, tt.count)
+			}
+			if pending, _ := store.Pending(context.Background(), 100); len(pending) != 0 {
+				t.Errorf("Pending() = %d messages after drain, want 0", len(pending))
+			}
+
+			received, err := client.ReceiveMessageBatch(context.Background(), queueURL, 100,
+				sqspkg.WithAllMessageAttributes(), sqspkg.WithAllSystemAttributes())
+			if err != nil {
+				t.Fatalf("ReceiveMessageBatch() error = %v", err)
+			}
+			if len(received) != tt.count {
+				t.Fatalf("received %d messages, want %d", len(received), tt.count)
+			}
+			bodies := make(map[string]bool, len(received))
+			for _, m := range received {
+				bodies[aws.ToString(m.Body)] = true
+				if got := aws.ToString(m.MessageAttributes[outbox.EventTypeAttribute].StringValue); got != "user.updated" {
+					t.Errorf("eventType attribute = %q, want user.updated", got)
+				}
+				if sqspkg.IsFIFOQueue(tt.queueName) && sqspkg.MessageGroupID(m) == "" {
+					t.Error("FIFO message has no message group ID")
+				}
+			}
+			for _, msg := range want {
+				if !bodies[msg.Body] {
+					t.Errorf("message %s was not relayed", msg.ID)
+				}
+			}
+		})
+	}
+}
+
+// TestRelay_PartialFailure は送信に失敗したイベントが未送信のまま残り、失敗が記録されることを確認します。
+func TestRelay_PartialFailure(t *testing.T) {
+	t.Parallel()
+
+	server := memsqs.New(memsqs.WithClock(clock.NewFake(time.Now())))
+	queueURL := server.MustCreateQueue("user-sync-queue")
+	client := sqspkg.NewSQSClientWithAPI(server)
+
+	store := outbox.NewMemoryStore()
+	ok := newMessage(t, "user.created", "user-1")
+	// 本文が空のメッセージはSQSがエントリ単位で拒否する
+	broken := outbox.Message{ID: "broken", EventType: "user.created", MessageGroupID: "user-2"}
+	store.Add(ok, broken)
+
+	relay := outbox.NewRelay(store, client, queueURL)
+	if err := relay.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	pending, _ := store.Pending(context.Background(), 100)
+	if len(pending) != 1 || pending[0].ID != "broken" || pending[0].Attempts != 1 {
+		t.Fatalf("Pending() = %+v, want only the broken message with 1 attempt", pending)
+	}
+	if store.LastError("broken") == nil {
+		t.Error("LastError() = nil, want the send error")
+	}
+
+	// 次回の実行で再送される
+	if sent, err := relay.Drain(context.Background()); err != nil || sent != 0 {
+		t.Errorf("Drain() = %d, %v, want 0 sent", sent, err)
+	}
+	if pending, _ := store.Pending(context.Background(), 100); pending[0].Attempts != 2 {
+		t.Errorf("Attempts = %d after retry, want 2", pending[0].Attempts)
+	}
+	if got := server.Stats("user-sync-queue").Visible; got != 1 {
+		t.Errorf("queue has %d messages, want 1", got)
+	}
+}
+
+// TestRelay_TraceParent はイベントを書き込んだ処理のトレースコンテキストがメッセージ属性で送信されることを確認します。
+func TestRelay_TraceParent(t *testing.T) {
+	t.Parallel()
+
+	server := memsqs.New(memsqs.WithClock(clock.NewFake(time.Now())))
+	queueURL := server.MustCreateQueue("user-sync-queue")
+	client := sqspkg.NewSQSClientWithAPI(server)
+
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	traced := newMessage(t, "user.updated", "user-1")
+	traced.TraceParent = traceParent
+	untraced := newMessage(t, "user.updated", "user-2")
+	store := outbox.NewMemoryStore()
+	store.Add(traced, untraced)
+
+	if _, err := outbox.NewRelay(store, client, queueURL).Drain(context.Background()); err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+	msgs, err := client.ReceiveMessages(context.Background(), queueURL, sqspkg.WithMaxMessages(10), sqspkg.WithTraceParentAttribute())
+	if err != nil || len(msgs) != 2 {
+		t.Fatalf("ReceiveMessages() = %d messages, %v, want 2", len(msgs), err)
+	}
+	got := make(map[string]string, len(msgs))
+	for _, msg := range msgs {
+		e, err := tasks.DecodeEnvelope(aws.ToString(msg.Body))
+		if err != nil {
+			t.Fatalf("DecodeEnvelope() error = %v", err)
+		}
+		got[e.ID] = aws.ToString(msg.MessageAttributes[sqspkg.TraceParentAttribute].StringValue)
+	}
+	if got[traced.ID] != traceParent {
+		t.Errorf("traceparent of traced message = %q, want %q", got[traced.ID], traceParent)
+	}
+	if got[untraced.ID] != "" {
+		t.Errorf("traceparent of untraced message = %q, want empty", got[untraced.ID])
+	}
+}
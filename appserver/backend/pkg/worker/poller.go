@@ -0,0 +1,420 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/trace"
+)
+
+const (
+	defaultPollerMaxMessages         = 10
+	defaultPollerWaitTimeSeconds     = 20
+	defaultPollerErrorBackoff        = 1 * time.Second
+	defaultPollerQueueFullBackoff    = 100 * time.Millisecond
+	defaultPollerMaxQueueFullBackoff = 5 * time.Second
+	// maxVisibilityTimeout はSQSで設定できる可視性タイムアウトの上限 (12時間)
+	maxVisibilityTimeout = 12 * time.Hour
+)
+
+// MessageHandler はSQSメッセージをWorkerで実行するTaskに変換する関数型です。
+// 変換に失敗した場合はエラーを返します。そのメッセージは削除されず、
+// 可視性タイムアウト後に再配信されます(最終的にはDLQへ移動)。
+type MessageHandler func(msg types.Message) (Task, error)
+
+// SQSPoller はSQSをロングポーリングし、受信したメッセージをWorkerに投入します。
+// タスクが成功した場合、またはデッドレターとして退避できた場合のみメッセージを削除します(At-Least-Once配信)。
+type SQSPoller struct {
+	client              sqs.SQS
+	worker              Worker
+	queueURL            string
+	handler             MessageHandler
+	maxMessages         int32
+	waitTimeSeconds     int32
+	errorBackoff        time.Duration
+	queueFullBackoff    time.Duration
+	maxQueueFullBackoff time.Duration
+	priority            Priority
+	// visibilityTimeout と heartbeatInterval は WithVisibilityHeartbeat で設定し、0 の場合はハートビートを行わない
+	visibilityTimeout time.Duration
+	heartbeatInterval time.Duration
+	receiveOptions    []sqs.ReceiveMessageOptionFunc
+	// groups は WithPollerFIFO の場合のみ設定され、メッセージグループごとの実行順を管理する
+	groups *messageGroups
+}
+
+// PollerOption はSQSPollerのオプション関数型です。
+type PollerOption func(*SQSPoller)
+
+// WithPollerMaxMessages は一度に受信するメッセージの最大数を設定します (1-10)。
+func WithPollerMaxMessages(maxMessages int32) PollerOption {
+	if maxMessages <= 0 || maxMessages > 10 {
+		maxMessages = defaultPollerMaxMessages
+	}
+	return func(p *SQSPoller) {
+		p.maxMessages = maxMessages
+	}
+}
+
+// WithPollerWaitTimeSeconds はロングポーリングの待機時間を設定します (0-20)。
+func WithPollerWaitTimeSeconds(waitTimeSeconds int32) PollerOption {
+	if waitTimeSeconds < 0 || waitTimeSeconds > 20 {
+		waitTimeSeconds = defaultPollerWaitTimeSeconds
+	}
+	return func(p *SQSPoller) {
+		p.waitTimeSeconds = waitTimeSeconds
+	}
+}
+
+// WithPollerErrorBackoff は受信エラー発生時に次のポーリングまで待機する時間を設定します。
+func WithPollerErrorBackoff(backoff time.Duration) PollerOption {
+	if backoff <= 0 {
+		backoff = defaultPollerErrorBackoff
+	}
+	return func(p *SQSPoller) {
+		p.errorBackoff = backoff
+	}
+}
+
+// WithPollerQueueFullBackoff はジョブキューが満杯の場合のバックオフを設定します。
+// 待機時間は initial から始まり、再試行のたびに倍になります(上限 max)。
+func WithPollerQueueFullBackoff(initial, max time.Duration) PollerOption {
+	if initial <= 0 {
+		initial = defaultPollerQueueFullBackoff
+	}
+	if max < initial {
+		max = initial
+	}
+	return func(p *SQSPoller) {
+		p.queueFullBackoff = initial
+		p.maxQueueFullBackoff = max
+	}
+}
+
+// WithPollerPriority はメッセージをWorkerに投入する際の優先度を設定します (デフォルト: PriorityNormal)。
+// 緊急のタスクを別のキューで受け付ける場合に、そのキューのポーラーに PriorityHigh を指定します。
+func WithPollerPriority(priority Priority) PollerOption {
+	return func(p *SQSPoller) {
+		p.priority = priority
+	}
+}
+
+// WithVisibilityHeartbeat はタスクの実行中にメッセージの可視性タイムアウトを延長し続けるハートビートを有効にします。
+// 受信時の可視性タイムアウトを visibilityTimeout に設定し、interval ごとに残り時間を visibilityTimeout に戻します。
+// キューの可視性タイムアウトより長く実行されるタスクが、実行中に再配信されて二重に実行されるのを防ぎます。
+// interval が 0 以下、または visibilityTimeout 以上の場合は visibilityTimeout の 1/3 を使用します。
+// visibilityTimeout は秒単位に切り上げ、1秒から12時間の範囲に収めます。
+// 使用例: worker.WithVisibilityHeartbeat(30*time.Second, 10*time.Second)
+func WithVisibilityHeartbeat(visibilityTimeout, interval time.Duration) PollerOption {
+	visibilityTimeout = min(max(visibilityTimeout, time.Second), maxVisibilityTimeout)
+	if interval <= 0 || interval >= visibilityTimeout {
+		interval = visibilityTimeout / 3
+	}
+	return func(p *SQSPoller) {
+		p.visibilityTimeout = visibilityTimeout
+		p.heartbeatInterval = interval
+	}
+}
+
+// WithPollerReceiveOptions は受信時のオプションを追加します。
+// ハンドラーでメッセージ属性や受信回数を参照する場合に指定します。
+// 使用例: worker.WithPollerReceiveOptions(sqs.WithAllMessageAttributes(), sqs.WithApproximateReceiveCount())
+func WithPollerReceiveOptions(options ...sqs.ReceiveMessageOptionFunc) PollerOption {
+	return func(p *SQSPoller) {
+		p.receiveOptions = append(p.receiveOptions, options...)
+	}
+}
+
+// WithPollerFIFO はFIFOキューのメッセージグループの順序を守って処理します。
+// 同じグループのメッセージは前のメッセージの処理が終わってから順に1件ずつWorkerに投入し、
+// 異なるグループのメッセージは並行に処理します。
+// 前のメッセージが失敗して削除されなかった場合、同じグループの後続のメッセージは実行せずに再配信に任せます。
+func WithPollerFIFO() PollerOption {
+	return func(p *SQSPoller) {
+		p.groups = newMessageGroups()
+	}
+}
+
+// NewSQSPoller はSQSPollerを生成するコンストラクタです。
+// 引数:
+//   - client: SQSクライアント
+//   - w: タスクを実行するWorker
+//   - queueURL: ポーリング対象のキューURL
+//   - handler: メッセージをTaskに変換するハンドラー
+//   - opts: オプション
+func NewSQSPoller(client sqs.SQS, w Worker, queueURL string, handler MessageHandler, opts ...PollerOption) *SQSPoller {
+	p := &SQSPoller{
+		client:              client,
+		worker:              w,
+		queueURL:            queueURL,
+		handler:             handler,
+		maxMessages:         defaultPollerMaxMessages,
+		waitTimeSeconds:     defaultPollerWaitTimeSeconds,
+		errorBackoff:        defaultPollerErrorBackoff,
+		queueFullBackoff:    defaultPollerQueueFullBackoff,
+		maxQueueFullBackoff: defaultPollerMaxQueueFullBackoff,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Run はコンテキストがキャンセルされるまでポーリングを続けます。
+// キャンセルによる停止は正常終了として nil を返します。
+// Workerが先に停止した場合は ErrWorkerStopped を返します。
+// 注意事項: Workerは事前に Run しておく必要があります。
+func (p *SQSPoller) Run(ctx context.Context) error {
+	slog.Info("sqs poller started", "queueURL", p.queueURL, "maxMessages", p.maxMessages, "waitTimeSeconds", p.waitTimeSeconds)
+	defer slog.Info("sqs poller stopped", "queueURL", p.queueURL)
+
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		receiveOpts := []sqs.ReceiveMessageOptionFunc{
+			sqs.WithMaxMessages(p.maxMessages),
+			sqs.WithWaitTimeSeconds(p.waitTimeSeconds),
+			sqs.WithTraceParentAttribute(),
+		}
+		if p.visibilityTimeout > 0 {
+			receiveOpts = append(receiveOpts, sqs.WithVisibilityTimeout(p.visibilityTimeoutSeconds()))
+		}
+		if p.groups != nil {
+			receiveOpts = append(receiveOpts, sqs.WithSystemAttributeNames(types.MessageSystemAttributeNameMessageGroupId))
+		}
+		receiveOpts = append(receiveOpts, p.receiveOptions...)
+		messages, err := p.client.ReceiveMessages(ctx, p.queueURL, receiveOpts...)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			slog.Error("failed to receive messages", "queueURL", p.queueURL, "error", err)
+			if !sleepContext(ctx, p.errorBackoff) {
+				return nil
+			}
+			continue
+		}
+
+		// 投入を待っている間に可視性タイムアウトが切れないよう、受信した時点でハートビートを開始する
+		heartbeats := make([]*visibilityHeartbeat, len(messages))
+		for i, msg := range messages {
+			heartbeats[i] = p.startHeartbeat(msg)
+		}
+		for i, msg := range messages {
+			var err error
+			if groupID := sqs.MessageGroupID(msg); p.groups != nil && groupID != "" {
+				err = p.dispatchInGroup(ctx, groupID, msg, heartbeats[i])
+			} else {
+				_, err = p.dispatch(ctx, msg, heartbeats[i])
+			}
+			if err != nil {
+				// 投入できなかったメッセージは可視性タイムアウト後に再配信される
+				for _, hb := range heartbeats[i+1:] {
+					hb.stop()
+				}
+				if errors.Is(err, ErrWorkerStopped) {
+					return err
+				}
+				return nil
+			}
+		}
+	}
+}
+
+// dispatch はメッセージをTaskに変換してWorkerに投入します。
+// ジョブキューが満杯の場合は空きができるまでバックオフしながら再試行します。
+// コンテキストがキャンセルされた場合と、Workerが停止している場合(ErrWorkerStopped)のみエラーを返します。
+// ハートビートはジョブの完了 (Worker停止時の破棄を含む) で停止し、投入できなかった場合は即座に停止します。
+// 投入できた場合のみ JobHandle を返します。
+func (p *SQSPoller) dispatch(ctx context.Context, msg types.Message, hb *visibilityHeartbeat) (*JobHandle, error) {
+	messageID := aws.ToString(msg.MessageId)
+
+	task, err := p.handler(msg)
+	if err != nil {
+		// 削除せずに残し、再配信(最終的にはDLQ)に任せる
+		hb.stop()
+		slog.Error("failed to convert message to task", "messageID", messageID, "error", err)
+		return nil, nil
+	}
+
+	job := &sqsMessageTask{task: task, poller: p, message: msg, heartbeat: hb}
+	backoff := p.queueFullBackoff
+	for {
+		handle, err := p.worker.Submit(ctx, []Task{job}, WithJobPriority(p.priority), WithNonBlocking(), WithJobID(messageID))
+		if err == nil {
+			slog.Info("message dispatched to worker", "messageID", messageID)
+			if hb != nil {
+				go func() {
+					<-handle.Done()
+					hb.stop()
+				}()
+			}
+			return handle, nil
+		}
+		if errors.Is(err, ErrWorkerStopped) {
+			hb.stop()
+			slog.Error("worker is stopped, stop dispatching", "messageID", messageID)
+			return nil, err
+		}
+		if !errors.Is(err, ErrJobQueueFull) {
+			hb.stop()
+			slog.Error("failed to dispatch message", "messageID", messageID, "error", err)
+			return nil, nil
+		}
+
+		slog.Warn("job queue is full, backing off", "messageID", messageID, "backoff", backoff)
+		if !sleepContext(ctx, backoff) {
+			hb.stop()
+			return nil, ctx.Err()
+		}
+		backoff = min(backoff*2, p.maxQueueFullBackoff)
+	}
+}
+
+// visibilityTimeoutSeconds は可視性タイムアウトを秒単位で返します。
+func (p *SQSPoller) visibilityTimeoutSeconds() int32 {
+	return int32(math.Ceil(p.visibilityTimeout.Seconds()))
+}
+
+// startHeartbeat はメッセージの可視性タイムアウトを延長し続けるゴルーチンを起動します。
+// ハートビートが無効な場合は nil を返します (nil の stop は何もしません)。
+func (p *SQSPoller) startHeartbeat(msg types.Message) *visibilityHeartbeat {
+	if p.visibilityTimeout <= 0 {
+		return nil
+	}
+	// 停止はタスクの完了に合わせるため、ポーリングのコンテキストとは独立させる
+	ctx, cancel := context.WithCancel(context.Background())
+	hb := &visibilityHeartbeat{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(hb.done)
+		p.runHeartbeat(ctx, msg)
+	}()
+	return hb
+}
+
+// runHeartbeat は ctx がキャンセルされるまで、一定間隔で可視性タイムアウトを延長します。
+// 延長に失敗した場合は警告を記録して次の間隔で再試行します (最終的にタイムアウトすれば再配信されます)。
+func (p *SQSPoller) runHeartbeat(ctx context.Context, msg types.Message) {
+	messageID := aws.ToString(msg.MessageId)
+	receiptHandle := aws.ToString(msg.ReceiptHandle)
+	seconds := p.visibilityTimeoutSeconds()
+
+	ticker := time.NewTicker(p.heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := p.client.ChangeMessageVisibility(ctx, p.queueURL, receiptHandle, seconds); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Warn("failed to extend message visibility", "messageID", messageID, "error", err)
+			continue
+		}
+		slog.Debug("message visibility extended", "messageID", messageID, "visibilityTimeout", seconds)
+	}
+}
+
+// visibilityHeartbeat は1件のメッセージの可視性タイムアウトを延長するゴルーチンを管理します。
+type visibilityHeartbeat struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// stop はハートビートを停止し、ゴルーチンの終了を待機します。複数回呼び出しても安全です。
+func (hb *visibilityHeartbeat) stop() {
+	if hb == nil {
+		return
+	}
+	hb.cancel()
+	<-hb.done
+}
+
+// deleteMessage は処理が完了したメッセージをキューから削除します。
+func (p *SQSPoller) deleteMessage(ctx context.Context, msg types.Message) {
+	messageID := aws.ToString(msg.MessageId)
+	if err := p.client.DeleteMessage(ctx, p.queueURL, sqs.WithReceiptHandle(aws.ToString(msg.ReceiptHandle))); err != nil {
+		// 削除できなかったメッセージは再配信される
+		slog.Error("failed to delete message", "messageID", messageID, "error", err)
+		return
+	}
+	slog.Info("message deleted", "messageID", messageID)
+}
+
+// sqsMessageTask はSQSメッセージに紐づくTaskです。
+// 再試行を含めた最終結果が成功、またはデッドレターに退避済みの場合のみメッセージを削除します。
+type sqsMessageTask struct {
+	task    Task
+	poller  *SQSPoller
+	message types.Message
+	// heartbeat は WithVisibilityHeartbeat が有効な場合のみ設定される
+	heartbeat *visibilityHeartbeat
+}
+
+// Execute はメッセージ属性の traceparent を引き継いだスパンで元のTaskを実行します。
+// 送信元 (HTTPリクエストやアウトボックス) のトレースの続きとして、試行ごとにスパンを記録します。
+func (t *sqsMessageTask) Execute(ctx context.Context) error {
+	ctx, span := trace.Start(sqs.ContextWithMessageTrace(ctx, t.message), "sqs.process", trace.WithSpanKind(trace.SpanKindConsumer), trace.WithAttributes(
+		trace.String("messaging.system", "aws_sqs"),
+		trace.String("messaging.destination", t.poller.queueURL),
+		trace.String("messaging.message_id", aws.ToString(t.message.MessageId)),
+		trace.String("task.type", t.TaskType()),
+	))
+	defer span.End()
+	err := t.task.Execute(ctx)
+	span.RecordError(err)
+	return err
+}
+
+// TaskType は元のTaskの種別名を返します。
+func (t *sqsMessageTask) TaskType() string {
+	return taskTypeOf(t.task)
+}
+
+// DeadLetterBody は受信したメッセージの本文をそのまま返します。
+// デッドレターから再投入した際に、元と同じメッセージとして処理させるためです。
+func (t *sqsMessageTask) DeadLetterBody() (string, error) {
+	return aws.ToString(t.message.Body), nil
+}
+
+// DeadLetterMessageGroupID はFIFOキューのメッセージのグループIDを返します (標準キューの場合は空)。
+func (t *sqsMessageTask) DeadLetterMessageGroupID() string {
+	return sqs.MessageGroupID(t.message)
+}
+
+// OnComplete は最終結果に応じてメッセージを削除し、元のTaskにも結果を通知します。
+// 削除後に可視性タイムアウトを変更しないよう、先にハートビートを停止します。
+func (t *sqsMessageTask) OnComplete(ctx context.Context, result TaskResult) {
+	t.heartbeat.stop()
+	if result.Succeeded() || result.DeadLettered {
+		t.poller.deleteMessage(ctx, t.message)
+	}
+	if completer, ok := t.task.(TaskCompleter); ok {
+		result.Task = t.task
+		completer.OnComplete(ctx, result)
+	}
+}
+
+// sleepContext は指定時間待機します。
+// コンテキストがキャンセルされた場合は false を返します。
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,103 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// messageGroups はFIFOキューのメッセージグループごとに、最後に受け付けたメッセージを追跡します。
+// 同じグループのメッセージは、前のメッセージの処理が終わるまでWorkerに投入しません。
+type messageGroups struct {
+	mu    sync.Mutex
+	tails map[string]*groupTail
+}
+
+// groupTail はグループ内の1件のメッセージの処理状況です。
+type groupTail struct {
+	// done は処理が終わった (または投入しなかった) 時点でクローズされる
+	done chan struct{}
+	// deleted はメッセージが削除されたかどうかで、done のクローズ後にのみ参照する
+	deleted bool
+}
+
+func newMessageGroups() *messageGroups {
+	return &messageGroups{tails: make(map[string]*groupTail)}
+}
+
+// next はグループの末尾に新しいメッセージを追加し、直前のメッセージ (なければ nil) と追加したメッセージを返します。
+func (g *messageGroups) next(groupID string) (prev, tail *groupTail) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	prev = g.tails[groupID]
+	tail = &groupTail{done: make(chan struct{})}
+	g.tails[groupID] = tail
+	return prev, tail
+}
+
+// finish はメッセージの処理が終わったことを後続のメッセージに通知します。
+// グループの末尾であれば追跡をやめ、処理中のメッセージがないグループを保持し続けないようにします。
+func (g *messageGroups) finish(groupID string, tail *groupTail, deleted bool) {
+	tail.deleted = deleted
+	close(tail.done)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.tails[groupID] == tail {
+		delete(g.tails, groupID)
+	}
+}
+
+// dispatchInGroup はFIFOキューのメッセージを、同じグループの前のメッセージの処理が終わってから投入します。
+// 前のメッセージがなければ dispatch と同じく即座に投入し、Workerの停止などのエラーを返します。
+// 前のメッセージがある場合は待機と投入をゴルーチンで行い、ポーリングは止めずに nil を返します。
+func (p *SQSPoller) dispatchInGroup(ctx context.Context, groupID string, msg types.Message, hb *visibilityHeartbeat) error {
+	prev, tail := p.groups.next(groupID)
+	if prev == nil {
+		return p.dispatchAndFinish(ctx, groupID, msg, hb, tail)
+	}
+
+	go func() {
+		select {
+		case <-prev.done:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil || !prev.deleted {
+			// 前のメッセージが再配信されるため、順序を守るには後続のメッセージも実行せずに再配信に任せる
+			hb.stop()
+			p.groups.finish(groupID, tail, false)
+			slog.Warn("skip message after preceding message in the group was not processed",
+				"messageID", aws.ToString(msg.MessageId), "groupID", groupID)
+			return
+		}
+		// Workerの停止は次に受信したメッセージの投入で検知するため、ここではエラーを返さない
+		_ = p.dispatchAndFinish(ctx, groupID, msg, hb, tail)
+	}()
+	return nil
+}
+
+// dispatchAndFinish はメッセージを投入し、ジョブの完了時にグループの後続のメッセージへ結果を通知します。
+func (p *SQSPoller) dispatchAndFinish(ctx context.Context, groupID string, msg types.Message, hb *visibilityHeartbeat, tail *groupTail) error {
+	handle, err := p.dispatch(ctx, msg, hb)
+	if handle == nil {
+		p.groups.finish(groupID, tail, false)
+		return err
+	}
+	go func() {
+		<-handle.Done()
+		result, _ := handle.Result()
+		p.groups.finish(groupID, tail, messageDeleted(result))
+	}()
+	return nil
+}
+
+// messageDeleted はジョブの結果からメッセージが削除されたかどうかを判定します (sqsMessageTask.OnComplete と同じ条件)。
+func messageDeleted(result JobResult) bool {
+	if len(result.Tasks) != 1 {
+		return false
+	}
+	task := result.Tasks[0]
+	return task.Succeeded() || task.DeadLettered
+}
@@ -0,0 +1,162 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	sqspkg "github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs/memsqs"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/clock"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/trace"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks"
+)
+
+// TestSQSPoller_RedriveWithMemSQS はインメモリのSQSで、失敗し続けるメッセージが
+// 可視性タイムアウト後に再配信され、受信回数の上限でDLQに移動することを確認します。
+func TestSQSPoller_RedriveWithMemSQS(t *testing.T) {
+	clk := clock.NewFake(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+	server := memsqs.New(memsqs.WithClock(clk))
+	server.MustCreateQueue("worker-queue-dlq")
+	queueURL := server.MustCreateQueue("worker-queue",
+		memsqs.WithVisibilityTimeout(30*time.Second),
+		memsqs.WithRedrivePolicy("worker-queue-dlq", 2),
+	)
+	client := sqspkg.NewSQSClientWithAPI(server)
+
+	w := worker.NewWorker()
+	w.Run(context.Background())
+	defer w.Shutdown(context.Background())
+
+	receiveCounts := make(chan int, 10)
+	handler := func(msg types.Message) (worker.Task, error) {
+		count := sqspkg.ApproximateReceiveCount(msg)
+		return tasks.NewTask(func(ctx context.Context) error {
+			receiveCounts <- count
+			return errors.New("task failed")
+		}), nil
+	}
+	stop := runPoller(t, worker.NewSQSPoller(client, w, queueURL, handler,
+		worker.WithPollerReceiveOptions(sqspkg.WithApproximateReceiveCount()),
+	))
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	// waitPolling はポーラーが次のロングポーリングで待機するまで待つ
+	waitPolling := func() {
+		t.Helper()
+		if err := clk.BlockUntil(ctx, 1); err != nil {
+			t.Fatalf("poller did not start long polling: %v", err)
+		}
+	}
+
+	if _, err := client.SendMessage(context.Background(), queueURL, sqspkg.WithMessageBody(`{"task":"test"}`)); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	for want := 1; want <= 2; want++ {
+		select {
+		case got := <-receiveCounts:
+			if got != want {
+				t.Fatalf("receive count = %d, want %d", got, want)
+			}
+		case <-ctx.Done():
+			t.Fatalf("message was not delivered for receive %d", want)
+		}
+		// 失敗したメッセージは削除されず、可視性タイムアウト後に再配信される
+		waitPolling()
+		if got := server.Stats("worker-queue"); got.InFlight != 1 {
+			t.Fatalf("Stats() = %+v, want the failed message to stay in flight", got)
+		}
+		clk.Advance(30 * time.Second)
+	}
+
+	// 3回目の受信で受信回数の上限に達し、タスクは実行されずにDLQへ移動する
+	waitPolling()
+	if got := server.Stats("worker-queue").Total(); got != 0 {
+		t.Errorf("Stats(worker-queue).Total() = %d, want 0", got)
+	}
+	if got := server.Stats("worker-queue-dlq"); got.Visible != 1 {
+		t.Errorf("Stats(worker-queue-dlq) = %+v, want 1 visible", got)
+	}
+	select {
+	case got := <-receiveCounts:
+		t.Errorf("task executed again with receive count %d after redrive", got)
+	default:
+	}
+
+	dlqURL, err := client.GetQueueURL(context.Background(), "worker-queue-dlq")
+	if err != nil {
+		t.Fatalf("GetQueueURL() error = %v", err)
+	}
+	dead, err := client.ReceiveMessages(context.Background(), dlqURL)
+	if err != nil || len(dead) != 1 || aws.ToString(dead[0].Body) != `{"task":"test"}` {
+		t.Fatalf("DLQ ReceiveMessages() = %v, %v, want the failed message", dead, err)
+	}
+}
+
+// TestSQSPoller_TracePropagation は送信時のスパンがメッセージ属性で伝播し、
+// 受信したタスクが同じトレースの子スパンとして実行されることを確認します。
+func TestSQSPoller_TracePropagation(t *testing.T) {
+	exporter := trace.NewMemoryExporter()
+	prev := trace.DefaultTracer()
+	trace.SetDefaultTracer(trace.NewTracer(exporter))
+	t.Cleanup(func() { trace.SetDefaultTracer(prev) })
+
+	server := memsqs.New()
+	queueURL := server.MustCreateQueue("worker-queue")
+	client := sqspkg.NewSQSClientWithAPI(server)
+
+	w := worker.NewWorker()
+	w.Run(context.Background())
+	defer w.Shutdown(context.Background())
+
+	taskSpans := make(chan trace.SpanContext, 1)
+	handler := func(msg types.Message) (worker.Task, error) {
+		return tasks.NewTask(func(ctx context.Context) error {
+			taskSpans <- trace.SpanContextFromContext(ctx)
+			return nil
+		}), nil
+	}
+	stop := runPoller(t, worker.NewSQSPoller(client, w, queueURL, handler, worker.WithPollerWaitTimeSeconds(1)))
+	defer stop()
+
+	// HTTPリクエストの処理中にメッセージを送信する
+	sendCtx, producer := trace.Start(context.Background(), "PUT /users/{id}", trace.WithSpanKind(trace.SpanKindServer))
+	if _, err := client.SendMessage(sendCtx, queueURL, sqspkg.WithMessageBody(`{"task":"test"}`)); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	producer.End()
+
+	var got trace.SpanContext
+	select {
+	case got = <-taskSpans:
+	case <-time.After(2 * time.Second):
+		t.Fatal("message was not delivered")
+	}
+	if got.TraceID != producer.SpanContext().TraceID {
+		t.Errorf("task TraceID = %s, want %s", got.TraceID, producer.SpanContext().TraceID)
+	}
+
+	// タスクの完了後に消費側のスパンが出力される
+	deadline := time.Now().Add(time.Second)
+	var consumer *trace.SpanData
+	for consumer == nil && time.Now().Before(deadline) {
+		for _, span := range exporter.Spans() {
+			if span.Name == "sqs.process" && span.TraceID == got.TraceID {
+				consumer = &span
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if consumer == nil {
+		t.Fatal("sqs.process span was not exported")
+	}
+	if consumer.ParentSpanID != producer.SpanContext().SpanID || consumer.SpanID != got.SpanID || consumer.Kind != trace.SpanKindConsumer {
+		t.Errorf("consumer span = %+v, want child of %s and parent of task", consumer, producer.SpanContext().SpanID)
+	}
+}
@@ -0,0 +1,520 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awssqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	sqspkg "github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs"
+	sqsmock "github.com/takeuchi-shogo/golang-learn/app/backend/pkg/aws/sqs/mock"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks"
+	"go.uber.org/mock/gomock"
+)
+
+const testQueueURL = "http://localhost:4566/000000000000/worker-queue"
+
+// expectReceiveOnce は1回目の受信でmessagesを返し、以降はコンテキストがキャンセルされるまでブロックする
+func expectReceiveOnce(mockSQS *sqsmock.MockSQS, messages []types.Message) {
+	mockSQS.EXPECT().
+		ReceiveMessages(gomock.Any(), testQueueURL, gomock.Any()).
+		Return(messages, nil).
+		Times(1)
+	mockSQS.EXPECT().
+		ReceiveMessages(gomock.Any(), testQueueURL, gomock.Any()).
+		DoAndReturn(func(ctx context.Context, queueURL string, options ...sqspkg.ReceiveMessageOptionFunc) ([]types.Message, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}).
+		AnyTimes()
+}
+
+func newTestMessage(id string) types.Message {
+	return types.Message{
+		MessageId:     aws.String(id),
+		ReceiptHandle: aws.String("receipt-" + id),
+		Body:          aws.String(`{"task":"test"}`),
+	}
+}
+
+// runPoller はポーラーをバックグラウンドで起動し、停止用の関数を返す
+func runPoller(t *testing.T, p *worker.SQSPoller) func() {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Run(ctx) }()
+	return func() {
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("expected poller to stop cleanly, got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Error("poller did not stop after context cancel")
+		}
+	}
+}
+
+// TestSQSPoller_DeletesMessageOnSuccess はタスク成功時にメッセージが削除されることを確認します。
+func TestSQSPoller_DeletesMessageOnSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockSQS := sqsmock.NewMockSQS(ctrl)
+	expectReceiveOnce(mockSQS, []types.Message{newTestMessage("msg-1")})
+
+	deleted := make(chan string, 1)
+	mockSQS.EXPECT().
+		DeleteMessage(gomock.Any(), testQueueURL, gomock.Any()).
+		DoAndReturn(func(ctx context.Context, queueURL string, options ...sqspkg.DeleteMessageOptionFunc) error {
+			input := applyDeleteOptions(options)
+			deleted <- aws.ToString(input.ReceiptHandle)
+			return nil
+		})
+
+	w := worker.NewWorker()
+	w.Run(context.Background())
+	defer w.Shutdown(context.Background())
+
+	handler := func(msg types.Message) (worker.Task, error) {
+		return tasks.NewTask(func(ctx context.Context) error { return nil }), nil
+	}
+	stop := runPoller(t, worker.NewSQSPoller(mockSQS, w, testQueueURL, handler))
+	defer stop()
+
+	select {
+	case receipt := <-deleted:
+		if receipt != "receipt-msg-1" {
+			t.Errorf("expected receipt handle 'receipt-msg-1', got %s", receipt)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message was not deleted")
+	}
+}
+
+// TestSQSPoller_KeepsMessageOnFailure はタスク失敗時にメッセージが削除されないことを確認します。
+func TestSQSPoller_KeepsMessageOnFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockSQS := sqsmock.NewMockSQS(ctrl)
+	expectReceiveOnce(mockSQS, []types.Message{newTestMessage("msg-1")})
+	mockSQS.EXPECT().DeleteMessage(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	w := worker.NewWorker()
+	w.Run(context.Background())
+	defer w.Shutdown(context.Background())
+
+	executed := make(chan struct{})
+	handler := func(msg types.Message) (worker.Task, error) {
+		return tasks.NewTask(func(ctx context.Context) error {
+			close(executed)
+			return errors.New("task failed")
+		}), nil
+	}
+	stop := runPoller(t, worker.NewSQSPoller(mockSQS, w, testQueueURL, handler))
+	defer stop()
+
+	select {
+	case <-executed:
+	case <-time.After(time.Second):
+		t.Fatal("task was not executed")
+	}
+	// 削除処理が走らないことを確認するため少し待機する
+	time.Sleep(50 * time.Millisecond)
+}
+
+// TestSQSPoller_DeletesDeadLetteredMessage はデッドレターに退避したメッセージが削除されることを確認します。
+func TestSQSPoller_DeletesDeadLetteredMessage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockSQS := sqsmock.NewMockSQS(ctrl)
+	expectReceiveOnce(mockSQS, []types.Message{newTestMessage("msg-1")})
+
+	deleted := make(chan string, 1)
+	mockSQS.EXPECT().
+		DeleteMessage(gomock.Any(), testQueueURL, gomock.Any()).
+		DoAndReturn(func(ctx context.Context, queueURL string, options ...sqspkg.DeleteMessageOptionFunc) error {
+			deleted <- aws.ToString(applyDeleteOptions(options).ReceiptHandle)
+			return nil
+		})
+
+	sink := worker.NewMemoryDeadLetterSink()
+	w := worker.NewWorker(worker.WithDeadLetterSink(sink))
+	w.Run(context.Background())
+	defer w.Shutdown(context.Background())
+
+	handler := func(msg types.Message) (worker.Task, error) {
+		return tasks.NewTask(func(ctx context.Context) error {
+			return worker.Permanent(errors.New("invalid payload"))
+		}), nil
+	}
+	stop := runPoller(t, worker.NewSQSPoller(mockSQS, w, testQueueURL, handler))
+	defer stop()
+
+	select {
+	case receipt := <-deleted:
+		if receipt != "receipt-msg-1" {
+			t.Errorf("expected receipt-msg-1 to be deleted, got %s", receipt)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("dead lettered message was not deleted")
+	}
+
+	deadLetters := sink.List()
+	if len(deadLetters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(deadLetters))
+	}
+	if deadLetters[0].Body != `{"task":"test"}` {
+		t.Errorf("expected original message body, got %s", deadLetters[0].Body)
+	}
+}
+
+// TestSQSPoller_SkipsUnconvertibleMessage は変換に失敗したメッセージが投入されないことを確認します。
+func TestSQSPoller_SkipsUnconvertibleMessage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockSQS := sqsmock.NewMockSQS(ctrl)
+	expectReceiveOnce(mockSQS, []types.Message{newTestMessage("bad"), newTestMessage("good")})
+
+	deleted := make(chan string, 2)
+	mockSQS.EXPECT().
+		DeleteMessage(gomock.Any(), testQueueURL, gomock.Any()).
+		DoAndReturn(func(ctx context.Context, queueURL string, options ...sqspkg.DeleteMessageOptionFunc) error {
+			deleted <- aws.ToString(applyDeleteOptions(options).ReceiptHandle)
+			return nil
+		})
+
+	w := worker.NewWorker()
+	w.Run(context.Background())
+	defer w.Shutdown(context.Background())
+
+	handler := func(msg types.Message) (worker.Task, error) {
+		if aws.ToString(msg.MessageId) == "bad" {
+			return nil, errors.New("invalid message")
+		}
+		return tasks.NewTask(func(ctx context.Context) error { return nil }), nil
+	}
+	stop := runPoller(t, worker.NewSQSPoller(mockSQS, w, testQueueURL, handler))
+	defer stop()
+
+	select {
+	case receipt := <-deleted:
+		if receipt != "receipt-good" {
+			t.Errorf("expected only 'receipt-good' to be deleted, got %s", receipt)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message was not deleted")
+	}
+}
+
+// TestSQSPoller_BacksOffWhenQueueFull はジョブキューが満杯の場合に再試行されることを確認します。
+func TestSQSPoller_BacksOffWhenQueueFull(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockSQS := sqsmock.NewMockSQS(ctrl)
+	expectReceiveOnce(mockSQS, []types.Message{newTestMessage("msg-1")})
+
+	deleted := make(chan struct{})
+	mockSQS.EXPECT().
+		DeleteMessage(gomock.Any(), testQueueURL, gomock.Any()).
+		DoAndReturn(func(ctx context.Context, queueURL string, options ...sqspkg.DeleteMessageOptionFunc) error {
+			close(deleted)
+			return nil
+		})
+
+	// キューサイズ1のWorkerを起動前に埋めておく
+	w := worker.NewWorker(worker.WithMaxWorkerJobs(1))
+	if err := w.AddJobAsync(tasks.NewTask(func(ctx context.Context) error { return nil })); err != nil {
+		t.Fatalf("failed to fill job queue: %v", err)
+	}
+
+	handler := func(msg types.Message) (worker.Task, error) {
+		return tasks.NewTask(func(ctx context.Context) error { return nil }), nil
+	}
+	stop := runPoller(t, worker.NewSQSPoller(mockSQS, w, testQueueURL, handler,
+		worker.WithPollerQueueFullBackoff(time.Millisecond, 5*time.Millisecond),
+	))
+	defer stop()
+
+	// バックオフ中であることを確認してからWorkerを起動する
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case <-deleted:
+		t.Fatal("message should not be processed while the job queue is full")
+	default:
+	}
+
+	w.Run(context.Background())
+	defer w.Shutdown(context.Background())
+
+	select {
+	case <-deleted:
+	case <-time.After(time.Second):
+		t.Fatal("message was not processed after the job queue drained")
+	}
+}
+
+// heartbeatAPI は SQSAPI のモックで1件のメッセージを受信させ、可視性タイムアウトの延長回数と削除を記録する
+type heartbeatAPI struct {
+	extended atomic.Int32
+	deleted  chan struct{}
+}
+
+func newHeartbeatAPI(t *testing.T) (*heartbeatAPI, sqspkg.SQS) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	mockAPI := sqsmock.NewMockSQSAPI(ctrl)
+	h := &heartbeatAPI{deleted: make(chan struct{})}
+
+	mockAPI.EXPECT().
+		ReceiveMessage(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, params *awssqs.ReceiveMessageInput, optFns ...func(*awssqs.Options)) (*awssqs.ReceiveMessageOutput, error) {
+			// 受信時の可視性タイムアウトもハートビートと同じ値にする
+			if params.VisibilityTimeout != 1 {
+				t.Errorf("expected VisibilityTimeout to be 1, got %d", params.VisibilityTimeout)
+			}
+			return &awssqs.ReceiveMessageOutput{Messages: []types.Message{newTestMessage("msg-1")}}, nil
+		}).
+		Times(1)
+	mockAPI.EXPECT().
+		ReceiveMessage(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, params *awssqs.ReceiveMessageInput, optFns ...func(*awssqs.Options)) (*awssqs.ReceiveMessageOutput, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}).
+		AnyTimes()
+	mockAPI.EXPECT().
+		ChangeMessageVisibility(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, params *awssqs.ChangeMessageVisibilityInput, optFns ...func(*awssqs.Options)) (*awssqs.ChangeMessageVisibilityOutput, error) {
+			if aws.ToString(params.ReceiptHandle) != "receipt-msg-1" || params.VisibilityTimeout != 1 {
+				t.Errorf("unexpected ChangeMessageVisibility input: %s, %d", aws.ToString(params.ReceiptHandle), params.VisibilityTimeout)
+			}
+			h.extended.Add(1)
+			return &awssqs.ChangeMessageVisibilityOutput{}, nil
+		}).
+		AnyTimes()
+	mockAPI.EXPECT().
+		DeleteMessage(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, params *awssqs.DeleteMessageInput, optFns ...func(*awssqs.Options)) (*awssqs.DeleteMessageOutput, error) {
+			close(h.deleted)
+			return &awssqs.DeleteMessageOutput{}, nil
+		}).
+		MaxTimes(1)
+	return h, sqspkg.NewSQSClientWithAPI(mockAPI)
+}
+
+// waitExtended は可視性タイムアウトが n 回以上延長されるまで待機する
+func (h *heartbeatAPI) waitExtended(t *testing.T, n int32) {
+	t.Helper()
+	deadline := time.After(time.Second)
+	for h.extended.Load() < n {
+		select {
+		case <-deadline:
+			t.Fatalf("visibility was extended %d times, want at least %d", h.extended.Load(), n)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+// assertStopped はハートビートが停止し、以降は可視性タイムアウトが延長されないことを確認する
+func (h *heartbeatAPI) assertStopped(t *testing.T) {
+	t.Helper()
+	before := h.extended.Load()
+	time.Sleep(50 * time.Millisecond)
+	if after := h.extended.Load(); after != before {
+		t.Errorf("visibility was extended %d times after the heartbeat should have stopped", after-before)
+	}
+}
+
+// TestSQSPoller_VisibilityHeartbeat は実行中のタスクの可視性タイムアウトが延長され、完了後に停止することを確認します。
+func TestSQSPoller_VisibilityHeartbeat(t *testing.T) {
+	api, client := newHeartbeatAPI(t)
+
+	w := worker.NewWorker()
+	w.Run(context.Background())
+	defer w.Shutdown(context.Background())
+
+	release := make(chan struct{})
+	handler := func(msg types.Message) (worker.Task, error) {
+		return tasks.NewTask(func(ctx context.Context) error {
+			<-release
+			return nil
+		}), nil
+	}
+	stop := runPoller(t, worker.NewSQSPoller(client, w, testQueueURL, handler,
+		worker.WithVisibilityHeartbeat(time.Second, 10*time.Millisecond),
+	))
+	defer stop()
+
+	// タスクの実行中は延長し続ける
+	api.waitExtended(t, 3)
+	close(release)
+
+	select {
+	case <-api.deleted:
+	case <-time.After(time.Second):
+		t.Fatal("message was not deleted")
+	}
+	api.assertStopped(t)
+}
+
+// TestSQSPoller_VisibilityHeartbeatStopsOnShutdown はWorkerの停止でジョブが破棄された場合にハートビートが停止することを確認します。
+func TestSQSPoller_VisibilityHeartbeatStopsOnShutdown(t *testing.T) {
+	api, client := newHeartbeatAPI(t)
+
+	// Run 前のWorkerに投入し、キューで待機している間も延長されることを確認する
+	w := worker.NewWorker()
+	handler := func(msg types.Message) (worker.Task, error) {
+		return tasks.NewTask(func(ctx context.Context) error { return nil }), nil
+	}
+	stop := runPoller(t, worker.NewSQSPoller(client, w, testQueueURL, handler,
+		worker.WithVisibilityHeartbeat(time.Second, 10*time.Millisecond),
+	))
+	defer stop()
+
+	api.waitExtended(t, 2)
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	api.assertStopped(t)
+
+	// 破棄されたメッセージは削除せず、再配信に任せる
+	select {
+	case <-api.deleted:
+		t.Error("discarded message should not be deleted")
+	default:
+	}
+}
+
+func applyDeleteOptions(options []sqspkg.DeleteMessageOptionFunc) *awssqs.DeleteMessageInput {
+	input := &awssqs.DeleteMessageInput{}
+	for _, option := range options {
+		option(input)
+	}
+	return input
+}
+
+func newGroupMessage(id, groupID string) types.Message {
+	msg := newTestMessage(id)
+	msg.Attributes = map[string]string{string(types.MessageSystemAttributeNameMessageGroupId): groupID}
+	return msg
+}
+
+// TestSQSPoller_FIFO は同じグループのメッセージが順に1件ずつ処理され、
+// 前のメッセージが失敗した場合は後続のメッセージを実行しないことを確認します。
+func TestSQSPoller_FIFO(t *testing.T) {
+	tests := []struct {
+		name        string
+		failing     string
+		wantOrder   map[string][]string
+		wantDeleted int
+	}{
+		{
+			name:        "正常系: グループごとに受信順で処理する",
+			wantOrder:   map[string][]string{"g1": {"a1", "a2", "a3"}, "g2": {"b1", "b2"}},
+			wantDeleted: 5,
+		},
+		{
+			name:        "異常系: 失敗したメッセージ以降の同じグループのメッセージは実行しない",
+			failing:     "a2",
+			wantOrder:   map[string][]string{"g1": {"a1", "a2"}, "g2": {"b1", "b2"}},
+			wantDeleted: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockSQS := sqsmock.NewMockSQS(ctrl)
+			messages := []types.Message{
+				newGroupMessage("a1", "g1"), newGroupMessage("b1", "g2"), newGroupMessage("a2", "g1"),
+				newGroupMessage("b2", "g2"), newGroupMessage("a3", "g1"),
+			}
+			mockSQS.EXPECT().
+				ReceiveMessages(gomock.Any(), testQueueURL, gomock.Any()).
+				DoAndReturn(func(ctx context.Context, queueURL string, options ...sqspkg.ReceiveMessageOptionFunc) ([]types.Message, error) {
+					// グループIDを判定するためにシステム属性を要求する
+					input := &awssqs.ReceiveMessageInput{}
+					for _, option := range options {
+						option(input)
+					}
+					if len(input.MessageSystemAttributeNames) != 1 || input.MessageSystemAttributeNames[0] != types.MessageSystemAttributeNameMessageGroupId {
+						t.Errorf("expected MessageGroupId attribute to be requested, got %v", input.MessageSystemAttributeNames)
+					}
+					return messages, nil
+				}).
+				Times(1)
+			mockSQS.EXPECT().
+				ReceiveMessages(gomock.Any(), testQueueURL, gomock.Any()).
+				DoAndReturn(func(ctx context.Context, queueURL string, options ...sqspkg.ReceiveMessageOptionFunc) ([]types.Message, error) {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}).
+				AnyTimes()
+
+			var deleted atomic.Int32
+			mockSQS.EXPECT().
+				DeleteMessage(gomock.Any(), testQueueURL, gomock.Any()).
+				DoAndReturn(func(ctx context.Context, queueURL string, options ...sqspkg.DeleteMessageOptionFunc) error {
+					deleted.Add(1)
+					return nil
+				}).
+				AnyTimes()
+
+			w := worker.NewWorker(worker.WithRunningWorkers(5), worker.WithMaxWorkerJobs(10), worker.WithRetryPolicy(worker.RetryPolicy{MaxAttempts: 1}))
+			w.Run(context.Background())
+
+			var mu sync.Mutex
+			executed := make(map[string][]string)
+			running := make(map[string]bool)
+			handler := func(msg types.Message) (worker.Task, error) {
+				id := aws.ToString(msg.MessageId)
+				groupID := sqspkg.MessageGroupID(msg)
+				return tasks.NewTask(func(ctx context.Context) error {
+					mu.Lock()
+					if running[groupID] {
+						t.Errorf("messages in group %s were processed concurrently", groupID)
+					}
+					running[groupID] = true
+					executed[groupID] = append(executed[groupID], id)
+					mu.Unlock()
+
+					time.Sleep(5 * time.Millisecond)
+
+					mu.Lock()
+					running[groupID] = false
+					mu.Unlock()
+					if id == tt.failing {
+						return errors.New("task failed")
+					}
+					return nil
+				}), nil
+			}
+			stop := runPoller(t, worker.NewSQSPoller(mockSQS, w, testQueueURL, handler, worker.WithPollerFIFO()))
+
+			deadline := time.After(time.Second)
+			for deleted.Load() < int32(tt.wantDeleted) {
+				select {
+				case <-deadline:
+					t.Fatalf("deleted %d messages, want %d", deleted.Load(), tt.wantDeleted)
+				case <-time.After(5 * time.Millisecond):
+				}
+			}
+			// スキップしたメッセージが実行されないことを確認するため少し待機する
+			time.Sleep(30 * time.Millisecond)
+			stop()
+			w.Shutdown(context.Background())
+
+			mu.Lock()
+			defer mu.Unlock()
+			if fmt.Sprint(executed) != fmt.Sprint(tt.wantOrder) {
+				t.Errorf("executed = %v, want %v", executed, tt.wantOrder)
+			}
+			if got := deleted.Load(); got != int32(tt.wantDeleted) {
+				t.Errorf("deleted %d messages, want %d", got, tt.wantDeleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAutoscaleInterval         = 1 * time.Second
+	defaultAutoscaleCoolDown         = 30 * time.Second
+	defaultAutoscaleTargetQueueDelay = 1 * time.Second
+	latencyEWMAAlpha                 = 0.2
+)
+
+// ScaleReason は同時実行数を変更した理由です。
+type ScaleReason string
+
+const (
+	// ScaleReasonManual は Resize による変更です。
+	ScaleReasonManual ScaleReason = "manual"
+	// ScaleReasonQueueDepth はキューの滞留を解消するための拡大です。
+	ScaleReasonQueueDepth ScaleReason = "queue_depth"
+	// ScaleReasonIdle はクールダウン期間を過ぎても使われなかった枠の縮小です。
+	ScaleReasonIdle ScaleReason = "idle"
+)
+
+// ScaleEvent は同時実行数の変更を表します。
+type ScaleEvent struct {
+	Worker     string
+	From       int
+	To         int
+	Reason     ScaleReason
+	QueueDepth int
+	AvgLatency time.Duration
+	At         time.Time
+}
+
+// ScaleHook は同時実行数の変更を受け取る関数型です。
+type ScaleHook func(event ScaleEvent)
+
+// PoolStats はプールの現在の状態です。負荷をかけた状態での調整に使用します。
+type PoolStats struct {
+	// Size は現在の同時実行数の上限です。
+	Size int
+	// Active は実行中のジョブ数です。
+	Active int
+	// Min, Max は同時実行数の範囲です (WithMinWorkerJobs / WithMaxWorkerJobs)。
+	Min int
+	Max int
+	// QueueDepth はキューで待機しているジョブ数です (全優先度の合計)。
+	QueueDepth int
+	// AvgLatency はジョブの処理時間の指数移動平均です。
+	AvgLatency time.Duration
+}
+
+// AutoscalePolicy はキューの滞留とタスクの処理時間から同時実行数を調整する方針です。
+// ゼロ値のフィールドにはデフォルト値が使用されます。
+type AutoscalePolicy struct {
+	// Interval は調整を判定する間隔です。
+	Interval time.Duration
+	// TargetQueueDelay はキューに滞留したジョブを処理し終えるまでの目標時間です。
+	// 滞留数 × 平均処理時間 ÷ 同時実行数 がこの時間を超える場合に拡大します。
+	TargetQueueDelay time.Duration
+	// CoolDown は使われていない枠を縮小するまでの待機時間です。
+	CoolDown time.Duration
+}
+
+func (p AutoscalePolicy) normalize() AutoscalePolicy {
+	if p.Interval <= 0 {
+		p.Interval = defaultAutoscaleInterval
+	}
+	if p.TargetQueueDelay <= 0 {
+		p.TargetQueueDelay = defaultAutoscaleTargetQueueDelay
+	}
+	if p.CoolDown <= 0 {
+		p.CoolDown = defaultAutoscaleCoolDown
+	}
+	return p
+}
+
+// WithAutoscaling は同時実行数の自動調整を有効にします。
+// 同時実行数は WithRunningWorkers の値から始まり、WithMinWorkerJobs と WithMaxWorkerJobs の範囲で増減します。
+func WithAutoscaling(policy AutoscalePolicy) WorkerOption {
+	return func(w *worker) {
+		normalized := policy.normalize()
+		w.autoscale = &normalized
+	}
+}
+
+// WithScaleHook は同時実行数の変更を受け取るフックを設定します。
+func WithScaleHook(hook ScaleHook) WorkerOption {
+	return func(w *worker) {
+		w.scaleHooks = append(w.scaleHooks, hook)
+	}
+}
+
+// WithSerialExecution はジョブを1つずつ投入順に実行します。
+// 同時実行数は1に固定され (Resize と自動調整は無効)、WithMaxWorkerJobs はキューの容量としてのみ使用されます。
+// 再試行の待機中も後続のジョブは開始しないため、同じ優先度のジョブの順序が保たれます。
+func WithSerialExecution() WorkerOption {
+	return func(w *worker) {
+		w.serial = true
+	}
+}
+
+// pool はサイズを変更できる同時実行枠と、ジョブの処理時間の統計を管理します。
+type pool struct {
+	mu     sync.Mutex
+	size   int
+	active int
+	// peak は前回の判定以降の実行中ジョブ数の最大値
+	peak int
+	// released は枠が空いた、またはサイズが変わったときにクローズして待機中の acquire を起こす
+	released   chan struct{}
+	avgLatency time.Duration
+	// lastBusy は最後に枠が不足していた(または変更された)時刻で、縮小のクールダウンの起点
+	lastBusy time.Time
+}
+
+func newPool(size int) *pool {
+	return &pool{
+		size:     size,
+		released: make(chan struct{}),
+		lastBusy: time.Now(),
+	}
+}
+
+// acquire は枠が空くまで待機して1つ確保します。
+func (p *pool) acquire(ctx context.Context) error {
+	for {
+		p.mu.Lock()
+		if p.active < p.size {
+			p.active++
+			p.peak = max(p.peak, p.active)
+			p.mu.Unlock()
+			return nil
+		}
+		released := p.released
+		p.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release は枠を解放します。
+func (p *pool) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.active--
+	p.notifyLocked()
+}
+
+// observe はジョブの処理時間を統計に反映します。
+func (p *pool) observe(latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.avgLatency == 0 {
+		p.avgLatency = latency
+		return
+	}
+	p.avgLatency = time.Duration(latencyEWMAAlpha*float64(latency) + (1-latencyEWMAAlpha)*float64(p.avgLatency))
+}
+
+// resize はサイズを変更し、変更前のサイズを返します。
+// 縮小した場合、実行中のジョブは中断せず、完了して枠が空くまで新しいジョブを開始しません。
+func (p *pool) resize(size int) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	from := p.size
+	p.size = size
+	p.lastBusy = time.Now()
+	p.notifyLocked()
+	return from
+}
+
+func (p *pool) notifyLocked() {
+	close(p.released)
+	p.released = make(chan struct{})
+}
+
+// sample は判定に使う値を取得し、peak をリセットします。
+func (p *pool) sample() (size, peak int, avgLatency time.Duration, lastBusy time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	size, peak, avgLatency, lastBusy = p.size, p.peak, p.avgLatency, p.lastBusy
+	p.peak = p.active
+	return
+}
+
+func (p *pool) markBusy(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastBusy = now
+}
+
+// Resize は同時実行数の上限を変更し、適用した値を返します。
+// 値は WithMinWorkerJobs と WithMaxWorkerJobs の範囲に丸められます。
+// 自動調整が有効な場合、変更後の値からクールダウン期間が再開されます。
+func (w *worker) Resize(n int) int {
+	size := w.clampSize(n)
+	w.applySize(size, ScaleReasonManual)
+	return size
+}
+
+// Stats はプールの現在の状態を返します。
+func (w *worker) Stats() PoolStats {
+	w.pool.mu.Lock()
+	defer w.pool.mu.Unlock()
+	return PoolStats{
+		Size:       w.pool.size,
+		Active:     w.pool.active,
+		Min:        w.clampSize(w.minWorkerJobs),
+		Max:        w.clampSize(w.maxWorkerJobs),
+		QueueDepth: w.lanes.len(),
+		AvgLatency: w.pool.avgLatency,
+	}
+}
+
+func (w *worker) clampSize(n int) int {
+	if w.serial {
+		return 1
+	}
+	return min(max(n, w.minWorkerJobs), w.maxWorkerJobs)
+}
+
+// applySize はサイズを変更し、変更があればログとフックに通知します。
+func (w *worker) applySize(size int, reason ScaleReason) {
+	from := w.pool.resize(size)
+	if from == size {
+		return
+	}
+	stats := w.Stats()
+	event := ScaleEvent{
+		Worker:     w.name,
+		From:       from,
+		To:         size,
+		Reason:     reason,
+		QueueDepth: stats.QueueDepth,
+		AvgLatency: stats.AvgLatency,
+		At:         time.Now(),
+	}
+	slog.Info("worker pool resized", "name", w.name, "from", from, "to", size, "reason", reason, "queueDepth", event.QueueDepth, "avgLatency", event.AvgLatency)
+	for _, hook := range w.scaleHooks {
+		hook(event)
+	}
+}
+
+// autoscaleLoop は停止するまで一定間隔で同時実行数を調整します。
+func (w *worker) autoscaleLoop(ctx context.Context) {
+	ticker := time.NewTicker(w.autoscale.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.state.stopped:
+			return
+		case now := <-ticker.C:
+			w.autoscaleOnce(now)
+		}
+	}
+}
+
+// autoscaleOnce は1回分の調整を判定します。
+//   - 拡大: 滞留数 × 平均処理時間 ÷ 同時実行数 が TargetQueueDelay を超える場合、超えない数まで即座に拡大する
+//   - 縮小: 判定間隔中の最大実行数がサイズを下回る状態が CoolDown 続いた場合、その最大実行数まで縮小する
+func (w *worker) autoscaleOnce(now time.Time) {
+	size, peak, avgLatency, lastBusy := w.pool.sample()
+	depth := w.lanes.len()
+
+	if depth > 0 {
+		latency := max(avgLatency, time.Millisecond)
+		needed := int(math.Ceil(float64(depth) * float64(latency) / float64(w.autoscale.TargetQueueDelay)))
+		if desired := w.clampSize(max(needed, size)); desired > size {
+			w.applySize(desired, ScaleReasonQueueDepth)
+			return
+		}
+		// 滞留があるうちは縮小しない
+		w.pool.markBusy(now)
+		return
+	}
+
+	if peak >= size {
+		w.pool.markBusy(now)
+		return
+	}
+	if now.Sub(lastBusy) < w.autoscale.CoolDown {
+		return
+	}
+	if desired := w.clampSize(peak); desired < size {
+		w.applySize(desired, ScaleReasonIdle)
+	}
+}
@@ -0,0 +1,167 @@
+package worker_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks"
+)
+
+// concurrencyProbe は同時に実行されたタスク数の最大値を記録する
+type concurrencyProbe struct {
+	current atomic.Int32
+	peak    atomic.Int32
+}
+
+func (p *concurrencyProbe) task(d time.Duration) worker.Task {
+	return tasks.NewTask(func(ctx context.Context) error {
+		n := p.current.Add(1)
+		defer p.current.Add(-1)
+		for {
+			peak := p.peak.Load()
+			if n <= peak || p.peak.CompareAndSwap(peak, n) {
+				break
+			}
+		}
+		time.Sleep(d)
+		return nil
+	})
+}
+
+// scaleRecorder はScaleHookで受け取ったイベントを記録する
+type scaleRecorder struct {
+	mu     sync.Mutex
+	events []worker.ScaleEvent
+}
+
+func (r *scaleRecorder) hook(event worker.ScaleEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *scaleRecorder) find(reason worker.ScaleReason) (worker.ScaleEvent, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, event := range r.events {
+		if event.Reason == reason {
+			return event, true
+		}
+	}
+	return worker.ScaleEvent{}, false
+}
+
+// TestWorker_Resize は Resize が範囲に丸められ、同時実行数に反映されることを確認します。
+func TestWorker_Resize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		size     int
+		expected int
+	}{
+		{name: "正常系: 範囲内の値はそのまま適用される", size: 3, expected: 3},
+		{name: "正常系: 下限未満は minWorkerJobs に丸められる", size: 0, expected: 2},
+		{name: "正常系: 上限超過は maxWorkerJobs に丸められる", size: 100, expected: 8},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			recorder := &scaleRecorder{}
+			w := worker.NewWorker(
+				worker.WithMinWorkerJobs(2),
+				worker.WithMaxWorkerJobs(8),
+				worker.WithRunningWorkers(4),
+				worker.WithScaleHook(recorder.hook),
+			)
+			if got := w.Resize(tt.size); got != tt.expected {
+				t.Fatalf("expected resize to %d, got %d", tt.expected, got)
+			}
+			if got := w.Stats().Size; got != tt.expected {
+				t.Errorf("expected pool size %d, got %d", tt.expected, got)
+			}
+			event, ok := recorder.find(worker.ScaleReasonManual)
+			if !ok || event.From != 4 || event.To != tt.expected {
+				t.Errorf("expected manual scale event 4 -> %d, got %+v", tt.expected, event)
+			}
+		})
+	}
+}
+
+// TestWorker_PoolLimitsConcurrency はプールのサイズを超えてジョブが同時実行されないことを確認します。
+func TestWorker_PoolLimitsConcurrency(t *testing.T) {
+	t.Parallel()
+
+	probe := &concurrencyProbe{}
+	w := worker.NewWorker(worker.WithRunningWorkers(2))
+	w.Run(context.Background())
+	for i := 0; i < 8; i++ {
+		w.AddJob(probe.task(10 * time.Millisecond))
+	}
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+	if got := probe.peak.Load(); got != 2 {
+		t.Errorf("expected peak concurrency 2, got %d", got)
+	}
+}
+
+// TestWorker_Autoscaling はキューが滞留すると拡大し、アイドル状態がクールダウン期間続くと縮小することを確認します。
+func TestWorker_Autoscaling(t *testing.T) {
+	t.Parallel()
+
+	recorder := &scaleRecorder{}
+	probe := &concurrencyProbe{}
+	w := worker.NewWorker(
+		worker.WithMinWorkerJobs(1),
+		worker.WithMaxWorkerJobs(32),
+		worker.WithRunningWorkers(1),
+		worker.WithAutoscaling(worker.AutoscalePolicy{
+			Interval:         5 * time.Millisecond,
+			TargetQueueDelay: 20 * time.Millisecond,
+			CoolDown:         30 * time.Millisecond,
+		}),
+		worker.WithScaleHook(recorder.hook),
+	)
+	w.Run(context.Background())
+	defer w.Shutdown(context.Background())
+
+	for i := 0; i < 30; i++ {
+		if err := w.AddJob(probe.task(10 * time.Millisecond)); err != nil {
+			t.Fatalf("failed to add job: %v", err)
+		}
+	}
+
+	// 縮小は段階的に行われるため、下限に戻るまで待機する
+	waitFor(t, func() bool {
+		_, ok := recorder.find(worker.ScaleReasonIdle)
+		return ok && w.Stats().Size == 1
+	})
+
+	up, ok := recorder.find(worker.ScaleReasonQueueDepth)
+	if !ok || up.To <= 1 || up.QueueDepth == 0 {
+		t.Errorf("expected a scale up event caused by queue depth, got %+v", up)
+	}
+	if probe.peak.Load() <= 1 {
+		t.Errorf("expected jobs to run concurrently after scale up, got peak %d", probe.peak.Load())
+	}
+}
+
+// waitFor は条件が満たされるまで待機する
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition was not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
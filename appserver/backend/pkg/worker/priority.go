@@ -0,0 +1,201 @@
+package worker
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidPriority は定義されていない優先度を指定した場合のエラー
+var ErrInvalidPriority = errors.New("invalid job priority")
+
+// Priority はジョブの優先度(レーン)です。ゼロ値は PriorityNormal です。
+type Priority int
+
+const (
+	// PriorityNormal は通常のジョブです。AddJob / AddJobAsync はこのレーンに追加します。
+	PriorityNormal Priority = iota
+	// PriorityHigh はメールアドレス変更の確認など、利用者を待たせる緊急のジョブです。
+	PriorityHigh
+	// PriorityLow は一括処理など、遅れても問題のないジョブです。
+	PriorityLow
+
+	numPriorities = 3
+)
+
+const (
+	defaultHighWeight   = 6
+	defaultNormalWeight = 3
+	defaultLowWeight    = 1
+)
+
+// Priorities はすべての優先度を高い順に返します。
+func Priorities() []Priority {
+	return []Priority{PriorityHigh, PriorityNormal, PriorityLow}
+}
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityHigh:
+		return "high"
+	case PriorityNormal:
+		return "normal"
+	case PriorityLow:
+		return "low"
+	}
+	return "unknown"
+}
+
+func (p Priority) valid() bool {
+	return p >= 0 && p < numPriorities
+}
+
+// WithPriorityWeights はレーンごとの重みを設定します (デフォルト: high=6, normal=3, low=1)。
+// 全レーンにジョブがある場合、取り出されるジョブの比率は重みの比率になります。
+// 重みが1未満のレーンは1として扱い、低優先度のジョブも必ず処理が進むようにします。
+func WithPriorityWeights(high, normal, low int) WorkerOption {
+	return func(w *worker) {
+		w.laneWeights = [numPriorities]int{
+			PriorityHigh:   max(high, 1),
+			PriorityNormal: max(normal, 1),
+			PriorityLow:    max(low, 1),
+		}
+	}
+}
+
+// lanes は優先度ごとのジョブキューです。
+// 取り出しは Smooth Weighted Round Robin で行い、各レーンを重みに比例した頻度で公平に処理します。
+type lanes struct {
+	queues  [numPriorities]chan job
+	weights [numPriorities]int
+	// current は重み付きラウンドロビンの現在値で、dispatchLoop のゴルーチンからのみ参照する
+	current [numPriorities]int
+}
+
+func newLanes(capacity int, weights [numPriorities]int) *lanes {
+	l := &lanes{weights: weights}
+	for i := range l.queues {
+		l.queues[i] = make(chan job, capacity)
+	}
+	return l
+}
+
+// len は全レーンのジョブ数の合計を返します。
+func (l *lanes) len() int {
+	n := 0
+	for _, q := range l.queues {
+		n += len(q)
+	}
+	return n
+}
+
+// lengths は優先度ごとのジョブ数を返します。
+func (l *lanes) lengths() map[Priority]int {
+	lengths := make(map[Priority]int, numPriorities)
+	for _, p := range Priorities() {
+		lengths[p] = len(l.queues[p])
+	}
+	return lengths
+}
+
+// close はすべてのレーンを閉じます。送信側との排他は呼び出し元で行います。
+func (l *lanes) close() {
+	for _, q := range l.queues {
+		close(q)
+	}
+}
+
+// drain は閉じられたレーンに残ったジョブをすべて取り出します。
+// lanes.close の後、取り出し側のゴルーチンが存在しない状態で呼び出すこと。
+func (l *lanes) drain() []job {
+	var jobs []job
+	for _, q := range l.queues {
+		for j := range q {
+			jobs = append(jobs, j)
+		}
+	}
+	return jobs
+}
+
+// pick はジョブのあるレーンから重み付きラウンドロビンで1つ選びます。
+// すべてのレーンが空の場合は false を返します。
+func (l *lanes) pick() (Priority, bool) {
+	total := 0
+	best := Priority(-1)
+	for i, q := range l.queues {
+		if len(q) == 0 {
+			continue
+		}
+		l.current[i] += l.weights[i]
+		total += l.weights[i]
+		if best < 0 || l.current[i] > l.current[best] {
+			best = Priority(i)
+		}
+	}
+	if best < 0 {
+		return 0, false
+	}
+	l.current[best] -= total
+	return best, true
+}
+
+// next は次に処理するジョブを取り出します。
+// ジョブがあるレーンが複数ある場合は重みに従って選び、すべて空の場合はいずれかに追加されるまで待機します。
+// すべてのレーンが閉じられて空になった場合は ok=false を返します。
+func (l *lanes) next(ctx context.Context) (j job, ok bool, err error) {
+	// 取り出しは dispatchLoop のみが行うため、len が正のレーンからの受信はブロックしない
+	if p, found := l.pick(); found {
+		if j, ok := <-l.queues[p]; ok {
+			return j, true, nil
+		}
+	}
+
+	queues := l.queues
+	for {
+		open := false
+		for _, q := range queues {
+			if q != nil {
+				open = true
+			}
+		}
+		if !open {
+			return job{}, false, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return job{}, false, ctx.Err()
+		case j, ok := <-queues[PriorityHigh]:
+			if ok {
+				return j, true, nil
+			}
+			queues[PriorityHigh] = nil
+		case j, ok := <-queues[PriorityNormal]:
+			if ok {
+				return j, true, nil
+			}
+			queues[PriorityNormal] = nil
+		case j, ok := <-queues[PriorityLow]:
+			if ok {
+				return j, true, nil
+			}
+			queues[PriorityLow] = nil
+		}
+	}
+}
+
+// AddJobWithPriority は優先度を指定してジョブをキューに追加します。
+// キューが満杯の場合は空きができるまで待機します。
+func (w *worker) AddJobWithPriority(priority Priority, tasks ...Task) error {
+	return w.enqueue(context.Background(), priority, job{task: tasks}, true)
+}
+
+// AddJobAsyncWithPriority は優先度を指定してジョブをキューに追加します。
+// レーンが満杯の場合は ErrJobQueueFull を返します。
+func (w *worker) AddJobAsyncWithPriority(priority Priority, tasks ...Task) error {
+	return w.enqueue(context.Background(), priority, job{task: tasks}, false)
+}
+
+// QueueLengths は優先度ごとのキューの長さを返します。
+func (w *worker) QueueLengths() map[Priority]int {
+	return w.lanes.lengths()
+}
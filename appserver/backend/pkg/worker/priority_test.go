@@ -0,0 +1,144 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks"
+)
+
+// orderRecorder はタスクが実行された順に優先度を記録する
+type orderRecorder struct {
+	mu    sync.Mutex
+	order []worker.Priority
+}
+
+func (r *orderRecorder) task(p worker.Priority) worker.Task {
+	return tasks.NewTask(func(ctx context.Context) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.order = append(r.order, p)
+		return nil
+	})
+}
+
+func (r *orderRecorder) all() []worker.Priority {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]worker.Priority(nil), r.order...)
+}
+
+// fillLanes は Run 前のWorkerに優先度ごとのジョブを追加する
+func fillLanes(t *testing.T, w worker.Worker, recorder *orderRecorder, counts map[worker.Priority]int) {
+	t.Helper()
+	for _, p := range []worker.Priority{worker.PriorityLow, worker.PriorityNormal, worker.PriorityHigh} {
+		for i := 0; i < counts[p]; i++ {
+			if err := w.AddJobWithPriority(p, recorder.task(p)); err != nil {
+				t.Fatalf("failed to add %s job: %v", p, err)
+			}
+		}
+	}
+}
+
+// TestWorker_PriorityWeightedFair は全レーンにジョブがある場合、重みの比率で取り出されることを確認します。
+func TestWorker_PriorityWeightedFair(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		opts     []worker.WorkerOption
+		expected map[worker.Priority]int
+	}{
+		{
+			name:     "正常系: デフォルトの重み (6:3:1)",
+			expected: map[worker.Priority]int{worker.PriorityHigh: 6, worker.PriorityNormal: 3, worker.PriorityLow: 1},
+		},
+		{
+			name:     "正常系: 重みを変更できる (2:2:1)",
+			opts:     []worker.WorkerOption{worker.WithPriorityWeights(2, 2, 1)},
+			expected: map[worker.Priority]int{worker.PriorityHigh: 4, worker.PriorityNormal: 4, worker.PriorityLow: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// 同時実行数1では取り出した順に実行される
+			recorder := &orderRecorder{}
+			w := worker.NewWorker(append([]worker.WorkerOption{worker.WithRunningWorkers(1)}, tt.opts...)...)
+			fillLanes(t, w, recorder, map[worker.Priority]int{
+				worker.PriorityHigh: 50, worker.PriorityNormal: 50, worker.PriorityLow: 50,
+			})
+			w.Run(context.Background())
+			if err := w.Shutdown(context.Background()); err != nil {
+				t.Fatalf("failed to shutdown: %v", err)
+			}
+
+			order := recorder.all()
+			if len(order) != 150 {
+				t.Fatalf("expected 150 executed jobs, got %d", len(order))
+			}
+			// 重みの合計2周分で比率を確認する
+			window := 0
+			for _, n := range tt.expected {
+				window += n
+			}
+			got := map[worker.Priority]int{}
+			for _, p := range order[:window] {
+				got[p]++
+			}
+			for p, n := range tt.expected {
+				if got[p] != n {
+					t.Errorf("expected %d %s jobs in the first %d, got %d (order=%v)", n, p, window, got[p], order[:window])
+				}
+			}
+		})
+	}
+}
+
+// TestWorker_PriorityHighJumpsBacklog は低優先度の滞留があっても高優先度のジョブが先に処理されることを確認します。
+func TestWorker_PriorityHighJumpsBacklog(t *testing.T) {
+	t.Parallel()
+
+	recorder := &orderRecorder{}
+	w := worker.NewWorker(worker.WithRunningWorkers(1))
+	fillLanes(t, w, recorder, map[worker.Priority]int{worker.PriorityLow: 20, worker.PriorityHigh: 1})
+
+	lengths := w.QueueLengths()
+	if lengths[worker.PriorityLow] != 20 || lengths[worker.PriorityHigh] != 1 || lengths[worker.PriorityNormal] != 0 {
+		t.Errorf("unexpected queue lengths: %v", lengths)
+	}
+
+	w.Run(context.Background())
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+	if order := recorder.all(); order[0] != worker.PriorityHigh {
+		t.Errorf("expected the high priority job to run first, got %v", order)
+	}
+}
+
+// TestWorker_PriorityLaneCapacity はレーンごとに容量が独立していることを確認します。
+func TestWorker_PriorityLaneCapacity(t *testing.T) {
+	t.Parallel()
+
+	w := worker.NewWorker(worker.WithMaxWorkerJobs(1))
+	if err := w.AddJobAsyncWithPriority(worker.PriorityLow, noopTask()); err != nil {
+		t.Fatalf("failed to add low job: %v", err)
+	}
+	if err := w.AddJobAsyncWithPriority(worker.PriorityLow, noopTask()); !errors.Is(err, worker.ErrJobQueueFull) {
+		t.Errorf("expected ErrJobQueueFull for the full low lane, got %v", err)
+	}
+	if err := w.AddJobAsyncWithPriority(worker.PriorityHigh, noopTask()); err != nil {
+		t.Errorf("expected high lane to accept a job, got %v", err)
+	}
+	if err := w.AddJobWithPriority(worker.Priority(99), noopTask()); !errors.Is(err, worker.ErrInvalidPriority) {
+		t.Errorf("expected ErrInvalidPriority, got %v", err)
+	}
+	w.Shutdown(context.Background())
+}
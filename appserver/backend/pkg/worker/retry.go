@@ -0,0 +1,196 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	defaultRetryMaxAttempts    = 1
+	defaultRetryInitialBackoff = 100 * time.Millisecond
+	defaultRetryMaxBackoff     = 30 * time.Second
+	defaultRetryMultiplier     = 2.0
+	defaultRetryJitter         = 0.2
+)
+
+// RetryPolicy はタスク失敗時の再試行方針です。
+// Jitter 以外のゼロ値のフィールドにはデフォルト値が使用されます。
+type RetryPolicy struct {
+	// MaxAttempts は初回を含む最大試行回数です (1の場合は再試行しない)。
+	MaxAttempts int
+	// InitialBackoff は1回目の再試行までの待機時間です。
+	InitialBackoff time.Duration
+	// MaxBackoff は待機時間の上限です。
+	MaxBackoff time.Duration
+	// Multiplier は再試行ごとに待機時間へ掛ける倍率です。
+	Multiplier float64
+	// Jitter は待機時間に加えるランダム幅の割合です (0.0-1.0)。
+	// 例えば 0.2 の場合、待機時間は ±20% の範囲でばらつきます。
+	Jitter float64
+	// Retryable はエラーが再試行可能かどうかを判定します。
+	// nil の場合は DefaultRetryable を使用します。
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy は再試行しないデフォルトの方針です。
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    defaultRetryMaxAttempts,
+	InitialBackoff: defaultRetryInitialBackoff,
+	MaxBackoff:     defaultRetryMaxBackoff,
+	Multiplier:     defaultRetryMultiplier,
+	Jitter:         defaultRetryJitter,
+}
+
+// normalize はゼロ値や範囲外のフィールドをデフォルト値で補完した方針を返します。
+func (p RetryPolicy) normalize() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaultRetryMaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = defaultRetryInitialBackoff
+	}
+	if p.MaxBackoff < p.InitialBackoff {
+		p.MaxBackoff = max(defaultRetryMaxBackoff, p.InitialBackoff)
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = defaultRetryMultiplier
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		p.Jitter = defaultRetryJitter
+	}
+	if p.Retryable == nil {
+		p.Retryable = DefaultRetryable
+	}
+	return p
+}
+
+// Backoff は attempt 回目の試行が失敗した後の待機時間を返します。
+// 待機時間は InitialBackoff * Multiplier^(attempt-1) を MaxBackoff で頭打ちにし、Jitter を加えたものです。
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	p = p.normalize()
+	if attempt < 1 {
+		attempt = 1
+	}
+	backoff := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	backoff = math.Min(backoff, float64(p.MaxBackoff))
+	if p.Jitter > 0 {
+		backoff += backoff * p.Jitter * (rand.Float64()*2 - 1)
+	}
+	return time.Duration(backoff)
+}
+
+// PermanentError は再試行しても成功しないエラーを表します。
+// タスクは Permanent でラップしたエラーを返すことで、即座に失敗として扱わせることができます。
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return fmt.Sprintf("permanent error: %v", e.Err)
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent はエラーを再試行不可能なエラーとしてラップします。
+// nil を渡した場合は nil を返します。
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent はエラーが再試行不可能なエラーかどうかを判定します。
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// DefaultRetryable はデフォルトの再試行判定です。
+// PermanentError とコンテキストのキャンセルは再試行しません。
+func DefaultRetryable(err error) bool {
+	if IsPermanent(err) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	return true
+}
+
+// TaskResult はタスクの最終結果です。
+type TaskResult struct {
+	// Task は実行されたタスクです。
+	Task Task
+	// TaskType はタスク種別名です (TypedTask を実装していない場合は空)。
+	TaskType string
+	// Attempts は実行した試行回数です。
+	Attempts int
+	// Err は最後の試行のエラーです。成功した場合は nil です。
+	Err error
+	// Duration は最初の試行の開始から最終結果が確定するまでの時間です (再試行の待機を含む)。
+	Duration time.Duration
+	// DeadLettered は失敗したタスクが DeadLetterSink に送信されたかどうかです。
+	DeadLettered bool
+}
+
+// Succeeded はタスクが成功したかどうかを返します。
+func (r TaskResult) Succeeded() bool {
+	return r.Err == nil
+}
+
+// ResultHook はタスクの最終結果を受け取る関数型です。
+type ResultHook func(ctx context.Context, result TaskResult)
+
+// TypedTask はタスク種別名を持つTaskです。
+// 種別ごとの再試行方針の選択に使用されます。
+type TypedTask interface {
+	Task
+	TaskType() string
+}
+
+// TaskCompleter は自身の最終結果の通知を受け取るTaskです。
+// 再試行がすべて終わった後に一度だけ呼び出されます。
+type TaskCompleter interface {
+	Task
+	OnComplete(ctx context.Context, result TaskResult)
+}
+
+// taskTypeOf はタスク種別名を返します。TypedTask を実装していない場合は空文字を返します。
+func taskTypeOf(task Task) string {
+	if typed, ok := task.(TypedTask); ok {
+		return typed.TaskType()
+	}
+	return ""
+}
+
+// WithRetryPolicy はすべてのタスクに適用する再試行方針を設定します。
+func WithRetryPolicy(policy RetryPolicy) WorkerOption {
+	return func(w *worker) {
+		w.retryPolicy = policy.normalize()
+	}
+}
+
+// WithTaskRetryPolicy は特定のタスク種別に適用する再試行方針を設定します。
+// TypedTask を実装したタスクにのみ適用され、WithRetryPolicy より優先されます。
+func WithTaskRetryPolicy(taskType string, policy RetryPolicy) WorkerOption {
+	return func(w *worker) {
+		if w.taskRetryPolicies == nil {
+			w.taskRetryPolicies = make(map[string]RetryPolicy)
+		}
+		w.taskRetryPolicies[taskType] = policy.normalize()
+	}
+}
+
+// WithResultHook はタスクの最終結果を受け取るフックを設定します。
+func WithResultHook(hook ResultHook) WorkerOption {
+	return func(w *worker) {
+		w.resultHooks = append(w.resultHooks, hook)
+	}
+}
@@ -0,0 +1,178 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks"
+)
+
+// typedTask はテスト用のタスク種別付きTask
+type typedTask struct {
+	taskType string
+	fn       func(ctx context.Context) error
+}
+
+func (t *typedTask) Execute(ctx context.Context) error { return t.fn(ctx) }
+func (t *typedTask) TaskType() string                  { return t.taskType }
+
+// resultRecorder はResultHookで受け取った結果を記録する
+type resultRecorder struct {
+	mu      sync.Mutex
+	results []worker.TaskResult
+}
+
+func (r *resultRecorder) hook(ctx context.Context, result worker.TaskResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, result)
+}
+
+func (r *resultRecorder) all() []worker.TaskResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]worker.TaskResult(nil), r.results...)
+}
+
+// failingTask は指定回数だけ失敗した後に成功するタスクを返す
+func failingTask(failures int, err error) worker.Task {
+	var mu sync.Mutex
+	calls := 0
+	return tasks.NewTask(func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls <= failures {
+			return err
+		}
+		return nil
+	})
+}
+
+var fastRetry = worker.RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     5 * time.Millisecond,
+}
+
+// TestWorker_Retry は再試行の結果と試行回数が通知されることを確認します。
+// Shutdown は再試行待ちのタスクが完了するまで待機するため、sleep なしで結果を検証できます。
+func TestWorker_Retry(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		task             worker.Task
+		expectedAttempts int
+		expectedSuccess  bool
+	}{
+		{
+			name:             "正常系: 一時的なエラーは再試行して成功する",
+			task:             failingTask(2, errors.New("temporary")),
+			expectedAttempts: 3,
+			expectedSuccess:  true,
+		},
+		{
+			name:             "異常系: 最大試行回数に達すると失敗する",
+			task:             failingTask(10, errors.New("temporary")),
+			expectedAttempts: 3,
+			expectedSuccess:  false,
+		},
+		{
+			name:             "異常系: PermanentErrorは再試行しない",
+			task:             failingTask(10, worker.Permanent(errors.New("invalid input"))),
+			expectedAttempts: 1,
+			expectedSuccess:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			recorder := &resultRecorder{}
+			w := worker.NewWorker(worker.WithRetryPolicy(fastRetry), worker.WithResultHook(recorder.hook))
+			w.Run(context.Background())
+			w.AddJob(tt.task)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if err := w.Shutdown(ctx); err != nil {
+				t.Fatalf("failed to shutdown: %v", err)
+			}
+
+			results := recorder.all()
+			if len(results) != 1 {
+				t.Fatalf("expected 1 result, got %d", len(results))
+			}
+			if results[0].Attempts != tt.expectedAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.expectedAttempts, results[0].Attempts)
+			}
+			if results[0].Succeeded() != tt.expectedSuccess {
+				t.Errorf("expected success=%v, got err=%v", tt.expectedSuccess, results[0].Err)
+			}
+		})
+	}
+}
+
+// TestWorker_TaskRetryPolicy はタスク種別ごとの再試行方針が優先されることを確認します。
+func TestWorker_TaskRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	recorder := &resultRecorder{}
+	w := worker.NewWorker(
+		worker.WithRetryPolicy(worker.RetryPolicy{MaxAttempts: 1}),
+		worker.WithTaskRetryPolicy("user.sync", worker.RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Millisecond}),
+		worker.WithResultHook(recorder.hook),
+	)
+	w.Run(context.Background())
+
+	alwaysFail := func(ctx context.Context) error { return errors.New("failed") }
+	w.AddJob(&typedTask{taskType: "user.sync", fn: alwaysFail}, &typedTask{taskType: "other", fn: alwaysFail})
+
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	attempts := map[string]int{}
+	for _, result := range recorder.all() {
+		attempts[result.TaskType] = result.Attempts
+	}
+	if attempts["user.sync"] != 4 {
+		t.Errorf("expected 4 attempts for user.sync, got %d", attempts["user.sync"])
+	}
+	if attempts["other"] != 1 {
+		t.Errorf("expected 1 attempt for other, got %d", attempts["other"])
+	}
+}
+
+// TestRetryPolicy_Backoff は待機時間が指数的に増加し上限で頭打ちになることを確認します。
+func TestRetryPolicy_Backoff(t *testing.T) {
+	t.Parallel()
+
+	policy := worker.RetryPolicy{
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     50 * time.Millisecond,
+		Multiplier:     2,
+	}
+	expected := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond}
+	for i, want := range expected {
+		if got := policy.Backoff(i + 1); got != want {
+			t.Errorf("attempt %d: expected backoff %v, got %v", i+1, want, got)
+		}
+	}
+
+	// ジッターを加えた場合は ±Jitter の範囲に収まる
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		got := policy.Backoff(1)
+		if got < 5*time.Millisecond || got > 15*time.Millisecond {
+			t.Fatalf("expected backoff within [5ms, 15ms], got %v", got)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCronExpression はcron式を解釈できない場合のエラー
+var ErrInvalidCronExpression = errors.New("invalid cron expression")
+
+// Schedule は繰り返し実行の時刻を計算するインターフェースです。
+type Schedule interface {
+	// Next は t より後の最初の実行時刻を返します。該当する時刻がない場合はゼロ値を返します。
+	Next(t time.Time) time.Time
+}
+
+// maxSearchYears は実行時刻を探索する年数の上限 (2月30日のように存在しない日付で無限ループしないため)
+const maxSearchYears = 5
+
+// cronField はcron式の1フィールドの範囲と名前です。
+type cronField struct {
+	name     string
+	min, max int
+	aliases  map[string]int
+}
+
+var (
+	minuteField = cronField{name: "minute", min: 0, max: 59}
+	hourField   = cronField{name: "hour", min: 0, max: 23}
+	domField    = cronField{name: "day of month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, aliases: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 曜日は 0 と 7 のどちらも日曜日として扱う
+	dowField = cronField{name: "day of week", min: 0, max: 7, aliases: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// CronSchedule は5フィールド(分 時 日 月 曜日)のcron式によるスケジュールです。
+// 時刻は Next に渡した時刻のタイムゾーンで評価します。
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// 日と曜日の両方が指定された場合は、標準のcronと同様にどちらかに一致すれば実行する
+	domRestricted, dowRestricted bool
+}
+
+// ParseCron はcron式を解釈します。
+// 対応する書式:
+//   - 5フィールド: "分 時 日 月 曜日" (例: "*/5 * * * *", "0 3 * * mon-fri")
+//   - 各フィールドで *, 数値, 範囲 (1-5), リスト (1,15), 間隔 (*/10, 0-30/5), 月と曜日の英語略称
+//   - 記述子: @yearly, @monthly, @weekly, @daily, @midnight, @hourly
+//   - 固定間隔: @every <duration> (例: "@every 15m")
+func ParseCron(expr string) (Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrInvalidCronExpression, expr, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("%w: %q: interval must be positive", ErrInvalidCronExpression, expr)
+		}
+		return Every(d), nil
+	}
+	if replaced, ok := descriptors[strings.ToLower(spec)]; ok {
+		spec = replaced
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: %q: expected 5 fields, got %d", ErrInvalidCronExpression, expr, len(fields))
+	}
+
+	var s CronSchedule
+	var err error
+	parsers := []struct {
+		field cronField
+		dst   *uint64
+	}{
+		{minuteField, &s.minute},
+		{hourField, &s.hour},
+		{domField, &s.dom},
+		{monthField, &s.month},
+		{dowField, &s.dow},
+	}
+	for i, p := range parsers {
+		if *p.dst, err = parseField(fields[i], p.field); err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrInvalidCronExpression, expr, err)
+		}
+	}
+	// 7 (日曜日) は 0 に寄せる
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domRestricted = fields[2] != "*" && !strings.HasPrefix(fields[2], "*/")
+	s.dowRestricted = fields[4] != "*" && !strings.HasPrefix(fields[4], "*/")
+	return &s, nil
+}
+
+// MustParseCron は ParseCron と同じですが、解釈できない場合はpanicします。
+// 固定のcron式をパッケージ変数で定義する場合に使用します。
+func MustParseCron(expr string) Schedule {
+	s, err := ParseCron(expr)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// parseField はカンマ区切りのフィールドをビット集合に変換します。
+func parseField(value string, field cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(value, ",") {
+		b, err := parseRange(part, field)
+		if err != nil {
+			return 0, err
+		}
+		bits |= b
+	}
+	return bits, nil
+}
+
+// parseRange は "*", "n", "a-b" とそれぞれに "/step" を付けた形式を解釈します。
+func parseRange(part string, field cronField) (uint64, error) {
+	rangePart, stepPart, hasStep := strings.Cut(part, "/")
+	step := 1
+	if hasStep {
+		n, err := strconv.Atoi(stepPart)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid step %q in %s field", stepPart, field.name)
+		}
+		step = n
+	}
+
+	var lo, hi int
+	switch {
+	case rangePart == "*":
+		lo, hi = field.min, field.max
+		if field.name == dowField.name {
+			// "*" の曜日は 0-6 とし、7 との重複で間隔がずれないようにする
+			hi = 6
+		}
+	case strings.Contains(rangePart, "-"):
+		from, to, _ := strings.Cut(rangePart, "-")
+		var err error
+		if lo, err = parseValue(from, field); err != nil {
+			return 0, err
+		}
+		if hi, err = parseValue(to, field); err != nil {
+			return 0, err
+		}
+		if lo > hi {
+			return 0, fmt.Errorf("invalid range %q in %s field", rangePart, field.name)
+		}
+	default:
+		n, err := parseValue(rangePart, field)
+		if err != nil {
+			return 0, err
+		}
+		lo, hi = n, n
+		if hasStep {
+			// "5/15" は "5-最大値/15" と同じ
+			hi = field.max
+		}
+	}
+
+	var bits uint64
+	for v := lo; v <= hi; v += step {
+		bits |= 1 << v
+	}
+	return bits, nil
+}
+
+func parseValue(value string, field cronField) (int, error) {
+	if n, ok := field.aliases[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s field", value, field.name)
+	}
+	if n < field.min || n > field.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d] in %s field", n, field.min, field.max, field.name)
+	}
+	return n, nil
+}
+
+// Next は t より後の最初の実行時刻を返します (秒以下は切り捨て)。
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// Every は固定間隔のスケジュールを返します。
+// 実行時刻はUnixエポックからの間隔の倍数に揃えるため、複数のプロセスで同じ時刻を計算できます。
+func Every(interval time.Duration) Schedule {
+	return everySchedule{interval: interval}
+}
+
+type everySchedule struct {
+	interval time.Duration
+}
+
+func (s everySchedule) Next(t time.Time) time.Time {
+	elapsed := t.Sub(time.Unix(0, 0))
+	return t.Add(s.interval - elapsed%s.interval)
+}
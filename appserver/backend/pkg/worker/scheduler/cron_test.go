@@ -0,0 +1,177 @@
+package scheduler_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/scheduler"
+)
+
+// TestParseCron_Next はcron式から次の実行時刻を計算できることを確認します。
+func TestParseCron_Next(t *testing.T) {
+	t.Parallel()
+
+	// 2025-01-15 (水) 10:30:20 UTC
+	base := time.Date(2025, 1, 15, 10, 30, 20, 0, time.UTC)
+	tests := []struct {
+		name     string
+		expr     string
+		from     time.Time
+		expected []time.Time
+	}{
+		{
+			name: "正常系: 毎分",
+			expr: "* * * * *",
+			from: base,
+			expected: []time.Time{
+				time.Date(2025, 1, 15, 10, 31, 0, 0, time.UTC),
+				time.Date(2025, 1, 15, 10, 32, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "正常系: 15分間隔",
+			expr: "*/15 * * * *",
+			from: base,
+			expected: []time.Time{
+				time.Date(2025, 1, 15, 10, 45, 0, 0, time.UTC),
+				time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "正常系: 毎日3時 (日付をまたぐ)",
+			expr: "0 3 * * *",
+			from: base,
+			expected: []time.Time{
+				time.Date(2025, 1, 16, 3, 0, 0, 0, time.UTC),
+				time.Date(2025, 1, 17, 3, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "正常系: 平日の9時 (曜日の範囲と名前)",
+			expr: "0 9 * * mon-fri",
+			from: time.Date(2025, 1, 17, 12, 0, 0, 0, time.UTC), // 金曜日
+			expected: []time.Time{
+				time.Date(2025, 1, 20, 9, 0, 0, 0, time.UTC), // 月曜日
+				time.Date(2025, 1, 21, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "正常系: リストと月の名前",
+			expr: "0 0 1,15 feb *",
+			from: base,
+			expected: []time.Time{
+				time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2025, 2, 15, 0, 0, 0, 0, time.UTC),
+				time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "正常系: 日と曜日の両方を指定した場合はどちらかに一致すれば実行する",
+			expr: "0 0 20 * sun",
+			from: base,
+			expected: []time.Time{
+				time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC), // 日曜日
+				time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), // 20日
+				time.Date(2025, 1, 26, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "正常系: 曜日の7は日曜日",
+			expr: "0 0 * * 7",
+			from: base,
+			expected: []time.Time{
+				time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "正常系: 開始値付きの間隔",
+			expr: "5/20 * * * *",
+			from: base,
+			expected: []time.Time{
+				time.Date(2025, 1, 15, 10, 45, 0, 0, time.UTC),
+				time.Date(2025, 1, 15, 11, 5, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "正常系: @daily",
+			expr: "@daily",
+			from: base,
+			expected: []time.Time{
+				time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "正常系: @every はエポックからの間隔の倍数に揃える",
+			expr: "@every 15m",
+			from: base,
+			expected: []time.Time{
+				time.Date(2025, 1, 15, 10, 45, 0, 0, time.UTC),
+				time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "正常系: 閏日",
+			expr: "0 0 29 2 *",
+			from: base,
+			expected: []time.Time{
+				time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "正常系: タイムゾーンは基準時刻のものを使う",
+			expr: "0 3 * * *",
+			from: time.Date(2025, 1, 15, 10, 0, 0, 0, time.FixedZone("JST", 9*60*60)),
+			expected: []time.Time{
+				time.Date(2025, 1, 16, 3, 0, 0, 0, time.FixedZone("JST", 9*60*60)),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			schedule, err := scheduler.ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) error = %v", tt.expr, err)
+			}
+			at := tt.from
+			for i, want := range tt.expected {
+				at = schedule.Next(at)
+				if !at.Equal(want) {
+					t.Fatalf("Next #%d = %v, want %v", i+1, at, want)
+				}
+			}
+		})
+	}
+}
+
+// TestParseCron_Invalid は解釈できないcron式がエラーになることを確認します。
+func TestParseCron_Invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{name: "異常系: フィールド数が足りない", expr: "* * * *"},
+		{name: "異常系: フィールド数が多い", expr: "0 * * * * *"},
+		{name: "異常系: 範囲外の分", expr: "60 * * * *"},
+		{name: "異常系: 範囲外の日", expr: "0 0 0 * *"},
+		{name: "異常系: 逆順の範囲", expr: "0 10-5 * * *"},
+		{name: "異常系: 0の間隔", expr: "*/0 * * * *"},
+		{name: "異常系: 不明な名前", expr: "0 0 * * funday"},
+		{name: "異常系: 不明な記述子", expr: "@fortnightly"},
+		{name: "異常系: @every の不正な時間", expr: "@every soon"},
+		{name: "異常系: @every の負の時間", expr: "@every -1m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := scheduler.ParseCron(tt.expr); !errors.Is(err, scheduler.ErrInvalidCronExpression) {
+				t.Errorf("ParseCron(%q) error = %v, want ErrInvalidCronExpression", tt.expr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlErrDuplicateEntry は一意制約違反を表すMySQLのエラー番号
+const mysqlErrDuplicateEntry = 1062
+
+// Locker は複数のプロセスで同じエントリが二重に実行されないよう、実行権を調停するインターフェースです。
+// スケジュールの実行時刻はプロセス間で同じ値になるため、(エントリ名, 実行時刻) ごとに最初の1プロセスだけが実行権を得ます。
+type Locker interface {
+	// Claim は name の fireAt 時刻の実行権を取得します。
+	// 取得できた場合は true、他のプロセスが取得済みの場合は false を返します。
+	Claim(ctx context.Context, name string, fireAt time.Time) (bool, error)
+}
+
+// MemoryLocker は実行権をメモリで管理するLockerです。
+// 同一プロセス内の複数のSchedulerで共有する場合やテストで使用します。
+type MemoryLocker struct {
+	mu      sync.Mutex
+	claimed map[string]struct{}
+}
+
+// NewMemoryLocker は空のMemoryLockerを生成します。
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{claimed: make(map[string]struct{})}
+}
+
+// Claim は未取得の場合のみ実行権を取得します。
+func (l *MemoryLocker) Claim(ctx context.Context, name string, fireAt time.Time) (bool, error) {
+	key := name + "@" + fireAt.UTC().Format(time.RFC3339Nano)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.claimed[key]; ok {
+		return false, nil
+	}
+	l.claimed[key] = struct{}{}
+	return true, nil
+}
+
+// SQLLocker は scheduler_runs テーブルの一意制約で実行権を管理するLockerです。
+// 実行権の取得はINSERTの成否で判定するため、トランザクションや行ロックを保持しません。
+// 注意事項: テーブルは migrations/20250601000001_create_scheduler_runs_table.sql で作成する
+type SQLLocker struct {
+	db    *sql.DB
+	owner string
+}
+
+// NewSQLLocker はSQLLockerを生成するコンストラクタです。
+// 実行権を取得したプロセスの識別のため、ホスト名とプロセスIDを owner として記録します。
+func NewSQLLocker(db *sql.DB) *SQLLocker {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return &SQLLocker{db: db, owner: fmt.Sprintf("%s:%d", host, os.Getpid())}
+}
+
+// Claim は実行記録をINSERTし、一意制約違反の場合は取得済みとして false を返します。
+func (l *SQLLocker) Claim(ctx context.Context, name string, fireAt time.Time) (bool, error) {
+	_, err := l.db.ExecContext(ctx,
+		"INSERT INTO scheduler_runs (name, fire_at, owner) VALUES (?, ?, ?)",
+		name, fireAt.UTC(), l.owner,
+	)
+	if err == nil {
+		return true, nil
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to claim scheduler run (name=%s, fireAt=%s): %w", name, fireAt.Format(time.RFC3339), err)
+}
+
+// Prune は before より前の実行記録を削除し、削除した件数を返します。
+// 実行記録は実行のたびに1行増えるため、定期的に呼び出して古い記録を削除します。
+func (l *SQLLocker) Prune(ctx context.Context, before time.Time) (int64, error) {
+	result, err := l.db.ExecContext(ctx, "DELETE FROM scheduler_runs WHERE fire_at < ?", before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune scheduler runs (before=%s): %w", before.Format(time.RFC3339), err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,329 @@
+// Package scheduler はcron式や遅延時間に従って worker.Task をWorkerに投入するスケジューラーです。
+package scheduler
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/clock"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker"
+)
+
+var (
+	// ErrDuplicateEntry は同じ名前のエントリが登録済みの場合のエラー
+	ErrDuplicateEntry = errors.New("scheduler entry already exists")
+	// ErrSchedulerRunning は実行中のSchedulerの Run を再度呼び出した場合のエラー
+	ErrSchedulerRunning = errors.New("scheduler is already running")
+)
+
+// Entry は登録されたスケジュールの状態です。
+type Entry struct {
+	// Name はエントリ名です。複数のプロセス間で実行権を調停するキーにもなります。
+	Name string
+	// Schedule は繰り返し実行のスケジュールです。一度だけ実行するエントリの場合は nil です。
+	Schedule Schedule
+	// Next は次の実行時刻です。
+	Next time.Time
+	// Prev は前回の実行時刻です。未実行の場合はゼロ値です。
+	Prev time.Time
+	// Priority はWorkerに投入する際の優先度です。
+	Priority worker.Priority
+}
+
+// SchedulerOption はSchedulerの設定を変更する関数型です。
+type SchedulerOption func(*Scheduler)
+
+// WithClock は時刻の取得に使用する Clock を設定します (デフォルト: 実時間)。
+// テストでは clock.NewFake を渡して時間を進めます。
+func WithClock(c clock.Clock) SchedulerOption {
+	return func(s *Scheduler) {
+		s.clock = c
+	}
+}
+
+// WithLocker は複数のプロセス間で実行権を調停する Locker を設定します。
+// 設定しない場合はプロセス内で実行権を判定せず、すべての実行時刻で投入します。
+func WithLocker(l Locker) SchedulerOption {
+	return func(s *Scheduler) {
+		s.locker = l
+	}
+}
+
+// WithLocation はcron式を評価するタイムゾーンを設定します (デフォルト: time.Local)。
+func WithLocation(loc *time.Location) SchedulerOption {
+	return func(s *Scheduler) {
+		s.location = loc
+	}
+}
+
+// EntryOption はエントリの設定を変更する関数型です。
+type EntryOption func(*entry)
+
+// WithPriority はWorkerに投入する際の優先度を設定します (デフォルト: PriorityNormal)。
+func WithPriority(p worker.Priority) EntryOption {
+	return func(e *entry) {
+		e.priority = p
+	}
+}
+
+type entry struct {
+	name     string
+	schedule Schedule
+	task     worker.Task
+	priority worker.Priority
+	next     time.Time
+	prev     time.Time
+}
+
+// Scheduler は登録されたエントリの実行時刻になるとタスクをWorkerに投入します。
+//   - 実行時刻を過ぎていた場合 (停止中や処理の遅れ) は、まとめて1回だけ投入する
+//   - Locker を設定すると、複数のプロセスで同じ実行時刻に二重に投入しない
+//   - タスクの実行・再試行・デッドレターはWorkerの設定に従う
+type Scheduler struct {
+	worker   worker.Worker
+	clock    clock.Clock
+	locker   Locker
+	location *time.Location
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	// wake はエントリの追加・削除を Run のループに通知する
+	wake    chan struct{}
+	running atomic.Bool
+}
+
+// New はSchedulerを生成するコンストラクタです。
+// 引数:
+//   - w: タスクを投入するWorker
+//   - opts: Schedulerの設定
+func New(w worker.Worker, opts ...SchedulerOption) *Scheduler {
+	s := &Scheduler{
+		worker:   w,
+		clock:    clock.New(),
+		location: time.Local,
+		entries:  make(map[string]*entry),
+		wake:     make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Cron はcron式に従って繰り返し実行するエントリを登録します。
+// 使用例: s.Cron("health.check", "* * * * *", healthtask.HealthTask)
+func (s *Scheduler) Cron(name, expr string, task worker.Task, opts ...EntryOption) error {
+	schedule, err := ParseCron(expr)
+	if err != nil {
+		return err
+	}
+	return s.Schedule(name, schedule, task, opts...)
+}
+
+// Schedule は任意の Schedule に従って繰り返し実行するエントリを登録します。
+func (s *Scheduler) Schedule(name string, schedule Schedule, task worker.Task, opts ...EntryOption) error {
+	next := schedule.Next(s.clock.Now().In(s.location))
+	if next.IsZero() {
+		return fmt.Errorf("schedule never fires (name=%s)", name)
+	}
+	return s.add(&entry{name: name, schedule: schedule, task: task, next: next}, opts)
+}
+
+// After は delay 経過後に一度だけ実行するエントリを登録します。
+// 注意事項: 実行時刻は登録したプロセスの現在時刻から計算するため、
+// 複数のプロセスで同じタスクを登録して二重実行を防ぐ場合は At で絶対時刻を指定する
+func (s *Scheduler) After(name string, delay time.Duration, task worker.Task, opts ...EntryOption) error {
+	return s.At(name, s.clock.Now().Add(delay), task, opts...)
+}
+
+// At は指定した時刻に一度だけ実行するエントリを登録します。
+// 過去の時刻を指定した場合は次のループで即座に投入します。
+func (s *Scheduler) At(name string, at time.Time, task worker.Task, opts ...EntryOption) error {
+	return s.add(&entry{name: name, task: task, next: at}, opts)
+}
+
+func (s *Scheduler) add(e *entry, opts []EntryOption) error {
+	for _, opt := range opts {
+		opt(e)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[e.name]; ok {
+		return fmt.Errorf("%w (name=%s)", ErrDuplicateEntry, e.name)
+	}
+	s.entries[e.name] = e
+	s.notify()
+	return nil
+}
+
+// Remove はエントリを削除します。エントリが存在した場合は true を返します。
+func (s *Scheduler) Remove(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[name]; !ok {
+		return false
+	}
+	delete(s.entries, name)
+	s.notify()
+	return true
+}
+
+// Entries は登録されているエントリを次の実行時刻の順に返します。
+func (s *Scheduler) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, Entry{
+			Name:     e.name,
+			Schedule: e.schedule,
+			Next:     e.next,
+			Prev:     e.prev,
+			Priority: e.priority,
+		})
+	}
+	slices.SortFunc(entries, func(a, b Entry) int {
+		if c := a.Next.Compare(b.Next); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return entries
+}
+
+// notify は Run のループを起こします。呼び出し元で mu を保持していること。
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run はコンテキストがキャンセルされるまで、実行時刻を迎えたエントリのタスクをWorkerに投入します。
+// キャンセルされた場合は nil を返します。投入済みのタスクの完了はWorkerの Shutdown で待機します。
+func (s *Scheduler) Run(ctx context.Context) error {
+	if !s.running.CompareAndSwap(false, true) {
+		return ErrSchedulerRunning
+	}
+	defer s.running.Store(false)
+
+	slog.Info("scheduler started", "entries", len(s.Entries()))
+	for {
+		now := s.clock.Now()
+		due, next := s.collectDue(now)
+		for _, d := range due {
+			s.fire(ctx, d)
+		}
+		if len(due) > 0 {
+			// 投入中に時間が経過している可能性があるため、待機せずに再判定する
+			continue
+		}
+
+		var timer clock.Timer
+		var fired <-chan time.Time
+		if !next.IsZero() {
+			timer = s.clock.NewTimer(next.Sub(now))
+			fired = timer.C()
+		}
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			slog.Info("scheduler stopped")
+			return nil
+		case <-s.wake:
+			if timer != nil {
+				timer.Stop()
+			}
+		case <-fired:
+		}
+	}
+}
+
+// dueEntry は投入対象のエントリの実行時刻時点のスナップショットです。
+type dueEntry struct {
+	name     string
+	task     worker.Task
+	priority worker.Priority
+	fireAt   time.Time
+	// missed は実行時刻を過ぎていたためにまとめた回数
+	missed int
+}
+
+// collectDue は now までに実行時刻を迎えたエントリを取り出し、次の実行時刻を進めます。
+// 併せて、残ったエントリの中で最も早い実行時刻を返します。
+func (s *Scheduler) collectDue(now time.Time) ([]dueEntry, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []dueEntry
+	var earliest time.Time
+	for name, e := range s.entries {
+		if e.next.After(now) {
+			if earliest.IsZero() || e.next.Before(earliest) {
+				earliest = e.next
+			}
+			continue
+		}
+
+		d := dueEntry{name: e.name, task: e.task, priority: e.priority, fireAt: e.next}
+		e.prev = e.next
+		if e.schedule == nil {
+			delete(s.entries, name)
+			due = append(due, d)
+			continue
+		}
+		// 過ぎてしまった実行時刻はまとめて1回とし、次回は現在時刻以降から計算する
+		next := e.schedule.Next(e.next.In(s.location))
+		for !next.IsZero() && !next.After(now) {
+			d.missed++
+			next = e.schedule.Next(next)
+		}
+		if next.IsZero() {
+			delete(s.entries, name)
+		} else {
+			e.next = next
+			if earliest.IsZero() || next.Before(earliest) {
+				earliest = next
+			}
+		}
+		due = append(due, d)
+	}
+	slices.SortFunc(due, func(a, b dueEntry) int {
+		return a.fireAt.Compare(b.fireAt)
+	})
+	return due, earliest
+}
+
+// fire は実行権を取得できた場合にタスクをWorkerに投入します。
+// 投入に失敗した場合はログに記録し、その実行時刻の実行は見送ります。
+func (s *Scheduler) fire(ctx context.Context, d dueEntry) {
+	if d.missed > 0 {
+		slog.Warn("scheduler coalesced missed runs", "name", d.name, "fireAt", d.fireAt, "missed", d.missed)
+	}
+	if s.locker != nil {
+		claimed, err := s.locker.Claim(ctx, d.name, d.fireAt)
+		if err != nil {
+			// 実行権を確認できない場合は二重実行を避けるため投入しない
+			slog.Error("failed to claim scheduled run", "name", d.name, "fireAt", d.fireAt, "error", err)
+			return
+		}
+		if !claimed {
+			slog.Debug("scheduled run claimed by another process", "name", d.name, "fireAt", d.fireAt)
+			return
+		}
+	}
+	if err := s.worker.AddJobAsyncWithPriority(d.priority, d.task); err != nil {
+		slog.Error("failed to submit scheduled task", "name", d.name, "fireAt", d.fireAt, "error", err)
+		return
+	}
+	slog.Debug("scheduled task submitted", "name", d.name, "fireAt", d.fireAt)
+}
@@ -0,0 +1,369 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/clock"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/scheduler"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks"
+)
+
+// 2025-01-15 10:00:00 UTC
+var epoch = time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+// runRecorder はタスクの実行をエントリ名ごとに記録する
+type runRecorder struct {
+	mu   sync.Mutex
+	runs map[string]int
+	ch   chan string
+}
+
+func newRunRecorder() *runRecorder {
+	return &runRecorder{runs: make(map[string]int), ch: make(chan string, 100)}
+}
+
+func (r *runRecorder) task(name string) worker.Task {
+	return tasks.NewTask(func(ctx context.Context) error {
+		r.mu.Lock()
+		r.runs[name]++
+		r.mu.Unlock()
+		r.ch <- name
+		return nil
+	})
+}
+
+func (r *runRecorder) count(name string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.runs[name]
+}
+
+// wait は n 回タスクが実行されるまで待機する
+func (r *runRecorder) wait(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.ch:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %d task run(s), got %d", n, i)
+		}
+	}
+}
+
+// expectNoRun は追加でタスクが実行されないことを確認する
+func (r *runRecorder) expectNoRun(t *testing.T) {
+	t.Helper()
+	select {
+	case name := <-r.ch:
+		t.Fatalf("unexpected task run: %s", name)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// startWorker はテスト用のWorkerを起動し、終了時に停止する
+func startWorker(t *testing.T) worker.Worker {
+	t.Helper()
+	w := worker.NewWorker(worker.WithName("scheduler-test"))
+	if err := w.Run(context.Background()); err != nil {
+		t.Fatalf("failed to run worker: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = w.Shutdown(ctx)
+	})
+	return w
+}
+
+// startScheduler はSchedulerを起動し、タイマーを待機し始めるまで待つ
+func startScheduler(t *testing.T, s *scheduler.Scheduler, clk *clock.Fake) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	})
+	blockUntil(t, clk, 1)
+}
+
+// blockUntil はタイマーが n 個待機するまで待つ
+func blockUntil(t *testing.T, clk *clock.Fake, n int) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := clk.BlockUntil(ctx, n); err != nil {
+		t.Fatalf("timed out waiting for %d timer(s): %v", n, err)
+	}
+}
+
+// TestScheduler_Cron はcron式のエントリが実行時刻ごとに投入されることを確認します。
+func TestScheduler_Cron(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewFake(epoch)
+	recorder := newRunRecorder()
+	s := scheduler.New(startWorker(t), scheduler.WithClock(clk), scheduler.WithLocation(time.UTC))
+	if err := s.Cron("health", "*/5 * * * *", recorder.task("health")); err != nil {
+		t.Fatalf("Cron() error = %v", err)
+	}
+	startScheduler(t, s, clk)
+
+	// 実行時刻前は投入しない
+	clk.Advance(4 * time.Minute)
+	blockUntil(t, clk, 1)
+	recorder.expectNoRun(t)
+
+	for i := 1; i <= 3; i++ {
+		clk.Advance(time.Minute)
+		recorder.wait(t, 1)
+		blockUntil(t, clk, 1)
+		clk.Advance(4 * time.Minute)
+		blockUntil(t, clk, 1)
+	}
+	if got := recorder.count("health"); got != 3 {
+		t.Errorf("runs = %d, want 3", got)
+	}
+
+	entries := s.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("len(Entries()) = %d, want 1", len(entries))
+	}
+	if want := epoch.Add(20 * time.Minute); !entries[0].Next.Equal(want) {
+		t.Errorf("Next = %v, want %v", entries[0].Next, want)
+	}
+	if want := epoch.Add(15 * time.Minute); !entries[0].Prev.Equal(want) {
+		t.Errorf("Prev = %v, want %v", entries[0].Prev, want)
+	}
+}
+
+// TestScheduler_CoalescesMissedRuns は過ぎてしまった複数の実行時刻を1回にまとめることを確認します。
+func TestScheduler_CoalescesMissedRuns(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewFake(epoch)
+	recorder := newRunRecorder()
+	s := scheduler.New(startWorker(t), scheduler.WithClock(clk), scheduler.WithLocation(time.UTC))
+	if err := s.Cron("every-minute", "* * * * *", recorder.task("every-minute")); err != nil {
+		t.Fatalf("Cron() error = %v", err)
+	}
+	startScheduler(t, s, clk)
+
+	clk.Advance(10*time.Minute + 30*time.Second)
+	recorder.wait(t, 1)
+	blockUntil(t, clk, 1)
+	recorder.expectNoRun(t)
+
+	if want := epoch.Add(11 * time.Minute); !s.Entries()[0].Next.Equal(want) {
+		t.Errorf("Next = %v, want %v", s.Entries()[0].Next, want)
+	}
+}
+
+// TestScheduler_OneOff は遅延実行のエントリが一度だけ投入され、削除されることを確認します。
+func TestScheduler_OneOff(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		register func(s *scheduler.Scheduler, task worker.Task) error
+	}{
+		{
+			name: "正常系: After",
+			register: func(s *scheduler.Scheduler, task worker.Task) error {
+				return s.After("delayed", 15*time.Minute, task)
+			},
+		},
+		{
+			name: "正常系: At",
+			register: func(s *scheduler.Scheduler, task worker.Task) error {
+				return s.At("delayed", epoch.Add(15*time.Minute), task)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			clk := clock.NewFake(epoch)
+			recorder := newRunRecorder()
+			s := scheduler.New(startWorker(t), scheduler.WithClock(clk))
+			if err := tt.register(s, recorder.task("delayed")); err != nil {
+				t.Fatalf("register error = %v", err)
+			}
+			startScheduler(t, s, clk)
+
+			clk.Advance(14 * time.Minute)
+			blockUntil(t, clk, 1)
+			recorder.expectNoRun(t)
+
+			clk.Advance(time.Minute)
+			recorder.wait(t, 1)
+			clk.Advance(time.Hour)
+			recorder.expectNoRun(t)
+			if got := len(s.Entries()); got != 0 {
+				t.Errorf("len(Entries()) = %d, want 0", got)
+			}
+		})
+	}
+}
+
+// TestScheduler_AddWhileRunning は実行中に追加したエントリの時刻で待機し直すことを確認します。
+func TestScheduler_AddWhileRunning(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewFake(epoch)
+	recorder := newRunRecorder()
+	s := scheduler.New(startWorker(t), scheduler.WithClock(clk), scheduler.WithLocation(time.UTC))
+	if err := s.Cron("daily", "@daily", recorder.task("daily")); err != nil {
+		t.Fatalf("Cron() error = %v", err)
+	}
+	startScheduler(t, s, clk)
+
+	if err := s.After("soon", time.Minute, recorder.task("soon")); err != nil {
+		t.Fatalf("After() error = %v", err)
+	}
+	// 古いタイマーで待機中に時刻が進んでも、待機し直した時点で実行時刻を過ぎていれば投入される
+	clk.Advance(time.Minute)
+	recorder.wait(t, 1)
+	if got := recorder.count("soon"); got != 1 {
+		t.Errorf("soon runs = %d, want 1", got)
+	}
+	if got := recorder.count("daily"); got != 0 {
+		t.Errorf("daily runs = %d, want 0", got)
+	}
+
+	if !s.Remove("daily") {
+		t.Error("Remove() = false, want true")
+	}
+	if s.Remove("daily") {
+		t.Error("Remove() = true, want false for a removed entry")
+	}
+}
+
+// TestScheduler_LockerPreventsDoubleFire は複数のSchedulerで同じエントリを実行しても1回だけ投入されることを確認します。
+func TestScheduler_LockerPreventsDoubleFire(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewFake(epoch)
+	recorder := newRunRecorder()
+	locker := scheduler.NewMemoryLocker()
+
+	const processes = 3
+	for i := 0; i < processes; i++ {
+		s := scheduler.New(startWorker(t), scheduler.WithClock(clk), scheduler.WithLocker(locker), scheduler.WithLocation(time.UTC))
+		if err := s.Cron("reconcile", "@hourly", recorder.task("reconcile")); err != nil {
+			t.Fatalf("Cron() error = %v", err)
+		}
+		startScheduler(t, s, clk)
+	}
+	blockUntil(t, clk, processes)
+
+	clk.Advance(time.Hour)
+	recorder.wait(t, 1)
+	blockUntil(t, clk, processes)
+	recorder.expectNoRun(t)
+
+	clk.Advance(time.Hour)
+	recorder.wait(t, 1)
+	recorder.expectNoRun(t)
+	if got := recorder.count("reconcile"); got != 2 {
+		t.Errorf("runs = %d, want 2", got)
+	}
+}
+
+// failingLocker は実行権の確認に失敗するLocker
+type failingLocker struct{}
+
+func (failingLocker) Claim(ctx context.Context, name string, fireAt time.Time) (bool, error) {
+	return false, errors.New("database unavailable")
+}
+
+// TestScheduler_LockerErrorSkipsRun は実行権を確認できない場合に投入しないことを確認します。
+func TestScheduler_LockerErrorSkipsRun(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewFake(epoch)
+	recorder := newRunRecorder()
+	s := scheduler.New(startWorker(t), scheduler.WithClock(clk), scheduler.WithLocker(failingLocker{}))
+	if err := s.After("job", time.Minute, recorder.task("job")); err != nil {
+		t.Fatalf("After() error = %v", err)
+	}
+	startScheduler(t, s, clk)
+
+	clk.Advance(time.Minute)
+	recorder.expectNoRun(t)
+}
+
+// TestScheduler_Register はエントリ登録時の検証を確認します。
+func TestScheduler_Register(t *testing.T) {
+	t.Parallel()
+
+	task := tasks.NewTask(func(ctx context.Context) error { return nil })
+	tests := []struct {
+		name     string
+		register func(s *scheduler.Scheduler) error
+		wantErr  error
+	}{
+		{
+			name: "異常系: 同じ名前のエントリ",
+			register: func(s *scheduler.Scheduler) error {
+				if err := s.Cron("dup", "@hourly", task); err != nil {
+					return err
+				}
+				return s.After("dup", time.Minute, task)
+			},
+			wantErr: scheduler.ErrDuplicateEntry,
+		},
+		{
+			name: "異常系: 不正なcron式",
+			register: func(s *scheduler.Scheduler) error {
+				return s.Cron("invalid", "every minute", task)
+			},
+			wantErr: scheduler.ErrInvalidCronExpression,
+		},
+		{
+			name: "正常系: 優先度を指定できる",
+			register: func(s *scheduler.Scheduler) error {
+				return s.Cron("high", "@hourly", task, scheduler.WithPriority(worker.PriorityHigh))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s := scheduler.New(worker.NewWorker(), scheduler.WithClock(clock.NewFake(epoch)))
+			err := tt.register(s)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && s.Entries()[0].Priority != worker.PriorityHigh {
+				t.Errorf("Priority = %v, want high", s.Entries()[0].Priority)
+			}
+		})
+	}
+}
+
+// TestScheduler_RunTwice は実行中のSchedulerを再度起動できないことを確認します。
+func TestScheduler_RunTwice(t *testing.T) {
+	t.Parallel()
+
+	clk := clock.NewFake(epoch)
+	s := scheduler.New(startWorker(t), scheduler.WithClock(clk))
+	if err := s.Cron("job", "@hourly", tasks.NewTask(func(ctx context.Context) error { return nil })); err != nil {
+		t.Fatalf("Cron() error = %v", err)
+	}
+	startScheduler(t, s, clk)
+
+	if err := s.Run(context.Background()); !errors.Is(err, scheduler.ErrSchedulerRunning) {
+		t.Errorf("Run() error = %v, want ErrSchedulerRunning", err)
+	}
+}
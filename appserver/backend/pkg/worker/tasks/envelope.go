@@ -0,0 +1,103 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EnvelopeVersion は現在のメッセージエンベロープのバージョンです。
+// 互換性のない形式変更を行う場合にインクリメントします。
+const EnvelopeVersion = 1
+
+// Envelope はキューメッセージで送受信するタスクの共通フォーマットです。
+// プロデューサーは Marshal した文字列を SQS.SendMessage の本文に設定し、
+// コンシューマーは Registry.Decode で worker.Task に復元します。
+//
+// 例:
+//
+//	{"version":1,"id":"...","type":"user.sync","payload":{"user_id":"..."},
+//	 "attempt":0,"enqueued_at":"2025-11-25T00:00:00Z","trace_id":"..."}
+type Envelope struct {
+	Version    int             `json:"version"`
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Payload    json.RawMessage `json:"payload"`
+	Attempt    int             `json:"attempt"`
+	EnqueuedAt time.Time       `json:"enqueued_at"`
+	TraceID    string          `json:"trace_id,omitempty"`
+}
+
+// EnvelopeOption はEnvelope生成時のオプション関数型です。
+type EnvelopeOption func(*Envelope)
+
+// WithTraceID はトレースIDを設定します。
+func WithTraceID(traceID string) EnvelopeOption {
+	return func(e *Envelope) {
+		e.TraceID = traceID
+	}
+}
+
+// WithAttempt は試行回数を設定します。再投入時に前回までの試行回数を引き継ぐ場合に使用します。
+func WithAttempt(attempt int) EnvelopeOption {
+	return func(e *Envelope) {
+		e.Attempt = attempt
+	}
+}
+
+// NewEnvelope はタスク種別とペイロードからEnvelopeを生成します。
+// 引数:
+//   - taskType: タスク種別名 (例: "user.sync")
+//   - payload: JSONエンコード可能なペイロード
+//   - opts: オプション
+//
+// 戻り値:
+//   - *Envelope: 生成したエンベロープ
+//   - error: ペイロードのエンコードに失敗した場合のエラー
+func NewEnvelope(taskType string, payload any, opts ...EnvelopeOption) (*Envelope, error) {
+	if taskType == "" {
+		return nil, fmt.Errorf("%w: task type is empty", ErrInvalidEnvelope)
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload (type=%s): %w", taskType, err)
+	}
+	e := &Envelope{
+		Version:    EnvelopeVersion,
+		ID:         uuid.NewString(),
+		Type:       taskType,
+		Payload:    raw,
+		EnqueuedAt: time.Now().UTC(),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e, nil
+}
+
+// Marshal はエンベロープをメッセージ本文用のJSON文字列に変換します。
+func (e *Envelope) Marshal() (string, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal envelope (type=%s): %w", e.Type, err)
+	}
+	return string(b), nil
+}
+
+// DecodeEnvelope はメッセージ本文をEnvelopeに変換します。
+// 戻り値のエラーは ErrInvalidEnvelope または ErrUnsupportedVersion をラップします。
+func DecodeEnvelope(body string) (*Envelope, error) {
+	var e Envelope
+	if err := json.Unmarshal([]byte(body), &e); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEnvelope, err)
+	}
+	if e.Version != EnvelopeVersion {
+		return nil, fmt.Errorf("%w: version=%d", ErrUnsupportedVersion, e.Version)
+	}
+	if e.Type == "" {
+		return nil, fmt.Errorf("%w: task type is empty", ErrInvalidEnvelope)
+	}
+	return &e, nil
+}
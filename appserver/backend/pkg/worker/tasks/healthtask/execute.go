@@ -8,7 +8,18 @@ import (
 	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks"
 )
 
+// TaskType はキューメッセージで使用するヘルスチェックタスクの種別名
+const TaskType = "health.check"
+
 var HealthTask = tasks.NewTask(func(ctx context.Context) error {
 	slog.Info("health task executed", "time", time.Now().Format(time.RFC3339))
 	return nil
 })
+
+// Register はヘルスチェックタスクをレジストリに登録する
+// 注意事項: ペイロードは使用しないため空のオブジェクトを受け付ける
+func Register(r *tasks.Registry) error {
+	return tasks.Register(r, TaskType, func(ctx context.Context, _ struct{}) error {
+		return HealthTask.Execute(ctx)
+	})
+}
@@ -0,0 +1,150 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker"
+)
+
+// レジストリとエンベロープの処理で発生するエラー
+// 使用例: errors.Is(err, tasks.ErrUnknownTaskType) でエラーの種類を判定
+var (
+	// ErrUnknownTaskType は登録されていないタスク種別のメッセージを受信した場合のエラー
+	ErrUnknownTaskType = errors.New("unknown task type")
+
+	// ErrInvalidPayload はペイロードをハンドラーの型にデコードできない場合のエラー
+	ErrInvalidPayload = errors.New("invalid task payload")
+
+	// ErrInvalidEnvelope はメッセージ本文がエンベロープとして解釈できない場合のエラー
+	ErrInvalidEnvelope = errors.New("invalid task envelope")
+
+	// ErrUnsupportedVersion はエンベロープのバージョンが未対応の場合のエラー
+	ErrUnsupportedVersion = errors.New("unsupported envelope version")
+
+	// ErrDuplicateTaskType は同じタスク種別を二重に登録しようとした場合のエラー
+	ErrDuplicateTaskType = errors.New("task type already registered")
+)
+
+// Handler は型付きペイロードを受け取るタスクハンドラーです。
+type Handler[P any] func(ctx context.Context, payload P) error
+
+// binder はペイロードをデコードし、実行可能な関数に束縛します。
+type binder func(payload json.RawMessage) (func(ctx context.Context) error, error)
+
+// Registry はタスク種別名とハンドラーの対応を管理します。
+type Registry struct {
+	mu       sync.RWMutex
+	handlers map[string]binder
+}
+
+// NewRegistry は空のRegistryを生成します。
+func NewRegistry() *Registry {
+	return &Registry{handlers: make(map[string]binder)}
+}
+
+// Register はタスク種別にハンドラーを登録します。
+// 引数:
+//   - r: 登録先のレジストリ
+//   - taskType: タスク種別名 (例: "user.sync")
+//   - handler: 型付きペイロードを受け取るハンドラー
+//
+// 戻り値: 同じ種別が登録済みの場合は ErrDuplicateTaskType
+// 注意事項: Goのメソッドは型パラメータを持てないため関数として提供する
+func Register[P any](r *Registry, taskType string, handler Handler[P]) error {
+	if taskType == "" {
+		return fmt.Errorf("task type is empty")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.handlers[taskType]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateTaskType, taskType)
+	}
+	r.handlers[taskType] = func(raw json.RawMessage) (func(ctx context.Context) error, error) {
+		var payload P
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &payload); err != nil {
+				return nil, fmt.Errorf("%w: type=%s: %v", ErrInvalidPayload, taskType, err)
+			}
+		}
+		return func(ctx context.Context) error {
+			return handler(ctx, payload)
+		}, nil
+	}
+	return nil
+}
+
+// MustRegister はRegisterと同じですが、登録に失敗した場合はpanicします。
+// パッケージ初期化時など、失敗がプログラミングミスである場合に使用します。
+func MustRegister[P any](r *Registry, taskType string, handler Handler[P]) {
+	if err := Register(r, taskType, handler); err != nil {
+		panic(err)
+	}
+}
+
+// TaskFromEnvelope はエンベロープを実行可能なTaskに変換します。
+// 戻り値のエラーは ErrUnknownTaskType または ErrInvalidPayload をラップします。
+func (r *Registry) TaskFromEnvelope(e *Envelope) (*EnvelopeTask, error) {
+	r.mu.RLock()
+	bind, ok := r.handlers[e.Type]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTaskType, e.Type)
+	}
+	fn, err := bind(e.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return &EnvelopeTask{envelope: e, fn: fn}, nil
+}
+
+// Decode はメッセージ本文をデコードしてTaskに変換します。
+func (r *Registry) Decode(body string) (*EnvelopeTask, error) {
+	e, err := DecodeEnvelope(body)
+	if err != nil {
+		return nil, err
+	}
+	return r.TaskFromEnvelope(e)
+}
+
+// MessageHandler はSQSPollerで使用する worker.MessageHandler を返します。
+func (r *Registry) MessageHandler() worker.MessageHandler {
+	return func(msg types.Message) (worker.Task, error) {
+		task, err := r.Decode(aws.ToString(msg.Body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode message (messageID=%s): %w", aws.ToString(msg.MessageId), err)
+		}
+		return task, nil
+	}
+}
+
+// EnvelopeTask はエンベロープから復元されたTaskです。
+type EnvelopeTask struct {
+	envelope *Envelope
+	fn       func(ctx context.Context) error
+}
+
+// Execute は登録されたハンドラーをデコード済みのペイロードで実行します。
+func (t *EnvelopeTask) Execute(ctx context.Context) error {
+	return t.fn(ctx)
+}
+
+// TaskType はタスク種別名を返します。
+func (t *EnvelopeTask) TaskType() string {
+	return t.envelope.Type
+}
+
+// DeadLetterBody は元のエンベロープをメッセージ本文の形式で返します。
+func (t *EnvelopeTask) DeadLetterBody() (string, error) {
+	return t.envelope.Marshal()
+}
+
+// Envelope は元のエンベロープを返します。
+func (t *EnvelopeTask) Envelope() *Envelope {
+	return t.envelope
+}
@@ -0,0 +1,127 @@
+package tasks_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/worker/tasks"
+)
+
+type userSyncPayload struct {
+	UserID string `json:"user_id"`
+}
+
+// TestRegistry_RoundTrip はエンベロープの送信形式からTaskを復元して実行できることを確認します。
+func TestRegistry_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	registry := tasks.NewRegistry()
+	var got userSyncPayload
+	if err := tasks.Register(registry, "user.sync", func(ctx context.Context, p userSyncPayload) error {
+		got = p
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to register handler: %v", err)
+	}
+
+	envelope, err := tasks.NewEnvelope("user.sync", userSyncPayload{UserID: "user-1"}, tasks.WithTraceID("trace-1"))
+	if err != nil {
+		t.Fatalf("failed to create envelope: %v", err)
+	}
+	body, err := envelope.Marshal()
+	if err != nil {
+		t.Fatalf("failed to marshal envelope: %v", err)
+	}
+
+	task, err := registry.MessageHandler()(types.Message{MessageId: aws.String("msg-1"), Body: aws.String(body)})
+	if err != nil {
+		t.Fatalf("failed to decode message: %v", err)
+	}
+	if err := task.Execute(context.Background()); err != nil {
+		t.Fatalf("failed to execute task: %v", err)
+	}
+	if got.UserID != "user-1" {
+		t.Errorf("expected payload user_id 'user-1', got %q", got.UserID)
+	}
+
+	decoded := task.(*tasks.EnvelopeTask).Envelope()
+	if task.(*tasks.EnvelopeTask).TaskType() != "user.sync" {
+		t.Errorf("expected type 'user.sync', got %q", decoded.Type)
+	}
+	if decoded.ID != envelope.ID || decoded.TraceID != "trace-1" || decoded.Version != tasks.EnvelopeVersion {
+		t.Errorf("envelope fields were not preserved: %+v", decoded)
+	}
+	if !decoded.EnqueuedAt.Equal(envelope.EnqueuedAt) {
+		t.Errorf("expected enqueued_at %v, got %v", envelope.EnqueuedAt, decoded.EnqueuedAt)
+	}
+}
+
+// TestRegistry_DecodeErrors はデコード失敗の種類を errors.Is で判別できることを確認します。
+func TestRegistry_DecodeErrors(t *testing.T) {
+	t.Parallel()
+
+	registry := tasks.NewRegistry()
+	tasks.MustRegister(registry, "user.sync", func(ctx context.Context, p userSyncPayload) error { return nil })
+
+	tests := []struct {
+		name        string
+		body        string
+		expectedErr error
+	}{
+		{
+			name:        "異常系: 未登録のタスク種別",
+			body:        `{"version":1,"type":"user.unknown","payload":{}}`,
+			expectedErr: tasks.ErrUnknownTaskType,
+		},
+		{
+			name:        "異常系: ペイロードの型が一致しない",
+			body:        `{"version":1,"type":"user.sync","payload":{"user_id":123}}`,
+			expectedErr: tasks.ErrInvalidPayload,
+		},
+		{
+			name:        "異常系: JSONとして不正な本文",
+			body:        `{"task":`,
+			expectedErr: tasks.ErrInvalidEnvelope,
+		},
+		{
+			name:        "異常系: タスク種別が空",
+			body:        `{"version":1,"payload":{}}`,
+			expectedErr: tasks.ErrInvalidEnvelope,
+		},
+		{
+			name:        "異常系: 未対応のバージョン",
+			body:        `{"version":99,"type":"user.sync","payload":{}}`,
+			expectedErr: tasks.ErrUnsupportedVersion,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := registry.Decode(tt.body)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("expected error %v, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+// TestRegister_Duplicate は同じタスク種別の二重登録がエラーになることを確認します。
+func TestRegister_Duplicate(t *testing.T) {
+	t.Parallel()
+
+	registry := tasks.NewRegistry()
+	handler := func(ctx context.Context, p json.RawMessage) error { return nil }
+	if err := tasks.Register(registry, "user.sync", handler); err != nil {
+		t.Fatalf("failed to register handler: %v", err)
+	}
+	if err := tasks.Register(registry, "user.sync", handler); !errors.Is(err, tasks.ErrDuplicateTaskType) {
+		t.Errorf("expected ErrDuplicateTaskType, got %v", err)
+	}
+}
@@ -4,50 +4,22 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
 
-	"golang.org/x/sync/semaphore"
+	"github.com/takeuchi-shogo/golang-learn/app/backend/pkg/metrics"
 )
 
 var (
 	ErrJobQueueFull = errors.New("job queue is full")
 )
 
-type WorkerStatus int
-
-func (w WorkerStatus) String() string {
-	switch w {
-	case WorkerStatusRunning:
-		return "running"
-	case WorkerStatusProcessing:
-		return "processing"
-	case WorkerStatusStopped:
-		return "stopped"
-	}
-	return "unknown"
-}
-
-func (w WorkerStatus) IsRunning() bool {
-	return w == WorkerStatusRunning
-}
-
-func (w WorkerStatus) IsProcessing() bool {
-	return w == WorkerStatusProcessing
-}
-
-func (w WorkerStatus) IsStopped() bool {
-	return w == WorkerStatusStopped
-}
-
 const (
 	defaultWorkerName     = "default-worker"
 	defaultMinWorkerJobs  = 1
 	defaultMaxWorkerJobs  = 100
 	defaultRunningWorkers = 5
-	defaultWorkerStatus   = WorkerStatusRunning
-
-	WorkerStatusRunning WorkerStatus = iota
-	WorkerStatusProcessing
-	WorkerStatusStopped
 )
 
 type WorkerOption func(*worker)
@@ -93,16 +65,23 @@ func WithRunningWorkers(runningWorkers int) WorkerOption {
 
 type Worker interface {
 	Run(ctx context.Context) error
-	AddJob(tasks ...Task)
+	AddJob(tasks ...Task) error
 	AddJobAsync(tasks ...Task) error
+	AddJobWithPriority(priority Priority, tasks ...Task) error
+	AddJobAsyncWithPriority(priority Priority, tasks ...Task) error
 	Shutdown(ctx context.Context) error
 	Status() WorkerStatus
-	QueueLength() int
+	QueueLengths() map[Priority]int
 	IsRunning() bool
+	Resize(n int) int
+	Stats() PoolStats
+	Submit(ctx context.Context, tasks []Task, opts ...JobOption) (*JobHandle, error)
 }
 
 type job struct {
 	task []Task
+	// handle は Submit で投入した場合のみ設定され、全タスクの完了時に結果を通知する
+	handle *JobHandle
 }
 
 type Task interface {
@@ -110,24 +89,42 @@ type Task interface {
 }
 
 type worker struct {
-	name           string
-	minWorkerJobs  int
-	maxWorkerJobs  int
-	status         WorkerStatus
-	runningWorkers int
-	sem            *semaphore.Weighted
-	jobQueue       chan job
-	running        bool
+	name              string
+	minWorkerJobs     int
+	maxWorkerJobs     int
+	runningWorkers    int
+	pool              *pool
+	autoscale         *AutoscalePolicy
+	scaleHooks        []ScaleHook
+	lanes             *lanes
+	laneWeights       [numPriorities]int
+	retryPolicy       RetryPolicy
+	taskRetryPolicies map[string]RetryPolicy
+	resultHooks       []ResultHook
+	deadLetterSink    DeadLetterSink
+	taskTimeout       time.Duration
+	taskTimeouts      map[string]time.Duration
+	// serial は同時実行数を1に固定し、ジョブを投入順に実行する
+	serial bool
+	// inFlight は実行中(再試行待ちを含む)のジョブ数を追跡する
+	inFlight *sync.WaitGroup
+	// state はライフサイクルの状態を管理する
+	state *lifecycle
+	// metrics は WithMetrics で指定したメトリクスの登録先
+	metrics *metrics.Registry
 }
 
 var defaultWorker = worker{
 	name:           defaultWorkerName,
 	minWorkerJobs:  defaultMinWorkerJobs,
 	maxWorkerJobs:  defaultMaxWorkerJobs,
-	status:         defaultWorkerStatus,
 	runningWorkers: defaultRunningWorkers,
-	sem:            semaphore.NewWeighted(int64(defaultRunningWorkers)),
-	jobQueue:       make(chan job, defaultMaxWorkerJobs),
+	laneWeights: [numPriorities]int{
+		PriorityHigh:   defaultHighWeight,
+		PriorityNormal: defaultNormalWeight,
+		PriorityLow:    defaultLowWeight,
+	},
+	retryPolicy: DefaultRetryPolicy.normalize(),
 }
 
 func NewWorker(opts ...WorkerOption) Worker {
@@ -135,95 +132,262 @@ func NewWorker(opts ...WorkerOption) Worker {
 	for _, opt := range opts {
 		opt(&options)
 	}
-	return &worker{
-		name:           options.name,
-		minWorkerJobs:  options.minWorkerJobs,
-		maxWorkerJobs:  options.maxWorkerJobs,
-		status:         options.status,
-		runningWorkers: options.runningWorkers,
-		sem:            semaphore.NewWeighted(int64(options.runningWorkers)),
-		jobQueue:       make(chan job, options.maxWorkerJobs),
+	// 同時実行数は minWorkerJobs から maxWorkerJobs の範囲で、runningWorkers から開始する
+	options.minWorkerJobs = min(options.minWorkerJobs, options.maxWorkerJobs)
+	options.runningWorkers = min(max(options.runningWorkers, options.minWorkerJobs), options.maxWorkerJobs)
+	if options.serial {
+		options.runningWorkers = 1
+		options.autoscale = nil
+	}
+	w := &worker{
+		name:              options.name,
+		minWorkerJobs:     options.minWorkerJobs,
+		maxWorkerJobs:     options.maxWorkerJobs,
+		runningWorkers:    options.runningWorkers,
+		lanes:             newLanes(options.maxWorkerJobs, options.laneWeights),
+		laneWeights:       options.laneWeights,
+		pool:              newPool(options.runningWorkers),
+		autoscale:         options.autoscale,
+		scaleHooks:        options.scaleHooks,
+		retryPolicy:       options.retryPolicy,
+		taskRetryPolicies: options.taskRetryPolicies,
+		resultHooks:       options.resultHooks,
+		deadLetterSink:    options.deadLetterSink,
+		taskTimeout:       options.taskTimeout,
+		taskTimeouts:      options.taskTimeouts,
+		serial:            options.serial,
+		inFlight:          &sync.WaitGroup{},
+		state:             newLifecycle(),
+	}
+	if options.metrics != nil {
+		w.registerMetrics(options.metrics)
 	}
+	return w
 }
 
 func (w *worker) processJob(ctx context.Context, job *job) {
-	if err := w.sem.Acquire(ctx, 1); err != nil {
-		slog.Error("failed to acquire semaphore", "error", err)
-		return
-	}
-	defer w.sem.Release(1)
+	defer w.inFlight.Done()
+	results := make([]TaskResult, 0, len(job.task))
+	defer func() {
+		// タスクのpanicは executeOnce で回復するため、ここに来るのはフックなどでpanicした場合
+		if r := recover(); r != nil {
+			slog.Error("job processing panicked", "name", w.name, "panic", r, "stack", string(debug.Stack()))
+		}
+		if job.handle != nil {
+			// 中断により結果のないタスクは実行されなかったものとして扱う
+			for _, task := range job.task[len(results):] {
+				results = append(results, TaskResult{Task: task, TaskType: taskTypeOf(task), Err: ErrJobDiscarded})
+			}
+			job.handle.complete(results)
+		}
+	}()
+
+	// 同時実行枠は dispatchLoop で確保済み
+	start := time.Now()
+	defer func() {
+		w.pool.observe(time.Since(start))
+		w.pool.release()
+	}()
 
 	for _, task := range job.task {
-		if err := task.Execute(ctx); err != nil {
-			slog.Error("task execution failed", "error", err)
-			continue
+		taskStart := time.Now()
+		result := w.executeWithRetry(ctx, task)
+		result.Duration = time.Since(taskStart)
+		results = append(results, w.complete(ctx, result))
+	}
+}
+
+// executeWithRetry はタスクを再試行方針に従って実行し、最終結果を返す
+func (w *worker) executeWithRetry(ctx context.Context, task Task) TaskResult {
+	taskType := taskTypeOf(task)
+	policy := w.retryPolicyFor(taskType)
+	timeout := w.taskTimeoutFor(taskType)
+
+	for attempt := 1; ; attempt++ {
+		err := executeOnce(ctx, task, timeout)
+		if err == nil {
+			return TaskResult{Task: task, TaskType: taskType, Attempts: attempt}
+		}
+		if attempt >= policy.MaxAttempts || !policy.Retryable(err) {
+			return TaskResult{Task: task, TaskType: taskType, Attempts: attempt, Err: err}
+		}
+
+		backoff := policy.Backoff(attempt)
+		slog.Warn("task execution failed, retrying", "name", w.name, "taskType", taskType, "attempt", attempt, "maxAttempts", policy.MaxAttempts, "backoff", backoff, "error", err)
+		if !sleepContext(ctx, backoff) {
+			return TaskResult{Task: task, TaskType: taskType, Attempts: attempt, Err: errors.Join(err, ctx.Err())}
 		}
-		slog.Info("task executed successfully")
 	}
 }
 
-func (w *worker) AddJob(tasks ...Task) {
-	w.jobQueue <- job{task: tasks}
+// retryPolicyFor はタスク種別に適用する再試行方針を返す
+func (w *worker) retryPolicyFor(taskType string) RetryPolicy {
+	if policy, ok := w.taskRetryPolicies[taskType]; ok {
+		return policy
+	}
+	return w.retryPolicy
 }
 
-func (w *worker) AddJobAsync(tasks ...Task) error {
-	select {
-	case w.jobQueue <- job{task: tasks}:
-		return nil
-	default:
-		return ErrJobQueueFull
+// complete はタスクの最終結果をログに記録し、フックとタスク自身に通知する
+// 戻り値はデッドレターへの送信結果を反映した最終結果
+func (w *worker) complete(ctx context.Context, result TaskResult) TaskResult {
+	var panicErr *PanicError
+	if errors.As(result.Err, &panicErr) {
+		slog.Error("task execution failed", "name", w.name, "taskType", result.TaskType, "attempts", result.Attempts, "permanent", IsPermanent(result.Err), "error", result.Err, "stack", string(panicErr.Stack))
+	} else if result.Err != nil {
+		slog.Error("task execution failed", "name", w.name, "taskType", result.TaskType, "attempts", result.Attempts, "permanent", IsPermanent(result.Err), "error", result.Err)
+	} else {
+		slog.Info("task executed successfully", "name", w.name, "taskType", result.TaskType, "attempts", result.Attempts)
+	}
+	if result.Err != nil {
+		result.DeadLettered = w.deadLetter(ctx, result)
 	}
+
+	for _, hook := range w.resultHooks {
+		hook(ctx, result)
+	}
+	if completer, ok := result.Task.(TaskCompleter); ok {
+		completer.OnComplete(ctx, result)
+	}
+	return result
 }
 
-func (w *worker) Status() WorkerStatus {
-	return w.status
+// deadLetter は失敗したタスクをDeadLetterSinkに送信し、送信できたかどうかを返す
+// コンテキストのキャンセルで中断されたタスクは失敗が確定していないため送信しない
+func (w *worker) deadLetter(ctx context.Context, result TaskResult) bool {
+	if w.deadLetterSink == nil || ctx.Err() != nil {
+		return false
+	}
+	deadLetter := newDeadLetter(w.name, result)
+	if err := w.deadLetterSink.Send(ctx, deadLetter); err != nil {
+		slog.Error("failed to send dead letter", "name", w.name, "taskType", result.TaskType, "error", err)
+		return false
+	}
+	slog.Warn("task moved to dead letter sink", "name", w.name, "taskType", result.TaskType, "deadLetterID", deadLetter.ID, "attempts", result.Attempts)
+	return true
 }
 
-func (w *worker) QueueLength() int {
-	return len(w.jobQueue)
+// AddJob はジョブを通常優先度のキューに追加します。キューが満杯の場合は空きができるまで待機します。
+// 停止処理が始まっている場合、または待機中に停止処理が始まった場合は ErrWorkerStopped を返します。
+func (w *worker) AddJob(tasks ...Task) error {
+	return w.AddJobWithPriority(PriorityNormal, tasks...)
+}
+
+// AddJobAsync はジョブを通常優先度のキューに追加します。キューが満杯の場合は ErrJobQueueFull を返します。
+// 停止処理が始まっている場合は ErrWorkerStopped を返します。
+func (w *worker) AddJobAsync(tasks ...Task) error {
+	return w.AddJobAsyncWithPriority(PriorityNormal, tasks...)
+}
+
+func (w *worker) Status() WorkerStatus {
+	return w.state.load()
 }
 
 func (w *worker) IsRunning() bool {
-	return w.running
+	return w.Status().IsRunning()
+}
+
+// beginDrain は draining 状態に遷移し、新しいジョブの受け付けを止めます。
+// 戻り値は遷移前の状態です。既に停止処理が始まっている場合は何もしません。
+func (w *worker) beginDrain() WorkerStatus {
+	for {
+		status := w.state.load()
+		if status != WorkerStatusCreated && status != WorkerStatusRunning {
+			return status
+		}
+		if !w.state.transition(status, WorkerStatusDraining) {
+			continue
+		}
+		// 送信待ちの AddJob を解放してから、送信中のものがなくなった時点でキューを閉じる
+		close(w.state.draining)
+		w.state.sendMu.Lock()
+		w.lanes.close()
+		w.state.sendMu.Unlock()
+		return status
+	}
 }
 
+// Shutdown は新しいジョブの受け付けを止め、キューに残ったジョブと実行中のジョブの完了を待機します。
+// 複数回呼び出しても安全で、2回目以降も停止の完了を待機します。
 func (w *worker) Shutdown(ctx context.Context) error {
-	w.running = false
-	close(w.jobQueue)
+	if prev := w.beginDrain(); prev == WorkerStatusCreated {
+		// Run されていないため、キューに残ったジョブを処理するゴルーチンが存在しない
+		if n := w.discardQueued(); n > 0 {
+			slog.Warn("worker shutdown before run, discarding queued jobs", "name", w.name, "jobs", n)
+		}
+		w.state.markStopped()
+	}
 
-	// セマフォが解放されるまで待機
-	if err := w.sem.Acquire(ctx, int64(w.runningWorkers)); err != nil {
-		return err
+	select {
+	case <-w.state.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	defer w.sem.Release(int64(w.runningWorkers))
+}
 
-	w.status = WorkerStatusStopped
-	slog.Info("worker shutdown completed", "name", w.name)
-	return nil
+// discardQueued はキューに残ったジョブを取り出し、Submit の呼び出し元に ErrJobDiscarded を通知します。
+// beginDrain の後に呼び出すこと。戻り値は破棄したジョブ数です。
+func (w *worker) discardQueued() int {
+	jobs := w.lanes.drain()
+	for _, j := range jobs {
+		j.discard()
+	}
+	return len(jobs)
 }
 
+// Run はWorkerを起動し、バックグラウンドでジョブキューの監視を開始します。
+// 起動済みの場合は ErrWorkerAlreadyRunning、停止処理が始まっている場合は ErrWorkerStopped を返します。
+// ctx がキャンセルされた場合はキューに残ったジョブを破棄して停止します。
 func (w *worker) Run(ctx context.Context) error {
-	w.running = true
-	w.status = WorkerStatusRunning
-	slog.Info("worker started", "name", w.name, "minWorkerJobs", w.minWorkerJobs, "maxWorkerJobs", w.maxWorkerJobs, "runningWorkers", w.runningWorkers)
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				w.running = false
-				w.status = WorkerStatusStopped
-				slog.Info("worker stopped", "name", w.name)
-				return
-			case job, ok := <-w.jobQueue:
-				if !ok {
-					w.running = false
-					w.status = WorkerStatusStopped
-					slog.Info("job queue closed", "name", w.name)
-					return
-				}
-				go w.processJob(ctx, &job)
-			}
+	if !w.state.transition(WorkerStatusCreated, WorkerStatusRunning) {
+		if w.state.load() == WorkerStatusRunning {
+			return ErrWorkerAlreadyRunning
 		}
-	}()
+		return ErrWorkerStopped
+	}
+	slog.Info("worker started", "name", w.name, "minWorkerJobs", w.minWorkerJobs, "maxWorkerJobs", w.maxWorkerJobs, "runningWorkers", w.runningWorkers, "autoscale", w.autoscale != nil)
+	go w.dispatchLoop(ctx)
+	if w.autoscale != nil {
+		go w.autoscaleLoop(ctx)
+	}
 	return nil
 }
+
+// dispatchLoop は同時実行枠が空くたびに、優先度ごとのキューから重みに従ってジョブを取り出して実行します。
+// キューが閉じられて空になるか ctx がキャンセルされると、実行中のジョブを待って stopped に遷移します。
+func (w *worker) dispatchLoop(ctx context.Context) {
+	defer func() {
+		w.inFlight.Wait()
+		w.state.markStopped()
+		slog.Info("worker stopped", "name", w.name)
+	}()
+
+	canceled := func() {
+		w.beginDrain()
+		if n := w.discardQueued(); n > 0 {
+			slog.Warn("worker context canceled, discarding queued jobs", "name", w.name, "jobs", n)
+		}
+	}
+
+	for {
+		// 枠を確保してからジョブを取り出すことで、処理待ちのジョブはキューに留まる
+		// (キューの滞留数が自動調整とバックプレッシャーの指標になる)
+		if err := w.pool.acquire(ctx); err != nil {
+			canceled()
+			return
+		}
+		job, ok, err := w.lanes.next(ctx)
+		if err != nil {
+			w.pool.release()
+			canceled()
+			return
+		}
+		if !ok {
+			w.pool.release()
+			return
+		}
+		w.inFlight.Add(1)
+		go w.processJob(ctx, &job)
+	}
+}
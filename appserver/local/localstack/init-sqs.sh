@@ -8,6 +8,8 @@
 # 作成されるキュー:
 #   - worker-queue: メインのワーカーキュー
 #   - worker-queue-dlq: Dead Letter Queue (処理失敗メッセージ用)
+#   - user-sync-queue.fifo: ユーザー同期イベント用のFIFOキュー (ユーザーごとに順序を保証)
+#   - user-sync-queue-dlq.fifo: FIFOキュー用の Dead Letter Queue
 #
 # 使用方法:
 #   このスクリプトはLocalStackの初期化ディレクトリに配置され、
@@ -28,10 +30,12 @@ echo "=========================================="
 REGION="ap-northeast-1"
 QUEUE_NAME="worker-queue"
 DLQ_NAME="worker-queue-dlq"
+FIFO_QUEUE_NAME="user-sync-queue.fifo"
+FIFO_DLQ_NAME="user-sync-queue-dlq.fifo"
 
 # Dead Letter Queue (DLQ) を作成
 # 処理に3回失敗したメッセージはここに移動される
-echo "[1/3] Dead Letter Queue を作成中: $DLQ_NAME"
+echo "[1/4] Dead Letter Queue を作成中: $DLQ_NAME"
 awslocal sqs create-queue \
     --queue-name "$DLQ_NAME" \
     --region "$REGION" \
@@ -51,7 +55,7 @@ echo "  DLQ ARN: $DLQ_ARN"
 # - MessageRetentionPeriod: 345600秒 (4日間保持)
 # - ReceiveMessageWaitTimeSeconds: 20秒 (ロングポーリング)
 # - RedrivePolicy: 3回失敗したらDLQへ
-echo "[2/3] メインキューを作成中: $QUEUE_NAME"
+echo "[2/4] メインキューを作成中: $QUEUE_NAME"
 awslocal sqs create-queue \
     --queue-name "$QUEUE_NAME" \
     --region "$REGION" \
@@ -65,8 +69,32 @@ awslocal sqs create-queue \
 QUEUE_URL=$(awslocal sqs get-queue-url --queue-name "$QUEUE_NAME" --region "$REGION" --query 'QueueUrl' --output text)
 echo "  Queue URL: $QUEUE_URL"
 
+# ユーザー同期イベント用のFIFOキューを作成
+# - MessageGroupId にユーザーIDを指定し、同じユーザーのイベントを送信順に処理する
+# - ContentBasedDeduplication: 重複排除IDを省略した場合は本文のハッシュで重複を排除する
+# - FIFOキューのDLQもFIFOキューである必要がある
+echo "[3/4] FIFOキューを作成中: $FIFO_QUEUE_NAME"
+awslocal sqs create-queue \
+    --queue-name "$FIFO_DLQ_NAME" \
+    --region "$REGION" \
+    --attributes '{
+        "FifoQueue": "true",
+        "MessageRetentionPeriod": "1209600"
+    }'
+FIFO_DLQ_ARN="arn:aws:sqs:$REGION:000000000000:$FIFO_DLQ_NAME"
+awslocal sqs create-queue \
+    --queue-name "$FIFO_QUEUE_NAME" \
+    --region "$REGION" \
+    --attributes "{
+        \"FifoQueue\": \"true\",
+        \"ContentBasedDeduplication\": \"true\",
+        \"VisibilityTimeout\": \"30\",
+        \"ReceiveMessageWaitTimeSeconds\": \"20\",
+        \"RedrivePolicy\": \"{\\\"deadLetterTargetArn\\\":\\\"$FIFO_DLQ_ARN\\\",\\\"maxReceiveCount\\\":\\\"3\\\"}\"
+    }"
+
 # 作成されたキューを一覧表示
-echo "[3/3] 作成されたキュー一覧:"
+echo "[4/4] 作成されたキュー一覧:"
 awslocal sqs list-queues --region "$REGION"
 
 echo ""
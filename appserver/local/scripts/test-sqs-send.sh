@@ -41,14 +41,24 @@ show_help() {
     echo "  $0 --batch 10             # 10件のメッセージを送信"
 }
 
+# タスクエンベロープ生成 (pkg/worker/tasks.Envelope と同じ形式)
+# 引数: タスク種別, ペイロード(JSON), 送信時刻
+envelope() {
+    local task_type=$1
+    local payload=$2
+    local enqueued_at=$3
+    local id=$(uuidgen 2>/dev/null || cat /proc/sys/kernel/random/uuid)
+    echo "{\"version\":1,\"id\":\"$id\",\"type\":\"$task_type\",\"payload\":$payload,\"attempt\":0,\"enqueued_at\":\"$enqueued_at\"}"
+}
+
 # シングルメッセージ送信
 send_single_message() {
     local message_body=$1
     local timestamp=$(date -Iseconds)
 
-    # デフォルトメッセージ
+    # デフォルトメッセージ (tasks.Envelope 形式)
     if [ -z "$message_body" ]; then
-        message_body="{\"task\":\"test\",\"message\":\"Hello from test script\",\"timestamp\":\"$timestamp\"}"
+        message_body=$(envelope "health.check" "{}" "$timestamp")
     fi
 
     echo -e "${BLUE}送信中...${NC}"
@@ -78,7 +88,7 @@ send_batch_messages() {
     echo ""
 
     for i in $(seq 1 $count); do
-        message_body="{\"task\":\"batch_test\",\"id\":$i,\"timestamp\":\"$timestamp\"}"
+        message_body=$(envelope "health.check" "{\"id\":$i}" "$timestamp")
 
         result=$(aws --endpoint-url="$ENDPOINT_URL" sqs send-message \
             --queue-url "$QUEUE_URL" \